@@ -0,0 +1,37 @@
+// Command mqtt-bridge runs the MQTT telemetry bridge as its own process, for
+// deployments that keep device traffic away from the API servers. It uses
+// the same MQTT_* and database settings as the API.
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"github.com/adipras/tirta-saas-backend/pkg/mqttbridge"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Settings may also come from the environment alone
+	_ = godotenv.Load()
+
+	cfg, ok := mqttbridge.ConfigFromEnv()
+	if !ok {
+		log.Fatal("MQTT_BROKER_URL is not set")
+	}
+
+	logger.Init("INFO")
+	config.ConnectDB()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	bridge := &mqttbridge.Bridge{DB: config.DB, Config: cfg}
+	if err := bridge.Run(ctx); err != nil {
+		log.Fatalf("MQTT bridge stopped: %v", err)
+	}
+}
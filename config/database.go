@@ -42,6 +42,9 @@ func Migrate() {
 	// Migration order is important due to foreign key constraints
 	// 1. Base entities first (no dependencies)
 	// 2. Entities with foreign keys last
+	// The customer status backfill only runs on the migration that adds the column
+	needsStatusBackfill := !DB.Migrator().HasColumn(&models.Customer{}, "Status")
+
 	err := DB.AutoMigrate(
 		// Phase 1-4: Core Models
 		&models.Tenant{},                     // No dependencies
@@ -78,6 +81,27 @@ func Migrate() {
 		&models.MeterHistory{},               // References Tenant + Meter + Customer + User
 		&models.ReadingSession{},             // References Tenant + ReadingRoute + User
 		&models.ReadingAnomaly{},             // References Tenant + WaterUsage + User
+		&models.CustomerCredit{},             // References Tenant + Customer + Payment
+		&models.CashSession{},                // References Tenant + User
+		&models.CashSessionTotal{},           // References Tenant + CashSession + PaymentMethod
+		&models.SyncItem{},                   // References Tenant
+		&models.PaymentMandate{},             // References Tenant + Customer + PaymentMethod
+		&models.AutoDebitAttempt{},           // References Tenant + PaymentMandate + Invoice
+		&models.AutoDebitRun{},               // References Tenant
+		&models.ReceiptSequence{},            // References Tenant
+		&models.PaymentReceipt{},             // Archived receipt snapshot, no FK to Payment
+		&models.CalibrationPolicy{},          // References Tenant
+		&models.AnomalyDetectionConfig{},     // References Tenant
+		&models.MeterPhoto{},                 // References Tenant + WaterUsage + User
+		&models.MeterDevice{},                // References Tenant + Meter
+		&models.MeterInterval{},              // References Tenant + Meter + MeterDevice
+		&models.LeakDetectionConfig{},        // References Tenant
+		&models.BulkMeter{},                  // References Tenant + ServiceArea
+		&models.BulkMeterReading{},           // References Tenant + BulkMeter
+		&models.CustomerStatusHistory{},      // References Tenant + Customer + User
+		&models.ConnectionApplication{},      // References Tenant + SubscriptionType + ServiceArea + Customer
+		&models.ServiceOrder{},               // References Tenant + Customer + Meter + User
+		&models.DisconnectionPolicy{},        // References Tenant
 	)
 
 	if err != nil {
@@ -85,6 +109,10 @@ func Migrate() {
 	}
 
 	log.Println("✅ Migrasi database selesai.")
+
+	if needsStatusBackfill {
+		backfillCustomerStatus(DB)
+	}
 	
 	// Apply database optimizations after migration
 	if err := OptimizeDatabase(DB); err != nil {
@@ -151,3 +179,32 @@ func initializeDefaultPermissions(db *gorm.DB) {
 	
 	log.Println("✅ Default permissions initialized")
 }
+
+// backfillCustomerStatus maps customers created before the lifecycle existed
+// onto a status, once: active customers become active, inactive ones that were
+// ever connected (a paid registration or any monthly bill) were deactivated
+// since and become suspended, the rest stay applicants
+func backfillCustomerStatus(db *gorm.DB) {
+	result := db.Model(&models.Customer{}).
+		Where("is_active = ? AND status = ?", true, models.CustomerStatusApplicant).
+		Update("status", models.CustomerStatusActive)
+	if result.Error != nil {
+		log.Printf("⚠️ Failed to backfill customer status: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("✅ %d active customers given the active status", result.RowsAffected)
+	}
+
+	connected := db.Model(&models.Invoice{}).
+		Select("customer_id").
+		Where("(type = ? AND is_paid = ?) OR type = ?", "registration", true, "monthly")
+	result = db.Model(&models.Customer{}).
+		Where("is_active = ? AND status = ? AND id IN (?)", false, models.CustomerStatusApplicant, connected).
+		Update("status", models.CustomerStatusSuspended)
+	if result.Error != nil {
+		log.Printf("⚠️ Failed to backfill customer status: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("✅ %d deactivated customers given the suspended status", result.RowsAffected)
+	}
+}
@@ -265,7 +265,11 @@ func CustomerLogin(c *gin.Context) {
 		return
 	}
 
-	if !customer.IsActive {
+	if customer.Status == models.CustomerStatusClosed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Akun sudah ditutup"})
+		return
+	}
+	if !customer.CanSignIn() {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Akun belum aktif. Silakan lakukan pembayaran pendaftaran terlebih dahulu"})
 		return
 	}
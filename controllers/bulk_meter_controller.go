@@ -0,0 +1,388 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BulkMeterController struct {
+	DB *gorm.DB
+}
+
+func NewBulkMeterController(db *gorm.DB) *BulkMeterController {
+	return &BulkMeterController{DB: db}
+}
+
+// GetBulkMeters godoc
+// @Summary List bulk meters
+// @Description List the tenant's bulk (district) meters with their latest reading
+// @Tags Water Balance
+// @Produce json
+// @Param service_area_id query string false "Only this service area and the areas below it"
+// @Param status query string false "active or inactive"
+// @Security BearerAuth
+// @Success 200 {array} responses.BulkMeterResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/bulk-meters [get]
+func (ctrl *BulkMeterController) GetBulkMeters(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Preload("ServiceArea").Where("tenant_id = ?", tenantID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if areaParam := c.Query("service_area_id"); areaParam != "" {
+		areaID, err := uuid.Parse(areaParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service area ID"})
+			return
+		}
+		areaIDs, err := helpers.ServiceAreaTreeIDs(ctrl.DB, tenantID, areaID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service areas"})
+			return
+		}
+		query = query.Where("service_area_id IN ?", areaIDs)
+	}
+
+	var meters []models.BulkMeter
+	if err := query.Order("meter_number ASC").Find(&meters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bulk meters"})
+		return
+	}
+
+	meterResponses := make([]responses.BulkMeterResponse, len(meters))
+	for i := range meters {
+		meterResponses[i] = responses.ToBulkMeterResponse(&meters[i])
+		var last models.BulkMeterReading
+		if ctrl.DB.Where("bulk_meter_id = ?", meters[i].ID).Order("reading_month DESC").First(&last).Error == nil {
+			lastResponse := responses.ToBulkMeterReadingResponse(&last)
+			meterResponses[i].LastReading = &lastResponse
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": meterResponses, "total": len(meterResponses)})
+}
+
+// CreateBulkMeter godoc
+// @Summary Register bulk meter
+// @Description Register a bulk (district) meter measuring the water supplied into a service area
+// @Tags Water Balance
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateBulkMeterRequest true "Create bulk meter request"
+// @Security BearerAuth
+// @Success 201 {object} responses.BulkMeterResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/bulk-meters [post]
+func (ctrl *BulkMeterController) CreateBulkMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreateBulkMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	area, ok := ctrl.findArea(c, tenantID, req.ServiceAreaID)
+	if !ok {
+		return
+	}
+
+	installDate, err := time.Parse("2006-01-02", req.InstallDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid install_date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	var existing int64
+	ctrl.DB.Model(&models.BulkMeter{}).Where("tenant_id = ? AND meter_number = ?", tenantID, req.MeterNumber).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bulk meter number already exists"})
+		return
+	}
+
+	meter := models.BulkMeter{
+		TenantID:       tenantID,
+		ServiceAreaID:  area.ID,
+		MeterNumber:    req.MeterNumber,
+		Name:           req.Name,
+		Brand:          req.Brand,
+		Model:          req.Model,
+		InstallDate:    installDate,
+		InitialReading: req.InitialReading,
+		Status:         models.BulkMeterStatusActive,
+		Notes:          req.Notes,
+	}
+	if err := ctrl.DB.Omit("ServiceArea").Create(&meter).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bulk meter"})
+		return
+	}
+	meter.ServiceArea = *area
+
+	response := responses.ToBulkMeterResponse(&meter)
+	audit.LogCreate(c, "bulk_meter", meter.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Bulk meter created successfully", "data": response})
+}
+
+// GetBulkMeter godoc
+// @Summary Get bulk meter
+// @Description Get a bulk meter with its latest reading
+// @Tags Water Balance
+// @Produce json
+// @Param id path string true "Bulk meter ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.BulkMeterResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/bulk-meters/{id} [get]
+func (ctrl *BulkMeterController) GetBulkMeter(c *gin.Context) {
+	meter, ok := ctrl.findBulkMeter(c)
+	if !ok {
+		return
+	}
+
+	response := responses.ToBulkMeterResponse(meter)
+	var last models.BulkMeterReading
+	if ctrl.DB.Where("bulk_meter_id = ?", meter.ID).Order("reading_month DESC").First(&last).Error == nil {
+		lastResponse := responses.ToBulkMeterReadingResponse(&last)
+		response.LastReading = &lastResponse
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// UpdateBulkMeter godoc
+// @Summary Update bulk meter
+// @Description Move a bulk meter to another service area, rename it or (de)activate it. Omitted fields keep their current value.
+// @Tags Water Balance
+// @Accept json
+// @Produce json
+// @Param id path string true "Bulk meter ID"
+// @Param request body requests.UpdateBulkMeterRequest true "Update bulk meter request"
+// @Security BearerAuth
+// @Success 200 {object} responses.BulkMeterResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/bulk-meters/{id} [put]
+func (ctrl *BulkMeterController) UpdateBulkMeter(c *gin.Context) {
+	meter, ok := ctrl.findBulkMeter(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdateBulkMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldValues := responses.ToBulkMeterResponse(meter)
+
+	if req.ServiceAreaID != nil {
+		area, ok := ctrl.findArea(c, meter.TenantID, *req.ServiceAreaID)
+		if !ok {
+			return
+		}
+		meter.ServiceAreaID = area.ID
+		meter.ServiceArea = *area
+	}
+	if req.Name != nil {
+		meter.Name = *req.Name
+	}
+	if req.Brand != nil {
+		meter.Brand = *req.Brand
+	}
+	if req.Model != nil {
+		meter.Model = *req.Model
+	}
+	if req.Status != nil {
+		meter.Status = *req.Status
+	}
+	if req.Notes != nil {
+		meter.Notes = *req.Notes
+	}
+
+	if err := ctrl.DB.Omit("ServiceArea").Save(meter).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update bulk meter"})
+		return
+	}
+
+	response := responses.ToBulkMeterResponse(meter)
+	audit.LogUpdate(c, "bulk_meter", meter.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bulk meter updated successfully", "data": response})
+}
+
+// DeleteBulkMeter godoc
+// @Summary Delete bulk meter
+// @Description Delete a bulk meter registered by mistake. Meters with readings are part of past water balances; set them inactive instead.
+// @Tags Water Balance
+// @Produce json
+// @Param id path string true "Bulk meter ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/bulk-meters/{id} [delete]
+func (ctrl *BulkMeterController) DeleteBulkMeter(c *gin.Context) {
+	meter, ok := ctrl.findBulkMeter(c)
+	if !ok {
+		return
+	}
+
+	var readings int64
+	ctrl.DB.Model(&models.BulkMeterReading{}).Where("bulk_meter_id = ?", meter.ID).Count(&readings)
+	if readings > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bulk meter has readings; set it inactive instead"})
+		return
+	}
+
+	if err := ctrl.DB.Delete(meter).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bulk meter"})
+		return
+	}
+
+	audit.LogDelete(c, "bulk_meter", meter.ID, responses.ToBulkMeterResponse(meter))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bulk meter deleted successfully"})
+}
+
+// GetBulkMeterReadings godoc
+// @Summary List bulk meter readings
+// @Description List the monthly readings of a bulk meter, newest first
+// @Tags Water Balance
+// @Produce json
+// @Param id path string true "Bulk meter ID"
+// @Security BearerAuth
+// @Success 200 {array} responses.BulkMeterReadingResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/bulk-meters/{id}/readings [get]
+func (ctrl *BulkMeterController) GetBulkMeterReadings(c *gin.Context) {
+	meter, ok := ctrl.findBulkMeter(c)
+	if !ok {
+		return
+	}
+
+	var readings []models.BulkMeterReading
+	if err := ctrl.DB.Where("bulk_meter_id = ?", meter.ID).Order("reading_month DESC").Find(&readings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bulk meter readings"})
+		return
+	}
+
+	readingResponses := make([]responses.BulkMeterReadingResponse, len(readings))
+	for i := range readings {
+		readingResponses[i] = responses.ToBulkMeterReadingResponse(&readings[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": readingResponses, "total": len(readingResponses)})
+}
+
+// RecordBulkMeterReading godoc
+// @Summary Record bulk meter reading
+// @Description Record a bulk meter's end-of-month reading. Recording a month again corrects it, and the next month's volume is recounted.
+// @Tags Water Balance
+// @Accept json
+// @Produce json
+// @Param id path string true "Bulk meter ID"
+// @Param request body requests.RecordBulkMeterReadingRequest true "Bulk meter reading"
+// @Security BearerAuth
+// @Success 200 {object} responses.BulkMeterReadingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/bulk-meters/{id}/readings [post]
+func (ctrl *BulkMeterController) RecordBulkMeterReading(c *gin.Context) {
+	meter, ok := ctrl.findBulkMeter(c)
+	if !ok {
+		return
+	}
+
+	var req requests.RecordBulkMeterReadingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	readAt := time.Now()
+	if req.ReadAt != "" {
+		parsed, err := time.Parse(time.RFC3339, req.ReadAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid read_at format. Use RFC3339"})
+			return
+		}
+		readAt = parsed
+	}
+
+	var reading *models.BulkMeterReading
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reading, err = helpers.RecordBulkMeterReading(tx, meter, req.ReadingMonth, *req.ReadingEnd, &readAt, req.Notes, helpers.GetUserIDFromContext(c))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, helpers.ErrInvalidUsageMonth) || errors.Is(err, helpers.ErrMeterReadingNegative) ||
+			errors.Is(err, helpers.ErrMeterReadingBackward) || errors.Is(err, helpers.ErrBulkReadingAboveNext) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record bulk meter reading"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bulk meter reading recorded successfully", "data": responses.ToBulkMeterReadingResponse(reading)})
+}
+
+func (ctrl *BulkMeterController) findBulkMeter(c *gin.Context) (*models.BulkMeter, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	meterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bulk meter ID"})
+		return nil, false
+	}
+
+	var meter models.BulkMeter
+	if err := ctrl.DB.Preload("ServiceArea").Where("id = ? AND tenant_id = ?", meterID, tenantID).First(&meter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bulk meter not found"})
+		return nil, false
+	}
+	return &meter, true
+}
+
+func (ctrl *BulkMeterController) findArea(c *gin.Context, tenantID uuid.UUID, areaParam string) (*models.ServiceArea, bool) {
+	areaID, err := uuid.Parse(areaParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service area ID"})
+		return nil, false
+	}
+
+	var area models.ServiceArea
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", areaID, tenantID).First(&area).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Service area not found"})
+		return nil, false
+	}
+	return &area, true
+}
@@ -2,18 +2,25 @@ package controllers
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // BulkImportCustomers imports customers from CSV file
@@ -157,6 +164,10 @@ func BulkImportCustomers(c *gin.Context) {
 			Email:          email,
 			SubscriptionID: subscriptionType.ID,
 			IsActive:       isActive,
+			Status:         models.CustomerStatusApplicant,
+		}
+		if isActive {
+			customer.Status = models.CustomerStatusActive
 		}
 		
 		if err := config.DB.Create(&customer).Error; err != nil {
@@ -216,9 +227,8 @@ func BulkUpdateCustomers(c *gin.Context) {
 	var successCount, failureCount int
 	var errors []string
 	
-	// Allowed fields to update
+	// Allowed fields to update; status changes go through the customer lifecycle
 	allowedFields := map[string]bool{
-		"is_active": true,
 		"address":  true,
 		"phone":    true,
 		"email":    true,
@@ -288,31 +298,49 @@ func BulkActivateCustomers(c *gin.Context) {
 	}
 	
 	startTime := time.Now()
-	
-	result := config.DB.Model(&models.Customer{}).
-		Where("id IN ? AND tenant_id = ?", req.CustomerIDs, tenantID).
-		Updates(map[string]interface{}{
-			"is_active": true,
-		})
-	
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Status:  "error",
-			Message: "Failed to activate customers",
-			Error:   result.Error.Error(),
+	userID := helpers.GetUserIDFromContext(c)
+	var successCount int
+	var failures []string
+
+	// Each customer goes through the lifecycle so the change is recorded and
+	// disconnected customers are billed their reconnection fee
+	for _, customerID := range req.CustomerIDs {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var customer models.Customer
+			if err := tx.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+				return err
+			}
+			if customer.Status == models.CustomerStatusActive {
+				return nil
+			}
+			_, _, err := helpers.ChangeCustomerStatus(tx, &customer, helpers.CustomerStatusChange{
+				Status:    models.CustomerStatusActive,
+				Reason:    "Bulk activation",
+				ChangedBy: userID,
+			})
+			return err
 		})
-		return
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				failures = append(failures, fmt.Sprintf("Customer %s: not found", customerID))
+			} else {
+				failures = append(failures, fmt.Sprintf("Customer %s: %s", customerID, err.Error()))
+			}
+			continue
+		}
+		successCount++
 	}
 	
 	duration := time.Since(startTime)
 	
 	c.JSON(http.StatusOK, responses.SuccessResponse{
 		Status:  "success",
-		Message: fmt.Sprintf("Successfully activated %d customers", result.RowsAffected),
+		Message: fmt.Sprintf("Successfully activated %d customers", successCount),
 		Data: responses.BulkOperationResponse{
 			TotalRecords: len(req.CustomerIDs),
-			SuccessCount: int(result.RowsAffected),
-			FailureCount: len(req.CustomerIDs) - int(result.RowsAffected),
+			SuccessCount: successCount,
+			FailureCount: len(failures),
+			Errors:       failures,
 			ProcessedAt:  time.Now(),
 			DurationMs:   duration.Milliseconds(),
 		},
@@ -376,3 +404,358 @@ func ExportCustomers(c *gin.Context) {
 		}
 	}
 }
+
+// maxReadingImportSize is the largest meter reading import file accepted
+const maxReadingImportSize = 10 * 1024 * 1024
+
+// Reading import row status
+const (
+	readingImportOK      = "ok"
+	readingImportAnomaly = "anomaly"
+	readingImportError   = "error"
+)
+
+var (
+	errUnknownImportMeter = errors.New("Unknown meter number")
+
+	// errReadingImportRollback discards the readings of a dry run or of a file with errors
+	errReadingImportRollback = errors.New("reading import rolled back")
+)
+
+type readingImportRow struct {
+	meterNumber string
+	usageMonth  string
+	reading     float64
+	readAt      time.Time
+	notes       string
+	result      *responses.ReadingImportRowResult
+}
+
+// BulkImportReadings imports meter readings from a CSV or Excel file. Every
+// row is validated like a reading entered by hand; a dry run reports the
+// result without saving, otherwise the file is applied in one transaction
+// only when no row has errors.
+func BulkImportReadings(c *gin.Context) {
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "No file uploaded",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if file.Size > maxReadingImportSize {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "File too large",
+			Error:   fmt.Sprintf("Maximum file size is %d MB", maxReadingImportSize/(1024*1024)),
+		})
+		return
+	}
+
+	records, err := readImportFile(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to read file",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Empty file",
+			Error:   "The file has no header row",
+		})
+		return
+	}
+
+	// Headers such as "Meter Number" and "meter_number" are the same column
+	headerMap := make(map[string]int)
+	for i, header := range records[0] {
+		header = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+		headerMap[strings.NewReplacer(" ", "_", "-", "_").Replace(header)] = i
+	}
+	for _, required := range []string{"meter_number", "usage_month", "reading"} {
+		if _, exists := headerMap[required]; !exists {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Status:  "error",
+				Message: "Missing required header",
+				Error:   fmt.Sprintf("Required header '%s' not found", required),
+			})
+			return
+		}
+	}
+
+	startTime := time.Now()
+	var rows []*readingImportRow
+	for i, record := range records[1:] {
+		row := parseReadingImportRow(record, headerMap)
+		if row == nil {
+			continue
+		}
+		row.result.Line = i + 2
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Empty file",
+			Error:   "The file has no readings",
+		})
+		return
+	}
+
+	// Earlier months first, so a meter's readings in one file follow each other
+	ordered := make([]*readingImportRow, 0, len(rows))
+	for _, row := range rows {
+		if row.result.Status != readingImportError {
+			ordered = append(ordered, row)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].usageMonth < ordered[j].usageMonth
+	})
+
+	userID := helpers.GetUserIDFromContext(c)
+	report := responses.ReadingImportResponse{DryRun: dryRun, TotalRecords: len(rows)}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, row := range ordered {
+			if err := importReading(tx, tenantID, userID, row); err != nil {
+				return err
+			}
+		}
+
+		for _, row := range rows {
+			switch row.result.Status {
+			case readingImportError:
+				report.ErrorCount++
+			case readingImportAnomaly:
+				report.AnomalyCount++
+			}
+		}
+		if dryRun || report.ErrorCount > 0 {
+			return errReadingImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errReadingImportRollback) {
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Status:  "error",
+			Message: "Failed to import readings",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	report.Applied = err == nil
+	report.ValidCount = report.TotalRecords - report.ErrorCount
+	report.Rows = make([]responses.ReadingImportRowResult, 0, len(rows))
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row.result)
+	}
+	report.ProcessedAt = time.Now()
+	report.DurationMs = time.Since(startTime).Milliseconds()
+
+	switch {
+	case dryRun:
+		c.JSON(http.StatusOK, responses.SuccessResponse{
+			Status:  "success",
+			Message: fmt.Sprintf("Validation completed: %d valid, %d with errors, %d anomalies", report.ValidCount, report.ErrorCount, report.AnomalyCount),
+			Data:    report,
+		})
+	case !report.Applied:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("Import rejected: %d rows with errors, no readings were saved", report.ErrorCount),
+			"data":    report,
+		})
+	default:
+		c.JSON(http.StatusOK, responses.SuccessResponse{
+			Status:  "success",
+			Message: fmt.Sprintf("Bulk import completed: %d readings saved, %d anomalies flagged", report.ValidCount, report.AnomalyCount),
+			Data:    report,
+		})
+	}
+}
+
+// readImportFile returns the rows of an uploaded .csv or .xlsx file
+func readImportFile(file *multipart.FileHeader) ([][]string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".csv":
+		reader := csv.NewReader(f)
+		reader.FieldsPerRecord = -1
+		return reader.ReadAll()
+	case ".xlsx":
+		return utils.ReadXLSXRows(f, file.Size)
+	default:
+		return nil, errors.New("Only CSV and Excel (.xlsx) files are allowed")
+	}
+}
+
+// parseReadingImportRow reads one data row, or returns nil for a blank row.
+// Rows that cannot be parsed come back with an error result.
+func parseReadingImportRow(record []string, headerMap map[string]int) *readingImportRow {
+	value := func(column string) string {
+		if idx, exists := headerMap[column]; exists && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+
+	blank := true
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			blank = false
+			break
+		}
+	}
+	if blank {
+		return nil
+	}
+
+	row := &readingImportRow{
+		meterNumber: value("meter_number"),
+		notes:       value("notes"),
+	}
+	row.result = &responses.ReadingImportRowResult{
+		MeterNumber: row.meterNumber,
+		UsageMonth:  value("usage_month"),
+		Status:      readingImportOK,
+	}
+	invalid := func(message string) *readingImportRow {
+		row.result.Status = readingImportError
+		row.result.Issue = "invalid"
+		row.result.Message = message
+		return row
+	}
+
+	if row.meterNumber == "" {
+		return invalid("Missing meter number")
+	}
+
+	month, ok := parseImportMonth(row.result.UsageMonth)
+	if !ok {
+		return invalid(helpers.ErrInvalidUsageMonth.Error())
+	}
+	row.usageMonth = month
+	row.result.UsageMonth = month
+
+	reading, err := strconv.ParseFloat(value("reading"), 64)
+	if err != nil {
+		// Decimal comma, as typed in Indonesian spreadsheets
+		reading, err = strconv.ParseFloat(strings.Replace(value("reading"), ",", ".", 1), 64)
+	}
+	if err != nil {
+		return invalid("Invalid reading")
+	}
+	row.reading = reading
+	row.result.Reading = &reading
+
+	if readAt := value("read_at"); readAt != "" {
+		date, err := time.ParseInLocation("2006-01-02", readAt, time.Local)
+		if err != nil {
+			return invalid("Invalid read_at format. Use YYYY-MM-DD")
+		}
+		row.readAt = date
+	}
+	return row
+}
+
+// parseImportMonth accepts YYYY-MM as typed, a full date, or the date serial
+// Excel stores when it turns a typed month into a date
+func parseImportMonth(value string) (string, bool) {
+	for _, layout := range []string{"2006-01", "2006-01-02", "01/2006"} {
+		if month, err := time.Parse(layout, value); err == nil {
+			return month.Format("2006-01"), true
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial >= 1 && serial < 100000 {
+		return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial)).Format("2006-01"), true
+	}
+	return "", false
+}
+
+// importReading records the reading of row inside tx. Rule violations are
+// reported on the row; only database errors are returned.
+func importReading(tx *gorm.DB, tenantID uuid.UUID, userID *uuid.UUID, row *readingImportRow) error {
+	result := row.result
+	customerID, meterID, err := resolveImportMeter(tx, tenantID, row.meterNumber)
+	if err == nil {
+		var usage *models.WaterUsage
+		usage, err = helpers.RecordWaterUsage(tx, helpers.WaterUsageInput{
+			TenantID:      tenantID,
+			CustomerID:    customerID,
+			MeterID:       meterID,
+			UsageMonth:    row.usageMonth,
+			MeterEnd:      row.reading,
+			Notes:         row.notes,
+			RecordedBy:    userID,
+			ReadingMethod: models.ReadingMethodManual,
+			ReadAt:        row.readAt,
+			// Paper readings have no photo yet; it can be attached afterwards
+			SkipPhotoPolicy: true,
+		})
+		if err == nil {
+			result.MeterStart = &usage.MeterStart
+			result.UsageM3 = &usage.UsageM3
+			if usage.AnomalyDetails != nil {
+				result.Status = readingImportAnomaly
+				result.Issue = usage.AnomalyDetails.AnomalyType
+				result.Message = fmt.Sprintf("Usage %.2f m³, expected about %.2f m³", usage.UsageM3, usage.AnomalyDetails.ExpectedValue)
+				result.ExpectedM3 = &usage.AnomalyDetails.ExpectedValue
+			}
+			return nil
+		}
+	}
+
+	switch {
+	case errors.Is(err, errUnknownImportMeter), errors.Is(err, helpers.ErrUsageMeterNotFound):
+		result.Issue = "unknown_meter"
+	case errors.Is(err, helpers.ErrMeterReadingBackward):
+		result.Issue = "backward_reading"
+	case errors.Is(err, helpers.ErrReadingAlreadyExists):
+		result.Issue = "duplicate"
+	case helpers.IsWaterUsageRuleError(err):
+		result.Issue = "invalid"
+	default:
+		return err
+	}
+	result.Status = readingImportError
+	result.Message = err.Error()
+	return nil
+}
+
+// resolveImportMeter finds the customer and meter of a meter number. Customers
+// without meter records are matched on their own meter number.
+func resolveImportMeter(tx *gorm.DB, tenantID uuid.UUID, meterNumber string) (uuid.UUID, *uuid.UUID, error) {
+	var meter models.Meter
+	err := tx.Where("tenant_id = ? AND meter_number = ?", tenantID, meterNumber).First(&meter).Error
+	if err == nil {
+		if meter.Status != models.MeterStatusActive {
+			return uuid.Nil, nil, helpers.ErrUsageMeterNotFound
+		}
+		return meter.CustomerID, &meter.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, nil, err
+	}
+
+	var customer models.Customer
+	err = tx.Select("id").Where("tenant_id = ? AND meter_number = ?", tenantID, meterNumber).First(&customer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, nil, errUnknownImportMeter
+	}
+	return customer.ID, nil, err
+}
@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/pkg/calibration"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CalibrationController struct {
+	DB *gorm.DB
+}
+
+func NewCalibrationController(db *gorm.DB) *CalibrationController {
+	return &CalibrationController{DB: db}
+}
+
+// GetCalibrationPolicies godoc
+// @Summary List calibration policies
+// @Description List the tenant's calibration intervals by meter brand and model
+// @Tags Meter Calibration
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} responses.CalibrationPolicyResponse
+// @Router /api/calibrations/policies [get]
+func (ctrl *CalibrationController) GetCalibrationPolicies(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var policies []models.CalibrationPolicy
+	if err := ctrl.DB.Where("tenant_id = ?", tenantID).Order("brand ASC, model ASC").Find(&policies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calibration policies"})
+		return
+	}
+
+	policyResponses := make([]responses.CalibrationPolicyResponse, len(policies))
+	for i := range policies {
+		policyResponses[i] = responses.ToCalibrationPolicyResponse(&policies[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policyResponses, "total": len(policyResponses)})
+}
+
+// CreateCalibrationPolicy godoc
+// @Summary Create calibration policy
+// @Description Set the calibration interval for a meter brand/model. Leave model empty for every model of the brand, and both empty for the tenant default.
+// @Tags Meter Calibration
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateCalibrationPolicyRequest true "Create calibration policy request"
+// @Security BearerAuth
+// @Success 201 {object} responses.CalibrationPolicyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/calibrations/policies [post]
+func (ctrl *CalibrationController) CreateCalibrationPolicy(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreateCalibrationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	ctrl.DB.Model(&models.CalibrationPolicy{}).
+		Where("tenant_id = ? AND brand = ? AND model = ?", tenantID, req.Brand, req.Model).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A calibration policy for this brand and model already exists"})
+		return
+	}
+
+	policy := models.CalibrationPolicy{
+		TenantID:       tenantID,
+		Brand:          req.Brand,
+		Model:          req.Model,
+		IntervalMonths: req.IntervalMonths,
+		IsActive:       true,
+		Notes:          req.Notes,
+	}
+	if err := ctrl.DB.Create(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calibration policy"})
+		return
+	}
+
+	go calibration.RunForTenant(tenantID)
+
+	response := responses.ToCalibrationPolicyResponse(&policy)
+	audit.LogCreate(c, "calibration_policy", policy.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Calibration policy created successfully", "data": response})
+}
+
+// UpdateCalibrationPolicy godoc
+// @Summary Update calibration policy
+// @Description Change the interval of a calibration policy or (de)activate it. Due dates are recomputed in the background.
+// @Tags Meter Calibration
+// @Accept json
+// @Produce json
+// @Param id path string true "Calibration policy ID"
+// @Param request body requests.UpdateCalibrationPolicyRequest true "Update calibration policy request"
+// @Security BearerAuth
+// @Success 200 {object} responses.CalibrationPolicyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/calibrations/policies/{id} [put]
+func (ctrl *CalibrationController) UpdateCalibrationPolicy(c *gin.Context) {
+	policy, ok := ctrl.findPolicy(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdateCalibrationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldValues := responses.ToCalibrationPolicyResponse(policy)
+
+	if req.IntervalMonths != nil {
+		policy.IntervalMonths = *req.IntervalMonths
+	}
+	if req.IsActive != nil {
+		policy.IsActive = *req.IsActive
+	}
+	if req.Notes != nil {
+		policy.Notes = *req.Notes
+	}
+
+	if err := ctrl.DB.Save(policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update calibration policy"})
+		return
+	}
+
+	go calibration.RunForTenant(policy.TenantID)
+
+	response := responses.ToCalibrationPolicyResponse(policy)
+	audit.LogUpdate(c, "calibration_policy", policy.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calibration policy updated successfully", "data": response})
+}
+
+// DeleteCalibrationPolicy godoc
+// @Summary Delete calibration policy
+// @Description Delete a calibration policy. Meters it covered keep their current due date unless another policy applies.
+// @Tags Meter Calibration
+// @Produce json
+// @Param id path string true "Calibration policy ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/calibrations/policies/{id} [delete]
+func (ctrl *CalibrationController) DeleteCalibrationPolicy(c *gin.Context) {
+	policy, ok := ctrl.findPolicy(c)
+	if !ok {
+		return
+	}
+
+	// Hard delete so the brand/model pair can be configured again
+	if err := ctrl.DB.Unscoped().Delete(policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calibration policy"})
+		return
+	}
+
+	go calibration.RunForTenant(policy.TenantID)
+
+	audit.LogDelete(c, "calibration_policy", policy.ID, responses.ToCalibrationPolicyResponse(policy))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calibration policy deleted successfully"})
+}
+
+// RunCalibrationSchedule godoc
+// @Summary Recompute calibration due dates
+// @Description Recompute next_calib_date of all meters from the calibration policies. Runs daily and after each policy change.
+// @Tags Meter Calibration
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} calibration.Summary
+// @Router /api/calibrations/run [post]
+func (ctrl *CalibrationController) RunCalibrationSchedule(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := calibration.Run(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Calibration schedule run failed", "data": summary})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calibration schedule updated", "data": summary})
+}
+
+// GetCalibrationDueList godoc
+// @Summary Calibration due list
+// @Description List meters that are overdue or due for calibration within the given number of days, grouped by service area
+// @Tags Meter Calibration
+// @Produce json
+// @Param days query int false "Include meters due within this many days (default 30)"
+// @Param service_area_id query string false "Only this service area and the areas below it"
+// @Param overdue query bool false "Only overdue meters"
+// @Security BearerAuth
+// @Success 200 {array} responses.CalibrationDueGroup
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/calibrations/due [get]
+func (ctrl *CalibrationController) GetCalibrationDueList(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	days := 30
+	if daysParam := c.Query("days"); daysParam != "" {
+		days, err = strconv.Atoi(daysParam)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a non-negative number"})
+			return
+		}
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	until := today.AddDate(0, 0, days)
+	if c.Query("overdue") == "true" {
+		until = today.AddDate(0, 0, -1)
+	}
+
+	query := ctrl.DB.Table("meters").
+		Select("meters.id AS meter_id, meters.meter_number, meters.brand, meters.model, meters.last_calib_date, meters.next_calib_date, "+
+			"customers.id AS customer_id, customers.name AS customer_name, customers.address, "+
+			"service_areas.id AS service_area_id, service_areas.code AS service_area_code, service_areas.name AS service_area_name").
+		Joins("JOIN customers ON customers.id = meters.customer_id").
+		Joins("LEFT JOIN service_areas ON service_areas.id = customers.service_area_id").
+		Where("meters.tenant_id = ? AND meters.deleted_at IS NULL AND meters.status = ?", tenantID, models.MeterStatusActive).
+		Where("meters.next_calib_date IS NOT NULL AND meters.next_calib_date <= ?", until)
+
+	if areaParam := c.Query("service_area_id"); areaParam != "" {
+		areaID, err := uuid.Parse(areaParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service area ID"})
+			return
+		}
+		areaIDs, err := helpers.ServiceAreaTreeIDs(ctrl.DB, tenantID, areaID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service areas"})
+			return
+		}
+		query = query.Where("customers.service_area_id IN ?", areaIDs)
+	}
+
+	var rows []struct {
+		responses.CalibrationDueMeter
+		ServiceAreaID   *uuid.UUID
+		ServiceAreaCode *string
+		ServiceAreaName *string
+	}
+	if err := query.Order("meters.next_calib_date ASC").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calibration due list"})
+		return
+	}
+
+	groups := []*responses.CalibrationDueGroup{}
+	byArea := map[uuid.UUID]*responses.CalibrationDueGroup{}
+	for i := range rows {
+		row := &rows[i]
+		areaKey := uuid.Nil
+		if row.ServiceAreaID != nil {
+			areaKey = *row.ServiceAreaID
+		}
+		group, ok := byArea[areaKey]
+		if !ok {
+			group = &responses.CalibrationDueGroup{ServiceAreaID: row.ServiceAreaID, ServiceAreaName: "Tanpa wilayah"}
+			if row.ServiceAreaCode != nil {
+				group.ServiceAreaCode = *row.ServiceAreaCode
+			}
+			if row.ServiceAreaName != nil {
+				group.ServiceAreaName = *row.ServiceAreaName
+			}
+			byArea[areaKey] = group
+			groups = append(groups, group)
+		}
+
+		item := row.CalibrationDueMeter
+		item.DaysUntilDue = int(item.NextCalibDate.Sub(today).Hours() / 24)
+		item.Overdue = item.NextCalibDate.Before(today)
+		if item.Overdue {
+			group.OverdueCount++
+		} else {
+			group.DueCount++
+		}
+		group.Meters = append(group.Meters, item)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].ServiceAreaCode < groups[j].ServiceAreaCode
+	})
+
+	total := 0
+	for _, group := range groups {
+		total += len(group.Meters)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": groups, "total": total})
+}
+
+func (ctrl *CalibrationController) findPolicy(c *gin.Context) (*models.CalibrationPolicy, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	policyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calibration policy ID"})
+		return nil, false
+	}
+
+	var policy models.CalibrationPolicy
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", policyID, tenantID).First(&policy).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calibration policy not found"})
+		return nil, false
+	}
+	return &policy, true
+}
@@ -0,0 +1,499 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// cashDiscrepancyTolerance absorbs floating point noise when comparing totals
+const cashDiscrepancyTolerance = 0.01
+
+type CashSessionController struct {
+	DB *gorm.DB
+}
+
+func NewCashSessionController(db *gorm.DB) *CashSessionController {
+	return &CashSessionController{DB: db}
+}
+
+// OpenCashSession godoc
+// @Summary Open cash session
+// @Description Open a cash session for the current collector. Payments the collector records are attached to it until it is closed.
+// @Tags Cash Sessions
+// @Accept json
+// @Produce json
+// @Param request body requests.OpenCashSessionRequest false "Open cash session request"
+// @Security BearerAuth
+// @Success 201 {object} responses.CashSessionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/cash-sessions/open [post]
+func (ctrl *CashSessionController) OpenCashSession(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var req requests.OpenCashSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tx := ctrl.DB.Begin()
+
+	// Lock the collector so two concurrent opens cannot both find no open session
+	var collector models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", *userID).First(&collector).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	existing, err := helpers.FindOpenCashSession(tx, tenantID, *userID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check open cash session"})
+		return
+	}
+	if existing != nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Collector already has an open cash session", "session_id": existing.ID})
+		return
+	}
+
+	session := models.CashSession{
+		TenantID:    tenantID,
+		CollectorID: *userID,
+		Status:      models.CashSessionOpen,
+		OpenedAt:    time.Now(),
+		Notes:       req.Notes,
+	}
+	if err := tx.Omit("Collector", "Supervisor", "Totals").Create(&session).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open cash session"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open cash session"})
+		return
+	}
+
+	ctrl.DB.Preload("Collector").First(&session, "id = ?", session.ID)
+
+	response := responses.ToCashSessionResponse(&session)
+	c.JSON(http.StatusCreated, gin.H{"message": "Cash session opened successfully", "data": response})
+}
+
+// GetCurrentCashSession godoc
+// @Summary Get current cash session
+// @Description Get the current collector's open cash session with running totals per payment method
+// @Tags Cash Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} responses.CashSessionResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/cash-sessions/current [get]
+func (ctrl *CashSessionController) GetCurrentCashSession(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	session, err := helpers.FindOpenCashSession(ctrl.DB, tenantID, *userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cash session"})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No open cash session"})
+		return
+	}
+
+	if err := ctrl.fillRunningTotals(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate session totals"})
+		return
+	}
+
+	response := responses.ToCashSessionResponse(session)
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// GetCashSessions godoc
+// @Summary List cash sessions
+// @Description List cash sessions, filterable by collector, status, discrepancy and opening date
+// @Tags Cash Sessions
+// @Produce json
+// @Param collector_id query string false "Filter by collector"
+// @Param status query string false "open, closed or signed_off"
+// @Param has_discrepancy query bool false "Only sessions with discrepancies"
+// @Param date query string false "Opening date (YYYY-MM-DD)"
+// @Security BearerAuth
+// @Success 200 {array} responses.CashSessionResponse
+// @Router /api/cash-sessions [get]
+func (ctrl *CashSessionController) GetCashSessions(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+
+	if collectorID := c.Query("collector_id"); collectorID != "" {
+		query = query.Where("collector_id = ?", collectorID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if c.Query("has_discrepancy") == "true" {
+		query = query.Where("has_discrepancy = ?", true)
+	}
+	if date := c.Query("date"); date != "" {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("opened_at >= ? AND opened_at < ?", day, day.AddDate(0, 0, 1))
+	}
+
+	var sessions []models.CashSession
+	if err := query.Preload("Collector").Preload("Supervisor").Preload("Totals").
+		Order("opened_at DESC").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cash sessions"})
+		return
+	}
+
+	sessionResponses := make([]responses.CashSessionResponse, len(sessions))
+	for i := range sessions {
+		sessionResponses[i] = responses.ToCashSessionResponse(&sessions[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessionResponses, "total": len(sessionResponses)})
+}
+
+// GetCashSession godoc
+// @Summary Get cash session
+// @Description Get a cash session with its per-method totals and attached payments
+// @Tags Cash Sessions
+// @Produce json
+// @Param id path string true "Cash session ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.CashSessionResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/cash-sessions/{id} [get]
+func (ctrl *CashSessionController) GetCashSession(c *gin.Context) {
+	session, ok := ctrl.findSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status == models.CashSessionOpen {
+		if err := ctrl.fillRunningTotals(session); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate session totals"})
+			return
+		}
+	}
+
+	var payments []models.Payment
+	ctrl.DB.Where("cash_session_id = ?", session.ID).Order("paid_at ASC").Find(&payments)
+
+	response := responses.ToCashSessionResponse(session)
+	c.JSON(http.StatusOK, gin.H{"data": response, "payments": payments})
+}
+
+// CloseCashSession godoc
+// @Summary Close cash session
+// @Description Close a cash session with the amounts counted per payment method. Expected totals are computed from the attached payments and discrepancies are flagged.
+// @Tags Cash Sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Cash session ID"
+// @Param request body requests.CloseCashSessionRequest true "Close cash session request"
+// @Security BearerAuth
+// @Success 200 {object} responses.CashSessionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/cash-sessions/{id}/close [post]
+func (ctrl *CashSessionController) CloseCashSession(c *gin.Context) {
+	var req requests.CloseCashSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, ok := ctrl.findSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.CashSessionOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cash session is not open"})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil || (*userID != session.CollectorID && !isAdminRole(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the collector or an admin can close this session"})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+
+	// Lock the session so no payment can attach to it after the totals are taken
+	var locked models.CashSession
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", session.ID, session.TenantID).First(&locked).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close cash session"})
+		return
+	}
+	if locked.Status != models.CashSessionOpen {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cash session is not open"})
+		return
+	}
+
+	totals, err := helpers.CalculateCashSessionTotals(tx, session)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate session totals"})
+		return
+	}
+
+	// Apply counted amounts; a count for a method with no payments still gets its own row
+	for _, count := range req.Counts {
+		matched := false
+		for i := range totals {
+			if sameMethod(totals[i].PaymentMethodID, count.PaymentMethodID) {
+				totals[i].Counted += count.CountedAmount
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			name := "Tidak ditentukan"
+			if count.PaymentMethodID != nil {
+				var method models.PaymentMethod
+				if err := tx.Where("id = ? AND tenant_id = ?", *count.PaymentMethodID, session.TenantID).First(&method).Error; err != nil {
+					tx.Rollback()
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Payment method not found", "payment_method_id": count.PaymentMethodID})
+					return
+				}
+				name = method.Name
+			}
+			totals = append(totals, models.CashSessionTotal{
+				TenantID:        session.TenantID,
+				CashSessionID:   session.ID,
+				PaymentMethodID: count.PaymentMethodID,
+				MethodName:      name,
+				Counted:         count.CountedAmount,
+			})
+		}
+	}
+
+	session.TotalExpected = 0
+	session.TotalCounted = 0
+	session.HasDiscrepancy = false
+	for i := range totals {
+		totals[i].Difference = totals[i].Counted - totals[i].Expected
+		if math.Abs(totals[i].Difference) >= cashDiscrepancyTolerance {
+			session.HasDiscrepancy = true
+		}
+		session.TotalExpected += totals[i].Expected
+		session.TotalCounted += totals[i].Counted
+		if err := tx.Create(&totals[i]).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session totals"})
+			return
+		}
+	}
+
+	now := time.Now()
+	session.Discrepancy = session.TotalCounted - session.TotalExpected
+	session.Status = models.CashSessionClosed
+	session.ClosedAt = &now
+	session.ClosingNotes = req.Notes
+	session.Totals = nil
+	if err := tx.Omit("Collector", "Supervisor", "Totals").Save(session).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close cash session"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close cash session"})
+		return
+	}
+
+	audit.LogUpdate(c, "cash_session", session.ID, nil, gin.H{
+		"status":          session.Status,
+		"total_expected":  session.TotalExpected,
+		"total_counted":   session.TotalCounted,
+		"discrepancy":     session.Discrepancy,
+		"has_discrepancy": session.HasDiscrepancy,
+	})
+
+	session.Totals = totals
+	response := responses.ToCashSessionResponse(session)
+	c.JSON(http.StatusOK, gin.H{"message": "Cash session closed successfully", "data": response})
+}
+
+// SignOffCashSession godoc
+// @Summary Sign off cash session
+// @Description Supervisor sign-off of a closed cash session. The collector cannot sign off their own session.
+// @Tags Cash Sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Cash session ID"
+// @Param request body requests.SignOffCashSessionRequest false "Sign-off request"
+// @Security BearerAuth
+// @Success 200 {object} responses.CashSessionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/cash-sessions/{id}/sign-off [post]
+func (ctrl *CashSessionController) SignOffCashSession(c *gin.Context) {
+	var req requests.SignOffCashSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	session, ok := ctrl.findSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.CashSessionClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only closed cash sessions can be signed off"})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil || *userID == session.CollectorID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "A supervisor other than the collector must sign off"})
+		return
+	}
+
+	if session.HasDiscrepancy && req.Notes == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Notes are required to sign off a session with discrepancies"})
+		return
+	}
+
+	// Only a session still closed is signed off, so concurrent sign-offs cannot both succeed
+	now := time.Now()
+	result := ctrl.DB.Model(&models.CashSession{}).
+		Where("id = ? AND status = ?", session.ID, models.CashSessionClosed).
+		Updates(map[string]interface{}{
+			"status":           models.CashSessionSignedOff,
+			"supervisor_id":    *userID,
+			"signed_off_at":    now,
+			"supervisor_notes": req.Notes,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign off cash session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cash session was already signed off"})
+		return
+	}
+
+	audit.LogUpdate(c, "cash_session", session.ID,
+		gin.H{"status": models.CashSessionClosed},
+		gin.H{"status": models.CashSessionSignedOff, "supervisor_id": *userID, "discrepancy": session.Discrepancy})
+
+	ctrl.DB.Preload("Collector").Preload("Supervisor").Preload("Totals").First(session, "id = ?", session.ID)
+
+	response := responses.ToCashSessionResponse(session)
+	c.JSON(http.StatusOK, gin.H{"message": "Cash session signed off successfully", "data": response})
+}
+
+// findSession loads the session from the :id path param, writing the error response on failure
+func (ctrl *CashSessionController) findSession(c *gin.Context) (*models.CashSession, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cash session ID"})
+		return nil, false
+	}
+
+	var session models.CashSession
+	if err := ctrl.DB.Preload("Collector").Preload("Supervisor").Preload("Totals").
+		Where("id = ? AND tenant_id = ?", sessionID, tenantID).
+		First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cash session not found"})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// fillRunningTotals computes expected totals for a session that is still open
+func (ctrl *CashSessionController) fillRunningTotals(session *models.CashSession) error {
+	if session.Collector.ID == uuid.Nil {
+		ctrl.DB.Where("id = ?", session.CollectorID).First(&session.Collector)
+	}
+
+	totals, err := helpers.CalculateCashSessionTotals(ctrl.DB, session)
+	if err != nil {
+		return err
+	}
+
+	session.Totals = totals
+	session.TotalExpected = 0
+	for _, total := range totals {
+		session.TotalExpected += total.Expected
+	}
+	return nil
+}
+
+func sameMethod(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func isAdminRole(c *gin.Context) bool {
+	role := c.GetString("role")
+	return role == "admin" || role == string(constants.RoleTenantAdmin) || role == string(constants.RolePlatformOwner)
+}
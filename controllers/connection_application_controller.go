@@ -0,0 +1,691 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ConnectionApplicationController struct {
+	DB *gorm.DB
+}
+
+func NewConnectionApplicationController(db *gorm.DB) *ConnectionApplicationController {
+	return &ConnectionApplicationController{DB: db}
+}
+
+// SubmitPublicApplication godoc
+// @Summary Apply for a new connection
+// @Description Public endpoint for residents to apply for a water connection. The village code identifies the water utility. Returns the application number used to track the application.
+// @Tags Connection Applications
+// @Accept json
+// @Produce json
+// @Param request body requests.SubmitConnectionApplicationRequest true "Application"
+// @Success 201 {object} responses.ConnectionApplicationStatusResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/public/connection-applications [post]
+func (ctrl *ConnectionApplicationController) SubmitPublicApplication(c *gin.Context) {
+	var req requests.SubmitConnectionApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tenant models.Tenant
+	if err := ctrl.DB.Where("village_code = ? AND status = ?", req.VillageCode, models.TenantStatusActive).First(&tenant).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Village not found"})
+		return
+	}
+
+	application, ok := ctrl.createApplication(c, tenant.ID, req.ConnectionApplicationRequest, models.ApplicationSourcePublic, nil)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Application submitted successfully",
+		"data":    responses.ToConnectionApplicationStatusResponse(application),
+	})
+}
+
+// TrackPublicApplication godoc
+// @Summary Track a connection application
+// @Description Public endpoint for an applicant to follow their application. The phone number must match the one on the application.
+// @Tags Connection Applications
+// @Produce json
+// @Param number path string true "Application number"
+// @Param village_code query string true "Village code"
+// @Param phone query string true "Phone number on the application"
+// @Success 200 {object} responses.ConnectionApplicationStatusResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/public/connection-applications/{number} [get]
+func (ctrl *ConnectionApplicationController) TrackPublicApplication(c *gin.Context) {
+	villageCode := c.Query("village_code")
+	phone := c.Query("phone")
+	if villageCode == "" || phone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "village_code and phone are required"})
+		return
+	}
+
+	var application models.ConnectionApplication
+	if err := ctrl.DB.Preload("Customer").
+		Joins("JOIN tenants ON tenants.id = connection_applications.tenant_id AND tenants.deleted_at IS NULL").
+		Where("tenants.village_code = ? AND connection_applications.application_number = ? AND connection_applications.phone = ?",
+			villageCode, c.Param("number"), phone).
+		First(&application).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToConnectionApplicationStatusResponse(&application)})
+}
+
+// CreateApplication godoc
+// @Summary Enter a connection application
+// @Description Enter an application on behalf of a resident, e.g. at the counter
+// @Tags Connection Applications
+// @Accept json
+// @Produce json
+// @Param request body requests.ConnectionApplicationRequest true "Application"
+// @Security BearerAuth
+// @Success 201 {object} responses.ConnectionApplicationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/connection-applications [post]
+func (ctrl *ConnectionApplicationController) CreateApplication(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.ConnectionApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	application, ok := ctrl.createApplication(c, tenantID, req, models.ApplicationSourceStaff, helpers.GetUserIDFromContext(c))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Application created successfully",
+		"data":    responses.ToConnectionApplicationResponse(application),
+	})
+}
+
+// GetApplications godoc
+// @Summary List connection applications
+// @Description List connection applications, newest first
+// @Tags Connection Applications
+// @Produce json
+// @Param status query string false "submitted, surveyed, approved, rejected or installed"
+// @Param service_area_id query string false "Filter by service area"
+// @Param search query string false "Search application number, applicant name or phone"
+// @Security BearerAuth
+// @Success 200 {array} responses.ConnectionApplicationResponse
+// @Router /api/connection-applications [get]
+func (ctrl *ConnectionApplicationController) GetApplications(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if areaID := c.Query("service_area_id"); areaID != "" {
+		query = query.Where("service_area_id = ?", areaID)
+	}
+	if search := c.Query("search"); search != "" {
+		query = query.Where("application_number LIKE ? OR applicant_name LIKE ? OR phone LIKE ?",
+			"%"+search+"%", "%"+search+"%", "%"+search+"%")
+	}
+
+	var applications []models.ConnectionApplication
+	if err := query.Preload("Subscription").Preload("ServiceArea").
+		Order("created_at DESC").Find(&applications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch applications"})
+		return
+	}
+
+	result := make([]responses.ConnectionApplicationResponse, len(applications))
+	for i := range applications {
+		result[i] = responses.ToConnectionApplicationResponse(&applications[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result, "total": len(result)})
+}
+
+// GetApplication godoc
+// @Summary Get connection application
+// @Description Get a connection application with its survey, decision and installation
+// @Tags Connection Applications
+// @Produce json
+// @Param id path string true "Application ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ConnectionApplicationResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/connection-applications/{id} [get]
+func (ctrl *ConnectionApplicationController) GetApplication(c *gin.Context) {
+	application, ok := ctrl.findApplication(c, ctrl.DB)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToConnectionApplicationResponse(application)})
+}
+
+// SurveyApplication godoc
+// @Summary Record site survey
+// @Description Record the site survey of a submitted application: whether the connection is feasible, the distance to the nearest main and the materials needed. A surveyed application can be surveyed again before it is decided.
+// @Tags Connection Applications
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Param request body requests.SurveyConnectionApplicationRequest true "Survey"
+// @Security BearerAuth
+// @Success 200 {object} responses.ConnectionApplicationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/connection-applications/{id}/survey [post]
+func (ctrl *ConnectionApplicationController) SurveyApplication(c *gin.Context) {
+	var req requests.SurveyConnectionApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	application, ok := ctrl.findApplication(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+	if !ok {
+		tx.Rollback()
+		return
+	}
+	if application.Status != models.ApplicationSubmitted && application.Status != models.ApplicationSurveyed {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Only submitted or surveyed applications can be surveyed"})
+		return
+	}
+
+	areaID := application.ServiceAreaID
+	if req.ServiceAreaID != nil {
+		areaID = req.ServiceAreaID
+	}
+	lat, lng := application.Latitude, application.Longitude
+	if req.Latitude != nil || req.Longitude != nil {
+		lat, lng = req.Latitude, req.Longitude
+	}
+	if err := helpers.ValidateCustomerLocation(tx, application.TenantID, areaID, lat, lng); err != nil {
+		tx.Rollback()
+		if helpers.IsLocationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate location"})
+		return
+	}
+
+	var materials *string
+	if len(req.Materials) > 0 {
+		data, err := json.Marshal(req.Materials)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid materials"})
+			return
+		}
+		value := string(data)
+		materials = &value
+	}
+
+	oldValues := responses.ToConnectionApplicationResponse(application)
+	now := time.Now()
+	application.Status = models.ApplicationSurveyed
+	application.SurveyedBy = helpers.GetUserIDFromContext(c)
+	application.SurveyedAt = &now
+	application.Feasible = req.Feasible
+	application.DistanceToMainM = req.DistanceToMainM
+	application.Materials = materials
+	application.SurveyNotes = req.Notes
+	application.ServiceAreaID = areaID
+	application.Latitude, application.Longitude = lat, lng
+
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"status":             application.Status,
+		"surveyed_by":        application.SurveyedBy,
+		"surveyed_at":        application.SurveyedAt,
+		"feasible":           application.Feasible,
+		"distance_to_main_m": application.DistanceToMainM,
+		"materials":          application.Materials,
+		"survey_notes":       application.SurveyNotes,
+		"service_area_id":    application.ServiceAreaID,
+		"latitude":           application.Latitude,
+		"longitude":          application.Longitude,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record survey"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record survey"})
+		return
+	}
+
+	application.ServiceArea = nil
+	response := responses.ToConnectionApplicationResponse(application)
+	audit.LogUpdate(c, "connection_application", application.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Survey recorded successfully", "data": response})
+}
+
+// ApproveApplication godoc
+// @Summary Approve connection application
+// @Description Approve a surveyed application that was found feasible and set its connection fee. The fee defaults to the registration fee of the subscription type and is billed as the registration invoice on installation.
+// @Tags Connection Applications
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Param request body requests.ApproveConnectionApplicationRequest false "Approval"
+// @Security BearerAuth
+// @Success 200 {object} responses.ConnectionApplicationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/connection-applications/{id}/approve [post]
+func (ctrl *ConnectionApplicationController) ApproveApplication(c *gin.Context) {
+	var req requests.ApproveConnectionApplicationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tx := ctrl.DB.Begin()
+	application, ok := ctrl.findApplication(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+	if !ok {
+		tx.Rollback()
+		return
+	}
+	if application.Status != models.ApplicationSurveyed {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Only surveyed applications can be approved"})
+		return
+	}
+	if application.Feasible == nil || !*application.Feasible {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "The survey found the connection not feasible"})
+		return
+	}
+
+	subscriptionID := application.SubscriptionID
+	if req.SubscriptionID != nil {
+		subscriptionID = *req.SubscriptionID
+	}
+	var subscription models.SubscriptionType
+	if err := tx.Where("id = ? AND tenant_id = ?", subscriptionID, application.TenantID).First(&subscription).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subscription type not found"})
+		return
+	}
+
+	oldValues := responses.ToConnectionApplicationResponse(application)
+	now := time.Now()
+	application.Status = models.ApplicationApproved
+	application.SubscriptionID = subscription.ID
+	application.Subscription = subscription
+	application.DecidedBy = helpers.GetUserIDFromContext(c)
+	application.DecidedAt = &now
+	application.ConnectionFee = subscription.RegistrationFee
+	if req.ConnectionFee != nil {
+		application.ConnectionFee = *req.ConnectionFee
+	}
+
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"status":          application.Status,
+		"subscription_id": application.SubscriptionID,
+		"decided_by":      application.DecidedBy,
+		"decided_at":      application.DecidedAt,
+		"connection_fee":  application.ConnectionFee,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve application"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve application"})
+		return
+	}
+
+	response := responses.ToConnectionApplicationResponse(application)
+	audit.LogUpdate(c, "connection_application", application.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Application approved successfully", "data": response})
+}
+
+// RejectApplication godoc
+// @Summary Reject connection application
+// @Description Reject an application that has not been installed yet
+// @Tags Connection Applications
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Param request body requests.RejectConnectionApplicationRequest true "Reason"
+// @Security BearerAuth
+// @Success 200 {object} responses.ConnectionApplicationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/connection-applications/{id}/reject [post]
+func (ctrl *ConnectionApplicationController) RejectApplication(c *gin.Context) {
+	var req requests.RejectConnectionApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	application, ok := ctrl.findApplication(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+	if !ok {
+		tx.Rollback()
+		return
+	}
+	if !application.IsOpen() {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Application is already " + application.Status})
+		return
+	}
+
+	oldValues := responses.ToConnectionApplicationResponse(application)
+	now := time.Now()
+	application.Status = models.ApplicationRejected
+	application.DecidedBy = helpers.GetUserIDFromContext(c)
+	application.DecidedAt = &now
+	application.RejectionReason = req.Reason
+
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"status":           application.Status,
+		"decided_by":       application.DecidedBy,
+		"decided_at":       application.DecidedAt,
+		"rejection_reason": application.RejectionReason,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject application"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject application"})
+		return
+	}
+
+	response := responses.ToConnectionApplicationResponse(application)
+	audit.LogUpdate(c, "connection_application", application.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Application rejected", "data": response})
+}
+
+// InstallApplication godoc
+// @Summary Install connection
+// @Description Complete an approved application once the meter is fitted. Creates the customer, its meter and the registration invoice for the connection fee in one step. The customer becomes active when the invoice is paid, or at once when the fee is zero.
+// @Tags Connection Applications
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Param request body requests.InstallConnectionApplicationRequest true "Installation"
+// @Security BearerAuth
+// @Success 201 {object} responses.ConnectionInstallationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/connection-applications/{id}/install [post]
+func (ctrl *ConnectionApplicationController) InstallApplication(c *gin.Context) {
+	var req requests.InstallConnectionApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	installDate := time.Now().Truncate(24 * time.Hour)
+	if req.InstallDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.InstallDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid install_date format. Use YYYY-MM-DD"})
+			return
+		}
+		installDate = parsed
+	}
+
+	if err := helpers.ValidateLocation(req.Latitude, req.Longitude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var hashedPassword string
+	if req.Password != "" {
+		hashed, err := utils.HashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+		hashedPassword = hashed
+	}
+
+	tx := ctrl.DB.Begin()
+	application, ok := ctrl.findApplication(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+	if !ok {
+		tx.Rollback()
+		return
+	}
+	if application.Status != models.ApplicationApproved {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Only approved applications can be installed"})
+		return
+	}
+
+	customer, meter, invoice, err := helpers.InstallConnection(tx, application, helpers.ConnectionInstallation{
+		MeterNumber:    req.MeterNumber,
+		Brand:          req.Brand,
+		Model:          req.Model,
+		InstallDate:    installDate,
+		InitialReading: req.InitialReading,
+		RolloverAt:     req.RolloverAt,
+		Latitude:       req.Latitude,
+		Longitude:      req.Longitude,
+		Password:       hashedPassword,
+		InstalledBy:    *userID,
+		Notes:          req.Notes,
+	})
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, helpers.ErrCustomerNumberTaken) || errors.Is(err, helpers.ErrCustomerEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to install connection"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to install connection"})
+		return
+	}
+
+	meter.Customer = *customer
+	response := responses.ConnectionInstallationResponse{
+		Application: responses.ToConnectionApplicationResponse(application),
+		Customer: responses.CustomerResponse{
+			ID:             customer.ID,
+			MeterNumber:    customer.MeterNumber,
+			Name:           customer.Name,
+			Email:          customer.Email,
+			Phone:          customer.Phone,
+			Address:        customer.Address,
+			SubscriptionID: customer.SubscriptionID,
+			IsActive:       customer.IsActive,
+			Status:         customer.Status,
+			StatusSince:    customer.StatusSince,
+			ServiceAreaID:  customer.ServiceAreaID,
+			Latitude:       customer.Latitude,
+			Longitude:      customer.Longitude,
+			CreatedAt:      customer.CreatedAt,
+		},
+		Meter: responses.ToMeterResponse(meter),
+	}
+	if invoice != nil {
+		response.Invoice = &responses.InvoiceResponse{
+			ID:          invoice.ID,
+			CustomerID:  invoice.CustomerID,
+			UsageMonth:  invoice.UsageMonth,
+			TotalAmount: invoice.TotalAmount,
+			TotalPaid:   invoice.TotalPaid,
+			IsPaid:      invoice.IsPaid,
+			Type:        invoice.Type,
+			CreatedAt:   invoice.CreatedAt,
+		}
+	}
+
+	audit.LogCreate(c, "customer", customer.ID, response.Customer)
+	audit.LogCreate(c, "meter", meter.ID, response.Meter)
+	audit.LogUpdate(c, "connection_application", application.ID,
+		map[string]interface{}{"status": models.ApplicationApproved},
+		map[string]interface{}{"status": application.Status, "customer_id": customer.ID, "meter_id": meter.ID})
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Connection installed successfully", "data": response})
+}
+
+// createApplication validates and stores a new application, numbering it
+// within the tenant
+func (ctrl *ConnectionApplicationController) createApplication(c *gin.Context, tenantID uuid.UUID, req requests.ConnectionApplicationRequest, source string, createdBy *uuid.UUID) (*models.ConnectionApplication, bool) {
+	var subscription models.SubscriptionType
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", req.SubscriptionID, tenantID).First(&subscription).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subscription type not found"})
+		return nil, false
+	}
+
+	if err := helpers.ValidateCustomerLocation(ctrl.DB, tenantID, req.ServiceAreaID, req.Latitude, req.Longitude); err != nil {
+		if helpers.IsLocationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate location"})
+		return nil, false
+	}
+
+	tx := ctrl.DB.Begin()
+
+	// Lock the tenant so concurrent applications do not take the same number
+	var tenant models.Tenant
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", tenantID).First(&tenant).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application"})
+		return nil, false
+	}
+
+	var open int64
+	if err := tx.Model(&models.ConnectionApplication{}).
+		Where("tenant_id = ? AND phone = ? AND status IN ?", tenantID, req.Phone,
+			[]string{models.ApplicationSubmitted, models.ApplicationSurveyed, models.ApplicationApproved}).
+		Count(&open).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application"})
+		return nil, false
+	}
+	if open > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "An application with this phone number is already being processed"})
+		return nil, false
+	}
+
+	number, err := helpers.NextApplicationNumber(tx, tenantID, time.Now().Year())
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application"})
+		return nil, false
+	}
+
+	application := models.ConnectionApplication{
+		TenantID:          tenantID,
+		ApplicationNumber: number,
+		Status:            models.ApplicationSubmitted,
+		Source:            source,
+		CreatedBy:         createdBy,
+		ApplicantName:     req.ApplicantName,
+		IdentityNumber:    req.IdentityNumber,
+		Email:             req.Email,
+		Phone:             req.Phone,
+		Address:           req.Address,
+		SubscriptionID:    subscription.ID,
+		ServiceAreaID:     req.ServiceAreaID,
+		Latitude:          req.Latitude,
+		Longitude:         req.Longitude,
+		Notes:             req.Notes,
+	}
+	if err := tx.Omit("Tenant", "Subscription", "ServiceArea", "Customer").Create(&application).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application"})
+		return nil, false
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create application"})
+		return nil, false
+	}
+
+	application.Subscription = subscription
+	audit.LogCreate(c, "connection_application", application.ID, responses.ToConnectionApplicationResponse(&application))
+	return &application, true
+}
+
+func (ctrl *ConnectionApplicationController) findApplication(c *gin.Context, db *gorm.DB) (*models.ConnectionApplication, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	applicationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return nil, false
+	}
+
+	var application models.ConnectionApplication
+	if err := db.Where("id = ? AND tenant_id = ?", applicationID, tenantID).First(&application).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Application not found"})
+		return nil, false
+	}
+
+	// Loaded separately so the row lock above does not extend to them
+	ctrl.DB.Where("id = ?", application.SubscriptionID).First(&application.Subscription)
+	if application.ServiceAreaID != nil {
+		var area models.ServiceArea
+		if ctrl.DB.Where("id = ?", *application.ServiceAreaID).First(&area).Error == nil {
+			application.ServiceArea = &area
+		}
+	}
+	return &application, true
+}
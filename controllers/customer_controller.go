@@ -45,6 +45,15 @@ func CreateCustomer(c *gin.Context) {
 		return
 	}
 
+	if err := helpers.ValidateCustomerLocation(config.DB, tenantID, req.ServiceAreaID, req.Latitude, req.Longitude); err != nil {
+		if helpers.IsLocationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate customer location"})
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -65,7 +74,11 @@ func CreateCustomer(c *gin.Context) {
 		Address:        req.Address,
 		SubscriptionID: req.SubscriptionID,
 		IsActive:       false,
+		Status:         models.CustomerStatusApplicant,
 		TenantID:       tenantID,
+		ServiceAreaID:  req.ServiceAreaID,
+		Latitude:       req.Latitude,
+		Longitude:      req.Longitude,
 	}
 	if err := tx.Create(&customer).Error; err != nil {
 		tx.Rollback()
@@ -108,6 +121,11 @@ func CreateCustomer(c *gin.Context) {
 		Phone:          customer.Phone,
 		SubscriptionID: customer.SubscriptionID,
 		IsActive:       customer.IsActive,
+		Status:         customer.Status,
+		StatusSince:    customer.StatusSince,
+		ServiceAreaID:  customer.ServiceAreaID,
+		Latitude:       customer.Latitude,
+		Longitude:      customer.Longitude,
 	}
 	c.JSON(http.StatusCreated, response)
 }
@@ -118,6 +136,7 @@ func CreateCustomer(c *gin.Context) {
 // @Tags Customers
 // @Accept json
 // @Produce json
+// @Param status query string false "Lifecycle status (applicant, active, suspended, disconnected, closed)"
 // @Security BearerAuth
 // @Success 200 {array} responses.CustomerResponse
 // @Failure 401 {object} map[string]interface{}
@@ -138,6 +157,10 @@ func GetCustomers(c *gin.Context) {
 	}
 	// If no specific tenant (platform owner without filter), return all
 
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	if err := query.Find(&customers).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data"})
 		return
@@ -155,6 +178,11 @@ func GetCustomers(c *gin.Context) {
 			Phone:          customer.Phone,
 			SubscriptionID: customer.SubscriptionID,
 			IsActive:       customer.IsActive,
+			Status:         customer.Status,
+			StatusSince:    customer.StatusSince,
+			ServiceAreaID:  customer.ServiceAreaID,
+			Latitude:       customer.Latitude,
+			Longitude:      customer.Longitude,
 		}
 	}
 
@@ -207,6 +235,11 @@ func GetCustomer(c *gin.Context) {
 		Phone:          customer.Phone,
 		SubscriptionID: customer.SubscriptionID,
 		IsActive:       customer.IsActive,
+		Status:         customer.Status,
+		StatusSince:    customer.StatusSince,
+		ServiceAreaID:  customer.ServiceAreaID,
+		Latitude:       customer.Latitude,
+		Longitude:      customer.Longitude,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -256,6 +289,31 @@ func UpdateCustomer(c *gin.Context) {
 	customer.Phone = input.Phone
 	customer.SubscriptionID = input.SubscriptionID
 
+	// Only a changed area or location is checked against the boundary, so
+	// customers drawn outside a later boundary can still be edited
+	locationChanged := input.ServiceAreaID != nil || input.Latitude != nil || input.Longitude != nil
+	if input.ServiceAreaID != nil {
+		customer.ServiceAreaID = input.ServiceAreaID
+	}
+	if input.Latitude != nil || input.Longitude != nil {
+		if err := helpers.ValidateLocation(input.Latitude, input.Longitude); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		customer.Latitude = input.Latitude
+		customer.Longitude = input.Longitude
+	}
+	if locationChanged {
+		if err := helpers.ValidateCustomerLocation(config.DB, customer.TenantID, customer.ServiceAreaID, customer.Latitude, customer.Longitude); err != nil {
+			if helpers.IsLocationError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate customer location"})
+			return
+		}
+	}
+
 	if err := config.DB.Save(&customer).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui pelanggan"})
 		return
@@ -270,6 +328,11 @@ func UpdateCustomer(c *gin.Context) {
 		Phone:          customer.Phone,
 		SubscriptionID: customer.SubscriptionID,
 		IsActive:       customer.IsActive,
+		Status:         customer.Status,
+		StatusSince:    customer.StatusSince,
+		ServiceAreaID:  customer.ServiceAreaID,
+		Latitude:       customer.Latitude,
+		Longitude:      customer.Longitude,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// GetCustomerCredit godoc
+// @Summary Get customer credit
+// @Description Get the credit balance and credit ledger of a customer
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.CustomerCreditResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/customers/{id}/credit [get]
+func GetCustomerCredit(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+
+	var entries []models.CustomerCredit
+	if err := config.DB.Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Order("created_at desc").
+		Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data kredit"})
+		return
+	}
+
+	balance := 0.0
+	entryResponses := make([]responses.CustomerCreditEntryResponse, len(entries))
+	for i, entry := range entries {
+		balance += entry.Amount
+		entryResponses[i] = responses.CustomerCreditEntryResponse{
+			ID:        entry.ID,
+			Amount:    entry.Amount,
+			Type:      entry.Type,
+			PaymentID: entry.PaymentID,
+			Reference: entry.Reference,
+			Notes:     entry.Notes,
+			CreatedAt: entry.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, responses.CustomerCreditResponse{
+		CustomerID: customerID,
+		Balance:    balance,
+		Entries:    entryResponses,
+	})
+}
+
+// RefundCustomerCredit godoc
+// @Summary Refund customer credit
+// @Description Pay out part or all of a customer's credit balance
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param request body requests.RefundCustomerCreditRequest true "Refund request"
+// @Security BearerAuth
+// @Success 201 {object} responses.CustomerCreditEntryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/customers/{id}/refunds [post]
+func RefundCustomerCredit(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	var req requests.RefundCustomerCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+
+	if req.PaymentMethodID != nil {
+		var method models.PaymentMethod
+		if err := config.DB.Where("id = ? AND tenant_id = ?", *req.PaymentMethodID, tenantID).First(&method).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Metode pembayaran tidak ditemukan"})
+			return
+		}
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+
+	tx := config.DB.Begin()
+
+	// Lock the customer row so concurrent refunds cannot overdraw the balance
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", customerID).First(&customer).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data pelanggan"})
+		return
+	}
+
+	balance, err := helpers.GetCustomerCreditBalance(tx, tenantID, customerID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung saldo kredit"})
+		return
+	}
+
+	if req.Amount > balance {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Refund melebihi saldo kredit. Saldo: %.2f", balance),
+		})
+		return
+	}
+
+	refund := models.CustomerCredit{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Amount:     -req.Amount,
+		Type:       models.CreditTypeRefund,
+		Reference:  req.ReferenceNumber,
+		Notes:      req.Reason,
+		CreatedBy:  userID,
+
+		PaymentMethodID: req.PaymentMethodID,
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat refund"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat refund"})
+		return
+	}
+
+	audit.LogRefund(c, customerID, refund.ID, req.Amount, req.Reason)
+
+	c.JSON(http.StatusCreated, responses.CustomerCreditEntryResponse{
+		ID:        refund.ID,
+		Amount:    refund.Amount,
+		Type:      refund.Type,
+		Reference: refund.Reference,
+		Notes:     refund.Notes,
+		CreatedAt: refund.CreatedAt,
+	})
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// ChangeCustomerStatus godoc
+// @Summary Change customer lifecycle status
+// @Description Move a customer along its lifecycle: applicant → active → suspended → disconnected → closed. Closing the account raises the final bill for the closing month, deactivates its meters and takes it off its reading route; reactivating a disconnected customer bills the reconnection fee. Disconnected customers are not charged abonemen.
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param request body requests.ChangeCustomerStatusRequest true "New status"
+// @Security BearerAuth
+// @Success 200 {object} responses.CustomerStatusChangeResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/customers/{id}/status [post]
+func ChangeCustomerStatus(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	var req requests.ChangeCustomerStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var effectiveDate time.Time
+	if req.EffectiveDate != "" {
+		effectiveDate, err = time.Parse("2006-01-02", req.EffectiveDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective_date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	tx := config.DB.Begin()
+
+	// Lock the customer row so two changes cannot race from the same status
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+	fromStatus := customer.Status
+
+	history, invoice, err := helpers.ChangeCustomerStatus(tx, &customer, helpers.CustomerStatusChange{
+		Status:          req.Status,
+		Reason:          req.Reason,
+		EffectiveDate:   effectiveDate,
+		ChangedBy:       helpers.GetUserIDFromContext(c),
+		Notes:           req.Notes,
+		ReconnectionFee: req.ReconnectionFee,
+	})
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrCustomerStatusNotAllowed):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":               "Cannot change status from " + fromStatus + " to " + req.Status,
+				"allowed_transitions": models.CustomerStatusTransitions[fromStatus],
+			})
+		case errors.Is(err, helpers.ErrCustomerStatusInvalid), errors.Is(err, helpers.ErrStatusDateInFuture),
+			errors.Is(err, helpers.ErrStatusDateBeforeLast), errors.Is(err, helpers.ErrInvoiceUsageInvalid),
+			errors.Is(err, helpers.ErrInvoiceTotalOutOfRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change customer status"})
+		}
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change customer status"})
+		return
+	}
+
+	audit.LogUpdate(c, "customer", customer.ID,
+		map[string]interface{}{"status": fromStatus},
+		map[string]interface{}{"status": customer.Status, "reason": req.Reason, "effective_date": history.EffectiveDate})
+
+	response := responses.CustomerStatusChangeResponse{
+		CustomerID:         customer.ID,
+		Status:             customer.Status,
+		Change:             toCustomerStatusHistoryResponse(history),
+		AllowedTransitions: models.CustomerStatusTransitions[customer.Status],
+	}
+	if invoice != nil {
+		invoiceResponse := responses.ToInvoiceResponse(invoice)
+		response.Invoice = &invoiceResponse
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer status changed successfully", "data": response})
+}
+
+// GetCustomerStatusHistory godoc
+// @Summary Customer status history
+// @Description Lifecycle changes of a customer, newest first, with the allowed next statuses
+// @Tags Customers
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Security BearerAuth
+// @Success 200 {array} responses.CustomerStatusHistoryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/customers/{id}/status-history [get]
+func GetCustomerStatusHistory(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+
+	var history []models.CustomerStatusHistory
+	if err := config.DB.Preload("User").
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Order("effective_date DESC, created_at DESC").
+		Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status history"})
+		return
+	}
+
+	result := make([]responses.CustomerStatusHistoryResponse, len(history))
+	for i := range history {
+		result[i] = toCustomerStatusHistoryResponse(&history[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":                result,
+		"total":               len(result),
+		"status":              customer.Status,
+		"status_since":        customer.StatusSince,
+		"allowed_transitions": models.CustomerStatusTransitions[customer.Status],
+	})
+}
+
+func toCustomerStatusHistoryResponse(history *models.CustomerStatusHistory) responses.CustomerStatusHistoryResponse {
+	response := responses.CustomerStatusHistoryResponse{
+		ID:            history.ID,
+		FromStatus:    history.FromStatus,
+		ToStatus:      history.ToStatus,
+		Reason:        history.Reason,
+		EffectiveDate: history.EffectiveDate,
+		ChangedBy:     history.ChangedBy,
+		InvoiceID:     history.InvoiceID,
+		Notes:         history.Notes,
+		CreatedAt:     history.CreatedAt,
+	}
+	if history.User != nil {
+		response.ChangedByName = history.User.Name
+	}
+	return response
+}
@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/utils"
 
@@ -33,6 +35,7 @@ func GetCustomerProfile(c *gin.Context) {
 		"phone":         customer.Phone,
 		"subscription":  customer.Subscription,
 		"is_active":     customer.IsActive,
+		"status":        customer.Status,
 		"created_at":    customer.CreatedAt,
 	}
 
@@ -150,53 +153,41 @@ func CustomerMakePayment(c *gin.Context) {
 		return
 	}
 
-	if invoice.IsPaid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tagihan sudah lunas"})
-		return
-	}
-
-	if invoice.TotalPaid+input.Amount > invoice.TotalAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Pembayaran melebihi total tagihan",
-			"remaining_amount": invoice.TotalAmount - invoice.TotalPaid,
-		})
-		return
-	}
-
-	// Create payment record
-	payment := models.Payment{
-		InvoiceID: input.InvoiceID,
-		Amount:    input.Amount,
+	// Record the payment, update the invoice (a paid registration invoice
+	// activates the customer) and issue the receipt in one transaction
+	tx := config.DB.Begin()
+	payment, updatedInvoice, err := helpers.ApplyPayment(tx, helpers.PaymentInput{
 		TenantID:  tenantID,
-	}
-
-	if err := config.DB.Create(&payment).Error; err != nil {
+		InvoiceID: invoice.ID,
+		Amount:    input.Amount,
+	})
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrPaymentExceedsInvoice):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":            helpers.ErrPaymentExceedsInvoice.Error(),
+				"remaining_amount": updatedInvoice.TotalAmount - updatedInvoice.TotalPaid,
+			})
+		case helpers.IsPaymentRuleError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat pembayaran"})
+		}
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat pembayaran"})
 		return
 	}
 
-	// Update invoice
-	var totalPaid float64
-	config.DB.Model(&models.Payment{}).
-		Where("invoice_id = ?", input.InvoiceID).
-		Select("SUM(amount)").Scan(&totalPaid)
-
-	invoice.TotalPaid = totalPaid
-	invoice.IsPaid = totalPaid >= invoice.TotalAmount
-	config.DB.Save(&invoice)
-
-	// If registration invoice is now paid, activate customer
-	if invoice.Type == "registration" && invoice.IsPaid {
-		config.DB.Model(&models.Customer{}).
-			Where("id = ?", customerID).
-			Update("is_active", true)
-	}
-
 	c.JSON(http.StatusCreated, gin.H{
-		"message":     "Pembayaran berhasil dicatat",
-		"payment_id":  payment.ID,
-		"total_paid":  invoice.TotalPaid,
-		"is_paid":     invoice.IsPaid,
+		"message":        "Pembayaran berhasil dicatat",
+		"payment_id":     payment.ID,
+		"receipt_number": payment.ReceiptNumber,
+		"total_paid":     updatedInvoice.TotalPaid,
+		"is_paid":        updatedInvoice.IsPaid,
 	})
 }
 
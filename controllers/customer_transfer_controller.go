@@ -0,0 +1,298 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// TransferCustomer godoc
+// @Summary Transfer customer ownership
+// @Description Hand a connection over to a new owner, e.g. when the house is sold. The old account gets a final reading for every active meter and is closed with its final bill under an archived account number; a new active account takes over the account number, meters, service area, location and reading route stop. Unpaid bills stay with the previous owner (settle) or are billed to the new owner as one transfer invoice (carry_over).
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param request body requests.TransferCustomerRequest true "New owner and final readings"
+// @Security BearerAuth
+// @Success 201 {object} responses.CustomerTransferResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/customers/{id}/transfer [post]
+func TransferCustomer(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	var req requests.TransferCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var transferDate time.Time
+	if req.TransferDate != "" {
+		transferDate, err = time.Parse("2006-01-02", req.TransferDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer_date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	if req.SubscriptionID != nil {
+		var subType models.SubscriptionType
+		if err := config.DB.Where("id = ? AND tenant_id = ?", *req.SubscriptionID, tenantID).First(&subType).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Subscription type not found"})
+			return
+		}
+	}
+
+	var hashedPassword string
+	if req.Password != "" {
+		hashedPassword, err = utils.HashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+	}
+
+	finalReadings := make([]helpers.FinalMeterReading, len(req.FinalReadings))
+	for i, reading := range req.FinalReadings {
+		finalReadings[i] = helpers.FinalMeterReading{
+			MeterID: reading.MeterID,
+			Reading: reading.MeterReading,
+			PhotoID: reading.PhotoID,
+		}
+	}
+
+	tx := config.DB.Begin()
+
+	// Lock the customer row so a status change cannot race the transfer
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+	oldValues := responses.ToCustomerResponse(&customer)
+
+	result, err := helpers.TransferCustomer(tx, &customer, helpers.CustomerTransfer{
+		Date:           transferDate,
+		FinalReadings:  finalReadings,
+		ArchivedNumber: req.ArchivedNumber,
+		Name:           req.Name,
+		Email:          req.Email,
+		Phone:          req.Phone,
+		Password:       hashedPassword,
+		SubscriptionID: req.SubscriptionID,
+		Balance:        req.BalanceHandling,
+		Reason:         req.Reason,
+		Notes:          req.Notes,
+		TransferredBy:  *userID,
+	})
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrCustomerNotInService), errors.Is(err, helpers.ErrCustomerNumberTaken),
+			errors.Is(err, helpers.ErrCustomerEmailTaken), errors.Is(err, helpers.ErrReadingAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, helpers.ErrCustomerNoMeter), errors.Is(err, helpers.ErrTransferReadingMissing),
+			errors.Is(err, helpers.ErrBalanceHandlingInvalid), errors.Is(err, helpers.ErrStatusDateInFuture),
+			errors.Is(err, helpers.ErrStatusDateBeforeLast), errors.Is(err, helpers.ErrInvoiceUsageInvalid),
+			errors.Is(err, helpers.ErrInvoiceTotalOutOfRange), helpers.IsWaterUsageRuleError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer customer"})
+		}
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer customer"})
+		return
+	}
+
+	response := responses.CustomerTransferResponse{
+		OldCustomer:   responses.ToCustomerResponse(result.OldCustomer),
+		NewCustomer:   responses.ToCustomerResponse(result.NewCustomer),
+		Meters:        make([]responses.MeterResponse, len(result.Meters)),
+		FinalReadings: make([]responses.WaterUsageResponse, len(result.FinalReadings)),
+		FinalBills:    make([]responses.InvoiceResponse, len(result.FinalBills)),
+		Outstanding:   result.Outstanding,
+	}
+	for i := range result.Meters {
+		response.Meters[i] = responses.ToMeterResponse(&result.Meters[i])
+	}
+	for i := range result.FinalReadings {
+		response.FinalReadings[i] = responses.ToWaterUsageResponse(&result.FinalReadings[i])
+	}
+	for i := range result.FinalBills {
+		response.FinalBills[i] = responses.ToInvoiceResponse(&result.FinalBills[i])
+	}
+	if result.CarriedOver != nil {
+		carriedOver := responses.ToInvoiceResponse(result.CarriedOver)
+		response.CarriedOver = &carriedOver
+	}
+
+	audit.LogUpdate(c, "customer", customer.ID, oldValues, response.OldCustomer)
+	audit.LogCreate(c, "customer", result.NewCustomer.ID, response.NewCustomer)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Customer transferred successfully", "data": response})
+}
+
+// RelocateCustomer godoc
+// @Summary Relocate customer
+// @Description Move a customer to a new address, keeping the account and its history. With new_meter the meter at the old address is replaced and its final reading billed on the next invoice; without it the meter moves along. The customer leaves its reading route, which belongs to the old address.
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param request body requests.RelocateCustomerRequest true "New address and meter"
+// @Security BearerAuth
+// @Success 200 {object} responses.CustomerRelocationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/customers/{id}/relocate [post]
+func RelocateCustomer(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id tidak valid"})
+		return
+	}
+
+	var req requests.RelocateCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var relocationDate time.Time
+	if req.RelocationDate != "" {
+		relocationDate, err = time.Parse("2006-01-02", req.RelocationDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relocation_date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	tx := config.DB.Begin()
+
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+	oldValues := responses.ToCustomerResponse(&customer)
+
+	serviceAreaID := customer.ServiceAreaID
+	if req.ServiceAreaID != nil {
+		serviceAreaID = req.ServiceAreaID
+	}
+	if err := helpers.ValidateCustomerLocation(tx, tenantID, serviceAreaID, req.Latitude, req.Longitude); err != nil {
+		tx.Rollback()
+		if helpers.IsLocationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate customer location"})
+		return
+	}
+
+	relocation := helpers.CustomerRelocation{
+		Date:          relocationDate,
+		Address:       req.Address,
+		ServiceAreaID: req.ServiceAreaID,
+		Latitude:      req.Latitude,
+		Longitude:     req.Longitude,
+		MeterID:       req.MeterID,
+		RelocatedBy:   *userID,
+		Notes:         req.Notes,
+	}
+	if req.NewMeter != nil {
+		relocation.NewMeter = &helpers.MeterReplacement{
+			FinalReading:   req.NewMeter.FinalReading,
+			FinalPhotoID:   req.NewMeter.FinalPhotoID,
+			MeterNumber:    req.NewMeter.MeterNumber,
+			Brand:          req.NewMeter.Brand,
+			Model:          req.NewMeter.Model,
+			InitialReading: req.NewMeter.InitialReading,
+			RolloverAt:     req.NewMeter.RolloverAt,
+			Notes:          req.NewMeter.Notes,
+		}
+	}
+
+	meter, finalUsage, err := helpers.RelocateCustomer(tx, &customer, relocation)
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrCustomerNotInService), errors.Is(err, helpers.ErrMeterNumberTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, helpers.ErrCustomerNoMeter), helpers.IsWaterUsageRuleError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to relocate customer"})
+		}
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to relocate customer"})
+		return
+	}
+
+	response := responses.CustomerRelocationResponse{Customer: responses.ToCustomerResponse(&customer)}
+	if meter != nil {
+		meterResponse := responses.ToMeterResponse(meter)
+		response.Meter = &meterResponse
+	}
+	if finalUsage != nil {
+		usageResponse := responses.ToWaterUsageResponse(finalUsage)
+		response.FinalReading = &usageResponse
+	}
+
+	audit.LogUpdate(c, "customer", customer.ID, oldValues, response.Customer)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer relocated successfully", "data": response})
+}
@@ -0,0 +1,374 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type GeoController struct {
+	DB *gorm.DB
+}
+
+func NewGeoController(db *gorm.DB) *GeoController {
+	return &GeoController{DB: db}
+}
+
+// customerArrears is the unpaid balance of one customer
+type customerArrears struct {
+	CustomerID     uuid.UUID
+	UnpaidInvoices int
+	Amount         float64
+	OldestMonth    string
+}
+
+// bbox is a min longitude, min latitude, max longitude, max latitude filter
+type bbox struct {
+	MinLng, MinLat, MaxLng, MaxLat float64
+}
+
+func parseBBox(raw string) (*bbox, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bbox must be min_lng,min_lat,max_lng,max_lat")
+	}
+	var values [4]float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, errors.New("bbox must be min_lng,min_lat,max_lng,max_lat")
+		}
+		values[i] = value
+	}
+	box := &bbox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+	if box.MinLng > box.MaxLng || box.MinLat > box.MaxLat {
+		return nil, errors.New("bbox minimum must not exceed its maximum")
+	}
+	return box, nil
+}
+
+// areaFilter resolves the service_area_id query to the area and the areas
+// below it, or nil when no area was requested
+func (ctrl *GeoController) areaFilter(c *gin.Context, tenantID uuid.UUID) ([]uuid.UUID, bool) {
+	areaParam := c.Query("service_area_id")
+	if areaParam == "" {
+		return nil, true
+	}
+	areaID, err := uuid.Parse(areaParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service area ID"})
+		return nil, false
+	}
+	var area models.ServiceArea
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", areaID, tenantID).First(&area).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service area not found"})
+		return nil, false
+	}
+	ids, err := helpers.ServiceAreaTreeIDs(ctrl.DB, tenantID, areaID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service areas"})
+		return nil, false
+	}
+	return ids, true
+}
+
+// GetServiceAreaFeatures godoc
+// @Summary Service areas as GeoJSON
+// @Description FeatureCollection of the tenant's service areas with their boundaries. Areas without a boundary have a null geometry.
+// @Tags Maps
+// @Produce json
+// @Param service_area_id query string false "Only this service area and the areas below it"
+// @Security BearerAuth
+// @Success 200 {object} responses.FeatureCollection
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/geo/service-areas [get]
+func (ctrl *GeoController) GetServiceAreaFeatures(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	areaIDs, ok := ctrl.areaFilter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if areaIDs != nil {
+		query = query.Where("id IN ?", areaIDs)
+	}
+	var areas []models.ServiceArea
+	if err := query.Order("code").Find(&areas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service areas"})
+		return
+	}
+
+	type areaCount struct {
+		ServiceAreaID uuid.UUID
+		Customers     int
+	}
+	var counts []areaCount
+	if err := ctrl.DB.Model(&models.Customer{}).
+		Select("service_area_id, COUNT(*) AS customers").
+		Where("tenant_id = ? AND service_area_id IS NOT NULL", tenantID).
+		Group("service_area_id").
+		Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count customers"})
+		return
+	}
+	customerCount := make(map[uuid.UUID]int, len(counts))
+	for _, count := range counts {
+		customerCount[count.ServiceAreaID] = count.Customers
+	}
+
+	features := make([]responses.Feature, 0, len(areas))
+	for _, area := range areas {
+		var geometry json.RawMessage
+		if area.Boundary != nil {
+			geometry = json.RawMessage(*area.Boundary)
+		}
+		features = append(features, responses.NewFeature(area.ID.String(), geometry, map[string]interface{}{
+			"id":             area.ID,
+			"code":           area.Code,
+			"name":           area.Name,
+			"type":           area.Type,
+			"parent_id":      area.ParentID,
+			"is_active":      area.IsActive,
+			"customer_count": customerCount[area.ID],
+		}))
+	}
+
+	c.JSON(http.StatusOK, responses.NewFeatureCollection(features))
+}
+
+// GetCustomerFeatures godoc
+// @Summary Customers as GeoJSON
+// @Description FeatureCollection of the customers that have a location, with their status and arrears. in_area tells whether the point lies inside the boundary of the customer's service area (null when the area has no boundary).
+// @Tags Maps
+// @Produce json
+// @Param service_area_id query string false "Only customers in this service area and the areas below it"
+// @Param has_arrears query bool false "Only customers with (true) or without (false) unpaid invoices"
+// @Param outside_area query bool false "Only customers located outside their service area's boundary"
+// @Param bbox query string false "min_lng,min_lat,max_lng,max_lat"
+// @Security BearerAuth
+// @Success 200 {object} responses.FeatureCollection
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/geo/customers [get]
+func (ctrl *GeoController) GetCustomerFeatures(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	box, err := parseBBox(c.Query("bbox"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	areaIDs, ok := ctrl.areaFilter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ? AND latitude IS NOT NULL AND longitude IS NOT NULL", tenantID)
+	if areaIDs != nil {
+		query = query.Where("service_area_id IN ?", areaIDs)
+	}
+	if box != nil {
+		query = query.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", box.MinLat, box.MaxLat, box.MinLng, box.MaxLng)
+	}
+	var customers []models.Customer
+	if err := query.Order("name").Find(&customers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customers"})
+		return
+	}
+
+	var arrears []customerArrears
+	if err := ctrl.DB.Model(&models.Invoice{}).
+		Select("customer_id, COUNT(*) AS unpaid_invoices, SUM(total_amount - total_paid) AS amount, MIN(usage_month) AS oldest_month").
+		Where("tenant_id = ? AND is_paid = ? AND carried_to_id IS NULL", tenantID, false).
+		Group("customer_id").
+		Scan(&arrears).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch arrears"})
+		return
+	}
+	arrearsByCustomer := make(map[uuid.UUID]customerArrears, len(arrears))
+	for _, a := range arrears {
+		arrearsByCustomer[a.CustomerID] = a
+	}
+
+	boundaries, err := ctrl.areaBoundaries(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service area boundaries"})
+		return
+	}
+
+	hasArrearsFilter := c.Query("has_arrears")
+	outsideOnly := c.Query("outside_area") == "true"
+	features := make([]responses.Feature, 0, len(customers))
+	for _, customer := range customers {
+		lat, lng := *customer.Latitude, *customer.Longitude
+
+		var inArea *bool
+		if customer.ServiceAreaID != nil {
+			if polygons := boundaries[*customer.ServiceAreaID]; polygons != nil {
+				inside := utils.BoundaryContains(polygons, lat, lng)
+				inArea = &inside
+			}
+		}
+		if outsideOnly && (inArea == nil || *inArea) {
+			continue
+		}
+
+		a := arrearsByCustomer[customer.ID]
+		if hasArrearsFilter != "" && (a.UnpaidInvoices > 0) != (hasArrearsFilter == "true") {
+			continue
+		}
+		var oldestUnpaid *string
+		if a.UnpaidInvoices > 0 {
+			oldestUnpaid = &a.OldestMonth
+		}
+
+		features = append(features, responses.NewFeature(customer.ID.String(), responses.PointGeometry(lat, lng), map[string]interface{}{
+			"id":                  customer.ID,
+			"name":                customer.Name,
+			"meter_number":        customer.MeterNumber,
+			"address":             customer.Address,
+			"service_area_id":     customer.ServiceAreaID,
+			"is_active":           customer.IsActive,
+			"status":              customer.Status,
+			"in_area":             inArea,
+			"unpaid_invoices":     a.UnpaidInvoices,
+			"arrears_amount":      a.Amount,
+			"oldest_unpaid_month": oldestUnpaid,
+		}))
+	}
+
+	c.JSON(http.StatusOK, responses.NewFeatureCollection(features))
+}
+
+// GetMeterFeatures godoc
+// @Summary Meters as GeoJSON
+// @Description FeatureCollection of the installed meters. A meter without its own location is placed at its customer's location (location_source tells which).
+// @Tags Maps
+// @Produce json
+// @Param service_area_id query string false "Only meters of customers in this service area and the areas below it"
+// @Param status query string false "Meter status (active, inactive, broken, replaced)"
+// @Param bbox query string false "min_lng,min_lat,max_lng,max_lat"
+// @Security BearerAuth
+// @Success 200 {object} responses.FeatureCollection
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/geo/meters [get]
+func (ctrl *GeoController) GetMeterFeatures(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	box, err := parseBBox(c.Query("bbox"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	areaIDs, ok := ctrl.areaFilter(c, tenantID)
+	if !ok {
+		return
+	}
+
+	query := ctrl.DB.Preload("Customer").Where("meters.tenant_id = ?", tenantID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("meters.status = ?", status)
+	} else {
+		query = query.Where("meters.status <> ?", models.MeterStatusReplaced)
+	}
+	if areaIDs != nil {
+		query = query.Where("meters.customer_id IN (?)",
+			ctrl.DB.Model(&models.Customer{}).Select("id").Where("tenant_id = ? AND service_area_id IN ?", tenantID, areaIDs))
+	}
+	var meters []models.Meter
+	if err := query.Order("meters.meter_number").Find(&meters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meters"})
+		return
+	}
+
+	type issueCount struct {
+		MeterID uuid.UUID
+		Issues  int
+	}
+	var counts []issueCount
+	if err := ctrl.DB.Model(&models.MeterIssue{}).
+		Select("meter_id, COUNT(*) AS issues").
+		Where("tenant_id = ? AND status IN ?", tenantID, []string{models.MeterIssueStatusOpen, models.MeterIssueStatusInProgress}).
+		Group("meter_id").
+		Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count meter issues"})
+		return
+	}
+	openIssues := make(map[uuid.UUID]int, len(counts))
+	for _, count := range counts {
+		openIssues[count.MeterID] = count.Issues
+	}
+
+	features := make([]responses.Feature, 0, len(meters))
+	for _, meter := range meters {
+		lat, lng, source := meter.Latitude, meter.Longitude, "meter"
+		if lat == nil || lng == nil {
+			lat, lng, source = meter.Customer.Latitude, meter.Customer.Longitude, "customer"
+		}
+		if lat == nil || lng == nil {
+			continue
+		}
+		if box != nil && (*lat < box.MinLat || *lat > box.MaxLat || *lng < box.MinLng || *lng > box.MaxLng) {
+			continue
+		}
+
+		features = append(features, responses.NewFeature(meter.ID.String(), responses.PointGeometry(*lat, *lng), map[string]interface{}{
+			"id":              meter.ID,
+			"meter_number":    meter.MeterNumber,
+			"status":          meter.Status,
+			"customer_id":     meter.CustomerID,
+			"customer_name":   meter.Customer.Name,
+			"next_calib_date": meter.NextCalibDate,
+			"open_issues":     openIssues[meter.ID],
+			"location_source": source,
+		}))
+	}
+
+	c.JSON(http.StatusOK, responses.NewFeatureCollection(features))
+}
+
+// areaBoundaries returns the parsed boundaries of the tenant's service areas
+// that have one
+func (ctrl *GeoController) areaBoundaries(tenantID uuid.UUID) (map[uuid.UUID][]utils.Polygon, error) {
+	var areas []models.ServiceArea
+	if err := ctrl.DB.Select("id", "tenant_id", "boundary").
+		Where("tenant_id = ? AND boundary IS NOT NULL", tenantID).
+		Find(&areas).Error; err != nil {
+		return nil, err
+	}
+	boundaries := make(map[uuid.UUID][]utils.Polygon, len(areas))
+	for i := range areas {
+		polygons, err := helpers.AreaBoundary(&areas[i])
+		if err != nil {
+			return nil, err
+		}
+		boundaries[areas[i].ID] = polygons
+	}
+	return boundaries, nil
+}
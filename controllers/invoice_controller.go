@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"github.com/adipras/tirta-saas-backend/helpers"
 	"net/http"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/autodebit"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
 	"github.com/adipras/tirta-saas-backend/responses"
 
 	"github.com/gin-gonic/gin"
@@ -67,74 +70,89 @@ func GenerateMonthlyInvoice(c *gin.Context) {
 		return
 	}
 
+	// Satu invoice per pelanggan: pembacaan dari beberapa meter digabung
+	customerOrder := []uuid.UUID{}
+	usagesByCustomer := map[uuid.UUID][]models.WaterUsage{}
+	for _, usage := range usages {
+		if _, ok := usagesByCustomer[usage.CustomerID]; !ok {
+			customerOrder = append(customerOrder, usage.CustomerID)
+		}
+		usagesByCustomer[usage.CustomerID] = append(usagesByCustomer[usage.CustomerID], usage)
+	}
+
 	created := 0
+	held := 0
 	skipped := 0
+	failed := []gin.H{}
+	fail := func(customerID uuid.UUID, message string) {
+		failed = append(failed, gin.H{"customer_id": customerID, "error": message})
+	}
 
-	for _, usage := range usages {
+	for _, customerID := range customerOrder {
 		// Cek apakah invoice sudah pernah dibuat
 		var existing models.Invoice
-		err := config.DB.Where("customer_id = ? AND usage_month = ? AND type = ?",
-			usage.CustomerID, usage.UsageMonth, "monthly").First(&existing).Error
+		err := config.DB.Where("customer_id = ? AND usage_month = ? AND type IN ?",
+			customerID, req.UsageMonth, helpers.UsageInvoiceTypes).First(&existing).Error
 		if err == nil {
 			skipped++
 			continue
 		}
 
-		// Ambil data pelanggan & SubscriptionType
+		// Ambil data pelanggan
 		var customer models.Customer
-		if err := config.DB.Where("id = ? AND tenant_id = ?", usage.CustomerID, tenantID).First(&customer).Error; err != nil {
+		if err := config.DB.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+			fail(customerID, "Pelanggan tidak ditemukan")
 			continue
 		}
 
-		var subType models.SubscriptionType
-		if err := config.DB.Where("id = ? AND tenant_id = ?", customer.SubscriptionID, tenantID).First(&subType).Error; err != nil {
+		// Invalid usage records and unreasonable totals are reported per customer
+		invoice, err := helpers.BuildUsageInvoice(config.DB, &customer, req.UsageMonth, usagesByCustomer[customerID], "monthly")
+		if errors.Is(err, helpers.ErrInvoiceUsageInvalid) || errors.Is(err, helpers.ErrInvoiceTotalOutOfRange) {
+			fail(customerID, err.Error())
 			continue
 		}
-
-		// Business rule validations
-		if usage.UsageM3 < 0 {
-			continue // Skip invalid usage records
-		}
-
-		if usage.AmountCalculated < 0 {
-			continue // Skip invalid calculated amounts
+		if err != nil {
+			fail(customerID, "Gagal menghitung invoice")
+			continue
 		}
-
-		total := usage.AmountCalculated + subType.MonthlyFee + subType.MaintenanceFee
-
-		// Validate calculated total is reasonable
-		if total <= 0 || total > 999999 {
-			continue // Skip invoices with invalid totals
+		if invoice == nil {
+			continue
 		}
 
-		// Calculate price per m3 safely
-		pricePerM3 := 0.0
-		if usage.UsageM3 > 0 {
-			pricePerM3 = usage.AmountCalculated / usage.UsageM3
+		if err := config.DB.Create(invoice).Error; err != nil {
+			fail(customerID, "Gagal menyimpan invoice")
+			continue
 		}
-
-		invoice := models.Invoice{
-			CustomerID:  usage.CustomerID,
-			UsageMonth:  usage.UsageMonth,
-			UsageM3:     usage.UsageM3,
-			Abonemen:    subType.MonthlyFee,
-			PricePerM3:  pricePerM3,
-			TotalAmount: total,
-			TotalPaid:   0,
-			IsPaid:      false,
-			TenantID:    tenantID,
-			Type:        "monthly",
+		created++
+		if invoice.OnHold {
+			held++
 		}
+	}
 
-		if err := config.DB.Create(&invoice).Error; err == nil {
-			created++
+	// Collect new bills from customers with an active auto-debit mandate;
+	// whatever cannot be collected stays unpaid for normal dunning. The
+	// invoices stand even if the run cannot be queued; it can be started
+	// with POST /api/mandates/run.
+	autoDebitQueued := false
+	if created > 0 {
+		if err := autodebit.Enqueue(config.DB, tenantID, req.UsageMonth, nil); err != nil {
+			logger.Error("Failed to queue auto-debit run", err, map[string]interface{}{
+				"tenant_id":   tenantID,
+				"usage_month": req.UsageMonth,
+			})
+		} else {
+			autoDebitQueued = true
 		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "Generate invoice selesai",
-		"created_count": created,
-		"skipped":       skipped,
+		"message":           "Generate invoice selesai",
+		"created_count":     created,
+		"held_count":        held,
+		"skipped":           skipped,
+		"failed_count":      len(failed),
+		"failed":            failed,
+		"auto_debit_queued": autoDebitQueued,
 	})
 }
 
@@ -183,6 +201,8 @@ func GetInvoices(c *gin.Context) {
 			TotalPaid:   invoice.TotalPaid,
 			IsPaid:      invoice.IsPaid,
 			Type:        invoice.Type,
+			OnHold:      invoice.OnHold,
+			HoldReason:  invoice.HoldReason,
 			CreatedAt:   invoice.CreatedAt,
 		}
 	}
@@ -243,6 +263,8 @@ func GetInvoice(c *gin.Context) {
 		TotalPaid:   invoice.TotalPaid,
 		IsPaid:      invoice.IsPaid,
 		Type:        invoice.Type,
+		OnHold:      invoice.OnHold,
+		HoldReason:  invoice.HoldReason,
 		CreatedAt:   invoice.CreatedAt,
 	}
 	c.JSON(http.StatusOK, response)
@@ -311,6 +333,8 @@ func UpdateInvoice(c *gin.Context) {
 		TotalPaid:   invoice.TotalPaid,
 		IsPaid:      invoice.IsPaid,
 		Type:        invoice.Type,
+		OnHold:      invoice.OnHold,
+		HoldReason:  invoice.HoldReason,
 		CreatedAt:   invoice.CreatedAt,
 	}
 	c.JSON(http.StatusOK, response)
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/pkg/leakdetect"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LeakDetectionController struct {
+	DB *gorm.DB
+}
+
+func NewLeakDetectionController(db *gorm.DB) *LeakDetectionController {
+	return &LeakDetectionController{DB: db}
+}
+
+// GetLeakSettings godoc
+// @Summary Get leak detection settings
+// @Description Get the tenant's smart meter leak thresholds, or the defaults when none are configured
+// @Tags Leak Detection
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} responses.LeakSettingsResponse
+// @Router /api/leak-detection/settings [get]
+func (ctrl *LeakDetectionController) GetLeakSettings(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := leakdetect.LoadConfig(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load leak detection settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToLeakSettingsResponse(cfg)})
+}
+
+// UpdateLeakSettings godoc
+// @Summary Update leak detection settings
+// @Description Change the tenant's smart meter leak thresholds. Omitted fields keep their current value.
+// @Tags Leak Detection
+// @Accept json
+// @Produce json
+// @Param request body requests.UpdateLeakSettingsRequest true "Leak detection settings"
+// @Security BearerAuth
+// @Success 200 {object} responses.LeakSettingsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/leak-detection/settings [put]
+func (ctrl *LeakDetectionController) UpdateLeakSettings(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.UpdateLeakSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := leakdetect.LoadConfig(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load leak detection settings"})
+		return
+	}
+	oldValues := responses.ToLeakSettingsResponse(cfg)
+
+	if req.IsEnabled != nil {
+		cfg.IsEnabled = *req.IsEnabled
+	}
+	if req.NightStartHour != nil {
+		cfg.NightStartHour = *req.NightStartHour
+	}
+	if req.NightEndHour != nil {
+		cfg.NightEndHour = *req.NightEndHour
+	}
+	if req.MinNightFlowLPH != nil {
+		cfg.MinNightFlowLPH = *req.MinNightFlowLPH
+	}
+	if req.LeakNights != nil {
+		cfg.LeakNights = *req.LeakNights
+	}
+	if req.BaselineDays != nil {
+		cfg.BaselineDays = *req.BaselineDays
+	}
+	if req.IncreasePercent != nil {
+		cfg.IncreasePercent = *req.IncreasePercent
+	}
+	if req.MinIncreaseM3 != nil {
+		cfg.MinIncreaseM3 = *req.MinIncreaseM3
+	}
+	if req.SustainedDays != nil {
+		cfg.SustainedDays = *req.SustainedDays
+	}
+	if req.NotifyCustomer != nil {
+		cfg.NotifyCustomer = *req.NotifyCustomer
+	}
+
+	// Flow is measured over whole hours, so the window needs at least two
+	if cfg.NightEndHour-cfg.NightStartHour < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "night_end_hour must be at least 2 hours after night_start_hour"})
+		return
+	}
+
+	if err := ctrl.DB.Save(cfg).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save leak detection settings"})
+		return
+	}
+
+	response := responses.ToLeakSettingsResponse(cfg)
+	audit.LogUpdate(c, "leak_detection_settings", cfg.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Leak detection settings updated successfully", "data": response})
+}
+
+// RunLeakDetection godoc
+// @Summary Run leak detection
+// @Description Check the smart meters' recent intervals for leaks now instead of waiting for the daily run. Suspected leaks are raised as leak meter issues and the customers notified.
+// @Tags Leak Detection
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} leakdetect.Summary
+// @Router /api/leak-detection/run [post]
+func (ctrl *LeakDetectionController) RunLeakDetection(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := leakdetect.Run(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Leak detection run failed", "data": summary})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Leak detection finished", "data": summary})
+}
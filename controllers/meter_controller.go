@@ -0,0 +1,608 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/pkg/calibration"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MeterController struct {
+	DB *gorm.DB
+}
+
+func NewMeterController(db *gorm.DB) *MeterController {
+	return &MeterController{DB: db}
+}
+
+// CreateMeter godoc
+// @Summary Register meter
+// @Description Register a water meter for a customer. A customer with several connections has one meter per connection.
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateMeterRequest true "Create meter request"
+// @Security BearerAuth
+// @Success 201 {object} responses.MeterResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/meters [post]
+func (ctrl *MeterController) CreateMeter(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreateMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerID, err := uuid.Parse(req.CustomerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	installDate, err := time.Parse("2006-01-02", req.InstallDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid install_date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	if err := helpers.ValidateLocation(req.Latitude, req.Longitude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var customer models.Customer
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", customerID, tenantID).First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+
+	var existing int64
+	ctrl.DB.Model(&models.Meter{}).Where("tenant_id = ? AND meter_number = ?", tenantID, req.MeterNumber).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Meter number already exists"})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	meter := models.Meter{
+		TenantID:       tenantID,
+		CustomerID:     customerID,
+		MeterNumber:    req.MeterNumber,
+		Brand:          req.Brand,
+		Model:          req.Model,
+		InstallDate:    installDate,
+		InitialReading: req.InitialReading,
+		RolloverAt:     req.RolloverAt,
+		Status:         models.MeterStatusActive,
+		Notes:          req.Notes,
+		Latitude:       req.Latitude,
+		Longitude:      req.Longitude,
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Omit("Customer").Create(&meter).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register meter"})
+		return
+	}
+	if err := helpers.RecordMeterHistory(tx, &meter, models.MeterActionInstall, "",
+		fmt.Sprintf("%s (awal %.2f)", meter.MeterNumber, meter.InitialReading), *userID, req.Notes); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register meter"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register meter"})
+		return
+	}
+
+	meter.Customer = customer
+	response := responses.ToMeterResponse(&meter)
+	audit.LogCreate(c, "meter", meter.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Meter registered successfully", "data": response})
+}
+
+// GetMeters godoc
+// @Summary List meters
+// @Description List meters, filterable by customer, status, brand, model and meter number
+// @Tags Meters
+// @Produce json
+// @Param customer_id query string false "Filter by customer"
+// @Param status query string false "active, inactive, broken or replaced"
+// @Param brand query string false "Filter by brand"
+// @Param model query string false "Filter by model"
+// @Param search query string false "Search meter number"
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterResponse
+// @Router /api/meters [get]
+func (ctrl *MeterController) GetMeters(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if brand := c.Query("brand"); brand != "" {
+		query = query.Where("brand = ?", brand)
+	}
+	if model := c.Query("model"); model != "" {
+		query = query.Where("model = ?", model)
+	}
+	if search := c.Query("search"); search != "" {
+		query = query.Where("meter_number LIKE ?", "%"+search+"%")
+	}
+
+	var meters []models.Meter
+	if err := query.Preload("Customer").Order("meter_number ASC").Find(&meters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meters"})
+		return
+	}
+
+	meterResponses := make([]responses.MeterResponse, len(meters))
+	for i := range meters {
+		meterResponses[i] = responses.ToMeterResponse(&meters[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": meterResponses, "total": len(meterResponses)})
+}
+
+// GetMeter godoc
+// @Summary Get meter
+// @Description Get a meter
+// @Tags Meters
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meters/{id} [get]
+func (ctrl *MeterController) GetMeter(c *gin.Context) {
+	meter, ok := ctrl.findMeter(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToMeterResponse(meter)})
+}
+
+// UpdateMeter godoc
+// @Summary Update meter
+// @Description Update meter details, calibration dates or status. Status changes are written to the meter history.
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.UpdateMeterRequest true "Update meter request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meters/{id} [put]
+func (ctrl *MeterController) UpdateMeter(c *gin.Context) {
+	meter, ok := ctrl.findMeter(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdateMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	oldValues := responses.ToMeterResponse(meter)
+	oldStatus := meter.Status
+
+	if req.Brand != "" {
+		meter.Brand = req.Brand
+	}
+	if req.Model != "" {
+		meter.Model = req.Model
+	}
+	if req.LastCalibDate != nil {
+		date, err := time.Parse("2006-01-02", *req.LastCalibDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_calib_date format. Use YYYY-MM-DD"})
+			return
+		}
+		meter.LastCalibDate = &date
+	}
+	if req.NextCalibDate != nil {
+		date, err := time.Parse("2006-01-02", *req.NextCalibDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid next_calib_date format. Use YYYY-MM-DD"})
+			return
+		}
+		meter.NextCalibDate = &date
+	}
+	if req.RolloverAt != nil {
+		meter.RolloverAt = *req.RolloverAt
+	}
+	if req.Status != "" {
+		if err := helpers.CheckMeterStatusChange(ctrl.DB, meter, req.Status); err != nil {
+			if helpers.IsMeterStatusError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter"})
+			return
+		}
+		meter.Status = req.Status
+	}
+	if req.Notes != "" {
+		meter.Notes = req.Notes
+	}
+	if req.Latitude != nil || req.Longitude != nil {
+		if err := helpers.ValidateLocation(req.Latitude, req.Longitude); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		meter.Latitude = req.Latitude
+		meter.Longitude = req.Longitude
+	}
+
+	tx := ctrl.DB.Begin()
+	if err := tx.Omit("Customer").Save(meter).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter"})
+		return
+	}
+	if meter.Status != oldStatus {
+		if err := helpers.RecordMeterHistory(tx, meter, models.MeterActionStatusChange, oldStatus, meter.Status, *userID, req.Notes); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter"})
+		return
+	}
+
+	response := responses.ToMeterResponse(meter)
+	audit.LogUpdate(c, "meter", meter.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meter updated successfully", "data": response})
+}
+
+// ReplaceMeter godoc
+// @Summary Replace meter
+// @Description Replace an active meter. The old meter's final reading is recorded as its last water usage, the old meter is marked replaced and a new meter is installed starting from new_initial_reading. Both readings are billed together on the customer's next invoice.
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.ReplaceMeterRequest true "Replace meter request"
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/meters/{id}/replace [post]
+func (ctrl *MeterController) ReplaceMeter(c *gin.Context) {
+	oldMeter, ok := ctrl.findMeter(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ReplaceMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if oldMeter.Status != models.MeterStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only active meters can be replaced"})
+		return
+	}
+
+	replacementDate := time.Now()
+	if req.ReplacementDate != "" {
+		date, err := time.Parse("2006-01-02", req.ReplacementDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid replacement_date format. Use YYYY-MM-DD"})
+			return
+		}
+		replacementDate = date
+	}
+
+	var finalPhotoID *uuid.UUID
+	if req.FinalPhotoID != nil && *req.FinalPhotoID != "" {
+		id, err := uuid.Parse(*req.FinalPhotoID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid final_photo_id"})
+			return
+		}
+		finalPhotoID = &id
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	oldValues := responses.ToMeterResponse(oldMeter)
+
+	var newMeter *models.Meter
+	var finalUsage *models.WaterUsage
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		newMeter, finalUsage, err = helpers.ReplaceMeter(tx, oldMeter, helpers.MeterReplacement{
+			Date:           replacementDate,
+			FinalReading:   req.FinalReading,
+			FinalPhotoID:   finalPhotoID,
+			MeterNumber:    req.NewMeterNumber,
+			Brand:          req.NewBrand,
+			Model:          req.NewModel,
+			InitialReading: req.NewInitialReading,
+			RolloverAt:     req.NewRolloverAt,
+			Reason:         req.Reason,
+			Notes:          req.Notes,
+			PerformedBy:    *userID,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, helpers.ErrMeterNumberTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if helpers.IsWaterUsageRuleError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace meter"})
+		return
+	}
+
+	newMeter.Customer = oldMeter.Customer
+	oldResponse := responses.ToMeterResponse(oldMeter)
+	newResponse := responses.ToMeterResponse(newMeter)
+	audit.LogUpdate(c, "meter", oldMeter.ID, oldValues, oldResponse)
+	audit.LogCreate(c, "meter", newMeter.ID, newResponse)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Meter replaced successfully",
+		"data": gin.H{
+			"old_meter":     oldResponse,
+			"new_meter":     newResponse,
+			"final_reading": responses.ToWaterUsageResponse(finalUsage),
+		},
+	})
+}
+
+// CalibrateMeter godoc
+// @Summary Record meter calibration
+// @Description Record a calibration of the meter. last_calib_date is set to the calibration date and next_calib_date from the tenant's calibration policy unless given. A failed calibration marks the meter broken.
+// @Tags Meters
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Param request body requests.RecordCalibrationRequest true "Record calibration request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meters/{id}/calibrate [post]
+func (ctrl *MeterController) CalibrateMeter(c *gin.Context) {
+	meter, ok := ctrl.findMeter(c)
+	if !ok {
+		return
+	}
+
+	var req requests.RecordCalibrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if meter.Status == models.MeterStatusReplaced {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Replaced meters cannot be calibrated"})
+		return
+	}
+
+	calibDate := time.Now()
+	if req.CalibrationDate != "" {
+		date, err := time.Parse("2006-01-02", req.CalibrationDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calibration_date format. Use YYYY-MM-DD"})
+			return
+		}
+		calibDate = date
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	oldValues := responses.ToMeterResponse(meter)
+	oldLastCalib := "-"
+	if meter.LastCalibDate != nil {
+		oldLastCalib = meter.LastCalibDate.Format("2006-01-02")
+	}
+	oldStatus := meter.Status
+
+	meter.LastCalibDate = &calibDate
+	if req.NextCalibDate != "" {
+		date, err := time.Parse("2006-01-02", req.NextCalibDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid next_calib_date format. Use YYYY-MM-DD"})
+			return
+		}
+		meter.NextCalibDate = &date
+	} else {
+		policies, err := calibration.LoadPolicies(ctrl.DB, meter.TenantID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calibration policies"})
+			return
+		}
+		if policy := calibration.PolicyFor(policies, meter); policy != nil {
+			next := calibration.NextDate(meter, policy)
+			meter.NextCalibDate = &next
+		} else {
+			meter.NextCalibDate = nil
+		}
+	}
+	if req.Result == models.CalibrationFailed {
+		meter.Status = models.MeterStatusBroken
+	}
+
+	nextCalib := "-"
+	if meter.NextCalibDate != nil {
+		nextCalib = meter.NextCalibDate.Format("2006-01-02")
+	}
+	notes := fmt.Sprintf("Hasil: %s", req.Result)
+	if req.CertificateNo != "" {
+		notes += fmt.Sprintf(", sertifikat %s", req.CertificateNo)
+	}
+	if req.Notes != "" {
+		notes += ". " + req.Notes
+	}
+
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Customer").Save(meter).Error; err != nil {
+			return err
+		}
+		if err := helpers.RecordMeterHistory(tx, meter, models.MeterActionCalibrate, oldLastCalib,
+			fmt.Sprintf("%s (berikutnya %s)", calibDate.Format("2006-01-02"), nextCalib), *userID, notes); err != nil {
+			return err
+		}
+		if meter.Status != oldStatus {
+			return helpers.RecordMeterHistory(tx, meter, models.MeterActionStatusChange, oldStatus, meter.Status, *userID, notes)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record calibration"})
+		return
+	}
+
+	response := responses.ToMeterResponse(meter)
+	audit.LogUpdate(c, "meter", meter.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calibration recorded successfully", "data": response})
+}
+
+// GetMeterReadings godoc
+// @Summary Get meter readings
+// @Description Get the reading history of a meter, newest month first
+// @Tags Meters
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Security BearerAuth
+// @Success 200 {array} responses.WaterUsageResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meters/{id}/readings [get]
+func (ctrl *MeterController) GetMeterReadings(c *gin.Context) {
+	meter, ok := ctrl.findMeter(c)
+	if !ok {
+		return
+	}
+
+	var usages []models.WaterUsage
+	if err := ctrl.DB.Where("meter_id = ? AND tenant_id = ?", meter.ID, meter.TenantID).
+		Order("usage_month DESC").Find(&usages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch readings"})
+		return
+	}
+
+	usageResponses := make([]responses.WaterUsageResponse, len(usages))
+	for i := range usages {
+		usageResponses[i] = responses.ToWaterUsageResponse(&usages[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": usageResponses, "total": len(usageResponses)})
+}
+
+// GetMeterHistory godoc
+// @Summary Get meter history
+// @Description Get installation, replacement, calibration and status changes of a meter
+// @Tags Meters
+// @Produce json
+// @Param id path string true "Meter ID"
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterHistoryResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meters/{id}/history [get]
+func (ctrl *MeterController) GetMeterHistory(c *gin.Context) {
+	meter, ok := ctrl.findMeter(c)
+	if !ok {
+		return
+	}
+
+	var history []models.MeterHistory
+	if err := ctrl.DB.Preload("Meter").Preload("Customer").Preload("User").
+		Where("meter_id = ? AND tenant_id = ?", meter.ID, meter.TenantID).
+		Order("created_at DESC").Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meter history"})
+		return
+	}
+
+	historyResponses := make([]responses.MeterHistoryResponse, len(history))
+	for i := range history {
+		historyResponses[i] = responses.ToMeterHistoryResponse(&history[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": historyResponses, "total": len(historyResponses)})
+}
+
+func (ctrl *MeterController) findMeter(c *gin.Context) (*models.Meter, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	meterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter ID"})
+		return nil, false
+	}
+
+	var meter models.Meter
+	if err := ctrl.DB.Preload("Customer").Where("id = ? AND tenant_id = ?", meterID, tenantID).First(&meter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter not found"})
+		return nil, false
+	}
+	return &meter, true
+}
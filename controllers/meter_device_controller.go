@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeviceKeyHeader carries the key of a meter device pushing readings
+const DeviceKeyHeader = "X-Device-Key"
+
+type MeterDeviceController struct {
+	DB *gorm.DB
+}
+
+func NewMeterDeviceController(db *gorm.DB) *MeterDeviceController {
+	return &MeterDeviceController{DB: db}
+}
+
+// GetMeterDevices godoc
+// @Summary List meter devices
+// @Description List the smart meter devices of the tenant
+// @Tags Meter Devices
+// @Produce json
+// @Security BearerAuth
+// @Param meter_id query string false "Filter by meter"
+// @Param is_active query bool false "Filter by active status"
+// @Success 200 {array} models.MeterDevice
+// @Router /api/meter-devices [get]
+func (ctrl *MeterDeviceController) GetMeterDevices(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Model(&models.MeterDevice{}).Where("tenant_id = ?", tenantID)
+	if meterID := c.Query("meter_id"); meterID != "" {
+		query = query.Where("meter_id = ?", meterID)
+	}
+	if isActive := c.Query("is_active"); isActive != "" {
+		active, _ := strconv.ParseBool(isActive)
+		query = query.Where("is_active = ?", active)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var devices []models.MeterDevice
+	if err := query.Preload("Meter").Order("created_at DESC").Find(&devices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meter devices"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": devices, "total": total})
+}
+
+// CreateMeterDevice godoc
+// @Summary Register a meter device
+// @Description Register a smart meter device for a meter. The device key is returned once and cannot be retrieved later.
+// @Tags Meter Devices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body requests.CreateMeterDeviceRequest true "Device"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-devices [post]
+func (ctrl *MeterDeviceController) CreateMeterDevice(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreateMeterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var meter models.Meter
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", req.MeterID, tenantID).First(&meter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter not found"})
+		return
+	}
+	if meter.Status != models.MeterStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Devices can only be registered for active meters"})
+		return
+	}
+
+	key, prefix, hash, err := utils.GenerateDeviceKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate device key"})
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = meter.MeterNumber
+	}
+	device := models.MeterDevice{
+		TenantID:       tenantID,
+		MeterID:        meter.ID,
+		Name:           name,
+		SerialNumber:   req.SerialNumber,
+		KeyPrefix:      prefix,
+		KeyHash:        hash,
+		IsActive:       true,
+		LitersPerPulse: req.LitersPerPulse,
+		PulseOffset:    req.PulseOffset,
+		CreatedBy:      helpers.GetUserIDFromContext(c),
+	}
+	if err := ctrl.DB.Omit("Tenant", "Meter").Create(&device).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register meter device"})
+		return
+	}
+	device.Meter = meter
+
+	audit.LogCreate(c, "meter_device", device.ID, device)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Meter device registered successfully. Store the device key now, it is not shown again.",
+		"data": gin.H{
+			"device":     device,
+			"device_key": key,
+		},
+	})
+}
+
+// GetMeterDevice godoc
+// @Summary Get meter device
+// @Tags Meter Devices
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID"
+// @Success 200 {object} models.MeterDevice
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-devices/{id} [get]
+func (ctrl *MeterDeviceController) GetMeterDevice(c *gin.Context) {
+	device, ok := ctrl.findDevice(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": device})
+}
+
+// UpdateMeterDevice godoc
+// @Summary Update meter device
+// @Description Rename a device, enable/disable its key or change its pulse settings
+// @Tags Meter Devices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID"
+// @Param request body requests.UpdateMeterDeviceRequest true "Changes"
+// @Success 200 {object} models.MeterDevice
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-devices/{id} [put]
+func (ctrl *MeterDeviceController) UpdateMeterDevice(c *gin.Context) {
+	device, ok := ctrl.findDevice(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdateMeterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldValues := *device
+	if req.Name != nil {
+		device.Name = *req.Name
+	}
+	if req.SerialNumber != nil {
+		device.SerialNumber = *req.SerialNumber
+	}
+	if req.IsActive != nil {
+		device.IsActive = *req.IsActive
+	}
+	if req.LitersPerPulse != nil {
+		device.LitersPerPulse = *req.LitersPerPulse
+	}
+	if req.PulseOffset != nil {
+		device.PulseOffset = *req.PulseOffset
+	}
+	if err := ctrl.DB.Model(device).Select("name", "serial_number", "is_active", "liters_per_pulse", "pulse_offset").Updates(device).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter device"})
+		return
+	}
+
+	audit.LogUpdate(c, "meter_device", device.ID, oldValues, device)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Meter device updated successfully",
+		"data":    device,
+	})
+}
+
+// RotateDeviceKey godoc
+// @Summary Rotate a meter device key
+// @Description Replace the device key. The old key stops working immediately.
+// @Tags Meter Devices
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-devices/{id}/rotate-key [post]
+func (ctrl *MeterDeviceController) RotateDeviceKey(c *gin.Context) {
+	device, ok := ctrl.findDevice(c)
+	if !ok {
+		return
+	}
+
+	key, prefix, hash, err := utils.GenerateDeviceKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate device key"})
+		return
+	}
+	if err := ctrl.DB.Model(device).Updates(map[string]interface{}{
+		"key_prefix": prefix,
+		"key_hash":   hash,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate device key"})
+		return
+	}
+	device.KeyPrefix = prefix
+
+	audit.LogSensitiveOperation(c, models.ActionUpdate, "meter_device", "Rotated meter device key", map[string]interface{}{
+		"device_id": device.ID,
+		"meter_id":  device.MeterID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device key rotated successfully. Store the device key now, it is not shown again.",
+		"data": gin.H{
+			"device":     device,
+			"device_key": key,
+		},
+	})
+}
+
+// DeleteMeterDevice godoc
+// @Summary Delete meter device
+// @Description Remove a device. Its stored interval readings are kept.
+// @Tags Meter Devices
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-devices/{id} [delete]
+func (ctrl *MeterDeviceController) DeleteMeterDevice(c *gin.Context) {
+	device, ok := ctrl.findDevice(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.DB.Delete(device).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete meter device"})
+		return
+	}
+
+	audit.LogDelete(c, "meter_device", device.ID, device)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meter device deleted successfully"})
+}
+
+// GetMeterIntervals godoc
+// @Summary List interval readings of a device's meter
+// @Description Raw interval readings of the meter the device is attached to, oldest first
+// @Tags Meter Devices
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID"
+// @Param from query string false "From date (YYYY-MM-DD), defaults to 7 days ago"
+// @Param to query string false "To date (YYYY-MM-DD), inclusive"
+// @Param limit query int false "Maximum readings (default 1000, max 5000)"
+// @Success 200 {array} models.MeterInterval
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-devices/{id}/intervals [get]
+func (ctrl *MeterDeviceController) GetMeterIntervals(c *gin.Context) {
+	device, ok := ctrl.findDevice(c)
+	if !ok {
+		return
+	}
+
+	from := time.Now().AddDate(0, 0, -7)
+	if value := c.Query("from"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format. Use YYYY-MM-DD"})
+			return
+		}
+		from = date
+	}
+	query := ctrl.DB.Where("tenant_id = ? AND meter_id = ? AND recorded_at >= ?", device.TenantID, device.MeterID, from)
+	if value := c.Query("to"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format. Use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("recorded_at < ?", date.AddDate(0, 0, 1))
+	}
+
+	limit := 1000
+	if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 {
+		limit = value
+	}
+	if limit > 5000 {
+		limit = 5000
+	}
+
+	var intervals []models.MeterInterval
+	if err := query.Order("recorded_at ASC").Limit(limit).Find(&intervals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch interval readings"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": intervals, "total": len(intervals)})
+}
+
+// DeviceAuth authenticates a meter device by the key in the X-Device-Key header
+func (ctrl *MeterDeviceController) DeviceAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		device, err := helpers.AuthenticateDevice(ctrl.DB, c.GetHeader(DeviceKeyHeader))
+		if err != nil {
+			if errors.Is(err, helpers.ErrInvalidDeviceKey) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate device"})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("meter_device", device)
+		c.Set("tenant_id", device.TenantID)
+		c.Next()
+	}
+}
+
+// IngestDeviceReadings godoc
+// @Summary Push interval readings from a meter device
+// @Description Devices push batches of register readings with their own timestamps. Readings already received for the same timestamp are skipped, so a batch can be resent safely. Completed months are rolled up into the meter's monthly water usage with reading method automatic.
+// @Tags Meter Devices
+// @Accept json
+// @Produce json
+// @Param X-Device-Key header string true "Device key"
+// @Param request body requests.DeviceReadingBatchRequest true "Interval readings"
+// @Success 200 {object} helpers.IngestResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/devices/readings [post]
+func (ctrl *MeterDeviceController) IngestDeviceReadings(c *gin.Context) {
+	device := c.MustGet("meter_device").(*models.MeterDevice)
+
+	var req requests.DeviceReadingBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	readings := make([]helpers.IntervalReading, len(req.Readings))
+	for i, item := range req.Readings {
+		readings[i] = helpers.IntervalReading{RecordedAt: item.Timestamp, Reading: item.Reading, Pulses: item.Pulses}
+	}
+
+	result, err := helpers.IngestIntervalReadings(ctrl.DB, device, readings)
+	if err != nil {
+		switch {
+		case errors.Is(err, helpers.ErrIntervalBatchSize):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, helpers.ErrDeviceMeterInactive):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store readings"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Readings received",
+		"data":    result,
+	})
+}
+
+func (ctrl *MeterDeviceController) findDevice(c *gin.Context) (*models.MeterDevice, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter device ID"})
+		return nil, false
+	}
+
+	var device models.MeterDevice
+	if err := ctrl.DB.Preload("Meter").Where("id = ? AND tenant_id = ?", deviceID, tenantID).First(&device).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter device not found"})
+		return nil, false
+	}
+	return &device, true
+}
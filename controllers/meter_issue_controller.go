@@ -0,0 +1,630 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errIssueStatusChanged is returned when a concurrent request moved the issue
+// before the transaction locked it
+var errIssueStatusChanged = errors.New("meter issue status changed")
+
+type MeterIssueController struct {
+	DB *gorm.DB
+}
+
+func NewMeterIssueController(db *gorm.DB) *MeterIssueController {
+	return &MeterIssueController{DB: db}
+}
+
+// ReportMeterIssue godoc
+// @Summary Report meter issue
+// @Description Report a problem with a meter (broken, leak, stuck, incorrect). The SLA deadline is set from the priority.
+// @Tags Meter Issues
+// @Accept json
+// @Produce json
+// @Param request body requests.ReportMeterIssueRequest true "Report meter issue request"
+// @Security BearerAuth
+// @Success 201 {object} responses.MeterIssueResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-issues [post]
+func (ctrl *MeterIssueController) ReportMeterIssue(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.ReportMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meterID, err := uuid.Parse(req.MeterID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter ID"})
+		return
+	}
+
+	var meter models.Meter
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", meterID, tenantID).First(&meter).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter not found"})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	priority := req.Priority
+	if priority == "" {
+		priority = models.MeterIssuePriorityNormal
+	}
+
+	issue := models.MeterIssue{
+		TenantID:    tenantID,
+		MeterID:     meter.ID,
+		ReportedBy:  userID,
+		IssueType:   req.IssueType,
+		Description: req.Description,
+		Status:      models.MeterIssueStatusOpen,
+		Priority:    priority,
+		PhotoURL:    req.PhotoURL,
+	}
+	issue.SetDueAt()
+
+	if err := ctrl.DB.Omit("Meter").Create(&issue).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report meter issue"})
+		return
+	}
+
+	created, err := ctrl.loadIssue(tenantID, issue.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load meter issue"})
+		return
+	}
+
+	response := responses.ToMeterIssueResponse(created)
+	audit.LogCreate(c, "meter_issue", issue.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Meter issue reported successfully", "data": response})
+}
+
+// GetMeterIssues godoc
+// @Summary List meter issues
+// @Description List meter issues, oldest SLA deadline first
+// @Tags Meter Issues
+// @Produce json
+// @Param status query string false "open, in_progress, resolved or closed"
+// @Param priority query string false "low, normal, high or critical"
+// @Param issue_type query string false "broken, leak, stuck, incorrect or other"
+// @Param meter_id query string false "Filter by meter"
+// @Param assigned_to query string false "Filter by assignee, 'me' for the current user"
+// @Param overdue query bool false "Only unresolved issues past their SLA deadline"
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterIssueResponse
+// @Router /api/meter-issues [get]
+func (ctrl *MeterIssueController) GetMeterIssues(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if priority := c.Query("priority"); priority != "" {
+		query = query.Where("priority = ?", priority)
+	}
+	if issueType := c.Query("issue_type"); issueType != "" {
+		query = query.Where("issue_type = ?", issueType)
+	}
+	if meterID := c.Query("meter_id"); meterID != "" {
+		query = query.Where("meter_id = ?", meterID)
+	}
+	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
+		if assignedTo == "me" {
+			userID := helpers.GetUserIDFromContext(c)
+			if userID == nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+				return
+			}
+			query = query.Where("assigned_to = ?", *userID)
+		} else {
+			query = query.Where("assigned_to = ?", assignedTo)
+		}
+	}
+	if c.Query("overdue") == "true" {
+		query = query.Where("status IN ? AND due_at < ?",
+			[]string{models.MeterIssueStatusOpen, models.MeterIssueStatusInProgress}, time.Now())
+	}
+
+	var issues []models.MeterIssue
+	if err := ctrl.preloadIssue(query).Order("due_at ASC").Find(&issues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meter issues"})
+		return
+	}
+
+	issueResponses := make([]responses.MeterIssueResponse, len(issues))
+	for i := range issues {
+		issueResponses[i] = responses.ToMeterIssueResponse(&issues[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": issueResponses, "total": len(issueResponses)})
+}
+
+// GetMeterIssue godoc
+// @Summary Get meter issue
+// @Description Get a meter issue
+// @Tags Meter Issues
+// @Produce json
+// @Param id path string true "Meter issue ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-issues/{id} [get]
+func (ctrl *MeterIssueController) GetMeterIssue(c *gin.Context) {
+	issue, ok := ctrl.findIssue(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToMeterIssueResponse(issue)})
+}
+
+// UpdateMeterIssue godoc
+// @Summary Assign or prioritise meter issue
+// @Description Assign a meter issue to a staff member or change its priority. A priority change moves the SLA deadline.
+// @Tags Meter Issues
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter issue ID"
+// @Param request body requests.UpdateMeterIssueRequest true "Update meter issue request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-issues/{id} [put]
+func (ctrl *MeterIssueController) UpdateMeterIssue(c *gin.Context) {
+	issue, ok := ctrl.findIssue(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdateMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if issue.Status == models.MeterIssueStatusResolved || issue.Status == models.MeterIssueStatusClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Resolved or closed issues cannot be reassigned"})
+		return
+	}
+
+	oldValues := responses.ToMeterIssueResponse(issue)
+
+	if req.AssignedTo != nil {
+		if *req.AssignedTo == "" {
+			issue.AssignedTo = nil
+			issue.AssignedAt = nil
+		} else {
+			assigneeID, err := uuid.Parse(*req.AssignedTo)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignee ID"})
+				return
+			}
+			var assignee models.User
+			if err := ctrl.DB.Where("id = ? AND tenant_id = ?", assigneeID, issue.TenantID).First(&assignee).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee not found in this tenant"})
+				return
+			}
+			now := time.Now()
+			issue.AssignedTo = &assignee.ID
+			issue.AssignedAt = &now
+		}
+	}
+	if req.Priority != "" && req.Priority != issue.Priority {
+		issue.Priority = req.Priority
+		issue.SetDueAt()
+	}
+
+	if err := ctrl.DB.Model(issue).Select("assigned_to", "assigned_at", "priority", "due_at").Updates(issue).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter issue"})
+		return
+	}
+
+	ctrl.respondIssue(c, issue.TenantID, issue.ID, "Meter issue updated successfully", oldValues)
+}
+
+// StartMeterIssue godoc
+// @Summary Start work on meter issue
+// @Description Move an open meter issue to in_progress. Unassigned issues are assigned to the current user.
+// @Tags Meter Issues
+// @Produce json
+// @Param id path string true "Meter issue ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-issues/{id}/start [post]
+func (ctrl *MeterIssueController) StartMeterIssue(c *gin.Context) {
+	issue, ok := ctrl.findIssue(c)
+	if !ok {
+		return
+	}
+
+	if !ctrl.checkTransition(c, issue, models.MeterIssueStatusInProgress) {
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var oldValues responses.MeterIssueResponse
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ctrl.lockIssue(tx, issue, models.MeterIssueStatusInProgress); err != nil {
+			return err
+		}
+		oldValues = responses.ToMeterIssueResponse(issue)
+
+		now := time.Now()
+		issue.Status = models.MeterIssueStatusInProgress
+		issue.StartedAt = &now
+		if issue.AssignedTo == nil {
+			issue.AssignedTo = userID
+			issue.AssignedAt = &now
+		}
+		return tx.Model(issue).Select("status", "started_at", "assigned_to", "assigned_at").Updates(issue).Error
+	})
+	if errors.Is(err, errIssueStatusChanged) {
+		ctrl.checkTransition(c, issue, models.MeterIssueStatusInProgress)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meter issue"})
+		return
+	}
+
+	ctrl.respondIssue(c, issue.TenantID, issue.ID, "Meter issue in progress", oldValues)
+}
+
+// ResolveMeterIssue godoc
+// @Summary Resolve meter issue
+// @Description Resolve an in-progress meter issue. The meter status is set to meter_status when given, otherwise the meter keeps its status; the resolution is written to the meter history.
+// @Tags Meter Issues
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter issue ID"
+// @Param request body requests.ResolveMeterIssueRequest true "Resolve meter issue request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-issues/{id}/resolve [post]
+func (ctrl *MeterIssueController) ResolveMeterIssue(c *gin.Context) {
+	issue, ok := ctrl.findIssue(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ResolveMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !ctrl.checkTransition(c, issue, models.MeterIssueStatusResolved) {
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var oldValues responses.MeterIssueResponse
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ctrl.lockIssue(tx, issue, models.MeterIssueStatusResolved); err != nil {
+			return err
+		}
+		oldValues = responses.ToMeterIssueResponse(issue)
+
+		now := time.Now()
+		issue.Status = models.MeterIssueStatusResolved
+		issue.ResolvedBy = userID
+		issue.ResolvedAt = &now
+		issue.Resolution = req.Resolution
+		if err := tx.Model(issue).Select("status", "resolved_by", "resolved_at", "resolution").Updates(issue).Error; err != nil {
+			return err
+		}
+
+		var meter models.Meter
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", issue.MeterID, issue.TenantID).First(&meter).Error; err != nil {
+			return err
+		}
+		meterStatus := req.MeterStatus
+		if meterStatus == "" || meter.Status == models.MeterStatusReplaced {
+			// Without a requested status, and always for a replaced meter, the
+			// meter keeps its status; the issue is only closed out
+			meterStatus = meter.Status
+		}
+		if err := helpers.CheckMeterStatusChange(tx, &meter, meterStatus); err != nil {
+			return err
+		}
+		oldStatus := meter.Status
+		if meterStatus != oldStatus {
+			if err := tx.Model(&models.Meter{}).Where("id = ?", meter.ID).Update("status", meterStatus).Error; err != nil {
+				return err
+			}
+		}
+
+		notes := req.Resolution
+		if req.Notes != "" {
+			notes = req.Resolution + " - " + req.Notes
+		}
+		return helpers.RecordMeterHistory(tx, &meter, models.MeterActionIssueResolved, oldStatus, meterStatus, *userID,
+			fmt.Sprintf("[%s] %s", issue.IssueType, notes))
+	})
+	if errors.Is(err, errIssueStatusChanged) {
+		ctrl.checkTransition(c, issue, models.MeterIssueStatusResolved)
+		return
+	}
+	if err != nil {
+		if helpers.IsMeterStatusError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve meter issue"})
+		return
+	}
+
+	ctrl.respondIssue(c, issue.TenantID, issue.ID, "Meter issue resolved successfully", oldValues)
+}
+
+// CloseMeterIssue godoc
+// @Summary Close meter issue
+// @Description Close a resolved meter issue after the resolution has been verified
+// @Tags Meter Issues
+// @Accept json
+// @Produce json
+// @Param id path string true "Meter issue ID"
+// @Param request body requests.CloseMeterIssueRequest false "Close meter issue request"
+// @Security BearerAuth
+// @Success 200 {object} responses.MeterIssueResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-issues/{id}/close [post]
+func (ctrl *MeterIssueController) CloseMeterIssue(c *gin.Context) {
+	issue, ok := ctrl.findIssue(c)
+	if !ok {
+		return
+	}
+
+	var req requests.CloseMeterIssueRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if !ctrl.checkTransition(c, issue, models.MeterIssueStatusClosed) {
+		return
+	}
+
+	oldValues := responses.ToMeterIssueResponse(issue)
+
+	now := time.Now()
+	issue.Status = models.MeterIssueStatusClosed
+	issue.ClosedAt = &now
+	if req.Notes != "" {
+		issue.Resolution = issue.Resolution + "\n" + req.Notes
+	}
+
+	if err := ctrl.DB.Model(issue).Select("status", "closed_at", "resolution").Updates(issue).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close meter issue"})
+		return
+	}
+
+	ctrl.respondIssue(c, issue.TenantID, issue.ID, "Meter issue closed successfully", oldValues)
+}
+
+// CustomerReportMeterIssue godoc
+// @Summary Report meter issue (customer)
+// @Description Customer reports a problem with one of their meters
+// @Tags Customer Self-Service
+// @Accept json
+// @Produce json
+// @Param request body requests.CustomerReportMeterIssueRequest true "Report meter issue request"
+// @Security BearerAuth
+// @Success 201 {object} responses.MeterIssueResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/customer/meter-issues [post]
+func (ctrl *MeterIssueController) CustomerReportMeterIssue(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	var req requests.CustomerReportMeterIssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var meterID *uuid.UUID
+	if req.MeterID != "" {
+		id, err := uuid.Parse(req.MeterID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter ID"})
+			return
+		}
+		meterID = &id
+	}
+
+	meter, err := helpers.ResolveUsageMeter(ctrl.DB, tenantID, customerID, meterID)
+	if err != nil {
+		if errors.Is(err, helpers.ErrUsageMeterNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, helpers.ErrUsageMeterRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat data meter"})
+		return
+	}
+	if meter == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter tidak ditemukan"})
+		return
+	}
+
+	issue := models.MeterIssue{
+		TenantID:             tenantID,
+		MeterID:              meter.ID,
+		ReportedByCustomerID: &customerID,
+		IssueType:            req.IssueType,
+		Description:          req.Description,
+		Status:               models.MeterIssueStatusOpen,
+		Priority:             models.MeterIssuePriorityNormal,
+		PhotoURL:             req.PhotoURL,
+	}
+	issue.SetDueAt()
+
+	if err := ctrl.DB.Omit("Meter").Create(&issue).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengirim laporan"})
+		return
+	}
+
+	created, err := ctrl.loadIssue(tenantID, issue.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat laporan"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Laporan gangguan meter berhasil dikirim", "data": responses.ToMeterIssueResponse(created)})
+}
+
+// CustomerGetMeterIssues godoc
+// @Summary List my meter issues (customer)
+// @Description List meter issues on the customer's meters
+// @Tags Customer Self-Service
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} responses.MeterIssueResponse
+// @Router /api/customer/meter-issues [get]
+func (ctrl *MeterIssueController) CustomerGetMeterIssues(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	var issues []models.MeterIssue
+	query := ctrl.DB.Where("tenant_id = ? AND meter_id IN (?)", tenantID,
+		ctrl.DB.Model(&models.Meter{}).Select("id").Where("customer_id = ? AND tenant_id = ?", customerID, tenantID))
+	if err := ctrl.preloadIssue(query).Order("created_at DESC").Find(&issues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat laporan"})
+		return
+	}
+
+	issueResponses := make([]responses.MeterIssueResponse, len(issues))
+	for i := range issues {
+		issueResponses[i] = responses.ToMeterIssueResponse(&issues[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": issueResponses, "total": len(issueResponses)})
+}
+
+func (ctrl *MeterIssueController) checkTransition(c *gin.Context, issue *models.MeterIssue, status string) bool {
+	if !issue.CanTransitionTo(status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Cannot move issue from %s to %s", issue.Status, status),
+		})
+		return false
+	}
+	return true
+}
+
+// lockIssue reloads the issue with a row lock inside tx and re-checks the
+// transition, so concurrent requests cannot both apply it
+func (ctrl *MeterIssueController) lockIssue(tx *gorm.DB, issue *models.MeterIssue, status string) error {
+	var locked models.MeterIssue
+	if err := ctrl.preloadIssue(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", issue.ID, issue.TenantID).First(&locked).Error; err != nil {
+		return err
+	}
+	*issue = locked
+	if !issue.CanTransitionTo(status) {
+		return errIssueStatusChanged
+	}
+	return nil
+}
+
+func (ctrl *MeterIssueController) respondIssue(c *gin.Context, tenantID, issueID uuid.UUID, message string, oldValues responses.MeterIssueResponse) {
+	updated, err := ctrl.loadIssue(tenantID, issueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load meter issue"})
+		return
+	}
+
+	response := responses.ToMeterIssueResponse(updated)
+	audit.LogUpdate(c, "meter_issue", issueID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": response})
+}
+
+func (ctrl *MeterIssueController) preloadIssue(query *gorm.DB) *gorm.DB {
+	return query.Preload("Meter").Preload("Meter.Customer").Preload("Reporter").
+		Preload("ReportingCustomer").Preload("Assignee").Preload("Resolver")
+}
+
+func (ctrl *MeterIssueController) loadIssue(tenantID, issueID uuid.UUID) (*models.MeterIssue, error) {
+	var issue models.MeterIssue
+	if err := ctrl.preloadIssue(ctrl.DB).Where("id = ? AND tenant_id = ?", issueID, tenantID).First(&issue).Error; err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+func (ctrl *MeterIssueController) findIssue(c *gin.Context) (*models.MeterIssue, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	issueID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter issue ID"})
+		return nil, false
+	}
+
+	issue, err := ctrl.loadIssue(tenantID, issueID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter issue not found"})
+		return nil, false
+	}
+	return issue, true
+}
@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// photoUploadURLTTL is how long a pre-signed photo upload URL stays valid
+const photoUploadURLTTL = 15 * time.Minute
+
+type MeterPhotoController struct {
+	DB *gorm.DB
+}
+
+func NewMeterPhotoController(db *gorm.DB) *MeterPhotoController {
+	return &MeterPhotoController{DB: db}
+}
+
+// UploadMeterPhoto godoc
+// @Summary Upload a meter photo
+// @Description Upload a JPEG or PNG photo of a meter register. The returned photo ID is passed as photo_id when recording the reading.
+// @Tags Meter Photos
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param photo formData file true "Meter photo (JPEG or PNG, max 5MB)"
+// @Success 201 {object} models.MeterPhoto
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/meter-photos [post]
+func (ctrl *MeterPhotoController) UploadMeterPhoto(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileHeader, err := c.FormFile("photo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No photo uploaded"})
+		return
+	}
+	if fileHeader.Size > utils.MaxImageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": helpers.ErrPhotoTooLarge.Error()})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read photo"})
+		return
+	}
+	defer file.Close()
+
+	photo := models.MeterPhoto{
+		TenantID:   tenantID,
+		UploadedBy: helpers.GetUserIDFromContext(c),
+	}
+	if err := helpers.StoreMeterPhoto(ctrl.DB, &photo, file); err != nil {
+		respondPhotoStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Meter photo uploaded successfully",
+		"data":    photo,
+	})
+}
+
+// CreatePhotoUploadURL godoc
+// @Summary Create a pre-signed meter photo upload URL
+// @Description Reserve a meter photo and get a short-lived URL the photo can be PUT to without a bearer token, e.g. by a mobile app uploading in the background
+// @Tags Meter Photos
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Router /api/meter-photos/upload-url [post]
+func (ctrl *MeterPhotoController) CreatePhotoUploadURL(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiresAt := time.Now().Add(photoUploadURLTTL)
+	photo := models.MeterPhoto{
+		TenantID:        tenantID,
+		UploadedBy:      helpers.GetUserIDFromContext(c),
+		Status:          models.MeterPhotoAwaitingUpload,
+		UploadExpiresAt: &expiresAt,
+	}
+	if err := ctrl.DB.Omit("Tenant", "WaterUsage", "Uploader").Create(&photo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload URL"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Upload URL created successfully",
+		"data": gin.H{
+			"photo_id":   photo.ID,
+			"upload_url": "/api/meter-photos/upload/" + utils.SignUploadToken(photo.ID, expiresAt),
+			"method":     http.MethodPut,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// CompletePhotoUpload godoc
+// @Summary Upload a meter photo to a pre-signed URL
+// @Description Upload the raw JPEG or PNG bytes as the request body. The token in the URL authorises the upload; no bearer token is needed.
+// @Tags Meter Photos
+// @Accept image/jpeg
+// @Accept image/png
+// @Produce json
+// @Param token path string true "Upload token"
+// @Success 200 {object} models.MeterPhoto
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/meter-photos/upload/{token} [put]
+func (ctrl *MeterPhotoController) CompletePhotoUpload(c *gin.Context) {
+	photoID, err := utils.VerifyUploadToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired upload URL"})
+		return
+	}
+
+	var photo models.MeterPhoto
+	if err := ctrl.DB.Where("id = ? AND status = ?", photoID, models.MeterPhotoAwaitingUpload).First(&photo).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired upload URL"})
+		return
+	}
+
+	if err := helpers.StoreMeterPhoto(ctrl.DB, &photo, c.Request.Body); err != nil {
+		respondPhotoStoreError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Meter photo uploaded successfully",
+		"data":    photo,
+	})
+}
+
+// GetMeterPhoto godoc
+// @Summary Get meter photo details
+// @Description Get a meter photo's EXIF capture time, GPS position and verification against its reading
+// @Tags Meter Photos
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Photo ID"
+// @Success 200 {object} models.MeterPhoto
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-photos/{id} [get]
+func (ctrl *MeterPhotoController) GetMeterPhoto(c *gin.Context) {
+	photo, ok := ctrl.findPhoto(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": photo})
+}
+
+// GetMeterPhotoFile godoc
+// @Summary Download a meter photo
+// @Tags Meter Photos
+// @Produce image/jpeg
+// @Produce image/png
+// @Security BearerAuth
+// @Param id path string true "Photo ID"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-photos/{id}/file [get]
+func (ctrl *MeterPhotoController) GetMeterPhotoFile(c *gin.Context) {
+	photo, ok := ctrl.findPhoto(c)
+	if !ok {
+		return
+	}
+	if photo.Status != models.MeterPhotoUploaded {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter photo has not been uploaded"})
+		return
+	}
+	c.File(photo.FilePath)
+}
+
+// GetMeterPhotoThumbnail godoc
+// @Summary Download a meter photo thumbnail
+// @Tags Meter Photos
+// @Produce image/jpeg
+// @Security BearerAuth
+// @Param id path string true "Photo ID"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-photos/{id}/thumbnail [get]
+func (ctrl *MeterPhotoController) GetMeterPhotoThumbnail(c *gin.Context) {
+	photo, ok := ctrl.findPhoto(c)
+	if !ok {
+		return
+	}
+	if photo.Status != models.MeterPhotoUploaded {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter photo has not been uploaded"})
+		return
+	}
+	c.File(photo.ThumbnailPath)
+}
+
+// AttachMeterPhoto godoc
+// @Summary Attach a photo to a reading
+// @Description Attach an uploaded photo to a reading recorded without one. The EXIF capture time is checked against the reading time.
+// @Tags Meter Photos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Photo ID"
+// @Param request body requests.AttachMeterPhotoRequest true "Reading to attach to"
+// @Success 200 {object} models.MeterPhoto
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/meter-photos/{id}/attach [post]
+func (ctrl *MeterPhotoController) AttachMeterPhoto(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	photoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter photo ID"})
+		return
+	}
+
+	var req requests.AttachMeterPhotoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	usageID, err := uuid.Parse(req.WaterUsageID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid water_usage_id"})
+		return
+	}
+
+	var usage models.WaterUsage
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", usageID, tenantID).First(&usage).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Water usage not found"})
+		return
+	}
+
+	var photo *models.MeterPhoto
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		photo, err = helpers.AttachPhotoToUsage(tx, photoID, &usage)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, helpers.ErrUsagePhotoNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, helpers.ErrUsagePhotoInUse), errors.Is(err, helpers.ErrUsageHasPhoto):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach meter photo"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Meter photo attached successfully",
+		"data": gin.H{
+			"photo":       photo,
+			"water_usage": responses.ToWaterUsageResponse(&usage),
+		},
+	})
+}
+
+func respondPhotoStoreError(c *gin.Context, err error) {
+	if errors.Is(err, helpers.ErrPhotoTooLarge) || errors.Is(err, helpers.ErrPhotoInvalidType) ||
+		errors.Is(err, helpers.ErrPhotoTooManyPixels) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store meter photo"})
+}
+
+func (ctrl *MeterPhotoController) findPhoto(c *gin.Context) (*models.MeterPhoto, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	photoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meter photo ID"})
+		return nil, false
+	}
+
+	var photo models.MeterPhoto
+	if err := ctrl.DB.Preload("Uploader").Where("id = ? AND tenant_id = ?", photoID, tenantID).First(&photo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meter photo not found"})
+		return nil, false
+	}
+	return &photo, true
+}
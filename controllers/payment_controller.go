@@ -2,16 +2,20 @@ package controllers
 
 import (
 	"github.com/adipras/tirta-saas-backend/helpers"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
 )
 
 // CreatePayment godoc
@@ -47,78 +51,47 @@ func CreatePayment(c *gin.Context) {
 
 	}
 
-	// Ambil invoice terkait
-	var invoice models.Invoice
-	if err := config.DB.Where("id = ? AND tenant_id = ?", req.InvoiceID, tenantID).
-		First(&invoice).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice tidak ditemukan"})
-		return
-	}
-
-	if invoice.IsPaid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tagihan sudah lunas"})
-		return
-	}
-
-	// Business rule validations
-	if req.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment amount must be greater than zero"})
-		return
-	}
-
-	if req.Amount > 999999 { // Max payment amount validation
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment amount exceeds maximum allowed limit"})
-		return
-	}
+	// Buat record pembayaran dan perbarui invoice dalam satu transaksi.
+	// Petugas penerima dicatat; jika ia punya sesi kas terbuka, pembayaran masuk ke sesi tsb
+	tx := config.DB.Begin()
 
-	if invoice.TotalPaid+req.Amount > invoice.TotalAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Pembayaran melebihi total tagihan. Sisa tagihan: %.2f", invoice.TotalAmount-invoice.TotalPaid),
-		})
+	payment, invoice, err := helpers.ApplyPayment(tx, helpers.PaymentInput{
+		TenantID:        tenantID,
+		InvoiceID:       req.InvoiceID,
+		Amount:          req.Amount,
+		PaymentMethodID: req.PaymentMethodID,
+		ReferenceNumber: req.ReferenceNumber,
+		Notes:           req.Notes,
+		ReceivedBy:      helpers.GetUserIDFromContext(c),
+	})
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrInvoiceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case helpers.IsPaymentRuleError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat pembayaran"})
+		}
 		return
 	}
 
-	// Buat record pembayaran
-	payment := models.Payment{
-		InvoiceID: req.InvoiceID,
-		Amount:    req.Amount,
-		TenantID:  tenantID,
-	}
-	if err := config.DB.Create(&payment).Error; err != nil {
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat pembayaran"})
 		return
 	}
 
-	// Hitung total bayar baru
-	var totalPaid float64
-	config.DB.Model(&models.Payment{}).
-		Where("invoice_id = ?", req.InvoiceID).
-		Select("SUM(amount)").Scan(&totalPaid)
-
-	// Update invoice
-	invoice.TotalPaid = totalPaid
-	invoice.IsPaid = totalPaid >= invoice.TotalAmount
-	if err := config.DB.Save(&invoice).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui status invoice"})
-		return
-	}
-
-	// Jika invoice pendaftaran dan sudah lunas → aktifkan customer
-	if invoice.Type == "registration" && invoice.IsPaid {
-		if err := config.DB.Model(&models.Customer{}).
-			Where("id = ? AND tenant_id = ?", invoice.CustomerID, tenantID).
-			Update("is_active", true).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengaktifkan pelanggan"})
-			return
-		}
-	}
+	audit.LogPayment(c, invoice.ID, payment.ID, payment.Amount, true, "")
 
 	// Kirim response
 	res := responses.PaymentResponse{
-		ID:        payment.ID,
-		InvoiceID: payment.InvoiceID,
-		Amount:    payment.Amount,
-		PaidAt:    payment.CreatedAt,
+		ID:            payment.ID,
+		InvoiceID:     payment.InvoiceID,
+		Amount:        payment.Amount,
+		PaidAt:        payment.CreatedAt,
+		CashSessionID: payment.CashSessionID,
+		ReceiptNumber: payment.ReceiptNumber,
 	}
 	c.JSON(http.StatusCreated, res)
 }
@@ -250,6 +223,11 @@ func UpdatePayment(c *gin.Context) {
 		return
 	}
 
+	if payment.Status == models.PaymentStatusReversed || payment.Type == models.PaymentTypeReversal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pembayaran yang sudah direversal tidak dapat diubah"})
+		return
+	}
+
 	type UpdatePaymentInput struct {
 		Amount float64 `json:"amount" binding:"required,min=0"`
 	}
@@ -292,22 +270,38 @@ func UpdatePayment(c *gin.Context) {
 		return
 	}
 
-	// Update payment
+	// Update payment and invoice total paid
+	tx := config.DB.Begin()
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", paymentID, tenantID).First(&payment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return
+	}
+	if err := helpers.CheckPaymentEditable(tx, &payment); err != nil {
+		tx.Rollback()
+		respondPaymentLocked(c, err, "Gagal memperbarui pembayaran")
+		return
+	}
+
 	payment.Amount = input.Amount
-	if err := config.DB.Save(&payment).Error; err != nil {
+	if err := tx.Save(&payment).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui pembayaran"})
 		return
 	}
 
-	// Update invoice total paid
-	var newTotalPaid float64
-	config.DB.Model(&models.Payment{}).
-		Where("invoice_id = ?", payment.InvoiceID).
-		Select("SUM(amount)").Scan(&newTotalPaid)
+	if err := helpers.RecalculateInvoicePayments(tx, &invoice); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui status invoice"})
+		return
+	}
 
-	invoice.TotalPaid = newTotalPaid
-	invoice.IsPaid = newTotalPaid >= invoice.TotalAmount
-	config.DB.Save(&invoice)
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui pembayaran"})
+		return
+	}
 
 	c.JSON(http.StatusOK, payment)
 }
@@ -337,34 +331,212 @@ func DeletePayment(c *gin.Context) {
 		return
 	}
 
-	// Store invoice ID before deleting payment
-	invoiceID := payment.InvoiceID
+	// Reversed payments and reversal rows are part of the ledger; undo them with a new reversal instead
+	if payment.Status == models.PaymentStatusReversed || payment.Type == models.PaymentTypeReversal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pembayaran yang sudah direversal tidak dapat dihapus"})
+		return
+	}
+
+	tx := config.DB.Begin()
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", paymentID, tenantID).First(&payment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return
+	}
+	if err := helpers.CheckPaymentEditable(tx, &payment); err != nil {
+		tx.Rollback()
+		respondPaymentLocked(c, err, "Gagal menghapus pembayaran")
+		return
+	}
 
 	// Delete payment
-	if err := config.DB.Delete(&payment).Error; err != nil {
+	if err := tx.Delete(&payment).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus pembayaran"})
 		return
 	}
 
-	// Update invoice total paid
+	// Update invoice total paid; a registration invoice that is no longer paid deactivates the customer
 	var invoice models.Invoice
-	if err := config.DB.Where("id = ? AND tenant_id = ?", invoiceID, tenantID).First(&invoice).Error; err == nil {
-		var newTotalPaid float64
-		config.DB.Model(&models.Payment{}).
-			Where("invoice_id = ?", invoiceID).
-			Select("COALESCE(SUM(amount), 0)").Scan(&newTotalPaid)
-
-		invoice.TotalPaid = newTotalPaid
-		invoice.IsPaid = newTotalPaid >= invoice.TotalAmount
-		config.DB.Save(&invoice)
-
-		// If this was a registration invoice and is no longer paid, deactivate customer
-		if invoice.Type == "registration" && !invoice.IsPaid {
-			config.DB.Model(&models.Customer{}).
-				Where("id = ?", invoice.CustomerID).
-				Update("is_active", false)
+	if err := tx.Where("id = ? AND tenant_id = ?", payment.InvoiceID, tenantID).First(&invoice).Error; err == nil {
+		if err := helpers.RecalculateInvoicePayments(tx, &invoice); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui status invoice"})
+			return
 		}
 	}
 
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus pembayaran"})
+		return
+	}
+
+	audit.LogDelete(c, "payment", payment.ID, payment)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Pembayaran berhasil dihapus"})
 }
+
+// respondPaymentLocked answers a failed CheckPaymentEditable
+func respondPaymentLocked(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, helpers.ErrPaymentReversed) || errors.Is(err, helpers.ErrPaymentSessionClosed) ||
+		errors.Is(err, helpers.ErrPaymentReceiptIssued) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
+
+// ReversePayment godoc
+// @Summary Reverse payment
+// @Description Reverse a payment (bounced transfer, wrong posting). Records a linked negative payment, reopens the invoice and, for registration invoices, deactivates the customer again. With disposition "credit" the amount is kept as customer credit.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param request body requests.ReversePaymentRequest true "Reverse payment request"
+// @Security BearerAuth
+// @Success 201 {object} responses.PaymentReversalResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/{id}/reverse [post]
+func ReversePayment(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var req requests.ReversePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Disposition == "" {
+		req.Disposition = models.ReversalDispositionReturned
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	now := time.Now()
+
+	tx := config.DB.Begin()
+
+	// Lock the payment so two reversals cannot both pass the status check
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", paymentID, tenantID).
+		First(&payment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pembayaran tidak ditemukan"})
+		return
+	}
+
+	if payment.Type == models.PaymentTypeReversal {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Baris reversal tidak dapat direversal"})
+		return
+	}
+	if payment.Status == models.PaymentStatusReversed {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pembayaran sudah direversal"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", payment.InvoiceID, tenantID).First(&invoice).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data invoice"})
+		return
+	}
+	wasPaid := invoice.IsPaid
+
+	reversal := models.Payment{
+		TenantID:        tenantID,
+		InvoiceID:       payment.InvoiceID,
+		Amount:          -payment.Amount,
+		Penalty:         -payment.Penalty,
+		PaymentMethodID: payment.PaymentMethodID,
+		ReceivedBy:      userID,
+		ReferenceNumber: payment.ReferenceNumber,
+		Notes:           req.Notes,
+		Status:          models.PaymentStatusCompleted,
+		Type:            models.PaymentTypeReversal,
+		ReversalOfID:    &payment.ID,
+		ReversalReason:  req.Reason,
+	}
+	if err := tx.Create(&reversal).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat reversal"})
+		return
+	}
+
+	payment.Status = models.PaymentStatusReversed
+	payment.ReversalReason = req.Reason
+	payment.ReversedAt = &now
+	payment.ReversedBy = userID
+	if err := tx.Save(&payment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui pembayaran"})
+		return
+	}
+
+	if err := helpers.RecalculateInvoicePayments(tx, &invoice); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui status invoice"})
+		return
+	}
+
+	if req.Disposition == models.ReversalDispositionCredit {
+		credit := models.CustomerCredit{
+			TenantID:   tenantID,
+			CustomerID: invoice.CustomerID,
+			Amount:     payment.Amount,
+			Type:       models.CreditTypeReversal,
+			PaymentID:  &payment.ID,
+			Reference:  payment.ReferenceNumber,
+			Notes:      req.Reason,
+			CreatedBy:  userID,
+		}
+		if err := tx.Create(&credit).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat kredit pelanggan"})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mencatat reversal"})
+		return
+	}
+
+	audit.LogPaymentReversal(c, invoice.ID, payment.ID, reversal.ID, payment.Amount, req.Reason, req.Disposition)
+	if invoice.Type == "registration" && wasPaid && !invoice.IsPaid {
+		audit.LogActivation(c, invoice.CustomerID, false)
+	}
+
+	var customer models.Customer
+	config.DB.Select("is_active").Where("id = ? AND tenant_id = ?", invoice.CustomerID, tenantID).First(&customer)
+	balance, _ := helpers.GetCustomerCreditBalance(config.DB, tenantID, invoice.CustomerID)
+
+	c.JSON(http.StatusCreated, responses.PaymentReversalResponse{
+		OriginalPaymentID: payment.ID,
+		ReversalPaymentID: reversal.ID,
+		InvoiceID:         invoice.ID,
+		Amount:            payment.Amount,
+		Reason:            req.Reason,
+		Disposition:       req.Disposition,
+		InvoiceTotalPaid:  invoice.TotalPaid,
+		InvoiceIsPaid:     invoice.IsPaid,
+		CustomerActive:    customer.IsActive,
+		CreditBalance:     balance,
+		ReversedAt:        now,
+	})
+}
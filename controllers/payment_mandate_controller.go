@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/pkg/autodebit"
+	"github.com/adipras/tirta-saas-backend/pkg/paymentprovider"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentMandateController struct {
+	DB *gorm.DB
+}
+
+func NewPaymentMandateController(db *gorm.DB) *PaymentMandateController {
+	return &PaymentMandateController{DB: db}
+}
+
+// CreatePaymentMandate godoc
+// @Summary Create payment mandate
+// @Description Register an auto-debit mandate for a customer. A customer can have one active mandate at a time.
+// @Tags Payment Mandates
+// @Accept json
+// @Produce json
+// @Param request body requests.CreatePaymentMandateRequest true "Create mandate request"
+// @Security BearerAuth
+// @Success 201 {object} responses.PaymentMandateResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/mandates [post]
+func (ctrl *PaymentMandateController) CreatePaymentMandate(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreatePaymentMandateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, ok := paymentprovider.Get(req.Provider); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Unknown payment provider",
+			"providers": paymentprovider.Names(),
+		})
+		return
+	}
+
+	var customer models.Customer
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", req.CustomerID, tenantID).First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+
+	if req.PaymentMethodID != nil {
+		var method models.PaymentMethod
+		// Auto-debit payments are booked through ApplyPayment, which only accepts active methods
+		if err := ctrl.DB.Where("id = ? AND tenant_id = ? AND is_active = ?", *req.PaymentMethodID, tenantID, true).First(&method).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Metode pembayaran tidak ditemukan atau tidak aktif"})
+			return
+		}
+	}
+
+	var activeCount int64
+	ctrl.DB.Model(&models.PaymentMandate{}).
+		Where("tenant_id = ? AND customer_id = ? AND status = ?", tenantID, req.CustomerID, models.MandateStatusActive).
+		Count(&activeCount)
+	if activeCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Customer already has an active mandate"})
+		return
+	}
+
+	mask := req.AccountMask
+	if mask == "" {
+		mask = maskAccountToken(req.AccountToken)
+	}
+
+	mandate := models.PaymentMandate{
+		TenantID:        tenantID,
+		CustomerID:      req.CustomerID,
+		Provider:        req.Provider,
+		AccountToken:    req.AccountToken,
+		AccountMask:     mask,
+		MaxAmount:       req.MaxAmount,
+		PaymentMethodID: req.PaymentMethodID,
+		Status:          models.MandateStatusActive,
+		Notes:           req.Notes,
+		CreatedBy:       helpers.GetUserIDFromContext(c),
+	}
+	if err := ctrl.DB.Create(&mandate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create mandate"})
+		return
+	}
+	mandate.Customer = customer
+
+	response := responses.ToPaymentMandateResponse(&mandate)
+	audit.LogCreate(c, "payment_mandate", mandate.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Mandate created successfully",
+		"data":    response,
+	})
+}
+
+// GetPaymentMandates godoc
+// @Summary List payment mandates
+// @Description List auto-debit mandates, filterable by customer, status and provider
+// @Tags Payment Mandates
+// @Produce json
+// @Param customer_id query string false "Filter by customer"
+// @Param status query string false "active, suspended or revoked"
+// @Param provider query string false "Filter by provider"
+// @Security BearerAuth
+// @Success 200 {array} responses.PaymentMandateResponse
+// @Router /api/mandates [get]
+func (ctrl *PaymentMandateController) GetPaymentMandates(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if provider := c.Query("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+
+	var mandates []models.PaymentMandate
+	if err := query.Preload("Customer").Order("created_at DESC").Find(&mandates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mandates"})
+		return
+	}
+
+	mandateResponses := make([]responses.PaymentMandateResponse, len(mandates))
+	for i := range mandates {
+		mandateResponses[i] = responses.ToPaymentMandateResponse(&mandates[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": mandateResponses, "total": len(mandateResponses)})
+}
+
+// GetPaymentMandate godoc
+// @Summary Get payment mandate
+// @Description Get a mandate with its collection attempts
+// @Tags Payment Mandates
+// @Produce json
+// @Param id path string true "Mandate ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.PaymentMandateResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/mandates/{id} [get]
+func (ctrl *PaymentMandateController) GetPaymentMandate(c *gin.Context) {
+	mandate, ok := ctrl.findMandate(c)
+	if !ok {
+		return
+	}
+
+	var attempts []models.AutoDebitAttempt
+	if err := ctrl.DB.Where("mandate_id = ?", mandate.ID).Order("attempted_at DESC").Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attempts"})
+		return
+	}
+
+	attemptResponses := make([]responses.AutoDebitAttemptResponse, len(attempts))
+	for i := range attempts {
+		attemptResponses[i] = responses.ToAutoDebitAttemptResponse(&attempts[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     responses.ToPaymentMandateResponse(mandate),
+		"attempts": attemptResponses,
+	})
+}
+
+// UpdatePaymentMandate godoc
+// @Summary Update payment mandate
+// @Description Change the limit, payment method or notes, or suspend/reactivate a mandate. Reactivating resets the failure counter.
+// @Tags Payment Mandates
+// @Accept json
+// @Produce json
+// @Param id path string true "Mandate ID"
+// @Param request body requests.UpdatePaymentMandateRequest true "Update mandate request"
+// @Security BearerAuth
+// @Success 200 {object} responses.PaymentMandateResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/mandates/{id} [put]
+func (ctrl *PaymentMandateController) UpdatePaymentMandate(c *gin.Context) {
+	mandate, ok := ctrl.findMandate(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdatePaymentMandateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if mandate.Status == models.MandateStatusRevoked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revoked mandates cannot be changed"})
+		return
+	}
+
+	oldValues := responses.ToPaymentMandateResponse(mandate)
+
+	if req.MaxAmount != nil {
+		mandate.MaxAmount = *req.MaxAmount
+	}
+	if req.PaymentMethodID != nil {
+		var method models.PaymentMethod
+		// Auto-debit payments are booked through ApplyPayment, which only accepts active methods
+		if err := ctrl.DB.Where("id = ? AND tenant_id = ? AND is_active = ?", *req.PaymentMethodID, mandate.TenantID, true).First(&method).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Metode pembayaran tidak ditemukan atau tidak aktif"})
+			return
+		}
+		mandate.PaymentMethodID = req.PaymentMethodID
+	}
+	if req.Notes != nil {
+		mandate.Notes = *req.Notes
+	}
+	if req.Status != "" && req.Status != mandate.Status {
+		if req.Status == models.MandateStatusActive {
+			var activeCount int64
+			ctrl.DB.Model(&models.PaymentMandate{}).
+				Where("tenant_id = ? AND customer_id = ? AND status = ? AND id <> ?", mandate.TenantID, mandate.CustomerID, models.MandateStatusActive, mandate.ID).
+				Count(&activeCount)
+			if activeCount > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Customer already has an active mandate"})
+				return
+			}
+			mandate.ConsecutiveFailures = 0
+		}
+		mandate.Status = req.Status
+	}
+
+	if err := ctrl.DB.Omit("Customer", "PaymentMethod").Save(mandate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mandate"})
+		return
+	}
+
+	response := responses.ToPaymentMandateResponse(mandate)
+	audit.LogUpdate(c, "payment_mandate", mandate.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Mandate updated successfully",
+		"data":    response,
+	})
+}
+
+// RevokePaymentMandate godoc
+// @Summary Revoke payment mandate
+// @Description Permanently revoke a mandate, e.g. at the customer's request. The account token is discarded.
+// @Tags Payment Mandates
+// @Accept json
+// @Produce json
+// @Param id path string true "Mandate ID"
+// @Param request body requests.RevokePaymentMandateRequest true "Revoke request"
+// @Security BearerAuth
+// @Success 200 {object} responses.PaymentMandateResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/mandates/{id}/revoke [post]
+func (ctrl *PaymentMandateController) RevokePaymentMandate(c *gin.Context) {
+	mandate, ok := ctrl.findMandate(c)
+	if !ok {
+		return
+	}
+
+	var req requests.RevokePaymentMandateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if mandate.Status == models.MandateStatusRevoked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mandate is already revoked"})
+		return
+	}
+
+	oldValues := responses.ToPaymentMandateResponse(mandate)
+
+	now := time.Now()
+	mandate.Status = models.MandateStatusRevoked
+	mandate.RevokedAt = &now
+	mandate.AccountToken = "revoked"
+	if mandate.Notes != "" {
+		mandate.Notes += "\n"
+	}
+	mandate.Notes += "Dicabut: " + req.Reason
+
+	if err := ctrl.DB.Omit("Customer", "PaymentMethod").Save(mandate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke mandate"})
+		return
+	}
+
+	response := responses.ToPaymentMandateResponse(mandate)
+	audit.LogUpdate(c, "payment_mandate", mandate.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Mandate revoked successfully",
+		"data":    response,
+	})
+}
+
+// GetAutoDebitAttempts godoc
+// @Summary List auto-debit attempts
+// @Description List collection attempts, filterable by invoice, customer and status
+// @Tags Payment Mandates
+// @Produce json
+// @Param invoice_id query string false "Filter by invoice"
+// @Param customer_id query string false "Filter by customer"
+// @Param status query string false "pending, succeeded, failed, skipped or abandoned"
+// @Security BearerAuth
+// @Success 200 {array} responses.AutoDebitAttemptResponse
+// @Router /api/mandates/attempts [get]
+func (ctrl *PaymentMandateController) GetAutoDebitAttempts(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if invoiceID := c.Query("invoice_id"); invoiceID != "" {
+		query = query.Where("invoice_id = ?", invoiceID)
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var attempts []models.AutoDebitAttempt
+	if err := query.Order("attempted_at DESC").Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attempts"})
+		return
+	}
+
+	attemptResponses := make([]responses.AutoDebitAttemptResponse, len(attempts))
+	for i := range attempts {
+		attemptResponses[i] = responses.ToAutoDebitAttemptResponse(&attempts[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": attemptResponses, "total": len(attemptResponses)})
+}
+
+// RunAutoDebit godoc
+// @Summary Run auto-debit
+// @Description Attempt collection of unpaid monthly invoices for a month from customers with active mandates. Runs automatically after invoice generation; use this to retry failed invoices.
+// @Tags Payment Mandates
+// @Accept json
+// @Produce json
+// @Param request body requests.RunAutoDebitRequest true "Run request"
+// @Security BearerAuth
+// @Success 200 {object} autodebit.Summary
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/mandates/run [post]
+func (ctrl *PaymentMandateController) RunAutoDebit(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.RunAutoDebitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := time.Parse("2006-01", req.UsageMonth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan YYYY-MM"})
+		return
+	}
+
+	summary, err := autodebit.Run(ctrl.DB, tenantID, req.UsageMonth)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Auto-debit run failed", "data": summary})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Auto-debit run finished",
+		"data":    summary,
+	})
+}
+
+func (ctrl *PaymentMandateController) findMandate(c *gin.Context) (*models.PaymentMandate, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	mandateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mandate ID"})
+		return nil, false
+	}
+
+	var mandate models.PaymentMandate
+	if err := ctrl.DB.Preload("Customer").Where("id = ? AND tenant_id = ?", mandateID, tenantID).First(&mandate).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mandate not found"})
+		return nil, false
+	}
+	return &mandate, true
+}
+
+// maskAccountToken keeps only the last four characters for display
+func maskAccountToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return "****" + token[len(token)-4:]
+}
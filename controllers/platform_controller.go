@@ -655,6 +655,7 @@ func GetTenantSettings(c *gin.Context) {
 		TimeZone:            settings.TimeZone,
 		Language:            settings.Language,
 		Currency:            settings.Currency,
+		RequireReadingPhoto: settings.RequireReadingPhoto,
 		CreatedAt:           settings.CreatedAt,
 		UpdatedAt:           settings.UpdatedAt,
 	}
@@ -754,6 +755,9 @@ func UpdateTenantSettings(c *gin.Context) {
 	if req.Language != "" {
 		settings.Language = req.Language
 	}
+	if req.RequireReadingPhoto != nil {
+		settings.RequireReadingPhoto = *req.RequireReadingPhoto
+	}
 	
 	if err := config.DB.Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
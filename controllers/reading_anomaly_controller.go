@@ -0,0 +1,585 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/pkg/autodebit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// anomalyHistoryMonths is how many readings the review chart shows
+const anomalyHistoryMonths = 13
+
+var (
+	errAnomalyNoMeter     = errors.New("Reading has no registered meter to raise an issue for")
+	errAnomalyReviewed    = errors.New("Anomaly has already been reviewed")
+	errAnomalyIssueRaised = errors.New("A meter issue has already been raised for this anomaly")
+)
+
+type ReadingAnomalyController struct {
+	DB *gorm.DB
+}
+
+func NewReadingAnomalyController(db *gorm.DB) *ReadingAnomalyController {
+	return &ReadingAnomalyController{DB: db}
+}
+
+// GetAnomalySettings godoc
+// @Summary Get anomaly detection settings
+// @Description Get the tenant's reading anomaly thresholds, or the defaults when none are configured
+// @Tags Reading Anomalies
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} responses.AnomalySettingsResponse
+// @Router /api/reading-anomalies/settings [get]
+func (ctrl *ReadingAnomalyController) GetAnomalySettings(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := helpers.LoadAnomalyConfig(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load anomaly settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToAnomalySettingsResponse(cfg)})
+}
+
+// UpdateAnomalySettings godoc
+// @Summary Update anomaly detection settings
+// @Description Change the tenant's reading anomaly thresholds. Omitted fields keep their current value. Applies to readings recorded from now on.
+// @Tags Reading Anomalies
+// @Accept json
+// @Produce json
+// @Param request body requests.UpdateAnomalySettingsRequest true "Anomaly settings"
+// @Security BearerAuth
+// @Success 200 {object} responses.AnomalySettingsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reading-anomalies/settings [put]
+func (ctrl *ReadingAnomalyController) UpdateAnomalySettings(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.UpdateAnomalySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := helpers.LoadAnomalyConfig(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load anomaly settings"})
+		return
+	}
+	oldValues := responses.ToAnomalySettingsResponse(cfg)
+
+	if req.IsEnabled != nil {
+		cfg.IsEnabled = *req.IsEnabled
+	}
+	if req.HistoryMonths != nil {
+		cfg.HistoryMonths = *req.HistoryMonths
+	}
+	if req.MinHistoryMonths != nil {
+		cfg.MinHistoryMonths = *req.MinHistoryMonths
+	}
+	if req.StdDevMultiplier != nil {
+		cfg.StdDevMultiplier = *req.StdDevMultiplier
+	}
+	if req.HighThresholdPercent != nil {
+		cfg.HighThresholdPercent = *req.HighThresholdPercent
+	}
+	if req.LowThresholdPercent != nil {
+		cfg.LowThresholdPercent = *req.LowThresholdPercent
+	}
+	if req.MinDeviationM3 != nil {
+		cfg.MinDeviationM3 = *req.MinDeviationM3
+	}
+	if req.UseSeasonal != nil {
+		cfg.UseSeasonal = *req.UseSeasonal
+	}
+	if req.StuckMonths != nil {
+		cfg.StuckMonths = *req.StuckMonths
+	}
+	if req.MaxMonthlyUsageM3 != nil {
+		cfg.MaxMonthlyUsageM3 = *req.MaxMonthlyUsageM3
+	}
+	if req.HoldInvoices != nil {
+		cfg.HoldInvoices = *req.HoldInvoices
+	}
+
+	if cfg.MinHistoryMonths > cfg.HistoryMonths {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_history_months cannot exceed history_months"})
+		return
+	}
+
+	if err := ctrl.DB.Save(cfg).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save anomaly settings"})
+		return
+	}
+
+	response := responses.ToAnomalySettingsResponse(cfg)
+	audit.LogUpdate(c, "anomaly_settings", cfg.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Anomaly settings updated successfully", "data": response})
+}
+
+// RunAnomalyDetection godoc
+// @Summary Run anomaly detection for a month
+// @Description Check the month's readings that are not flagged yet, e.g. readings recorded before detection was enabled or the thresholds changed
+// @Tags Reading Anomalies
+// @Accept json
+// @Produce json
+// @Param request body requests.RunAnomalyDetectionRequest true "Month to check"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reading-anomalies/detect [post]
+func (ctrl *ReadingAnomalyController) RunAnomalyDetection(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.RunAnomalyDetectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := time.Parse("2006-01", req.UsageMonth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan YYYY-MM"})
+		return
+	}
+
+	var usages []models.WaterUsage
+	if err := ctrl.DB.Where("tenant_id = ? AND usage_month = ? AND is_anomaly = ?", tenantID, req.UsageMonth, false).
+		Find(&usages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load readings"})
+		return
+	}
+
+	flagged := map[string]int{}
+	total := 0
+	for i := range usages {
+		var anomaly *models.ReadingAnomaly
+		err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			anomaly, err = helpers.CheckReadingAnomaly(tx, &usages[i])
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Anomaly detection failed", "checked": i, "flagged": total})
+			return
+		}
+		if anomaly != nil {
+			flagged[anomaly.AnomalyType]++
+			total++
+		}
+	}
+
+	audit.LogSensitiveOperation(c, models.ActionUpdate, "reading_anomaly", "Anomaly detection run", map[string]interface{}{
+		"usage_month": req.UsageMonth,
+		"checked":     len(usages),
+		"flagged":     total,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Anomaly detection finished",
+		"data": gin.H{
+			"usage_month": req.UsageMonth,
+			"checked":     len(usages),
+			"flagged":     total,
+			"by_type":     flagged,
+		},
+	})
+}
+
+// GetReadingAnomalies godoc
+// @Summary Anomaly review queue
+// @Description List reading anomalies, oldest first so the queue is worked in order
+// @Tags Reading Anomalies
+// @Produce json
+// @Param status query string false "pending, investigating, resolved, ignored or open (pending and investigating)"
+// @Param anomaly_type query string false "high_usage, low_usage, no_usage, negative or stuck"
+// @Param route_id query string false "Filter by the customer's reading route"
+// @Param usage_month query string false "Filter by reading month (YYYY-MM)"
+// @Param customer_id query string false "Filter by customer"
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingAnomalyResponse
+// @Router /api/reading-anomalies [get]
+func (ctrl *ReadingAnomalyController) GetReadingAnomalies(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Model(&models.ReadingAnomaly{}).Select("reading_anomalies.*").
+		Joins("JOIN water_usages ON water_usages.id = reading_anomalies.water_usage_id AND water_usages.deleted_at IS NULL").
+		Where("reading_anomalies.tenant_id = ?", tenantID)
+	switch status := c.Query("status"); status {
+	case "":
+	case "open":
+		query = query.Where("reading_anomalies.status IN ?", helpers.OpenAnomalyStatuses)
+	default:
+		query = query.Where("reading_anomalies.status = ?", status)
+	}
+	if anomalyType := c.Query("anomaly_type"); anomalyType != "" {
+		query = query.Where("reading_anomalies.anomaly_type = ?", anomalyType)
+	}
+	if usageMonth := c.Query("usage_month"); usageMonth != "" {
+		query = query.Where("water_usages.usage_month = ?", usageMonth)
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("water_usages.customer_id = ?", customerID)
+	}
+	if routeID := c.Query("route_id"); routeID != "" {
+		query = query.Joins("JOIN customers ON customers.id = water_usages.customer_id").
+			Where("customers.reading_route_id = ?", routeID)
+	}
+
+	var anomalies []models.ReadingAnomaly
+	if err := preloadAnomaly(query).Order("reading_anomalies.created_at ASC").Find(&anomalies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading anomalies"})
+		return
+	}
+
+	anomalyResponses := make([]responses.ReadingAnomalyResponse, len(anomalies))
+	for i := range anomalies {
+		anomalyResponses[i] = responses.ToReadingAnomalyResponse(&anomalies[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": anomalyResponses, "total": len(anomalyResponses)})
+}
+
+// GetReadingAnomaly godoc
+// @Summary Get reading anomaly
+// @Description Get an anomaly with the reading photo and the meter's usage history for charting
+// @Tags Reading Anomalies
+// @Produce json
+// @Param id path string true "Reading anomaly ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingAnomalyDetailResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-anomalies/{id} [get]
+func (ctrl *ReadingAnomalyController) GetReadingAnomaly(c *gin.Context) {
+	anomaly, ok := ctrl.findAnomaly(c)
+	if !ok {
+		return
+	}
+
+	detail, err := buildAnomalyDetail(ctrl.DB, anomaly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load usage history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": detail})
+}
+
+// ResolveReadingAnomaly godoc
+// @Summary Resolve reading anomaly
+// @Description Review an anomaly: confirm the reading as genuine, correct it with a re-read, replace it with an estimate, or raise a meter issue and keep investigating. A corrected or estimated reading is checked for anomalies again. The customer's invoice of the month is recalculated and released once no anomalies remain open; an overpayment left by a lower total is credited to the customer.
+// @Tags Reading Anomalies
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading anomaly ID"
+// @Param request body requests.ResolveAnomalyRequest true "Resolve anomaly request"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingAnomalyDetailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-anomalies/{id}/resolve [post]
+func (ctrl *ReadingAnomalyController) ResolveReadingAnomaly(c *gin.Context) {
+	anomaly, ok := ctrl.findAnomaly(c)
+	if !ok {
+		return
+	}
+
+	var req requests.ResolveAnomalyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Resolution == "correct" && req.MeterReading == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "meter_reading is required to correct the reading"})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	oldValues := responses.ToReadingAnomalyResponse(anomaly)
+	var usage *models.WaterUsage
+	wasHeld := false
+	var invoice *models.Invoice
+	var credit *models.CustomerCredit
+	var reflagged *models.ReadingAnomaly
+
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		// Re-read under lock so two reviewers cannot both resolve the anomaly
+		var locked models.ReadingAnomaly
+		if err := preloadAnomaly(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", anomaly.ID, anomaly.TenantID).First(&locked).Error; err != nil {
+			return err
+		}
+		*anomaly = locked
+		switch {
+		case anomaly.Status != models.AnomalyStatusPending && anomaly.Status != models.AnomalyStatusInvestigating:
+			return errAnomalyReviewed
+		case req.Resolution == "raise_issue" && anomaly.MeterIssueID != nil:
+			return errAnomalyIssueRaised
+		}
+		usage = &anomaly.WaterUsage
+
+		var held int64
+		if err := tx.Model(&models.Invoice{}).
+			Where("tenant_id = ? AND customer_id = ? AND usage_month = ? AND type IN ? AND is_paid = ? AND on_hold = ?",
+				anomaly.TenantID, usage.CustomerID, usage.UsageMonth, helpers.UsageInvoiceTypes, false, true).
+			Count(&held).Error; err != nil {
+			return err
+		}
+		wasHeld = held > 0
+
+		switch req.Resolution {
+		case "correct":
+			if err := helpers.ReviseWaterUsage(tx, usage, *req.MeterReading, models.ReadingMethodManual); err != nil {
+				return err
+			}
+			if req.PhotoURL != "" {
+				usage.PhotoURL = req.PhotoURL
+				if err := tx.Model(&models.WaterUsage{}).Where("id = ?", usage.ID).Update("photo_url", usage.PhotoURL).Error; err != nil {
+					return err
+				}
+			}
+		case "estimate":
+			estimate := anomaly.ExpectedValue
+			if req.EstimatedUsage != nil {
+				estimate = *req.EstimatedUsage
+			}
+			meterEnd := usage.MeterStart + estimate
+			if usage.Meter != nil && usage.Meter.RolloverAt > 0 && meterEnd >= usage.Meter.RolloverAt {
+				meterEnd -= usage.Meter.RolloverAt
+			}
+			if err := helpers.ReviseWaterUsage(tx, usage, meterEnd, models.ReadingMethodEstimated); err != nil {
+				return err
+			}
+		case "raise_issue":
+			issue, err := raiseAnomalyIssue(tx, anomaly, *userID, req)
+			if err != nil {
+				return err
+			}
+			anomaly.MeterIssueID = &issue.ID
+		}
+
+		now := time.Now()
+		anomaly.Resolution = req.Resolution
+		if req.Notes != "" {
+			anomaly.Notes = req.Notes
+		}
+		if req.Resolution == "raise_issue" {
+			anomaly.Status = models.AnomalyStatusInvestigating
+		} else {
+			anomaly.Status = models.AnomalyStatusResolved
+			anomaly.ResolvedBy = userID
+			anomaly.ResolvedAt = &now
+		}
+		if err := tx.Model(anomaly).
+			Select("status", "resolution", "meter_issue_id", "notes", "resolved_by", "resolved_at").
+			Updates(anomaly).Error; err != nil {
+			return err
+		}
+
+		// A revised reading goes through detection again like any new reading
+		if req.Resolution == "correct" || req.Resolution == "estimate" {
+			var err error
+			if reflagged, err = helpers.CheckReadingAnomaly(tx, usage); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		invoice, credit, err = helpers.RecalculateMonthlyInvoice(tx, anomaly.TenantID, usage.CustomerID, usage.UsageMonth, userID)
+		if err != nil {
+			return err
+		}
+
+		// A released invoice is collected like any other from the bill run
+		if wasHeld && invoice != nil && !invoice.OnHold {
+			return autodebit.Enqueue(tx, anomaly.TenantID, usage.UsageMonth, &invoice.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case helpers.IsWaterUsageRuleError(err), errors.Is(err, errAnomalyNoMeter),
+			errors.Is(err, errAnomalyReviewed), errors.Is(err, errAnomalyIssueRaised):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve reading anomaly"})
+		}
+		return
+	}
+
+	resolved, err := ctrl.loadAnomaly(anomaly.TenantID, anomaly.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reading anomaly"})
+		return
+	}
+	detail, err := buildAnomalyDetail(ctrl.DB, resolved)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load usage history"})
+		return
+	}
+	audit.LogUpdate(c, "reading_anomaly", anomaly.ID, oldValues, detail.ReadingAnomalyResponse)
+
+	response := gin.H{"message": "Reading anomaly updated", "data": detail}
+	if invoice != nil {
+		response["invoice"] = gin.H{
+			"id":           invoice.ID,
+			"usage_m3":     invoice.UsageM3,
+			"total_amount": invoice.TotalAmount,
+			"on_hold":      invoice.OnHold,
+		}
+	}
+	if credit != nil {
+		response["credit"] = gin.H{"id": credit.ID, "amount": credit.Amount}
+	}
+	if reflagged != nil {
+		response["new_anomaly"] = responses.ToReadingAnomalyResponse(reflagged)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// raiseAnomalyIssue opens a meter issue for the anomalous reading's meter
+func raiseAnomalyIssue(tx *gorm.DB, anomaly *models.ReadingAnomaly, userID uuid.UUID, req requests.ResolveAnomalyRequest) (*models.MeterIssue, error) {
+	usage := &anomaly.WaterUsage
+	if usage.MeterID == nil {
+		return nil, errAnomalyNoMeter
+	}
+
+	issueType := req.IssueType
+	if issueType == "" {
+		issueType = models.MeterIssueIncorrect
+		if anomaly.AnomalyType == models.AnomalyTypeStuck {
+			issueType = models.MeterIssueStuck
+		}
+	}
+	priority := req.Priority
+	if priority == "" {
+		priority = models.MeterIssuePriorityNormal
+	}
+	description := fmt.Sprintf("Reading anomaly (%s) in %s: expected %.2f m3, read %.2f m3",
+		anomaly.AnomalyType, usage.UsageMonth, anomaly.ExpectedValue, anomaly.ActualValue)
+	if req.Notes != "" {
+		description += ". " + req.Notes
+	}
+
+	issue := models.MeterIssue{
+		TenantID:    anomaly.TenantID,
+		MeterID:     *usage.MeterID,
+		ReportedBy:  &userID,
+		IssueType:   issueType,
+		Description: description,
+		Status:      models.MeterIssueStatusOpen,
+		Priority:    priority,
+		PhotoURL:    usage.PhotoURL,
+	}
+	issue.SetDueAt()
+	if err := tx.Omit("Meter").Create(&issue).Error; err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// buildAnomalyDetail adds the meter's recent usage, up to the anomalous month,
+// in chronological order
+func buildAnomalyDetail(db *gorm.DB, anomaly *models.ReadingAnomaly) (*responses.ReadingAnomalyDetailResponse, error) {
+	usage := &anomaly.WaterUsage
+	query := db.Where("tenant_id = ? AND customer_id = ? AND usage_month <= ?", usage.TenantID, usage.CustomerID, usage.UsageMonth)
+	if usage.MeterID != nil {
+		meterIDs := []uuid.UUID{*usage.MeterID}
+		if usage.Meter != nil && usage.Meter.ReplacesID != nil {
+			meterIDs = append(meterIDs, *usage.Meter.ReplacesID)
+		}
+		query = query.Where("(meter_id IN ? OR meter_id IS NULL)", meterIDs)
+	}
+
+	var history []models.WaterUsage
+	if err := query.Order("usage_month DESC").Limit(anomalyHistoryMonths).Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	detail := &responses.ReadingAnomalyDetailResponse{
+		ReadingAnomalyResponse: responses.ToReadingAnomalyResponse(anomaly),
+		ReadAt:                 usage.ReadAt,
+		History:                make([]responses.AnomalyHistoryPoint, len(history)),
+	}
+	for i, past := range history {
+		detail.History[len(history)-1-i] = responses.AnomalyHistoryPoint{
+			UsageMonth: past.UsageMonth,
+			UsageM3:    past.UsageM3,
+			IsAnomaly:  past.IsAnomaly,
+		}
+	}
+	if usage.Recorder != nil {
+		recordedBy := usage.Recorder.Email
+		detail.RecordedBy = &recordedBy
+	}
+	return detail, nil
+}
+
+func preloadAnomaly(query *gorm.DB) *gorm.DB {
+	return query.Preload("WaterUsage").Preload("WaterUsage.Customer").Preload("WaterUsage.Meter").
+		Preload("WaterUsage.Recorder").Preload("Resolver")
+}
+
+func (ctrl *ReadingAnomalyController) loadAnomaly(tenantID, anomalyID uuid.UUID) (*models.ReadingAnomaly, error) {
+	var anomaly models.ReadingAnomaly
+	if err := preloadAnomaly(ctrl.DB).Where("id = ? AND tenant_id = ?", anomalyID, tenantID).First(&anomaly).Error; err != nil {
+		return nil, err
+	}
+	return &anomaly, nil
+}
+
+func (ctrl *ReadingAnomalyController) findAnomaly(c *gin.Context) (*models.ReadingAnomaly, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	anomalyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reading anomaly ID"})
+		return nil, false
+	}
+
+	anomaly, err := ctrl.loadAnomaly(tenantID, anomalyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading anomaly not found"})
+		return nil, false
+	}
+	return anomaly, true
+}
@@ -0,0 +1,883 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReadingRouteController struct {
+	DB *gorm.DB
+}
+
+func NewReadingRouteController(db *gorm.DB) *ReadingRouteController {
+	return &ReadingRouteController{DB: db}
+}
+
+// CreateReadingRoute godoc
+// @Summary Create reading route
+// @Description Create a meter reading route, optionally assigned to a meter reader
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateReadingRouteRequest true "Create reading route request"
+// @Security BearerAuth
+// @Success 201 {object} responses.ReadingRouteResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/reading-routes [post]
+func (ctrl *ReadingRouteController) CreateReadingRoute(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreateReadingRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	ctrl.DB.Model(&models.ReadingRoute{}).Where("tenant_id = ? AND code = ?", tenantID, req.Code).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Route code already exists"})
+		return
+	}
+
+	assignedTo, ok := ctrl.resolveReader(c, tenantID, req.AssignedTo)
+	if !ok {
+		return
+	}
+
+	route := models.ReadingRoute{
+		TenantID:    tenantID,
+		Code:        req.Code,
+		Name:        req.Name,
+		Description: req.Description,
+		AssignedTo:  assignedTo,
+		ScheduleDay: req.ScheduleDay,
+		EstDuration: req.EstDuration,
+		IsActive:    true,
+	}
+	if err := ctrl.DB.Create(&route).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reading route"})
+		return
+	}
+
+	created, err := ctrl.loadRoute(tenantID, route.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reading route"})
+		return
+	}
+
+	response := responses.ToReadingRouteResponse(created)
+	audit.LogCreate(c, "reading_route", route.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Reading route created successfully", "data": response})
+}
+
+// GetReadingRoutes godoc
+// @Summary List reading routes
+// @Description List reading routes
+// @Tags Reading Routes
+// @Produce json
+// @Param assigned_to query string false "Filter by meter reader"
+// @Param schedule_day query int false "Filter by day of month"
+// @Param is_active query bool false "Filter by active status"
+// @Param search query string false "Search code or name"
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingRouteResponse
+// @Router /api/reading-routes [get]
+func (ctrl *ReadingRouteController) GetReadingRoutes(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
+		query = query.Where("assigned_to = ?", assignedTo)
+	}
+	if scheduleDay := c.Query("schedule_day"); scheduleDay != "" {
+		query = query.Where("schedule_day = ?", scheduleDay)
+	}
+	if isActive := c.Query("is_active"); isActive != "" {
+		query = query.Where("is_active = ?", isActive == "true")
+	}
+	if search := c.Query("search"); search != "" {
+		query = query.Where("code LIKE ? OR name LIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	var routes []models.ReadingRoute
+	if err := query.Preload("AssignedUser").Order("code ASC").Find(&routes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading routes"})
+		return
+	}
+
+	routeResponses := make([]responses.ReadingRouteResponse, len(routes))
+	for i := range routes {
+		routeResponses[i] = responses.ToReadingRouteResponse(&routes[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": routeResponses, "total": len(routeResponses)})
+}
+
+// GetReadingRoute godoc
+// @Summary Get reading route
+// @Description Get a reading route with its stops in visiting order and each meter's previous reading
+// @Tags Reading Routes
+// @Produce json
+// @Param id path string true "Reading route ID"
+// @Param usage_month query string false "Month being read (YYYY-MM), defaults to the current month"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteDetailResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-routes/{id} [get]
+func (ctrl *ReadingRouteController) GetReadingRoute(c *gin.Context) {
+	route, ok := ctrl.findRoute(c)
+	if !ok {
+		return
+	}
+
+	usageMonth := c.DefaultQuery("usage_month", time.Now().Format("2006-01"))
+	if _, err := time.Parse("2006-01", usageMonth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan YYYY-MM"})
+		return
+	}
+
+	detail, err := buildRouteDetail(ctrl.DB, route, usageMonth)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load route stops"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": detail})
+}
+
+// UpdateReadingRoute godoc
+// @Summary Update reading route
+// @Description Update a reading route. Send assigned_to as an empty string to unassign the reader.
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading route ID"
+// @Param request body requests.UpdateReadingRouteRequest true "Update reading route request"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-routes/{id} [put]
+func (ctrl *ReadingRouteController) UpdateReadingRoute(c *gin.Context) {
+	route, ok := ctrl.findRoute(c)
+	if !ok {
+		return
+	}
+
+	var req requests.UpdateReadingRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldValues := responses.ToReadingRouteResponse(route)
+
+	if req.AssignedTo != nil {
+		assignedTo, ok := ctrl.resolveReader(c, route.TenantID, req.AssignedTo)
+		if !ok {
+			return
+		}
+		route.AssignedTo = assignedTo
+	}
+	route.Name = req.Name
+	route.Description = req.Description
+	route.ScheduleDay = req.ScheduleDay
+	route.EstDuration = req.EstDuration
+	if req.IsActive != nil {
+		route.IsActive = *req.IsActive
+	}
+
+	if err := ctrl.DB.Model(route).
+		Select("assigned_to", "name", "description", "schedule_day", "est_duration", "is_active").
+		Updates(route).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading route"})
+		return
+	}
+
+	updated, err := ctrl.loadRoute(route.TenantID, route.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reading route"})
+		return
+	}
+
+	response := responses.ToReadingRouteResponse(updated)
+	audit.LogUpdate(c, "reading_route", route.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reading route updated successfully", "data": response})
+}
+
+// DeleteReadingRoute godoc
+// @Summary Delete reading route
+// @Description Delete a reading route. Its customers are left without a route.
+// @Tags Reading Routes
+// @Produce json
+// @Param id path string true "Reading route ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-routes/{id} [delete]
+func (ctrl *ReadingRouteController) DeleteReadingRoute(c *gin.Context) {
+	route, ok := ctrl.findRoute(c)
+	if !ok {
+		return
+	}
+
+	var activeSessions int64
+	ctrl.DB.Model(&models.ReadingSession{}).
+		Where("route_id = ? AND status = ?", route.ID, models.ReadingSessionInProgress).
+		Count(&activeSessions)
+	if activeSessions > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Route has a reading session in progress"})
+		return
+	}
+
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND reading_route_id = ?", route.TenantID, route.ID).
+			Updates(map[string]interface{}{"reading_route_id": nil, "route_sequence": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(route).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reading route"})
+		return
+	}
+
+	audit.LogDelete(c, "reading_route", route.ID, responses.ToReadingRouteResponse(route))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reading route deleted successfully"})
+}
+
+// SetRouteStops godoc
+// @Summary Set route stops
+// @Description Replace the stops of a route with the given customers in visiting order. Customers are moved from their previous route; customers left out are removed from this route.
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading route ID"
+// @Param request body requests.SetRouteStopsRequest true "Ordered customer IDs"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteDetailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-routes/{id}/stops [put]
+func (ctrl *ReadingRouteController) SetRouteStops(c *gin.Context) {
+	route, ok := ctrl.findRoute(c)
+	if !ok {
+		return
+	}
+
+	var req requests.SetRouteStopsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerIDs, ok := ctrl.parseRouteCustomers(c, route.TenantID, req.CustomerIDs)
+	if !ok {
+		return
+	}
+
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		affected, err := previousRoutes(tx, route.TenantID, customerIDs)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND reading_route_id = ?", route.TenantID, route.ID).
+			Updates(map[string]interface{}{"reading_route_id": nil, "route_sequence": 0}).Error; err != nil {
+			return err
+		}
+		for i, customerID := range customerIDs {
+			if err := tx.Model(&models.Customer{}).Where("id = ?", customerID).
+				Updates(map[string]interface{}{"reading_route_id": route.ID, "route_sequence": i + 1}).Error; err != nil {
+				return err
+			}
+		}
+
+		return refreshRouteCustomerCounts(tx, route.TenantID, append(affected, route.ID))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update route stops"})
+		return
+	}
+
+	audit.LogSensitiveOperation(c, models.ActionUpdate, "reading_route", "Route stops updated", map[string]interface{}{
+		"route_id":  route.ID,
+		"customers": len(customerIDs),
+	})
+
+	ctrl.respondRouteDetail(c, route.TenantID, route.ID, "Route stops updated successfully")
+}
+
+// AssignRouteCustomers godoc
+// @Summary Assign customers to route
+// @Description Add customers to the end of a route in the given order, moving them from their previous route. Customers already on the route keep their place.
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading route ID"
+// @Param request body requests.AssignRouteCustomersRequest true "Customer IDs"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteDetailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-routes/{id}/customers [post]
+func (ctrl *ReadingRouteController) AssignRouteCustomers(c *gin.Context) {
+	route, ok := ctrl.findRoute(c)
+	if !ok {
+		return
+	}
+
+	var req requests.AssignRouteCustomersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerIDs, ok := ctrl.parseRouteCustomers(c, route.TenantID, req.CustomerIDs)
+	if !ok {
+		return
+	}
+
+	assigned := 0
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		var onRoute []uuid.UUID
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND reading_route_id = ?", route.TenantID, route.ID).
+			Pluck("id", &onRoute).Error; err != nil {
+			return err
+		}
+		already := make(map[uuid.UUID]bool, len(onRoute))
+		for _, id := range onRoute {
+			already[id] = true
+		}
+
+		var toAssign []uuid.UUID
+		for _, id := range customerIDs {
+			if !already[id] {
+				toAssign = append(toAssign, id)
+			}
+		}
+		if len(toAssign) == 0 {
+			return nil
+		}
+
+		affected, err := previousRoutes(tx, route.TenantID, toAssign)
+		if err != nil {
+			return err
+		}
+
+		var lastSequence int
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND reading_route_id = ?", route.TenantID, route.ID).
+			Select("COALESCE(MAX(route_sequence), 0)").Scan(&lastSequence).Error; err != nil {
+			return err
+		}
+		for i, customerID := range toAssign {
+			if err := tx.Model(&models.Customer{}).Where("id = ?", customerID).
+				Updates(map[string]interface{}{"reading_route_id": route.ID, "route_sequence": lastSequence + i + 1}).Error; err != nil {
+				return err
+			}
+		}
+		assigned = len(toAssign)
+
+		return refreshRouteCustomerCounts(tx, route.TenantID, append(affected, route.ID))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign customers"})
+		return
+	}
+
+	audit.LogSensitiveOperation(c, models.ActionUpdate, "reading_route", "Customers assigned to route", map[string]interface{}{
+		"route_id": route.ID,
+		"assigned": assigned,
+	})
+
+	ctrl.respondRouteDetail(c, route.TenantID, route.ID, strconv.Itoa(assigned)+" customers assigned to route")
+}
+
+// RemoveRouteCustomers godoc
+// @Summary Remove customers from route
+// @Description Remove customers from a route. The remaining stops keep their order.
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading route ID"
+// @Param request body requests.AssignRouteCustomersRequest true "Customer IDs"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingRouteDetailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-routes/{id}/customers/remove [post]
+func (ctrl *ReadingRouteController) RemoveRouteCustomers(c *gin.Context) {
+	route, ok := ctrl.findRoute(c)
+	if !ok {
+		return
+	}
+
+	var req requests.AssignRouteCustomersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customerIDs, ok := ctrl.parseRouteCustomers(c, route.TenantID, req.CustomerIDs)
+	if !ok {
+		return
+	}
+
+	err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND reading_route_id = ? AND id IN ?", route.TenantID, route.ID, customerIDs).
+			Updates(map[string]interface{}{"reading_route_id": nil, "route_sequence": 0}).Error; err != nil {
+			return err
+		}
+		if err := resequenceRoute(tx, route.TenantID, route.ID); err != nil {
+			return err
+		}
+		return refreshRouteCustomerCounts(tx, route.TenantID, []uuid.UUID{route.ID})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove customers"})
+		return
+	}
+
+	audit.LogSensitiveOperation(c, models.ActionUpdate, "reading_route", "Customers removed from route", map[string]interface{}{
+		"route_id":  route.ID,
+		"customers": len(customerIDs),
+	})
+
+	ctrl.respondRouteDetail(c, route.TenantID, route.ID, "Customers removed from route")
+}
+
+// OptimizeRoute godoc
+// @Summary Optimise route stop order
+// @Description Reorder the stops of a route to shorten the walk between them, using the customers' coordinates (nearest neighbour improved by 2-opt, straight-line distances). Customers without coordinates keep their order at the end of the route. Saves the new stop sequence and duration estimate unless dry_run is set.
+// @Tags Reading Routes
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading route ID"
+// @Param request body requests.OptimizeRouteRequest false "Start point and estimate parameters"
+// @Security BearerAuth
+// @Success 200 {object} helpers.RouteOptimization
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-routes/{id}/optimize [post]
+func (ctrl *ReadingRouteController) OptimizeRoute(c *gin.Context) {
+	route, ok := ctrl.findRoute(c)
+	if !ok {
+		return
+	}
+
+	var req requests.OptimizeRouteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := helpers.ValidateLocation(req.StartLatitude, req.StartLongitude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := helpers.RouteOptimizeOptions{
+		StartLat:        req.StartLatitude,
+		StartLng:        req.StartLongitude,
+		WalkingSpeedKmh: helpers.DefaultWalkingSpeedKmh,
+		MinutesPerStop:  helpers.DefaultMinutesPerStop,
+	}
+	if req.WalkingSpeedKmh != nil {
+		opts.WalkingSpeedKmh = *req.WalkingSpeedKmh
+	}
+	if req.MinutesPerStop != nil {
+		opts.MinutesPerStop = *req.MinutesPerStop
+	}
+
+	result, err := helpers.OptimizeReadingRoute(ctrl.DB, route, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to optimise route"})
+		return
+	}
+	if result.OptimizedStops == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "None of the route's customers have coordinates"})
+		return
+	}
+	if req.DryRun {
+		c.JSON(http.StatusOK, gin.H{"message": "Proposed stop order (not saved)", "data": result})
+		return
+	}
+
+	if err := ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		return helpers.ApplyRouteOrder(tx, route, result)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update route stops"})
+		return
+	}
+
+	audit.LogSensitiveOperation(c, models.ActionUpdate, "reading_route", "Route stops optimised", map[string]interface{}{
+		"route_id":          route.ID,
+		"customers":         len(result.CustomerIDs),
+		"distance_before_m": result.DistanceBeforeM,
+		"distance_after_m":  result.DistanceAfterM,
+		"est_duration":      result.EstDuration,
+	})
+
+	detail, err := buildRouteDetail(ctrl.DB, route, time.Now().Format("2006-01"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load route stops"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Route stops optimised successfully", "data": result, "route": detail})
+}
+
+// GetMyRouteToday godoc
+// @Summary My reading route for today
+// @Description Routes assigned to the current meter reader that are scheduled today, with stops in visiting order and each meter's previous reading
+// @Tags Reading Routes
+// @Produce json
+// @Param date query string false "Date (YYYY-MM-DD), defaults to today"
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingRouteDetailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reading-routes/my/today [get]
+func (ctrl *ReadingRouteController) GetMyRouteToday(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	date := time.Now()
+	if dateParam := c.Query("date"); dateParam != "" {
+		date, err = time.Parse("2006-01-02", dateParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	var routes []models.ReadingRoute
+	if err := ctrl.DB.Preload("AssignedUser").
+		Where("tenant_id = ? AND assigned_to = ? AND is_active = ?", tenantID, *userID, true).
+		Order("code ASC").Find(&routes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading routes"})
+		return
+	}
+
+	usageMonth := date.Format("2006-01")
+	details := []responses.ReadingRouteDetailResponse{}
+	for i := range routes {
+		if !routes[i].ScheduledOn(date) {
+			continue
+		}
+		detail, err := buildRouteDetail(ctrl.DB, &routes[i], usageMonth)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load route stops"})
+			return
+		}
+		details = append(details, *detail)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": details, "total": len(details), "date": date.Format("2006-01-02")})
+}
+
+// buildRouteDetail loads the route's stops in visiting order with the
+// previous and current-month reading of every active meter
+func buildRouteDetail(db *gorm.DB, route *models.ReadingRoute, usageMonth string) (*responses.ReadingRouteDetailResponse, error) {
+	var customers []models.Customer
+	if err := db.Where("tenant_id = ? AND reading_route_id = ?", route.TenantID, route.ID).
+		Order("route_sequence ASC, name ASC").Find(&customers).Error; err != nil {
+		return nil, err
+	}
+
+	customerIDs := make([]uuid.UUID, len(customers))
+	for i := range customers {
+		customerIDs[i] = customers[i].ID
+	}
+
+	var meters []models.Meter
+	if err := db.Where("tenant_id = ? AND customer_id IN ? AND status = ?", route.TenantID, customerIDs, models.MeterStatusActive).
+		Order("meter_number ASC").Find(&meters).Error; err != nil {
+		return nil, err
+	}
+	metersByCustomer := map[uuid.UUID][]models.Meter{}
+	meterIDs := make([]uuid.UUID, len(meters))
+	for i := range meters {
+		metersByCustomer[meters[i].CustomerID] = append(metersByCustomer[meters[i].CustomerID], meters[i])
+		meterIDs[i] = meters[i].ID
+	}
+
+	// Latest earlier reading per meter, and per customer for readings taken before meters were registered
+	var meterPrevious []models.WaterUsage
+	if err := db.Where("tenant_id = ? AND (meter_id, usage_month) IN (?)", route.TenantID,
+		db.Model(&models.WaterUsage{}).Select("meter_id, MAX(usage_month)").
+			Where("tenant_id = ? AND meter_id IN ? AND usage_month < ?", route.TenantID, meterIDs, usageMonth).
+			Group("meter_id")).
+		Find(&meterPrevious).Error; err != nil {
+		return nil, err
+	}
+	var legacyPrevious []models.WaterUsage
+	if err := db.Where("tenant_id = ? AND meter_id IS NULL AND (customer_id, usage_month) IN (?)", route.TenantID,
+		db.Model(&models.WaterUsage{}).Select("customer_id, MAX(usage_month)").
+			Where("tenant_id = ? AND customer_id IN ? AND meter_id IS NULL AND usage_month < ?", route.TenantID, customerIDs, usageMonth).
+			Group("customer_id")).
+		Find(&legacyPrevious).Error; err != nil {
+		return nil, err
+	}
+	var current []models.WaterUsage
+	if err := db.Where("tenant_id = ? AND customer_id IN ? AND usage_month = ?", route.TenantID, customerIDs, usageMonth).
+		Find(&current).Error; err != nil {
+		return nil, err
+	}
+
+	previousByMeter := map[uuid.UUID]*models.WaterUsage{}
+	for i := range meterPrevious {
+		previousByMeter[*meterPrevious[i].MeterID] = &meterPrevious[i]
+	}
+	legacyByCustomer := map[uuid.UUID]*models.WaterUsage{}
+	for i := range legacyPrevious {
+		legacyByCustomer[legacyPrevious[i].CustomerID] = &legacyPrevious[i]
+	}
+	currentByMeter := map[uuid.UUID]float64{}
+	currentByCustomer := map[uuid.UUID]float64{}
+	for i := range current {
+		if current[i].MeterID != nil {
+			currentByMeter[*current[i].MeterID] = current[i].MeterEnd
+		} else {
+			currentByCustomer[current[i].CustomerID] = current[i].MeterEnd
+		}
+	}
+
+	detail := &responses.ReadingRouteDetailResponse{
+		ReadingRouteResponse: responses.ToReadingRouteResponse(route),
+		UsageMonth:           usageMonth,
+		Stops:                make([]responses.ReadingRouteStopResponse, 0, len(customers)),
+	}
+	for i := range customers {
+		customer := &customers[i]
+		stop := responses.ReadingRouteStopResponse{
+			Sequence:     customer.RouteSequence,
+			CustomerID:   customer.ID,
+			CustomerName: customer.Name,
+			Address:      customer.Address,
+			Phone:        customer.Phone,
+			Completed:    true,
+		}
+
+		customerMeters := metersByCustomer[customer.ID]
+		if len(customerMeters) == 0 {
+			stopMeter := responses.ReadingStopMeter{MeterNumber: customer.MeterNumber}
+			fillPrevious(&stopMeter, legacyByCustomer[customer.ID])
+			if reading, ok := currentByCustomer[customer.ID]; ok {
+				stopMeter.CurrentReading = &reading
+			}
+			stop.Meters = append(stop.Meters, stopMeter)
+		}
+		for j := range customerMeters {
+			meter := &customerMeters[j]
+			stopMeter := responses.ReadingStopMeter{
+				MeterID:         &meter.ID,
+				MeterNumber:     meter.MeterNumber,
+				PreviousReading: meter.InitialReading,
+			}
+			previous := previousByMeter[meter.ID]
+			if previous == nil && meter.ReplacesID == nil {
+				previous = legacyByCustomer[customer.ID]
+			}
+			fillPrevious(&stopMeter, previous)
+			if reading, ok := currentByMeter[meter.ID]; ok {
+				stopMeter.CurrentReading = &reading
+			}
+			stop.Meters = append(stop.Meters, stopMeter)
+		}
+
+		for _, stopMeter := range stop.Meters {
+			if stopMeter.CurrentReading == nil {
+				stop.Completed = false
+			}
+		}
+		if stop.Completed {
+			detail.CompletedCount++
+		}
+		detail.Stops = append(detail.Stops, stop)
+	}
+
+	return detail, nil
+}
+
+func fillPrevious(stopMeter *responses.ReadingStopMeter, previous *models.WaterUsage) {
+	if previous == nil {
+		return
+	}
+	usage := previous.UsageM3
+	stopMeter.PreviousReading = previous.MeterEnd
+	stopMeter.PreviousUsageMonth = previous.UsageMonth
+	stopMeter.PreviousUsageM3 = &usage
+}
+
+// previousRoutes returns the routes the customers are currently assigned to
+func previousRoutes(tx *gorm.DB, tenantID uuid.UUID, customerIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var routeIDs []uuid.UUID
+	err := tx.Model(&models.Customer{}).
+		Where("tenant_id = ? AND id IN ? AND reading_route_id IS NOT NULL", tenantID, customerIDs).
+		Distinct().Pluck("reading_route_id", &routeIDs).Error
+	return routeIDs, err
+}
+
+// resequenceRoute closes gaps in the stop order of a route
+func resequenceRoute(tx *gorm.DB, tenantID, routeID uuid.UUID) error {
+	var customerIDs []uuid.UUID
+	if err := tx.Model(&models.Customer{}).
+		Where("tenant_id = ? AND reading_route_id = ?", tenantID, routeID).
+		Order("route_sequence ASC, name ASC").Pluck("id", &customerIDs).Error; err != nil {
+		return err
+	}
+	for i, customerID := range customerIDs {
+		if err := tx.Model(&models.Customer{}).Where("id = ?", customerID).Update("route_sequence", i+1).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshRouteCustomerCounts recounts the customers of the given routes
+func refreshRouteCustomerCounts(tx *gorm.DB, tenantID uuid.UUID, routeIDs []uuid.UUID) error {
+	for _, routeID := range routeIDs {
+		var count int64
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND reading_route_id = ?", tenantID, routeID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ReadingRoute{}).Where("id = ?", routeID).Update("customer_count", count).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRouteCustomers parses the IDs and checks they are distinct customers of the tenant
+func (ctrl *ReadingRouteController) parseRouteCustomers(c *gin.Context, tenantID uuid.UUID, ids []string) ([]uuid.UUID, bool) {
+	customerIDs := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		customerID, err := uuid.Parse(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID: " + id})
+			return nil, false
+		}
+		if seen[customerID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate customer ID: " + id})
+			return nil, false
+		}
+		seen[customerID] = true
+		customerIDs = append(customerIDs, customerID)
+	}
+
+	if len(customerIDs) > 0 {
+		var count int64
+		ctrl.DB.Model(&models.Customer{}).Where("tenant_id = ? AND id IN ?", tenantID, customerIDs).Count(&count)
+		if int(count) != len(customerIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more customers not found"})
+			return nil, false
+		}
+	}
+	return customerIDs, true
+}
+
+// resolveReader parses an assigned_to value; an empty string unassigns
+func (ctrl *ReadingRouteController) resolveReader(c *gin.Context, tenantID uuid.UUID, assignedTo *string) (*uuid.UUID, bool) {
+	if assignedTo == nil || *assignedTo == "" {
+		return nil, true
+	}
+
+	readerID, err := uuid.Parse(*assignedTo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assigned_to user ID"})
+		return nil, false
+	}
+
+	var reader models.User
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", readerID, tenantID).First(&reader).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assigned user not found in this tenant"})
+		return nil, false
+	}
+	return &reader.ID, true
+}
+
+func (ctrl *ReadingRouteController) respondRouteDetail(c *gin.Context, tenantID, routeID uuid.UUID, message string) {
+	route, err := ctrl.loadRoute(tenantID, routeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reading route"})
+		return
+	}
+
+	detail, err := buildRouteDetail(ctrl.DB, route, time.Now().Format("2006-01"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load route stops"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": detail})
+}
+
+func (ctrl *ReadingRouteController) loadRoute(tenantID, routeID uuid.UUID) (*models.ReadingRoute, error) {
+	var route models.ReadingRoute
+	if err := ctrl.DB.Preload("AssignedUser").Where("id = ? AND tenant_id = ?", routeID, tenantID).First(&route).Error; err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
+func (ctrl *ReadingRouteController) findRoute(c *gin.Context) (*models.ReadingRoute, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	routeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reading route ID"})
+		return nil, false
+	}
+
+	route, err := ctrl.loadRoute(tenantID, routeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading route not found"})
+		return nil, false
+	}
+	return route, true
+}
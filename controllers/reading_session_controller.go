@@ -0,0 +1,695 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/pkg/readingsession"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session reading results
+const (
+	sessionReadingRecorded = "recorded"
+	sessionReadingRejected = "rejected"
+)
+
+var (
+	errCustomerNotOnRoute = errors.New("Pelanggan tidak termasuk dalam rute sesi ini")
+	errInvalidReadAt      = errors.New("Format read_at tidak valid. Gunakan RFC3339")
+)
+
+func isSessionReadingError(err error) bool {
+	return errors.Is(err, errCustomerNotOnRoute) || errors.Is(err, errInvalidReadAt)
+}
+
+type ReadingSessionController struct {
+	DB *gorm.DB
+}
+
+func NewReadingSessionController(db *gorm.DB) *ReadingSessionController {
+	return &ReadingSessionController{DB: db}
+}
+
+// CreateReadingSession godoc
+// @Summary Schedule reading session
+// @Description Schedule a reading session for a route by hand. Sessions are also created automatically on each route's schedule day.
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param request body requests.StartReadingSessionRequest true "Schedule reading session request"
+// @Security BearerAuth
+// @Success 201 {object} responses.ReadingSessionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/reading-sessions [post]
+func (ctrl *ReadingSessionController) CreateReadingSession(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.StartReadingSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scheduledDate, err := time.Parse("2006-01-02", req.ScheduledDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled_date format. Use YYYY-MM-DD"})
+		return
+	}
+	usageMonth := req.UsageMonth
+	if usageMonth == "" {
+		usageMonth = scheduledDate.Format("2006-01")
+	} else if _, err := time.Parse("2006-01", usageMonth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan YYYY-MM"})
+		return
+	}
+
+	var route models.ReadingRoute
+	if err := ctrl.DB.Where("id = ? AND tenant_id = ?", req.RouteID, tenantID).First(&route).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading route not found"})
+		return
+	}
+
+	readerID := route.AssignedTo
+	if req.ReaderID != nil && *req.ReaderID != "" {
+		var reader models.User
+		if err := ctrl.DB.Where("id = ? AND tenant_id = ?", *req.ReaderID, tenantID).First(&reader).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reader not found in this tenant"})
+			return
+		}
+		readerID = &reader.ID
+	}
+	if readerID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Route has no assigned reader, reader_id is required"})
+		return
+	}
+
+	session, err := readingsession.CreateSession(ctrl.DB, &route, *readerID, scheduledDate, usageMonth, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule reading session"})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Route already has a session on this date"})
+		return
+	}
+
+	created, err := ctrl.loadSession(tenantID, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reading session"})
+		return
+	}
+
+	response := responses.ToReadingSessionResponse(created)
+	audit.LogCreate(c, "reading_session", session.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Reading session scheduled successfully", "data": response})
+}
+
+// ScheduleReadingSessions godoc
+// @Summary Run session scheduling
+// @Description Create the sessions of every active route read on the given date. Runs automatically; safe to repeat.
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param request body requests.ScheduleReadingSessionsRequest false "Scheduling date"
+// @Security BearerAuth
+// @Success 200 {object} readingsession.Summary
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/reading-sessions/schedule [post]
+func (ctrl *ReadingSessionController) ScheduleReadingSessions(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.ScheduleReadingSessionsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	date := time.Now()
+	if req.Date != "" {
+		date, err = time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	summary, err := readingsession.Schedule(ctrl.DB, tenantID, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session scheduling failed", "data": summary})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reading sessions scheduled", "data": summary})
+}
+
+// GetReadingSessions godoc
+// @Summary List reading sessions
+// @Description List reading sessions, newest scheduled date first
+// @Tags Reading Sessions
+// @Produce json
+// @Param route_id query string false "Filter by route"
+// @Param reader_id query string false "Filter by reader, 'me' for the current user"
+// @Param status query string false "scheduled, in_progress, completed or cancelled"
+// @Param usage_month query string false "Filter by month being read (YYYY-MM)"
+// @Param date_from query string false "Scheduled on or after (YYYY-MM-DD)"
+// @Param date_to query string false "Scheduled on or before (YYYY-MM-DD)"
+// @Security BearerAuth
+// @Success 200 {array} responses.ReadingSessionResponse
+// @Router /api/reading-sessions [get]
+func (ctrl *ReadingSessionController) GetReadingSessions(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if routeID := c.Query("route_id"); routeID != "" {
+		query = query.Where("route_id = ?", routeID)
+	}
+	if readerID := c.Query("reader_id"); readerID != "" {
+		if readerID == "me" {
+			userID := helpers.GetUserIDFromContext(c)
+			if userID == nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+				return
+			}
+			query = query.Where("reader_id = ?", *userID)
+		} else {
+			query = query.Where("reader_id = ?", readerID)
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if usageMonth := c.Query("usage_month"); usageMonth != "" {
+		query = query.Where("usage_month = ?", usageMonth)
+	}
+	if dateFrom := c.Query("date_from"); dateFrom != "" {
+		query = query.Where("scheduled_date >= ?", dateFrom)
+	}
+	if dateTo := c.Query("date_to"); dateTo != "" {
+		query = query.Where("scheduled_date <= ?", dateTo)
+	}
+
+	var sessions []models.ReadingSession
+	if err := query.Preload("Route").Preload("Route.AssignedUser").Preload("Reader").
+		Order("scheduled_date DESC").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading sessions"})
+		return
+	}
+
+	sessionResponses := make([]responses.ReadingSessionResponse, len(sessions))
+	for i := range sessions {
+		sessionResponses[i] = responses.ToReadingSessionResponse(&sessions[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessionResponses, "total": len(sessionResponses)})
+}
+
+// GetReadingSession godoc
+// @Summary Get reading session
+// @Description Get a reading session with its progress and stops. Stops already read outside the session, e.g. in the session it was carried over from, are left out.
+// @Tags Reading Sessions
+// @Produce json
+// @Param id path string true "Reading session ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingSessionDetailResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id} [get]
+func (ctrl *ReadingSessionController) GetReadingSession(c *gin.Context) {
+	session, ok := ctrl.findSession(c)
+	if !ok {
+		return
+	}
+
+	detail, err := buildSessionDetail(ctrl.DB, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session stops"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": detail})
+}
+
+// StartReadingSession godoc
+// @Summary Start reading session
+// @Description The assigned reader starts a scheduled session
+// @Tags Reading Sessions
+// @Produce json
+// @Param id path string true "Reading session ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingSessionDetailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id}/start [post]
+func (ctrl *ReadingSessionController) StartReadingSession(c *gin.Context) {
+	session, ok := ctrl.findSession(c)
+	if !ok || !ctrl.checkReader(c, session) {
+		return
+	}
+
+	if session.Status != models.ReadingSessionScheduled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only scheduled sessions can be started"})
+		return
+	}
+
+	now := time.Now()
+	session.Status = models.ReadingSessionInProgress
+	session.StartTime = &now
+	if err := ctrl.DB.Model(session).Select("status", "start_time").Updates(session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start reading session"})
+		return
+	}
+
+	ctrl.respondSessionDetail(c, session, "Reading session started")
+}
+
+// SubmitSessionReadings godoc
+// @Summary Submit session readings
+// @Description Record meter readings into an in-progress session. Each reading is validated and stored on its own; rejected readings are reported per item and can be resubmitted.
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading session ID"
+// @Param request body requests.SubmitSessionReadingsRequest true "Readings"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id}/readings [post]
+func (ctrl *ReadingSessionController) SubmitSessionReadings(c *gin.Context) {
+	session, ok := ctrl.findSession(c)
+	if !ok || !ctrl.checkReader(c, session) {
+		return
+	}
+
+	var req requests.SubmitSessionReadingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if session.Status != models.ReadingSessionInProgress {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Session is not in progress"})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var routeCustomers []uuid.UUID
+	if err := ctrl.DB.Model(&models.Customer{}).
+		Where("tenant_id = ? AND reading_route_id = ?", session.TenantID, session.RouteID).
+		Pluck("id", &routeCustomers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load route customers"})
+		return
+	}
+	onRoute := make(map[uuid.UUID]bool, len(routeCustomers))
+	for _, id := range routeCustomers {
+		onRoute[id] = true
+	}
+
+	results := make([]responses.SessionReadingResult, len(req.Readings))
+	recorded := 0
+	for i, item := range req.Readings {
+		result := responses.SessionReadingResult{Index: i, CustomerID: item.CustomerID, Status: sessionReadingRejected}
+
+		usage, err := ctrl.recordSessionReading(session, item, *userID, onRoute)
+		if err != nil {
+			result.Error = err.Error()
+			if !helpers.IsWaterUsageRuleError(err) && !isSessionReadingError(err) {
+				result.Error = "Gagal menyimpan data"
+			}
+		} else {
+			reading := responses.ToWaterUsageResponse(usage)
+			result.Status = sessionReadingRecorded
+			result.Reading = &reading
+			recorded++
+		}
+		results[i] = result
+	}
+
+	detail, err := updateSessionProgress(ctrl.DB, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session progress"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Readings processed",
+		"recorded": recorded,
+		"rejected": len(req.Readings) - recorded,
+		"results":  results,
+		"data":     detail,
+	})
+}
+
+// CloseReadingSession godoc
+// @Summary Close reading session
+// @Description Close an in-progress session. Stops that were not read are carried over to a new session for the same reader and month, scheduled the next day unless carry_over_date is given. An open session of the route for the same month on that date takes them over instead (carried_over_merged); a closed one, or one for another month, is rejected.
+// @Tags Reading Sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Reading session ID"
+// @Param request body requests.CloseReadingSessionRequest false "Close reading session request"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingSessionDetailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id}/close [post]
+func (ctrl *ReadingSessionController) CloseReadingSession(c *gin.Context) {
+	session, ok := ctrl.findSession(c)
+	if !ok || !ctrl.checkReader(c, session) {
+		return
+	}
+
+	var req requests.CloseReadingSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if session.Status != models.ReadingSessionInProgress {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Session is not in progress"})
+		return
+	}
+
+	carryOverDate := session.ScheduledDate.AddDate(0, 0, 1)
+	if today := time.Now(); today.After(carryOverDate) {
+		carryOverDate = today.AddDate(0, 0, 1)
+	}
+	if req.CarryOverDate != "" {
+		date, err := time.Parse("2006-01-02", req.CarryOverDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid carry_over_date format. Use YYYY-MM-DD"})
+			return
+		}
+		carryOverDate = date
+	}
+	carryOverDate = time.Date(carryOverDate.Year(), carryOverDate.Month(), carryOverDate.Day(), 0, 0, 0, 0, carryOverDate.Location())
+
+	detail, err := updateSessionProgress(ctrl.DB, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session progress"})
+		return
+	}
+
+	var carryOver *models.ReadingSession
+	merged := false
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		session.Status = models.ReadingSessionCompleted
+		session.EndTime = &now
+		if req.Notes != "" {
+			session.Notes = req.Notes
+		}
+		if err := tx.Model(session).Select("status", "end_time", "notes").Updates(session).Error; err != nil {
+			return err
+		}
+
+		if detail.RemainingCount == 0 {
+			return nil
+		}
+		carryOver, merged, err = readingsession.CarryOver(tx, session, carryOverDate)
+		if err != nil || merged {
+			return err
+		}
+		carryOver.TotalCustomers = detail.RemainingCount
+		return tx.Model(carryOver).Update("total_customers", detail.RemainingCount).Error
+	})
+	if errors.Is(err, readingsession.ErrCarryOverDateTaken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close reading session"})
+		return
+	}
+
+	detail.Status = session.Status
+	detail.EndTime = session.EndTime
+	detail.Notes = session.Notes
+
+	audit.LogSensitiveOperation(c, models.ActionUpdate, "reading_session", "Reading session closed", map[string]interface{}{
+		"session_id": session.ID,
+		"completed":  detail.CompletedCount,
+		"remaining":  detail.RemainingCount,
+	})
+
+	response := gin.H{"message": "Reading session closed", "data": detail}
+	if carryOver != nil {
+		response["carried_over_session_id"] = carryOver.ID
+		response["carried_over_stops"] = detail.RemainingCount
+		response["carried_over_merged"] = merged
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelReadingSession godoc
+// @Summary Cancel reading session
+// @Description Cancel a session that has not started. The auto-scheduler does not recreate it.
+// @Tags Reading Sessions
+// @Produce json
+// @Param id path string true "Reading session ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ReadingSessionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/reading-sessions/{id}/cancel [post]
+func (ctrl *ReadingSessionController) CancelReadingSession(c *gin.Context) {
+	session, ok := ctrl.findSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.ReadingSessionScheduled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only scheduled sessions can be cancelled"})
+		return
+	}
+
+	oldValues := responses.ToReadingSessionResponse(session)
+	session.Status = models.ReadingSessionCancelled
+	if err := ctrl.DB.Model(session).Update("status", session.Status).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel reading session"})
+		return
+	}
+
+	response := responses.ToReadingSessionResponse(session)
+	audit.LogUpdate(c, "reading_session", session.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reading session cancelled", "data": response})
+}
+
+// recordSessionReading stores one reading of the session in its own transaction
+func (ctrl *ReadingSessionController) recordSessionReading(session *models.ReadingSession, item requests.SessionReadingItem, userID uuid.UUID, onRoute map[uuid.UUID]bool) (*models.WaterUsage, error) {
+	customerID, err := uuid.Parse(item.CustomerID)
+	if err != nil || !onRoute[customerID] {
+		return nil, errCustomerNotOnRoute
+	}
+
+	var meterID *uuid.UUID
+	if item.MeterID != nil && *item.MeterID != "" {
+		id, err := uuid.Parse(*item.MeterID)
+		if err != nil {
+			return nil, helpers.ErrUsageMeterNotFound
+		}
+		meterID = &id
+	}
+
+	var photoID *uuid.UUID
+	if item.PhotoID != nil && *item.PhotoID != "" {
+		id, err := uuid.Parse(*item.PhotoID)
+		if err != nil {
+			return nil, helpers.ErrUsagePhotoNotFound
+		}
+		photoID = &id
+	}
+
+	var readAt time.Time
+	if item.ReadAt != "" {
+		readAt, err = time.Parse(time.RFC3339, item.ReadAt)
+		if err != nil {
+			return nil, errInvalidReadAt
+		}
+	}
+
+	var usage *models.WaterUsage
+	err = ctrl.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		usage, err = helpers.RecordWaterUsage(tx, helpers.WaterUsageInput{
+			TenantID:         session.TenantID,
+			CustomerID:       customerID,
+			MeterID:          meterID,
+			UsageMonth:       session.UsageMonth,
+			MeterEnd:         item.MeterReading,
+			Notes:            item.Notes,
+			RecordedBy:       &userID,
+			ReadingMethod:    item.ReadingMethod,
+			ReadAt:           readAt,
+			ReadingSessionID: &session.ID,
+			PhotoURL:         item.PhotoURL,
+			PhotoID:          photoID,
+		})
+		return err
+	})
+	return usage, err
+}
+
+// buildSessionDetail lists the session's stops with its progress. Stops read
+// outside the session are left out, so a carried-over session only shows
+// what the earlier session missed.
+func buildSessionDetail(db *gorm.DB, session *models.ReadingSession) (*responses.ReadingSessionDetailResponse, error) {
+	routeDetail, err := buildRouteDetail(db, &session.Route, session.UsageMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionCustomers []uuid.UUID
+	if err := db.Model(&models.WaterUsage{}).
+		Where("reading_session_id = ?", session.ID).
+		Distinct().Pluck("customer_id", &sessionCustomers).Error; err != nil {
+		return nil, err
+	}
+	readInSession := make(map[uuid.UUID]bool, len(sessionCustomers))
+	for _, id := range sessionCustomers {
+		readInSession[id] = true
+	}
+
+	var anomalies int64
+	if err := db.Model(&models.WaterUsage{}).
+		Where("reading_session_id = ? AND is_anomaly = ?", session.ID, true).
+		Count(&anomalies).Error; err != nil {
+		return nil, err
+	}
+
+	detail := &responses.ReadingSessionDetailResponse{
+		ReadingSessionResponse: responses.ToReadingSessionResponse(session),
+		Stops:                  []responses.ReadingRouteStopResponse{},
+	}
+	completed := 0
+	for _, stop := range routeDetail.Stops {
+		if stop.Completed && !readInSession[stop.CustomerID] {
+			continue
+		}
+		if stop.Completed {
+			completed++
+		}
+		detail.Stops = append(detail.Stops, stop)
+	}
+
+	// Closed sessions keep the counts they were closed with
+	if session.Status == models.ReadingSessionScheduled || session.Status == models.ReadingSessionInProgress {
+		detail.TotalCustomers = len(detail.Stops)
+		detail.CompletedCount = completed
+		detail.AnomalyCount = int(anomalies)
+	}
+	detail.RemainingCount = detail.TotalCustomers - detail.CompletedCount
+	if detail.RemainingCount < 0 {
+		detail.RemainingCount = 0
+	}
+	if detail.TotalCustomers > 0 {
+		detail.ProgressPercent = float64(detail.CompletedCount) * 100 / float64(detail.TotalCustomers)
+	}
+	return detail, nil
+}
+
+// updateSessionProgress stores the current progress counts on the session
+func updateSessionProgress(db *gorm.DB, session *models.ReadingSession) (*responses.ReadingSessionDetailResponse, error) {
+	detail, err := buildSessionDetail(db, session)
+	if err != nil {
+		return nil, err
+	}
+
+	session.TotalCustomers = detail.TotalCustomers
+	session.CompletedCount = detail.CompletedCount
+	session.AnomalyCount = detail.AnomalyCount
+	if err := db.Model(session).Select("total_customers", "completed_count", "anomaly_count").Updates(session).Error; err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// checkReader allows only the session's reader, or a tenant admin, to work the session
+func (ctrl *ReadingSessionController) checkReader(c *gin.Context, session *models.ReadingSession) bool {
+	userID := helpers.GetUserIDFromContext(c)
+	if userID != nil && *userID == session.ReaderID {
+		return true
+	}
+	if constants.UserRole(c.GetString("role")) == constants.RoleTenantAdmin {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Session is assigned to another reader"})
+	return false
+}
+
+func (ctrl *ReadingSessionController) respondSessionDetail(c *gin.Context, session *models.ReadingSession, message string) {
+	detail, err := updateSessionProgress(ctrl.DB, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session stops"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": detail})
+}
+
+func (ctrl *ReadingSessionController) loadSession(tenantID, sessionID uuid.UUID) (*models.ReadingSession, error) {
+	var session models.ReadingSession
+	if err := ctrl.DB.Preload("Route").Preload("Route.AssignedUser").Preload("Reader").
+		Where("id = ? AND tenant_id = ?", sessionID, tenantID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (ctrl *ReadingSessionController) findSession(c *gin.Context) (*models.ReadingSession, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reading session ID"})
+		return nil, false
+	}
+
+	session, err := ctrl.loadSession(tenantID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading session not found"})
+		return nil, false
+	}
+	return session, true
+}
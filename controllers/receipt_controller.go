@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetPaymentReceipt godoc
+// @Summary Get payment receipt
+// @Description Get the receipt of a payment exactly as it was issued
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.PaymentReceiptResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/{id}/receipt [get]
+func GetPaymentReceipt(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment_id tidak valid"})
+		return
+	}
+
+	var receipt models.PaymentReceipt
+	if err := config.DB.Where("payment_id = ? AND tenant_id = ?", paymentID, tenantID).First(&receipt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuitansi tidak ditemukan"})
+		return
+	}
+
+	respondReceipt(c, &receipt, false)
+}
+
+// GetReceiptByNumber godoc
+// @Summary Get receipt by number
+// @Description Look up an issued receipt by its receipt number
+// @Tags Payments
+// @Produce json
+// @Param number path string true "Receipt number"
+// @Security BearerAuth
+// @Success 200 {object} responses.PaymentReceiptResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/receipts/{number} [get]
+func GetReceiptByNumber(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var receipt models.PaymentReceipt
+	if err := config.DB.Where("receipt_number = ? AND tenant_id = ?", c.Param("number"), tenantID).First(&receipt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuitansi tidak ditemukan"})
+		return
+	}
+
+	respondReceipt(c, &receipt, false)
+}
+
+// ReprintPaymentReceipt godoc
+// @Summary Reprint payment receipt
+// @Description Return the archived receipt marked as a copy for reprinting. Every reprint is recorded in the audit log.
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.PaymentReceiptResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/payments/{id}/receipt/reprint [post]
+func ReprintPaymentReceipt(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment_id tidak valid"})
+		return
+	}
+
+	var receipt models.PaymentReceipt
+	if err := config.DB.Where("payment_id = ? AND tenant_id = ?", paymentID, tenantID).First(&receipt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuitansi tidak ditemukan"})
+		return
+	}
+
+	audit.LogSensitiveOperation(c, models.ActionReceiptReprint, "payment_receipt",
+		"Cetak ulang kuitansi "+receipt.ReceiptNumber, map[string]interface{}{
+			"payment_id":     receipt.PaymentID,
+			"receipt_number": receipt.ReceiptNumber,
+		})
+
+	respondReceipt(c, &receipt, true)
+}
+
+// CustomerGetPaymentReceipt returns the receipt of one of the customer's own payments
+func CustomerGetPaymentReceipt(c *gin.Context) {
+	customerID := c.MustGet("customer_id").(uuid.UUID)
+	tenantID := c.MustGet("tenant_id").(uuid.UUID)
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment_id tidak valid"})
+		return
+	}
+
+	// The payment must belong to one of the customer's invoices
+	var count int64
+	config.DB.Model(&models.Payment{}).
+		Joins("JOIN invoices ON invoices.id = payments.invoice_id").
+		Where("payments.id = ? AND payments.tenant_id = ? AND invoices.customer_id = ?", paymentID, tenantID, customerID).
+		Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuitansi tidak ditemukan"})
+		return
+	}
+
+	var receipt models.PaymentReceipt
+	if err := config.DB.Where("payment_id = ? AND tenant_id = ?", paymentID, tenantID).First(&receipt).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kuitansi tidak ditemukan"})
+		return
+	}
+
+	respondReceipt(c, &receipt, false)
+}
+
+func respondReceipt(c *gin.Context, receipt *models.PaymentReceipt, isCopy bool) {
+	response, err := responses.ToPaymentReceiptResponse(receipt, isCopy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Data kuitansi rusak"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
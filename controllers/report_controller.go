@@ -292,7 +292,7 @@ func GetOutstandingReport(c *gin.Context) {
 		return
 	}
 
-	query := config.DB.Model(&models.Invoice{}).Where("is_paid = ?", false)
+	query := config.DB.Model(&models.Invoice{}).Where("is_paid = ? AND carried_to_id IS NULL", false)
 	
 	if hasSpecificTenant {
 		query = query.Where("tenant_id = ?", tenantID)
@@ -316,7 +316,7 @@ func GetOutstandingReport(c *gin.Context) {
 
 	oldestQuery := config.DB.Model(&models.Invoice{}).
 		Select("id as invoice_id, customer_id, total_amount, total_paid, (total_amount - total_paid) as outstanding, created_at").
-		Where("is_paid = ?", false)
+		Where("is_paid = ? AND carried_to_id IS NULL", false)
 	
 	if hasSpecificTenant {
 		oldestQuery = oldestQuery.Where("tenant_id = ?", tenantID)
@@ -3,9 +3,11 @@ package controllers
 import (
 	"net/http"
 
+	"github.com/adipras/tirta-saas-backend/helpers"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/adipras/tirta-saas-backend/requests"
 	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/adipras/tirta-saas-backend/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -68,6 +70,16 @@ func (ctrl *ServiceAreaController) CreateServiceArea(c *gin.Context) {
 		serviceArea.ParentID = &parentUUID
 	}
 
+	if len(req.Boundary) > 0 && string(req.Boundary) != "null" {
+		_, boundary, err := utils.ParseBoundary(req.Boundary)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		stored := string(boundary)
+		serviceArea.Boundary = &stored
+	}
+
 	if err := ctrl.DB.Create(&serviceArea).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service area"})
 		return
@@ -178,13 +190,37 @@ func (ctrl *ServiceAreaController) UpdateServiceArea(c *gin.Context) {
 		serviceArea.IsActive = *req.IsActive
 	}
 
+	// Omitted keeps the boundary, null removes it
+	boundaryChanged := len(req.Boundary) > 0
+	if boundaryChanged {
+		serviceArea.Boundary = nil
+		if string(req.Boundary) != "null" {
+			_, boundary, err := utils.ParseBoundary(req.Boundary)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			stored := string(boundary)
+			serviceArea.Boundary = &stored
+		}
+	}
+
 	if err := ctrl.DB.Save(&serviceArea).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service area"})
 		return
 	}
 
 	response := responses.ToServiceAreaResponse(&serviceArea)
-	c.JSON(http.StatusOK, gin.H{"message": "Service area updated successfully", "data": response})
+	result := gin.H{"message": "Service area updated successfully", "data": response}
+
+	// Customers placed before the boundary changed are not moved; report them
+	if boundaryChanged {
+		outside, err := helpers.CustomersOutsideArea(ctrl.DB, &serviceArea)
+		if err == nil {
+			result["customers_outside"] = outside
+		}
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 // DeleteServiceArea deletes a service area
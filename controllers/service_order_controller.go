@@ -0,0 +1,523 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/pkg/disconnection"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ServiceOrderController struct {
+	DB *gorm.DB
+}
+
+func NewServiceOrderController(db *gorm.DB) *ServiceOrderController {
+	return &ServiceOrderController{DB: db}
+}
+
+// GetDisconnectionSettings godoc
+// @Summary Get disconnection settings
+// @Description Get the tenant's arrears thresholds for disconnection orders, or the defaults when none are configured
+// @Tags Service Orders
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} responses.DisconnectionSettingsResponse
+// @Router /api/disconnection/settings [get]
+func (ctrl *ServiceOrderController) GetDisconnectionSettings(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := disconnection.LoadPolicy(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load disconnection settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToDisconnectionSettingsResponse(policy)})
+}
+
+// UpdateDisconnectionSettings godoc
+// @Summary Update disconnection settings
+// @Description Change the tenant's arrears thresholds. An order is raised when a customer has at least min_overdue_months overdue usage invoices or overdue arrears of at least min_arrears_amount; 0 turns a threshold off. Invoices are overdue grace_days after billing. Omitted fields keep their current value.
+// @Tags Service Orders
+// @Accept json
+// @Produce json
+// @Param request body requests.UpdateDisconnectionSettingsRequest true "Disconnection settings"
+// @Security BearerAuth
+// @Success 200 {object} responses.DisconnectionSettingsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/disconnection/settings [put]
+func (ctrl *ServiceOrderController) UpdateDisconnectionSettings(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.UpdateDisconnectionSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := disconnection.LoadPolicy(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load disconnection settings"})
+		return
+	}
+	oldValues := responses.ToDisconnectionSettingsResponse(policy)
+
+	if req.IsEnabled != nil {
+		policy.IsEnabled = *req.IsEnabled
+	}
+	if req.GraceDays != nil {
+		policy.GraceDays = *req.GraceDays
+	}
+	if req.MinOverdueMonths != nil {
+		policy.MinOverdueMonths = *req.MinOverdueMonths
+	}
+	if req.MinArrearsAmount != nil {
+		policy.MinArrearsAmount = *req.MinArrearsAmount
+	}
+	if req.NotifyCustomer != nil {
+		policy.NotifyCustomer = *req.NotifyCustomer
+	}
+
+	if policy.IsEnabled && policy.MinOverdueMonths == 0 && policy.MinArrearsAmount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set min_overdue_months or min_arrears_amount to enable disconnection orders"})
+		return
+	}
+
+	if err := ctrl.DB.Save(policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save disconnection settings"})
+		return
+	}
+
+	response := responses.ToDisconnectionSettingsResponse(policy)
+	audit.LogUpdate(c, "disconnection_settings", policy.ID, oldValues, response)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Disconnection settings updated successfully", "data": response})
+}
+
+// RunDisconnection godoc
+// @Summary Run disconnection policy
+// @Description Check the customers' arrears now instead of waiting for the daily run. Customers over the thresholds get a disconnection order and, when enabled, a notice.
+// @Tags Service Orders
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} disconnection.Summary
+// @Router /api/disconnection/run [post]
+func (ctrl *ServiceOrderController) RunDisconnection(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := disconnection.Run(ctrl.DB, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Disconnection run failed", "data": summary})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Disconnection run finished", "data": summary})
+}
+
+// GetServiceOrders godoc
+// @Summary List service orders
+// @Description List disconnection and reconnection orders, newest first
+// @Tags Service Orders
+// @Produce json
+// @Param order_type query string false "disconnection or reconnection"
+// @Param status query string false "open, completed or cancelled"
+// @Param customer_id query string false "Filter by customer"
+// @Param assigned_to query string false "Filter by assignee, 'me' for the current user"
+// @Security BearerAuth
+// @Success 200 {array} responses.ServiceOrderResponse
+// @Router /api/service-orders [get]
+func (ctrl *ServiceOrderController) GetServiceOrders(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if orderType := c.Query("order_type"); orderType != "" {
+		query = query.Where("order_type = ?", orderType)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if assignedTo := c.Query("assigned_to"); assignedTo != "" {
+		if assignedTo == "me" {
+			userID := helpers.GetUserIDFromContext(c)
+			if userID == nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+				return
+			}
+			query = query.Where("assigned_to = ?", *userID)
+		} else {
+			query = query.Where("assigned_to = ?", assignedTo)
+		}
+	}
+
+	var orders []models.ServiceOrder
+	if err := query.Preload("Customer").Preload("Meter").Preload("Assignee").
+		Order("created_at DESC").Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service orders"})
+		return
+	}
+
+	result := make([]responses.ServiceOrderResponse, len(orders))
+	for i := range orders {
+		result[i] = responses.ToServiceOrderResponse(&orders[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result, "total": len(result)})
+}
+
+// GetServiceOrder godoc
+// @Summary Get service order
+// @Description Get a disconnection or reconnection order
+// @Tags Service Orders
+// @Produce json
+// @Param id path string true "Service order ID"
+// @Security BearerAuth
+// @Success 200 {object} responses.ServiceOrderResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/service-orders/{id} [get]
+func (ctrl *ServiceOrderController) GetServiceOrder(c *gin.Context) {
+	order, ok := ctrl.findServiceOrder(c, ctrl.DB)
+	if !ok {
+		return
+	}
+	ctrl.loadRelations(order)
+
+	c.JSON(http.StatusOK, gin.H{"data": responses.ToServiceOrderResponse(order)})
+}
+
+// CreateServiceOrder godoc
+// @Summary Create service order
+// @Description Raise a disconnection order for an active or suspended customer, or a reconnection order for a disconnected one, without waiting for the arrears policy. A reconnection order bills the reconnection fee.
+// @Tags Service Orders
+// @Accept json
+// @Produce json
+// @Param request body requests.CreateServiceOrderRequest true "Service order"
+// @Security BearerAuth
+// @Success 201 {object} responses.ServiceOrderResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/service-orders [post]
+func (ctrl *ServiceOrderController) CreateServiceOrder(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.CreateServiceOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", req.CustomerID, tenantID).First(&customer).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
+		return
+	}
+
+	arrears, err := helpers.GetCustomerArrears(tx, tenantID, customer.ID, time.Time{})
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service order"})
+		return
+	}
+
+	order, _, err := helpers.RaiseServiceOrder(tx, &customer, helpers.ServiceOrderInput{
+		OrderType:       req.OrderType,
+		Reason:          req.Reason,
+		CreatedBy:       helpers.GetUserIDFromContext(c),
+		Arrears:         arrears,
+		ReconnectionFee: req.ReconnectionFee,
+	})
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrServiceOrderExists), errors.Is(err, helpers.ErrServiceOrderNotApplicable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, helpers.ErrServiceOrderType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service order"})
+		}
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service order"})
+		return
+	}
+
+	order.Customer = customer
+	response := responses.ToServiceOrderResponse(order)
+	audit.LogCreate(c, "service_order", order.ID, response)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Service order created successfully", "data": response})
+}
+
+// AssignServiceOrder godoc
+// @Summary Assign service order
+// @Description Assign an open service order to a field staff member, or unassign it with an empty assigned_to
+// @Tags Service Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Service order ID"
+// @Param request body requests.AssignServiceOrderRequest true "Assignee"
+// @Security BearerAuth
+// @Success 200 {object} responses.ServiceOrderResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/service-orders/{id}/assign [post]
+func (ctrl *ServiceOrderController) AssignServiceOrder(c *gin.Context) {
+	var req requests.AssignServiceOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, ok := ctrl.findServiceOrder(c, ctrl.DB)
+	if !ok {
+		return
+	}
+	if order.Status != models.ServiceOrderOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": helpers.ErrServiceOrderNotOpen.Error()})
+		return
+	}
+	oldAssignee := order.AssignedTo
+
+	if req.AssignedTo == "" {
+		order.AssignedTo = nil
+		order.AssignedAt = nil
+	} else {
+		assigneeID, err := uuid.Parse(req.AssignedTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignee ID"})
+			return
+		}
+		var assignee models.User
+		if err := ctrl.DB.Where("id = ? AND tenant_id = ?", assigneeID, order.TenantID).First(&assignee).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee not found in this tenant"})
+			return
+		}
+		now := time.Now()
+		order.AssignedTo = &assignee.ID
+		order.AssignedAt = &now
+	}
+
+	if err := ctrl.DB.Model(order).Select("assigned_to", "assigned_at").Updates(order).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign service order"})
+		return
+	}
+
+	ctrl.loadRelations(order)
+	response := responses.ToServiceOrderResponse(order)
+	audit.LogUpdate(c, "service_order", order.ID,
+		map[string]interface{}{"assigned_to": oldAssignee},
+		map[string]interface{}{"assigned_to": order.AssignedTo})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service order assigned successfully", "data": response})
+}
+
+// CompleteServiceOrder godoc
+// @Summary Complete service order
+// @Description Confirm a disconnection or reconnection in the field. A disconnection needs the final meter reading, which is recorded as the month's reading unless one exists; the customer becomes disconnected and is no longer charged abonemen. A reconnection makes the customer active again.
+// @Tags Service Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Service order ID"
+// @Param request body requests.CompleteServiceOrderRequest true "Field report"
+// @Security BearerAuth
+// @Success 200 {object} responses.ServiceOrderResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/service-orders/{id}/complete [post]
+func (ctrl *ServiceOrderController) CompleteServiceOrder(c *gin.Context) {
+	var req requests.CompleteServiceOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := helpers.GetUserIDFromContext(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var date time.Time
+	if req.Date != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	tx := ctrl.DB.Begin()
+	order, ok := ctrl.findServiceOrder(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	_, usage, err := helpers.CompleteServiceOrder(tx, order, helpers.ServiceOrderCompletion{
+		Date:         date,
+		MeterID:      req.MeterID,
+		MeterReading: req.MeterReading,
+		PhotoID:      req.PhotoID,
+		CompletedBy:  *userID,
+		Notes:        req.Notes,
+	})
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrServiceOrderNotOpen), errors.Is(err, helpers.ErrCustomerStatusNotAllowed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, helpers.ErrServiceOrderReadingRequired), errors.Is(err, helpers.ErrStatusDateInFuture),
+			errors.Is(err, helpers.ErrStatusDateBeforeLast), helpers.IsWaterUsageRuleError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete service order"})
+		}
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete service order"})
+		return
+	}
+
+	ctrl.loadRelations(order)
+	response := responses.ToServiceOrderResponse(order)
+	audit.LogUpdate(c, "service_order", order.ID,
+		map[string]interface{}{"status": models.ServiceOrderOpen},
+		map[string]interface{}{"status": order.Status, "meter_reading": order.MeterReading, "water_usage_id": order.WaterUsageID})
+	if usage != nil {
+		audit.LogCreate(c, "water_usage", usage.ID, usage)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service order completed successfully", "data": response})
+}
+
+// CancelServiceOrder godoc
+// @Summary Cancel service order
+// @Description Cancel an open service order. The fee invoice of a cancelled reconnection order is not voided.
+// @Tags Service Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Service order ID"
+// @Param request body requests.CancelServiceOrderRequest true "Reason"
+// @Security BearerAuth
+// @Success 200 {object} responses.ServiceOrderResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/service-orders/{id}/cancel [post]
+func (ctrl *ServiceOrderController) CancelServiceOrder(c *gin.Context) {
+	var req requests.CancelServiceOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := ctrl.DB.Begin()
+	order, ok := ctrl.findServiceOrder(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	if err := helpers.CancelServiceOrder(tx, order, req.Reason); err != nil {
+		tx.Rollback()
+		if errors.Is(err, helpers.ErrServiceOrderNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel service order"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel service order"})
+		return
+	}
+
+	ctrl.loadRelations(order)
+	response := responses.ToServiceOrderResponse(order)
+	audit.LogUpdate(c, "service_order", order.ID,
+		map[string]interface{}{"status": models.ServiceOrderOpen},
+		map[string]interface{}{"status": order.Status, "cancel_reason": order.CancelReason})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service order cancelled", "data": response})
+}
+
+func (ctrl *ServiceOrderController) findServiceOrder(c *gin.Context, db *gorm.DB) (*models.ServiceOrder, bool) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service order ID"})
+		return nil, false
+	}
+
+	var order models.ServiceOrder
+	if err := db.Where("id = ? AND tenant_id = ?", orderID, tenantID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service order not found"})
+		return nil, false
+	}
+	return &order, true
+}
+
+// loadRelations fills in what the response shows about the order
+func (ctrl *ServiceOrderController) loadRelations(order *models.ServiceOrder) {
+	ctrl.DB.Where("id = ?", order.CustomerID).First(&order.Customer)
+	if order.MeterID != nil {
+		var meter models.Meter
+		if ctrl.DB.Where("id = ?", *order.MeterID).First(&meter).Error == nil {
+			order.Meter = &meter
+		}
+	}
+	if order.AssignedTo != nil {
+		var assignee models.User
+		if ctrl.DB.Where("id = ?", *order.AssignedTo).First(&assignee).Error == nil {
+			order.Assignee = &assignee
+		}
+	}
+}
@@ -52,6 +52,7 @@ func CreateSubscriptionType(c *gin.Context) {
 		MaintenanceFee:  req.MaintenanceFee,
 		LateFeePerDay:   req.LateFeePerDay,
 		MaxLateFee:      req.MaxLateFee,
+		ReconnectionFee: req.ReconnectionFee,
 		TenantID:        tenantID,
 	}
 
@@ -69,6 +70,7 @@ func CreateSubscriptionType(c *gin.Context) {
 		MaintenanceFee:  sub.MaintenanceFee,
 		LateFeePerDay:   sub.LateFeePerDay,
 		MaxLateFee:      sub.MaxLateFee,
+		ReconnectionFee: sub.ReconnectionFee,
 		CreatedAt:       sub.CreatedAt,
 	}
 
@@ -115,6 +117,7 @@ func GetAllSubscriptionTypes(c *gin.Context) {
 			MaintenanceFee:  sub.MaintenanceFee,
 			LateFeePerDay:   sub.LateFeePerDay,
 			MaxLateFee:      sub.MaxLateFee,
+			ReconnectionFee: sub.ReconnectionFee,
 			CreatedAt:       sub.CreatedAt,
 		}
 		responseList = append(responseList, res)
@@ -168,6 +171,7 @@ func GetSubscriptionType(c *gin.Context) {
 		MaintenanceFee:  sub.MaintenanceFee,
 		LateFeePerDay:   sub.LateFeePerDay,
 		MaxLateFee:      sub.MaxLateFee,
+		ReconnectionFee: sub.ReconnectionFee,
 		CreatedAt:       sub.CreatedAt,
 	}
 
@@ -224,6 +228,7 @@ func UpdateSubscriptionType(c *gin.Context) {
 	sub.MaintenanceFee = req.MaintenanceFee
 	sub.LateFeePerDay = req.LateFeePerDay
 	sub.MaxLateFee = req.MaxLateFee
+	sub.ReconnectionFee = req.ReconnectionFee
 
 	if err := config.DB.Save(&sub).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui subscription type"})
@@ -239,6 +244,7 @@ func UpdateSubscriptionType(c *gin.Context) {
 		MaintenanceFee:  sub.MaintenanceFee,
 		LateFeePerDay:   sub.LateFeePerDay,
 		MaxLateFee:      sub.MaxLateFee,
+		ReconnectionFee: sub.ReconnectionFee,
 		CreatedAt:       sub.CreatedAt,
 	}
 
@@ -0,0 +1,324 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/audit"
+	"github.com/adipras/tirta-saas-backend/requests"
+	"github.com/adipras/tirta-saas-backend/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxSyncItems bounds how many payments and readings one sync call may carry
+const maxSyncItems = 500
+
+type SyncController struct {
+	DB *gorm.DB
+}
+
+func NewSyncController(db *gorm.DB) *SyncController {
+	return &SyncController{DB: db}
+}
+
+// syncEntry is one payment or reading from the batch, in apply order
+type syncEntry struct {
+	itemType   string
+	clientID   string
+	capturedAt time.Time
+	payment    *requests.SyncPaymentItem
+	reading    *requests.SyncReadingItem
+}
+
+// Sync godoc
+// @Summary Sync offline field data
+// @Description Apply payments and meter readings captured offline, in captured_at order, and return per-item results plus customers and invoices changed since the last sync token. Items are idempotent by client_id.
+// @Tags Sync
+// @Accept json
+// @Produce json
+// @Param request body requests.SyncRequest true "Sync request"
+// @Security BearerAuth
+// @Success 200 {object} responses.SyncResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/sync [post]
+func (ctrl *SyncController) Sync(c *gin.Context) {
+	// Captured before anything is applied so changes made during this call
+	// are included in the next delta as well
+	serverTime := time.Now()
+
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req requests.SyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Payments)+len(req.Readings) > maxSyncItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Terlalu banyak item dalam satu sinkronisasi. Maksimal " + strconv.Itoa(maxSyncItems)})
+		return
+	}
+
+	var since *time.Time
+	if req.SyncToken != "" {
+		nanos, err := strconv.ParseInt(req.SyncToken, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sync_token tidak valid"})
+			return
+		}
+		t := time.Unix(0, nanos)
+		since = &t
+	}
+
+	entries := make([]syncEntry, 0, len(req.Payments)+len(req.Readings))
+	for i := range req.Payments {
+		p := &req.Payments[i]
+		entries = append(entries, syncEntry{itemType: models.SyncItemPayment, clientID: p.ClientID, capturedAt: p.CapturedAt, payment: p})
+	}
+	for i := range req.Readings {
+		r := &req.Readings[i]
+		entries = append(entries, syncEntry{itemType: models.SyncItemReading, clientID: r.ClientID, capturedAt: r.CapturedAt, reading: r})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].capturedAt.Before(entries[j].capturedAt)
+	})
+
+	userID := helpers.GetUserIDFromContext(c)
+	role := constants.UserRole(c.GetString("role"))
+
+	results := make([]responses.SyncItemResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, ctrl.applyEntry(c, tenantID, userID, role, req.DeviceID, entry))
+	}
+
+	response := responses.SyncResponse{
+		Results:       results,
+		NextSyncToken: strconv.FormatInt(serverTime.UnixNano(), 10),
+		ServerTime:    serverTime,
+	}
+	if err := ctrl.fillDelta(tenantID, since, &response); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil perubahan data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// applyEntry applies one item in its own transaction. Applied items and
+// conflicts are stored under the client_id so a retried batch gets the same
+// answer; transient errors are not stored and may be retried.
+func (ctrl *SyncController) applyEntry(c *gin.Context, tenantID uuid.UUID, userID *uuid.UUID, role constants.UserRole, deviceID string, entry syncEntry) responses.SyncItemResult {
+	var existing models.SyncItem
+	if err := ctrl.DB.Where("tenant_id = ? AND client_id = ?", tenantID, entry.clientID).First(&existing).Error; err == nil {
+		return duplicateSyncResult(&existing)
+	}
+
+	item := models.SyncItem{
+		TenantID:    tenantID,
+		ClientID:    entry.clientID,
+		DeviceID:    deviceID,
+		ItemType:    entry.itemType,
+		CapturedAt:  entry.capturedAt,
+		SubmittedBy: userID,
+	}
+
+	requiredPermission := constants.PermRecordPayments
+	if entry.itemType == models.SyncItemReading {
+		requiredPermission = constants.PermRecordWaterUsage
+	}
+	if !constants.HasPermission(role, requiredPermission) {
+		return ctrl.storeConflict(&item, "forbidden", "Access denied. Insufficient permissions")
+	}
+
+	tx := ctrl.DB.Begin()
+
+	var (
+		resourceID uuid.UUID
+		payment    *models.Payment
+		applyErr   error
+	)
+	switch entry.itemType {
+	case models.SyncItemPayment:
+		p := entry.payment
+		payment, _, applyErr = helpers.ApplyPayment(tx, helpers.PaymentInput{
+			TenantID:        tenantID,
+			InvoiceID:       p.InvoiceID,
+			Amount:          p.Amount,
+			PaymentMethodID: p.PaymentMethodID,
+			ReferenceNumber: p.ReferenceNumber,
+			Notes:           p.Notes,
+			ReceivedBy:      userID,
+			PaidAt:          p.CapturedAt,
+		})
+		if applyErr == nil {
+			resourceID = payment.ID
+		}
+	case models.SyncItemReading:
+		r := entry.reading
+		var usage *models.WaterUsage
+		usage, applyErr = helpers.RecordWaterUsage(tx, helpers.WaterUsageInput{
+			TenantID:   tenantID,
+			CustomerID: r.CustomerID,
+			MeterID:    r.MeterID,
+			UsageMonth: r.UsageMonth,
+			MeterEnd:   r.MeterEnd,
+			Notes:      r.Notes,
+			RecordedBy: userID,
+			ReadAt:     r.CapturedAt,
+			PhotoID:    r.PhotoID,
+		})
+		if applyErr == nil {
+			resourceID = usage.ID
+		}
+	}
+
+	if applyErr != nil {
+		tx.Rollback()
+		if code := syncConflictCode(applyErr); code != "" {
+			return ctrl.storeConflict(&item, code, applyErr.Error())
+		}
+		return responses.SyncItemResult{
+			ClientID: entry.clientID,
+			Type:     entry.itemType,
+			Status:   models.SyncStatusError,
+			Message:  "Gagal memproses item, silakan coba lagi",
+		}
+	}
+
+	item.Status = models.SyncStatusApplied
+	item.ResourceID = &resourceID
+	if err := tx.Create(&item).Error; err != nil {
+		tx.Rollback()
+		// Another request stored the same client_id first
+		if err := ctrl.DB.Where("tenant_id = ? AND client_id = ?", tenantID, entry.clientID).First(&existing).Error; err == nil {
+			return duplicateSyncResult(&existing)
+		}
+		return responses.SyncItemResult{ClientID: entry.clientID, Type: entry.itemType, Status: models.SyncStatusError, Message: "Gagal memproses item, silakan coba lagi"}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return responses.SyncItemResult{ClientID: entry.clientID, Type: entry.itemType, Status: models.SyncStatusError, Message: "Gagal memproses item, silakan coba lagi"}
+	}
+
+	if payment != nil {
+		audit.LogPayment(c, payment.InvoiceID, payment.ID, payment.Amount, true, "")
+	} else {
+		audit.LogCreate(c, "water_usage", resourceID, entry.reading)
+	}
+
+	return responses.ToSyncItemResult(&item)
+}
+
+// storeConflict records a rejected item so retries return the same conflict
+func (ctrl *SyncController) storeConflict(item *models.SyncItem, code, message string) responses.SyncItemResult {
+	item.Status = models.SyncStatusConflict
+	item.ConflictCode = code
+	item.Message = message
+	if err := ctrl.DB.Create(item).Error; err != nil {
+		var existing models.SyncItem
+		if err := ctrl.DB.Where("tenant_id = ? AND client_id = ?", item.TenantID, item.ClientID).First(&existing).Error; err == nil {
+			return duplicateSyncResult(&existing)
+		}
+	}
+	return responses.ToSyncItemResult(item)
+}
+
+// fillDelta adds customers and invoices changed since the token. Without a
+// token the device gets connected customers and unpaid invoices to start from.
+func (ctrl *SyncController) fillDelta(tenantID uuid.UUID, since *time.Time, response *responses.SyncResponse) error {
+	var customers []models.Customer
+	customerQuery := ctrl.DB.Where("tenant_id = ?", tenantID)
+	invoiceQuery := ctrl.DB.Where("tenant_id = ?", tenantID)
+	if since != nil {
+		customerQuery = customerQuery.Where("updated_at > ?", *since)
+		invoiceQuery = invoiceQuery.Where("updated_at > ?", *since)
+	} else {
+		// Suspended and disconnected customers still settle their bills
+		customerQuery = customerQuery.Where("status IN ?", []string{models.CustomerStatusActive,
+			models.CustomerStatusSuspended, models.CustomerStatusDisconnected})
+		invoiceQuery = invoiceQuery.Where("is_paid = ? AND carried_to_id IS NULL", false)
+	}
+
+	if err := customerQuery.Order("updated_at asc").Find(&customers).Error; err != nil {
+		return err
+	}
+	var invoices []models.Invoice
+	if err := invoiceQuery.Order("updated_at asc").Find(&invoices).Error; err != nil {
+		return err
+	}
+
+	response.Customers = make([]responses.SyncCustomerDelta, len(customers))
+	for i := range customers {
+		response.Customers[i] = responses.ToSyncCustomerDelta(&customers[i])
+	}
+	response.Invoices = make([]responses.SyncInvoiceDelta, len(invoices))
+	for i := range invoices {
+		response.Invoices[i] = responses.ToSyncInvoiceDelta(&invoices[i])
+	}
+
+	response.DeletedCustomerIDs = []uuid.UUID{}
+	response.DeletedInvoiceIDs = []uuid.UUID{}
+	if since == nil {
+		return nil
+	}
+	if err := ctrl.DB.Unscoped().Model(&models.Customer{}).
+		Where("tenant_id = ? AND deleted_at > ?", tenantID, *since).
+		Pluck("id", &response.DeletedCustomerIDs).Error; err != nil {
+		return err
+	}
+	return ctrl.DB.Unscoped().Model(&models.Invoice{}).
+		Where("tenant_id = ? AND deleted_at > ?", tenantID, *since).
+		Pluck("id", &response.DeletedInvoiceIDs).Error
+}
+
+func duplicateSyncResult(item *models.SyncItem) responses.SyncItemResult {
+	result := responses.ToSyncItemResult(item)
+	result.Message = "Item sudah diproses sebelumnya (" + item.Status + ")"
+	if item.Status == models.SyncStatusConflict && item.Message != "" {
+		result.Message += ": " + item.Message
+	}
+	result.Status = models.SyncStatusDuplicate
+	return result
+}
+
+// syncConflictCode maps business rule errors to stable codes the device can
+// act on; it returns "" for errors that are not conflicts
+func syncConflictCode(err error) string {
+	switch {
+	case errors.Is(err, helpers.ErrInvoiceNotFound):
+		return "invoice_not_found"
+	case errors.Is(err, helpers.ErrInvoiceAlreadyPaid):
+		return "invoice_already_paid"
+	case errors.Is(err, helpers.ErrPaymentExceedsInvoice):
+		return "payment_exceeds_invoice"
+	case errors.Is(err, helpers.ErrPaymentMethodNotFound):
+		return "payment_method_not_found"
+	case errors.Is(err, helpers.ErrUsageCustomerNotFound):
+		return "customer_not_found"
+	case errors.Is(err, helpers.ErrReadingAlreadyExists):
+		return "reading_exists"
+	case errors.Is(err, helpers.ErrMeterReadingBackward):
+		return "meter_reading_backward"
+	case errors.Is(err, helpers.ErrUsageMeterNotFound):
+		return "meter_not_found"
+	case errors.Is(err, helpers.ErrUsageMeterRequired):
+		return "meter_required"
+	case errors.Is(err, helpers.ErrNoActiveWaterRate):
+		return "no_active_rate"
+	case helpers.IsPaymentRuleError(err), helpers.IsWaterUsageRuleError(err):
+		return "validation_failed"
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WaterBalanceController struct {
+	DB *gorm.DB
+}
+
+func NewWaterBalanceController(db *gorm.DB) *WaterBalanceController {
+	return &WaterBalanceController{DB: db}
+}
+
+// GetWaterBalance godoc
+// @Summary Monthly water balance
+// @Description Supplied volume from bulk meters against the customers' recorded usage and the resulting non-revenue water (NRW), per service area and rolled up the area hierarchy
+// @Tags Water Balance
+// @Produce json
+// @Param month query string false "YYYY-MM, defaults to last month"
+// @Param service_area_id query string false "Only this service area and the areas below it"
+// @Security BearerAuth
+// @Success 200 {object} helpers.WaterBalance
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/water-balance [get]
+func (ctrl *WaterBalanceController) GetWaterBalance(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	month := c.DefaultQuery("month", lastMonth())
+	balance, err := helpers.ComputeWaterBalance(ctrl.DB, tenantID, month)
+	if err != nil {
+		if errors.Is(err, helpers.ErrInvalidUsageMonth) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute water balance"})
+		return
+	}
+
+	if areaParam := c.Query("service_area_id"); areaParam != "" {
+		areaID, err := uuid.Parse(areaParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service area ID"})
+			return
+		}
+		area := balance.Area(areaID)
+		if area == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service area not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": area, "month": month})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": balance})
+}
+
+// GetWaterBalanceTrend godoc
+// @Summary Water balance trend
+// @Description Monthly supplied volume, measured usage and NRW for a service area, or the whole tenant, oldest month first
+// @Tags Water Balance
+// @Produce json
+// @Param service_area_id query string false "Service area, defaults to the whole tenant"
+// @Param month query string false "Last month of the trend (YYYY-MM), defaults to last month"
+// @Param months query int false "Number of months (1-24, default 12)"
+// @Security BearerAuth
+// @Success 200 {array} helpers.WaterBalancePoint
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/water-balance/trend [get]
+func (ctrl *WaterBalanceController) GetWaterBalanceTrend(c *gin.Context) {
+	tenantID, err := helpers.RequireTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	months := 12
+	if monthsParam := c.Query("months"); monthsParam != "" {
+		months, err = strconv.Atoi(monthsParam)
+		if err != nil || months < 1 || months > 24 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months must be between 1 and 24"})
+			return
+		}
+	}
+
+	var areaID *uuid.UUID
+	if areaParam := c.Query("service_area_id"); areaParam != "" {
+		parsed, err := uuid.Parse(areaParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service area ID"})
+			return
+		}
+		areaID = &parsed
+	}
+
+	points, err := helpers.WaterBalanceTrend(ctrl.DB, tenantID, areaID, c.DefaultQuery("month", lastMonth()), months)
+	if err != nil {
+		switch {
+		case errors.Is(err, helpers.ErrInvalidUsageMonth):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service area not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute water balance trend"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": points, "total": len(points)})
+}
+
+// lastMonth is the latest month whose readings are normally complete
+func lastMonth() string {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, now.Location()).Format("2006-01")
+}
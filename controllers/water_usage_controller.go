@@ -1,8 +1,8 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/helpers"
@@ -42,94 +42,38 @@ func CreateWaterUsage(c *gin.Context) {
 		return
 	}
 
-	// Business rule validation: Check reasonable meter reading
-	if req.MeterEnd < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter end reading cannot be negative"})
-		return
-	}
-
-	if req.MeterEnd > 99999999 { // 8 digit max reasonable meter reading
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter reading exceeds maximum allowed value"})
-		return
-	}
-
-	// Hitung bulan sebelumnya
-	prevMonth, err := time.Parse("2006-01", req.UsageMonth)
+	tx := config.DB.Begin()
+	usage, err := helpers.RecordWaterUsage(tx, helpers.WaterUsageInput{
+		TenantID:   tenantID,
+		CustomerID: req.CustomerID,
+		MeterID:    req.MeterID,
+		UsageMonth: req.UsageMonth,
+		MeterEnd:   req.MeterEnd,
+		Notes:      req.Notes,
+		RecordedBy: helpers.GetUserIDFromContext(c),
+		PhotoID:    req.PhotoID,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan YYYY-MM"})
-		return
-	}
-	prevMonth = prevMonth.AddDate(0, -1, 0)
-	prevMonthStr := prevMonth.Format("2006-01")
-
-	// Ambil meter_end bulan sebelumnya
-	var lastUsage models.WaterUsage
-	meterStart := 0.0
-	if err := config.DB.Where("customer_id = ? AND usage_month = ? AND tenant_id = ?", req.CustomerID, prevMonthStr, tenantID).
-		First(&lastUsage).Error; err == nil {
-		meterStart = lastUsage.MeterEnd
-	}
-
-	if req.MeterEnd < meterStart {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter akhir lebih kecil dari meter sebelumnya"})
-		return
-	}
-
-	// Ambil data customer
-	var customer models.Customer
-	if err := config.DB.Where("id = ? AND tenant_id = ?", req.CustomerID, tenantID).First(&customer).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Pelanggan tidak ditemukan"})
-		return
-	}
-
-	// Ambil tarif aktif untuk subscription pelanggan
-	var rate models.WaterRate
-	if err := config.DB.
-		Where("subscription_id = ? AND active = ?", customer.SubscriptionID, true).
-		Order("effective_date DESC").
-		First(&rate).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tarif air aktif tidak ditemukan"})
-		return
-	}
-
-	UsageM3 := req.MeterEnd - meterStart
-
-	// Business rule validation: Check reasonable usage amount
-	if UsageM3 < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Calculated usage cannot be negative"})
-		return
-	}
-
-	if UsageM3 > 1000 { // Max 1000 m3 per month seems reasonable
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Usage amount exceeds reasonable limit (1000 m3/month)"})
+		tx.Rollback()
+		switch {
+		case errors.Is(err, helpers.ErrUsageCustomerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, helpers.ErrReadingAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case helpers.IsWaterUsageRuleError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan data"})
+		}
 		return
 	}
 
-	usage := models.WaterUsage{
-		CustomerID:       req.CustomerID,
-		UsageMonth:       req.UsageMonth,
-		MeterStart:       meterStart,
-		MeterEnd:         req.MeterEnd,
-		UsageM3:          UsageM3,
-		AmountCalculated: UsageM3 * rate.Amount,
-		TenantID:         tenantID,
-	}
-
-	if err := config.DB.Create(&usage).Error; err != nil {
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan data"})
 		return
 	}
 
-	response := responses.WaterUsageResponse{
-		ID:               usage.ID,
-		CustomerID:       usage.CustomerID,
-		UsageMonth:       usage.UsageMonth,
-		MeterStart:       usage.MeterStart,
-		MeterEnd:         usage.MeterEnd,
-		UsageM3:          usage.UsageM3,
-		AmountCalculated: usage.AmountCalculated,
-		CreatedAt:        usage.CreatedAt,
-	}
+	response := responses.ToWaterUsageResponse(usage)
 	c.JSON(http.StatusCreated, response)
 }
 
@@ -166,17 +110,8 @@ func GetWaterUsages(c *gin.Context) {
 
 	// Convert to response format
 	usageResponses := make([]responses.WaterUsageResponse, len(records))
-	for i, record := range records {
-		usageResponses[i] = responses.WaterUsageResponse{
-			ID:               record.ID,
-			CustomerID:       record.CustomerID,
-			UsageMonth:       record.UsageMonth,
-			MeterStart:       record.MeterStart,
-			MeterEnd:         record.MeterEnd,
-			UsageM3:          record.UsageM3,
-			AmountCalculated: record.AmountCalculated,
-			CreatedAt:        record.CreatedAt,
-		}
+	for i := range records {
+		usageResponses[i] = responses.ToWaterUsageResponse(&records[i])
 	}
 
 	response := responses.WaterUsageListResponse{
@@ -206,16 +141,7 @@ func GetWaterUsageByID(c *gin.Context) {
 		return
 	}
 
-	response := responses.WaterUsageResponse{
-		ID:               usage.ID,
-		CustomerID:       usage.CustomerID,
-		UsageMonth:       usage.UsageMonth,
-		MeterStart:       usage.MeterStart,
-		MeterEnd:         usage.MeterEnd,
-		UsageM3:          usage.UsageM3,
-		AmountCalculated: usage.AmountCalculated,
-		CreatedAt:        usage.CreatedAt,
-	}
+	response := responses.ToWaterUsageResponse(&usage)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -257,8 +183,21 @@ func UpdateWaterUsage(c *gin.Context) {
 		return
 	}
 
-	if input.MeterEnd < usage.MeterStart {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Meter akhir tidak boleh lebih kecil dari awal"})
+	var meter *models.Meter
+	if usage.MeterID != nil {
+		var m models.Meter
+		if err := config.DB.Where("id = ? AND tenant_id = ?", *usage.MeterID, tenantID).First(&m).Error; err == nil {
+			meter = &m
+		}
+	}
+
+	UsageM3, rollover, err := helpers.ConsumptionBetween(usage.MeterStart, input.MeterEnd, meter)
+	if err != nil {
+		if errors.Is(err, helpers.ErrMeterReadingBackward) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Meter akhir tidak boleh lebih kecil dari awal"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -279,33 +218,27 @@ func UpdateWaterUsage(c *gin.Context) {
 		return
 	}
 
-	UsageM3 := input.MeterEnd - usage.MeterStart
-
-	// Business rule validation: Check reasonable usage amount
-	if UsageM3 > 1000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Usage amount exceeds reasonable limit (1000 m3/month)"})
-		return
-	}
-
 	usage.MeterEnd = input.MeterEnd
 	usage.UsageM3 = UsageM3
 	usage.AmountCalculated = UsageM3 * rate.Amount
+	usage.IsRollover = rollover
 
 	if err := config.DB.Save(&usage).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui data"})
 		return
 	}
 
-	response := responses.WaterUsageResponse{
-		ID:               usage.ID,
-		CustomerID:       usage.CustomerID,
-		UsageMonth:       usage.UsageMonth,
-		MeterStart:       usage.MeterStart,
-		MeterEnd:         usage.MeterEnd,
-		UsageM3:          usage.UsageM3,
-		AmountCalculated: usage.AmountCalculated,
-		CreatedAt:        usage.CreatedAt,
+	// A corrected reading that is still unusual goes to anomaly review
+	if !usage.IsAnomaly {
+		anomaly, err := helpers.CheckReadingAnomaly(config.DB, &usage)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa anomali pembacaan"})
+			return
+		}
+		usage.AnomalyDetails = anomaly
 	}
+
+	response := responses.ToWaterUsageResponse(&usage)
 	c.JSON(http.StatusOK, response)
 }
 
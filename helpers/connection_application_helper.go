@@ -0,0 +1,175 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCustomerNumberTaken = errors.New("Meter number already exists")
+	ErrCustomerEmailTaken  = errors.New("Email sudah digunakan")
+)
+
+// NextApplicationNumber returns the next connection application number of
+// the tenant for the year, e.g. PSB-2026-000042
+func NextApplicationNumber(tx *gorm.DB, tenantID uuid.UUID, year int) (string, error) {
+	prefix := fmt.Sprintf("PSB-%d-", year)
+	var last string
+	if err := tx.Model(&models.ConnectionApplication{}).
+		Where("tenant_id = ? AND application_number LIKE ?", tenantID, prefix+"%").
+		Select("COALESCE(MAX(application_number), '')").
+		Scan(&last).Error; err != nil {
+		return "", err
+	}
+	next := 1
+	if last != "" {
+		var n int
+		if _, err := fmt.Sscanf(last[len(prefix):], "%d", &n); err == nil {
+			next = n + 1
+		}
+	}
+	return fmt.Sprintf("%s%06d", prefix, next), nil
+}
+
+// ConnectionInstallation describes the meter fitted for an approved application
+type ConnectionInstallation struct {
+	MeterNumber    string // also the customer's account number
+	Brand          string
+	Model          string
+	InstallDate    time.Time
+	InitialReading float64
+	RolloverAt     float64
+	Latitude       *float64 // meter location, defaults to the application's
+	Longitude      *float64
+	Password       string // hashed portal password, empty until set
+	InstalledBy    uuid.UUID
+	Notes          string
+}
+
+// InstallConnection completes an approved application inside tx: it creates
+// the customer, its meter and the registration invoice for the connection
+// fee. The customer stays an applicant until the invoice is paid, or becomes
+// active at once when there is no fee.
+func InstallConnection(tx *gorm.DB, application *models.ConnectionApplication, install ConnectionInstallation) (*models.Customer, *models.Meter, *models.Invoice, error) {
+	var existing int64
+	if err := tx.Model(&models.Customer{}).Where("meter_number = ?", install.MeterNumber).Count(&existing).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	if existing == 0 {
+		if err := tx.Model(&models.Meter{}).
+			Where("tenant_id = ? AND meter_number = ?", application.TenantID, install.MeterNumber).
+			Count(&existing).Error; err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if existing > 0 {
+		return nil, nil, nil, ErrCustomerNumberTaken
+	}
+	if application.Email != "" {
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND email = ?", application.TenantID, application.Email).
+			Count(&existing).Error; err != nil {
+			return nil, nil, nil, err
+		}
+		if existing > 0 {
+			return nil, nil, nil, ErrCustomerEmailTaken
+		}
+	}
+
+	customer := models.Customer{
+		MeterNumber:    install.MeterNumber,
+		Name:           application.ApplicantName,
+		Email:          application.Email,
+		Password:       install.Password,
+		Address:        application.Address,
+		Phone:          application.Phone,
+		SubscriptionID: application.SubscriptionID,
+		IsActive:       false,
+		Status:         models.CustomerStatusApplicant,
+		TenantID:       application.TenantID,
+		ServiceAreaID:  application.ServiceAreaID,
+		Latitude:       application.Latitude,
+		Longitude:      application.Longitude,
+	}
+	if err := tx.Create(&customer).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	meter := models.Meter{
+		TenantID:       application.TenantID,
+		CustomerID:     customer.ID,
+		MeterNumber:    install.MeterNumber,
+		Brand:          install.Brand,
+		Model:          install.Model,
+		InstallDate:    install.InstallDate,
+		InitialReading: install.InitialReading,
+		RolloverAt:     install.RolloverAt,
+		Status:         models.MeterStatusActive,
+		Notes:          install.Notes,
+		Latitude:       application.Latitude,
+		Longitude:      application.Longitude,
+	}
+	if install.Latitude != nil && install.Longitude != nil {
+		meter.Latitude = install.Latitude
+		meter.Longitude = install.Longitude
+	}
+	if err := tx.Omit("Customer").Create(&meter).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	if err := RecordMeterHistory(tx, &meter, models.MeterActionInstall, "",
+		fmt.Sprintf("%s (awal %.2f)", meter.MeterNumber, meter.InitialReading), install.InstalledBy,
+		"Sambungan baru "+application.ApplicationNumber); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var invoice *models.Invoice
+	if application.ConnectionFee > 0 {
+		invoice = &models.Invoice{
+			CustomerID:  customer.ID,
+			TenantID:    application.TenantID,
+			Type:        "registration",
+			UsageMonth:  "-", // tidak relevan untuk registration
+			TotalAmount: application.ConnectionFee,
+			IsPaid:      false,
+			TotalPaid:   0,
+		}
+		if err := tx.Omit("Customer").Create(invoice).Error; err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		if _, err := recordCustomerStatus(tx, &customer, CustomerStatusChange{
+			Status:        models.CustomerStatusActive,
+			Reason:        "Connection installed without fee",
+			EffectiveDate: install.InstallDate,
+			ChangedBy:     &install.InstalledBy,
+		}, nil); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	application.Status = models.ApplicationInstalled
+	application.InstalledBy = &install.InstalledBy
+	application.InstalledAt = &now
+	application.CustomerID = &customer.ID
+	application.MeterID = &meter.ID
+	if invoice != nil {
+		application.InvoiceID = &invoice.ID
+	}
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"status":       application.Status,
+		"installed_by": application.InstalledBy,
+		"installed_at": application.InstalledAt,
+		"customer_id":  application.CustomerID,
+		"meter_id":     application.MeterID,
+		"invoice_id":   application.InvoiceID,
+	}).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	return &customer, &meter, invoice, nil
+}
@@ -0,0 +1,387 @@
+package helpers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCustomerStatusInvalid    = errors.New("Unknown customer status")
+	ErrCustomerStatusNotAllowed = errors.New("Customer status change not allowed")
+	ErrStatusDateInFuture       = errors.New("Effective date cannot be in the future")
+	ErrStatusDateBeforeLast     = errors.New("Effective date is before the customer's last status change")
+	ErrInvoiceUsageInvalid      = errors.New("Water usage records of the month are invalid")
+	ErrInvoiceTotalOutOfRange   = errors.New("Invoice total out of range")
+	ErrUsageCustomerClosed      = errors.New("Pelanggan sudah ditutup")
+)
+
+// UsageInvoiceTypes are the invoice types that bill a month's water usage.
+// A customer has at most one of them per usage month.
+var UsageInvoiceTypes = []string{"monthly", "final"}
+
+// ArrearsInvoiceTypes are the invoice types counted as arrears: usage bills
+// and the transfer invoice that takes over a previous owner's unpaid usage
+var ArrearsInvoiceTypes = []string{"monthly", "final", "transfer"}
+
+func isArrearsInvoice(invoice *models.Invoice) bool {
+	for _, invoiceType := range ArrearsInvoiceTypes {
+		if invoice.Type == invoiceType {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerStatusChange describes a lifecycle transition of a customer
+type CustomerStatusChange struct {
+	Status        string
+	Reason        string
+	EffectiveDate time.Time  // zero means today
+	ChangedBy     *uuid.UUID // nil for automatic changes
+	Notes         string
+
+	// ReconnectionFee overrides the subscription's fee when a disconnected
+	// customer is reactivated; 0 waives it
+	ReconnectionFee *float64
+}
+
+// CanChangeCustomerStatus reports whether a customer may move from one status to another
+func CanChangeCustomerStatus(from, to string) bool {
+	for _, allowed := range models.CustomerStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeCustomerStatus moves a customer to a new lifecycle status inside tx
+// and records it in the status history. Closing the account raises the final
+// bill for the closing month, deactivates the meters and takes the customer
+// off its reading route; reactivating a disconnected customer bills the
+// reconnection fee unless a reconnection order already did. Open service
+// orders the change makes moot are settled. The invoice raised, if any, is
+// returned.
+func ChangeCustomerStatus(tx *gorm.DB, customer *models.Customer, change CustomerStatusChange) (*models.CustomerStatusHistory, *models.Invoice, error) {
+	if _, ok := models.CustomerStatusTransitions[change.Status]; !ok {
+		return nil, nil, ErrCustomerStatusInvalid
+	}
+	if !CanChangeCustomerStatus(customer.Status, change.Status) {
+		return nil, nil, ErrCustomerStatusNotAllowed
+	}
+
+	today := truncateToDay(time.Now())
+	if change.EffectiveDate.IsZero() {
+		change.EffectiveDate = today
+	}
+	change.EffectiveDate = truncateToDay(change.EffectiveDate)
+	if change.EffectiveDate.After(today) {
+		return nil, nil, ErrStatusDateInFuture
+	}
+	if customer.StatusSince != nil && change.EffectiveDate.Before(truncateToDay(*customer.StatusSince)) {
+		return nil, nil, ErrStatusDateBeforeLast
+	}
+
+	var invoice *models.Invoice
+	var err error
+	switch {
+	case change.Status == models.CustomerStatusClosed:
+		if err := closeCustomerService(tx, customer, change); err != nil {
+			return nil, nil, err
+		}
+		// The closing month is billed now, including usage recorded up to closure
+		invoice, err = createFinalBill(tx, customer, change.EffectiveDate.Format("2006-01"))
+	case customer.Status == models.CustomerStatusDisconnected && change.Status == models.CustomerStatusActive:
+		// A reconnection order bills the fee when it is raised
+		if change.ReconnectionFee == nil {
+			orders, err := openServiceOrders(tx, customer, models.ServiceOrderReconnection)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(orders) > 0 {
+				noFee := 0.0
+				change.ReconnectionFee = &noFee
+			}
+		}
+		invoice, err = createReconnectionInvoice(tx, customer, change)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var invoiceID *uuid.UUID
+	if invoice != nil {
+		invoiceID = &invoice.ID
+	}
+	fromStatus := customer.Status
+	history, err := recordCustomerStatus(tx, customer, change, invoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := settleServiceOrders(tx, customer, fromStatus, change); err != nil {
+		return nil, nil, err
+	}
+	return history, invoice, nil
+}
+
+// recordCustomerStatus stores the new status on the customer and appends it
+// to the history, without checking the transition
+func recordCustomerStatus(tx *gorm.DB, customer *models.Customer, change CustomerStatusChange, invoiceID *uuid.UUID) (*models.CustomerStatusHistory, error) {
+	if change.EffectiveDate.IsZero() {
+		change.EffectiveDate = truncateToDay(time.Now())
+	}
+
+	history := models.CustomerStatusHistory{
+		TenantID:      customer.TenantID,
+		CustomerID:    customer.ID,
+		FromStatus:    customer.Status,
+		ToStatus:      change.Status,
+		Reason:        change.Reason,
+		EffectiveDate: change.EffectiveDate,
+		ChangedBy:     change.ChangedBy,
+		InvoiceID:     invoiceID,
+		Notes:         change.Notes,
+	}
+	if err := tx.Omit("Tenant", "Customer", "User").Create(&history).Error; err != nil {
+		return nil, err
+	}
+
+	customer.Status = change.Status
+	customer.IsActive = change.Status == models.CustomerStatusActive
+	customer.StatusSince = &history.EffectiveDate
+	if err := tx.Model(&models.Customer{}).Where("id = ?", customer.ID).Updates(map[string]interface{}{
+		"status":       customer.Status,
+		"is_active":    customer.IsActive,
+		"status_since": customer.StatusSince,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// closeCustomerService deactivates the meters of a closing account and takes
+// the customer off its reading route
+func closeCustomerService(tx *gorm.DB, customer *models.Customer, change CustomerStatusChange) error {
+	var meters []models.Meter
+	if err := tx.Where("tenant_id = ? AND customer_id = ? AND status = ?", customer.TenantID, customer.ID, models.MeterStatusActive).
+		Find(&meters).Error; err != nil {
+		return err
+	}
+	for i := range meters {
+		meter := &meters[i]
+		if err := tx.Model(meter).Update("status", models.MeterStatusInactive).Error; err != nil {
+			return err
+		}
+		// Meter history needs a staff member; automatic closures are in the customer's history
+		if change.ChangedBy != nil {
+			if err := RecordMeterHistory(tx, meter, models.MeterActionStatusChange, models.MeterStatusActive,
+				models.MeterStatusInactive, *change.ChangedBy, "Account closed: "+change.Reason); err != nil {
+				return err
+			}
+		}
+	}
+
+	return removeFromReadingRoute(tx, customer)
+}
+
+// removeFromReadingRoute takes the customer off its reading route, if any,
+// and recounts the route
+func removeFromReadingRoute(tx *gorm.DB, customer *models.Customer) error {
+	if customer.ReadingRouteID == nil {
+		return nil
+	}
+	routeID := *customer.ReadingRouteID
+	if err := tx.Model(&models.Customer{}).Where("id = ?", customer.ID).
+		Updates(map[string]interface{}{"reading_route_id": nil, "route_sequence": 0}).Error; err != nil {
+		return err
+	}
+	customer.ReadingRouteID = nil
+	customer.RouteSequence = 0
+	return recountReadingRoute(tx, customer.TenantID, routeID)
+}
+
+func recountReadingRoute(tx *gorm.DB, tenantID, routeID uuid.UUID) error {
+	var count int64
+	if err := tx.Model(&models.Customer{}).
+		Where("tenant_id = ? AND reading_route_id = ?", tenantID, routeID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.ReadingRoute{}).Where("id = ?", routeID).Update("customer_count", count).Error
+}
+
+// createFinalBill bills the customer's readings of the closing month. Nothing
+// is raised when the month is already invoiced or there is nothing to bill.
+func createFinalBill(tx *gorm.DB, customer *models.Customer, usageMonth string) (*models.Invoice, error) {
+	var invoiced int64
+	if err := tx.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND customer_id = ? AND usage_month = ? AND type IN ?", customer.TenantID, customer.ID, usageMonth, UsageInvoiceTypes).
+		Count(&invoiced).Error; err != nil {
+		return nil, err
+	}
+	if invoiced > 0 {
+		return nil, nil
+	}
+
+	var usages []models.WaterUsage
+	if err := tx.Where("tenant_id = ? AND customer_id = ? AND usage_month = ?", customer.TenantID, customer.ID, usageMonth).
+		Find(&usages).Error; err != nil {
+		return nil, err
+	}
+
+	invoice, err := BuildUsageInvoice(tx, customer, usageMonth, usages, "final")
+	if err != nil || invoice == nil {
+		return nil, err
+	}
+	if err := tx.Omit("Customer").Create(invoice).Error; err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// createReconnectionInvoice bills the reconnection fee of the customer's
+// subscription, or the overriding amount. A zero fee raises no invoice.
+func createReconnectionInvoice(tx *gorm.DB, customer *models.Customer, change CustomerStatusChange) (*models.Invoice, error) {
+	fee := 0.0
+	if change.ReconnectionFee != nil {
+		fee = *change.ReconnectionFee
+	} else {
+		var subType models.SubscriptionType
+		if err := tx.Where("id = ? AND tenant_id = ?", customer.SubscriptionID, customer.TenantID).First(&subType).Error; err != nil {
+			return nil, err
+		}
+		fee = subType.ReconnectionFee
+	}
+	if fee <= 0 {
+		return nil, nil
+	}
+
+	invoice := models.Invoice{
+		CustomerID:  customer.ID,
+		TenantID:    customer.TenantID,
+		Type:        "reconnection",
+		UsageMonth:  change.EffectiveDate.Format("2006-01"),
+		TotalAmount: fee,
+		IsPaid:      false,
+		TotalPaid:   0,
+	}
+	if err := tx.Omit("Customer").Create(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// AbonemenApplies reports whether the customer owes the abonemen for a usage
+// month: unless it was disconnected or closed for the whole month. A change
+// during the month does not prorate the fee.
+func AbonemenApplies(tx *gorm.DB, customer *models.Customer, usageMonth string) (bool, error) {
+	start, err := time.Parse("2006-01", usageMonth)
+	if err != nil {
+		return false, ErrInvalidUsageMonth
+	}
+	end := start.AddDate(0, 1, 0)
+
+	var history []models.CustomerStatusHistory
+	if err := tx.Where("tenant_id = ? AND customer_id = ?", customer.TenantID, customer.ID).
+		Order("effective_date ASC, created_at ASC").
+		Find(&history).Error; err != nil {
+		return false, err
+	}
+
+	status := customer.Status
+	if len(history) > 0 {
+		status = history[0].FromStatus
+	}
+	for _, change := range history {
+		if !change.EffectiveDate.Before(end) {
+			break
+		}
+		// The status held before a change during the month counts too
+		if change.EffectiveDate.After(start) && abonemenStatus(status) {
+			return true, nil
+		}
+		status = change.ToStatus
+	}
+	return abonemenStatus(status), nil
+}
+
+func abonemenStatus(status string) bool {
+	return status != models.CustomerStatusDisconnected && status != models.CustomerStatusClosed
+}
+
+// BuildUsageInvoice prices a customer's readings of a month into an unsaved
+// invoice of the given type, adding the subscription's abonemen and
+// maintenance fee when they apply (see AbonemenApplies). Returns nil when
+// there is nothing to bill.
+func BuildUsageInvoice(tx *gorm.DB, customer *models.Customer, usageMonth string, usages []models.WaterUsage, invoiceType string) (*models.Invoice, error) {
+	var subType models.SubscriptionType
+	if err := tx.Where("id = ? AND tenant_id = ?", customer.SubscriptionID, customer.TenantID).First(&subType).Error; err != nil {
+		return nil, err
+	}
+
+	usageM3 := 0.0
+	amountCalculated := 0.0
+	for _, usage := range usages {
+		// Business rule validations
+		if usage.UsageM3 < 0 || usage.AmountCalculated < 0 {
+			return nil, ErrInvoiceUsageInvalid
+		}
+		usageM3 += usage.UsageM3
+		amountCalculated += usage.AmountCalculated
+	}
+
+	chargeFees, err := AbonemenApplies(tx, customer, usageMonth)
+	if err != nil {
+		return nil, err
+	}
+	abonemen := 0.0
+	fees := 0.0
+	if chargeFees {
+		abonemen = subType.MonthlyFee
+		fees = subType.MonthlyFee + subType.MaintenanceFee
+	}
+
+	total := amountCalculated + fees
+	if total <= 0 {
+		return nil, nil
+	}
+	// Validate calculated total is reasonable
+	if total > 999999 {
+		return nil, ErrInvoiceTotalOutOfRange
+	}
+
+	// Calculate price per m3 safely
+	pricePerM3 := 0.0
+	if usageM3 > 0 {
+		pricePerM3 = amountCalculated / usageM3
+	}
+
+	// Readings still under anomaly review hold the invoice
+	holdReason, err := InvoiceHoldReason(tx, customer.TenantID, customer.ID, usageMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Invoice{
+		CustomerID:  customer.ID,
+		UsageMonth:  usageMonth,
+		UsageM3:     usageM3,
+		Abonemen:    abonemen,
+		PricePerM3:  pricePerM3,
+		TotalAmount: total,
+		TotalPaid:   0,
+		IsPaid:      false,
+		TenantID:    customer.TenantID,
+		Type:        invoiceType,
+		OnHold:      holdReason != "",
+		HoldReason:  holdReason,
+	}, nil
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
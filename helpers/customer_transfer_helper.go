@@ -0,0 +1,442 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCustomerNotInService   = errors.New("Only active or suspended customers can be transferred or relocated")
+	ErrCustomerNoMeter        = errors.New("Customer has no active meter")
+	ErrTransferReadingMissing = errors.New("A final reading is required for every active meter")
+	ErrBalanceHandlingInvalid = errors.New("Unknown balance handling")
+)
+
+// Outstanding balance handling on an ownership transfer
+const (
+	// BalanceSettle leaves the unpaid invoices on the closed account, to be
+	// settled by the previous owner
+	BalanceSettle = "settle"
+	// BalanceCarryOver bills the outstanding balance to the new owner as a
+	// single transfer invoice
+	BalanceCarryOver = "carry_over"
+)
+
+// FinalMeterReading is the last register of a meter on a closing account
+type FinalMeterReading struct {
+	MeterID *uuid.UUID // optional when the customer has one active meter
+	Reading float64
+	PhotoID *uuid.UUID
+}
+
+// CustomerTransfer describes the new owner of a connection
+type CustomerTransfer struct {
+	Date           time.Time // zero means today
+	FinalReadings  []FinalMeterReading
+	ArchivedNumber string // account number kept by the closed account, defaults to the old one with the date appended
+	Name           string
+	Email          string
+	Phone          string
+	Password       string     // hashed portal password, empty until set
+	SubscriptionID *uuid.UUID // defaults to the old account's
+	Balance        string     // BalanceSettle or BalanceCarryOver, empty means settle
+	Reason         string
+	Notes          string
+	TransferredBy  uuid.UUID
+}
+
+// CustomerTransferResult is what an ownership transfer recorded
+type CustomerTransferResult struct {
+	OldCustomer   *models.Customer
+	NewCustomer   *models.Customer
+	Meters        []models.Meter
+	FinalReadings []models.WaterUsage
+	FinalBills    []models.Invoice
+	CarriedOver   *models.Invoice // transfer invoice of the new owner, if any
+	Outstanding   float64         // unpaid balance left on the closed account
+}
+
+// TransferCustomer hands a connection over to a new owner inside tx. The old
+// account gets a final reading for each active meter and is closed with its
+// final bill; a new active account takes over the meters, the service area,
+// the location and the place on the reading route. The old account keeps its
+// history under an archived account number and the new one takes over the
+// original number. The customer row should be locked by the caller.
+func TransferCustomer(tx *gorm.DB, customer *models.Customer, transfer CustomerTransfer) (*CustomerTransferResult, error) {
+	if customer.Status != models.CustomerStatusActive && customer.Status != models.CustomerStatusSuspended {
+		return nil, ErrCustomerNotInService
+	}
+	if transfer.Balance == "" {
+		transfer.Balance = BalanceSettle
+	}
+	if transfer.Balance != BalanceSettle && transfer.Balance != BalanceCarryOver {
+		return nil, ErrBalanceHandlingInvalid
+	}
+	if transfer.Email != "" {
+		var existing int64
+		if err := tx.Model(&models.Customer{}).
+			Where("tenant_id = ? AND email = ?", customer.TenantID, transfer.Email).
+			Count(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing > 0 {
+			return nil, ErrCustomerEmailTaken
+		}
+	}
+
+	today := truncateToDay(time.Now())
+	date := today
+	readAt := time.Now()
+	if !transfer.Date.IsZero() {
+		date = truncateToDay(transfer.Date)
+		if date.After(today) {
+			return nil, ErrStatusDateInFuture
+		}
+		if date.Before(today) {
+			readAt = date
+		}
+	}
+
+	var meters []models.Meter
+	if err := tx.Where("tenant_id = ? AND customer_id = ? AND status = ?", customer.TenantID, customer.ID, models.MeterStatusActive).
+		Find(&meters).Error; err != nil {
+		return nil, err
+	}
+	if len(meters) == 0 {
+		return nil, ErrCustomerNoMeter
+	}
+	readings, err := matchFinalReadings(meters, transfer.FinalReadings)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CustomerTransferResult{OldCustomer: customer}
+	months := map[string]bool{}
+	for i := range meters {
+		meter := &meters[i]
+		reading := readings[meter.ID]
+		usageMonth, err := finalReadingMonth(tx, meter, date)
+		if err != nil {
+			return nil, err
+		}
+		usage, err := RecordWaterUsage(tx, WaterUsageInput{
+			TenantID:   customer.TenantID,
+			CustomerID: customer.ID,
+			MeterID:    &meter.ID,
+			UsageMonth: usageMonth,
+			MeterEnd:   reading.Reading,
+			Notes:      "Final reading at ownership transfer",
+			RecordedBy: &transfer.TransferredBy,
+			ReadAt:     readAt,
+			PhotoID:    reading.PhotoID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.FinalReadings = append(result.FinalReadings, *usage)
+		months[usageMonth] = true
+	}
+
+	routeID, routeSequence := customer.ReadingRouteID, customer.RouteSequence
+	oldNumber := customer.MeterNumber
+
+	reason := transfer.Reason
+	if reason == "" {
+		reason = "Ownership transferred to " + transfer.Name
+	}
+	_, bill, err := ChangeCustomerStatus(tx, customer, CustomerStatusChange{
+		Status:        models.CustomerStatusClosed,
+		Reason:        reason,
+		EffectiveDate: date,
+		ChangedBy:     &transfer.TransferredBy,
+		Notes:         transfer.Notes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if bill != nil {
+		result.FinalBills = append(result.FinalBills, *bill)
+	}
+	// Readings moved to the next month because the closing month was
+	// already read or invoiced get their own final bill
+	delete(months, date.Format("2006-01"))
+	for usageMonth := range months {
+		bill, err := createFinalBill(tx, customer, usageMonth)
+		if err != nil {
+			return nil, err
+		}
+		if bill != nil {
+			result.FinalBills = append(result.FinalBills, *bill)
+		}
+	}
+
+	archivedNumber := transfer.ArchivedNumber
+	if archivedNumber == "" {
+		archivedNumber = fmt.Sprintf("%s-%s", oldNumber, date.Format("20060102"))
+	}
+	var existing int64
+	if err := tx.Model(&models.Customer{}).Where("meter_number = ?", archivedNumber).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrCustomerNumberTaken
+	}
+	customer.MeterNumber = archivedNumber
+	if err := tx.Model(&models.Customer{}).Where("id = ?", customer.ID).
+		Update("meter_number", customer.MeterNumber).Error; err != nil {
+		return nil, err
+	}
+
+	subscriptionID := customer.SubscriptionID
+	if transfer.SubscriptionID != nil {
+		subscriptionID = *transfer.SubscriptionID
+	}
+	newCustomer := models.Customer{
+		MeterNumber:    oldNumber,
+		Name:           transfer.Name,
+		Email:          transfer.Email,
+		Password:       transfer.Password,
+		Address:        customer.Address,
+		Phone:          transfer.Phone,
+		SubscriptionID: subscriptionID,
+		IsActive:       false,
+		Status:         models.CustomerStatusApplicant,
+		TenantID:       customer.TenantID,
+		ServiceAreaID:  customer.ServiceAreaID,
+		ReadingRouteID: routeID,
+		RouteSequence:  routeSequence,
+		Latitude:       customer.Latitude,
+		Longitude:      customer.Longitude,
+	}
+	if err := tx.Create(&newCustomer).Error; err != nil {
+		return nil, err
+	}
+	if _, err := recordCustomerStatus(tx, &newCustomer, CustomerStatusChange{
+		Status:        models.CustomerStatusActive,
+		Reason:        "Ownership transferred from " + customer.Name,
+		EffectiveDate: date,
+		ChangedBy:     &transfer.TransferredBy,
+		Notes:         transfer.Notes,
+	}, nil); err != nil {
+		return nil, err
+	}
+	if routeID != nil {
+		if err := recountReadingRoute(tx, customer.TenantID, *routeID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The meters stay in the ground; the new account reads on from the
+	// final register of the old one
+	for i := range meters {
+		meter := &meters[i]
+		finalReading := readings[meter.ID].Reading
+		meter.CustomerID = newCustomer.ID
+		meter.Status = models.MeterStatusActive
+		meter.InitialReading = finalReading
+		if err := tx.Model(meter).Updates(map[string]interface{}{
+			"customer_id":     meter.CustomerID,
+			"status":          meter.Status,
+			"initial_reading": meter.InitialReading,
+		}).Error; err != nil {
+			return nil, err
+		}
+		if err := RecordMeterHistory(tx, meter, models.MeterActionTransfer,
+			fmt.Sprintf("%s (%s)", customer.Name, customer.MeterNumber),
+			fmt.Sprintf("%s (%s, awal %.2f)", newCustomer.Name, newCustomer.MeterNumber, finalReading),
+			transfer.TransferredBy, reason); err != nil {
+			return nil, err
+		}
+	}
+	result.NewCustomer = &newCustomer
+	result.Meters = meters
+
+	outstanding, err := GetCustomerArrears(tx, customer.TenantID, customer.ID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	result.Outstanding = outstanding.Amount
+	if transfer.Balance == BalanceCarryOver {
+		result.CarriedOver, err = carryOverBalance(tx, customer, &newCustomer)
+		if err != nil {
+			return nil, err
+		}
+		if result.CarriedOver != nil {
+			result.Outstanding = 0
+		}
+	}
+	return result, nil
+}
+
+// matchFinalReadings pairs the final readings with the active meters; every
+// meter needs one
+func matchFinalReadings(meters []models.Meter, finalReadings []FinalMeterReading) (map[uuid.UUID]FinalMeterReading, error) {
+	readings := make(map[uuid.UUID]FinalMeterReading, len(meters))
+	for _, reading := range finalReadings {
+		if reading.MeterID == nil {
+			if len(meters) > 1 {
+				return nil, ErrUsageMeterRequired
+			}
+			readings[meters[0].ID] = reading
+			continue
+		}
+		found := false
+		for i := range meters {
+			if meters[i].ID == *reading.MeterID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrUsageMeterNotFound
+		}
+		readings[*reading.MeterID] = reading
+	}
+	if len(readings) < len(meters) {
+		return nil, ErrTransferReadingMissing
+	}
+	return readings, nil
+}
+
+// carryOverBalance bills the unpaid usage invoices of the closed account to
+// the new owner as one transfer invoice and links them to it with
+// carried_to_id. They stay unpaid, since no money was received, but are no
+// longer collectable on the old account. Invoices on hold stay with the old
+// account until reviewed.
+func carryOverBalance(tx *gorm.DB, from, to *models.Customer) (*models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := tx.Where("tenant_id = ? AND customer_id = ? AND type IN ? AND is_paid = ? AND on_hold = ? AND carried_to_id IS NULL",
+		from.TenantID, from.ID, UsageInvoiceTypes, false, false).
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+
+	balance := 0.0
+	for _, invoice := range invoices {
+		balance += invoice.TotalAmount - invoice.TotalPaid
+	}
+	if balance <= 0 {
+		return nil, nil
+	}
+
+	transferInvoice := models.Invoice{
+		CustomerID:  to.ID,
+		TenantID:    to.TenantID,
+		Type:        "transfer",
+		UsageMonth:  "-", // tidak relevan untuk pengalihan saldo
+		TotalAmount: balance,
+		IsPaid:      false,
+		TotalPaid:   0,
+	}
+	if err := tx.Omit("Customer").Create(&transferInvoice).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range invoices {
+		if err := tx.Model(&invoices[i]).Update("carried_to_id", transferInvoice.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &transferInvoice, nil
+}
+
+// CustomerRelocation describes a customer moving to a new address
+type CustomerRelocation struct {
+	Date          time.Time // zero means today
+	Address       string
+	ServiceAreaID *uuid.UUID // nil keeps the current area
+	Latitude      *float64
+	Longitude     *float64
+
+	// MeterID is the meter at the old address, optional when the customer
+	// has one active meter
+	MeterID *uuid.UUID
+	// NewMeter, when set, replaces that meter with one fitted at the new
+	// address; otherwise the meter moves along with the customer
+	NewMeter *MeterReplacement
+
+	RelocatedBy uuid.UUID
+	Notes       string
+}
+
+// RelocateCustomer moves a customer to a new address inside tx, keeping the
+// account and its history. The meter is either replaced by one at the new
+// address, billing the old one's final reading, or moved along. The customer
+// leaves its reading route, which belongs to the old address. Returns the
+// meter now in service and the final usage of a replaced meter.
+func RelocateCustomer(tx *gorm.DB, customer *models.Customer, relocation CustomerRelocation) (*models.Meter, *models.WaterUsage, error) {
+	if customer.Status != models.CustomerStatusActive && customer.Status != models.CustomerStatusSuspended {
+		return nil, nil, ErrCustomerNotInService
+	}
+	if relocation.Date.IsZero() {
+		relocation.Date = time.Now()
+	}
+
+	meter, err := ResolveUsageMeter(tx, customer.TenantID, customer.ID, relocation.MeterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meter == nil && relocation.NewMeter != nil {
+		return nil, nil, ErrCustomerNoMeter
+	}
+
+	oldAddress := customer.Address
+	var finalUsage *models.WaterUsage
+	if relocation.NewMeter != nil {
+		replacement := *relocation.NewMeter
+		replacement.Date = relocation.Date
+		replacement.PerformedBy = relocation.RelocatedBy
+		if replacement.Reason == "" {
+			replacement.Reason = "Pindah alamat ke " + relocation.Address
+		}
+		if replacement.Latitude == nil && replacement.Longitude == nil {
+			replacement.Latitude = relocation.Latitude
+			replacement.Longitude = relocation.Longitude
+		}
+		oldNumber := meter.MeterNumber
+		meter, finalUsage, err = ReplaceMeter(tx, meter, replacement)
+		if err != nil {
+			return nil, nil, err
+		}
+		if customer.MeterNumber == oldNumber {
+			customer.MeterNumber = meter.MeterNumber
+		}
+	} else if meter != nil {
+		meter.Latitude = relocation.Latitude
+		meter.Longitude = relocation.Longitude
+		if err := tx.Model(meter).Updates(map[string]interface{}{
+			"latitude":  meter.Latitude,
+			"longitude": meter.Longitude,
+		}).Error; err != nil {
+			return nil, nil, err
+		}
+		if err := RecordMeterHistory(tx, meter, models.MeterActionUpdate, oldAddress, relocation.Address,
+			relocation.RelocatedBy, "Meter dipindahkan bersama pelanggan"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	customer.Address = relocation.Address
+	if relocation.ServiceAreaID != nil {
+		customer.ServiceAreaID = relocation.ServiceAreaID
+	}
+	customer.Latitude = relocation.Latitude
+	customer.Longitude = relocation.Longitude
+	if err := tx.Model(&models.Customer{}).Where("id = ?", customer.ID).Updates(map[string]interface{}{
+		"address":         customer.Address,
+		"service_area_id": customer.ServiceAreaID,
+		"latitude":        customer.Latitude,
+		"longitude":       customer.Longitude,
+	}).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := removeFromReadingRoute(tx, customer); err != nil {
+		return nil, nil, err
+	}
+	return meter, finalUsage, nil
+}
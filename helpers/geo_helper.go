@@ -0,0 +1,99 @@
+package helpers
+
+import (
+	"errors"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLocationIncomplete  = errors.New("latitude and longitude must be given together")
+	ErrLocationOutsideArea = errors.New("Location lies outside the customer's service area")
+	ErrServiceAreaNotFound = errors.New("Service area not found")
+)
+
+// AreaBoundary returns the parsed boundary of a service area, or nil when the
+// area has none
+func AreaBoundary(area *models.ServiceArea) ([]utils.Polygon, error) {
+	if area.Boundary == nil {
+		return nil, nil
+	}
+	polygons, _, err := utils.ParseBoundary([]byte(*area.Boundary))
+	return polygons, err
+}
+
+// ValidateLocation checks that a latitude and longitude are given together
+// and in range
+func ValidateLocation(lat, lng *float64) error {
+	if (lat == nil) != (lng == nil) {
+		return ErrLocationIncomplete
+	}
+	if lat == nil {
+		return nil
+	}
+	return utils.ValidateCoordinates(*lat, *lng)
+}
+
+// ValidateCustomerLocation checks a customer's location and service area.
+// The area must belong to the tenant, and when it has a boundary the
+// customer's point must lie inside it.
+func ValidateCustomerLocation(db *gorm.DB, tenantID uuid.UUID, areaID *uuid.UUID, lat, lng *float64) error {
+	if err := ValidateLocation(lat, lng); err != nil {
+		return err
+	}
+	if areaID == nil {
+		return nil
+	}
+
+	var area models.ServiceArea
+	if err := db.Where("id = ? AND tenant_id = ?", *areaID, tenantID).First(&area).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrServiceAreaNotFound
+		}
+		return err
+	}
+	if lat == nil {
+		return nil
+	}
+
+	polygons, err := AreaBoundary(&area)
+	if err != nil {
+		return err
+	}
+	if polygons != nil && !utils.BoundaryContains(polygons, *lat, *lng) {
+		return ErrLocationOutsideArea
+	}
+	return nil
+}
+
+// IsLocationError reports whether err is a validation error of a location
+func IsLocationError(err error) bool {
+	return errors.Is(err, ErrLocationIncomplete) || errors.Is(err, ErrLocationOutsideArea) ||
+		errors.Is(err, ErrServiceAreaNotFound) || errors.Is(err, utils.ErrCoordinatesOutOfRange)
+}
+
+// CustomersOutsideArea counts the area's customers whose location lies
+// outside its boundary
+func CustomersOutsideArea(db *gorm.DB, area *models.ServiceArea) (int, error) {
+	polygons, err := AreaBoundary(area)
+	if err != nil || polygons == nil {
+		return 0, err
+	}
+
+	var customers []models.Customer
+	if err := db.Select("id", "latitude", "longitude").
+		Where("tenant_id = ? AND service_area_id = ? AND latitude IS NOT NULL AND longitude IS NOT NULL", area.TenantID, area.ID).
+		Find(&customers).Error; err != nil {
+		return 0, err
+	}
+	outside := 0
+	for _, customer := range customers {
+		if !utils.BoundaryContains(polygons, *customer.Latitude, *customer.Longitude) {
+			outside++
+		}
+	}
+	return outside, nil
+}
@@ -1,9 +1,15 @@
 package helpers
 
 import (
+	"errors"
+	"fmt"
+	"math"
+
 	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateRegistrationInvoice membuat invoice untuk pendaftaran pelanggan baru
@@ -26,3 +32,71 @@ func CreateRegistrationInvoice(customerID, tenantID uuid.UUID, amount float64) (
 	}
 	return &invoice, nil
 }
+
+// RecalculateMonthlyInvoice reprices the customer's monthly or final invoice of
+// usageMonth from its current readings and re-evaluates its anomaly hold.
+// The fees billed on the invoice are kept. When the new total leaves more paid
+// than billed, the extra overpayment is credited to the customer and returned.
+// Returns nil when there is no open invoice for the month.
+func RecalculateMonthlyInvoice(tx *gorm.DB, tenantID, customerID uuid.UUID, usageMonth string, userID *uuid.UUID) (*models.Invoice, *models.CustomerCredit, error) {
+	var invoice models.Invoice
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND customer_id = ? AND usage_month = ? AND type IN ? AND carried_to_id IS NULL",
+			tenantID, customerID, usageMonth, UsageInvoiceTypes).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	overpaidBefore := math.Max(invoice.TotalPaid-invoice.TotalAmount, 0)
+
+	var usages []models.WaterUsage
+	if err := tx.Where("tenant_id = ? AND customer_id = ? AND usage_month = ?", tenantID, customerID, usageMonth).
+		Find(&usages).Error; err != nil {
+		return nil, nil, err
+	}
+	usageM3 := 0.0
+	amountCalculated := 0.0
+	for _, usage := range usages {
+		usageM3 += usage.UsageM3
+		amountCalculated += usage.AmountCalculated
+	}
+
+	fees := invoice.TotalAmount - invoice.UsageM3*invoice.PricePerM3
+	invoice.UsageM3 = usageM3
+	invoice.PricePerM3 = 0
+	if usageM3 > 0 {
+		invoice.PricePerM3 = amountCalculated / usageM3
+	}
+	invoice.TotalAmount = amountCalculated + fees
+
+	holdReason, err := InvoiceHoldReason(tx, tenantID, customerID, usageMonth)
+	if err != nil {
+		return nil, nil, err
+	}
+	invoice.OnHold = holdReason != ""
+	invoice.HoldReason = holdReason
+
+	if err := RecalculateInvoicePayments(tx, &invoice); err != nil {
+		return nil, nil, err
+	}
+
+	overpaid := math.Round((invoice.TotalPaid-invoice.TotalAmount-overpaidBefore)*100) / 100
+	if overpaid <= 0 {
+		return &invoice, nil, nil
+	}
+	credit := models.CustomerCredit{
+		TenantID:   tenantID,
+		CustomerID: customerID,
+		Amount:     overpaid,
+		Type:       models.CreditTypeAdjustment,
+		Reference:  "Invoice " + usageMonth,
+		Notes:      fmt.Sprintf("Kelebihan bayar tagihan %s setelah koreksi pemakaian", usageMonth),
+		CreatedBy:  userID,
+	}
+	if err := tx.Create(&credit).Error; err != nil {
+		return nil, nil, err
+	}
+	return &invoice, &credit, nil
+}
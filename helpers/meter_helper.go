@@ -0,0 +1,217 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecordMeterHistory appends an entry to the meter's history log
+func RecordMeterHistory(tx *gorm.DB, meter *models.Meter, action, oldValue, newValue string, performedBy uuid.UUID, notes string) error {
+	history := models.MeterHistory{
+		TenantID:    meter.TenantID,
+		MeterID:     meter.ID,
+		CustomerID:  meter.CustomerID,
+		Action:      action,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		PerformedBy: performedBy,
+		Notes:       notes,
+	}
+	return tx.Create(&history).Error
+}
+
+// MeterReplacement describes the meter fitted in place of an active one
+type MeterReplacement struct {
+	Date           time.Time // zero means today
+	FinalReading   float64   // last register of the old meter
+	FinalPhotoID   *uuid.UUID
+	MeterNumber    string
+	Brand          string // defaults to the old meter's
+	Model          string // defaults to the old meter's
+	InitialReading float64
+	RolloverAt     float64
+	Latitude       *float64 // where the new meter is installed
+	Longitude      *float64
+	Reason         string
+	Notes          string
+	PerformedBy    uuid.UUID
+}
+
+// ReplaceMeter swaps an active meter for a new one inside tx. The old meter's
+// final reading is recorded as its last water usage, billed together with the
+// new meter's first reading, and the customer's meter number follows the new
+// meter. Returns the new meter and the final usage.
+func ReplaceMeter(tx *gorm.DB, oldMeter *models.Meter, replacement MeterReplacement) (*models.Meter, *models.WaterUsage, error) {
+	var existing int64
+	if err := tx.Model(&models.Meter{}).
+		Where("tenant_id = ? AND meter_number = ?", oldMeter.TenantID, replacement.MeterNumber).
+		Count(&existing).Error; err != nil {
+		return nil, nil, err
+	}
+	if existing > 0 {
+		return nil, nil, ErrMeterNumberTaken
+	}
+
+	date := replacement.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	brand := replacement.Brand
+	if brand == "" {
+		brand = oldMeter.Brand
+	}
+	model := replacement.Model
+	if model == "" {
+		model = oldMeter.Model
+	}
+
+	usageMonth, err := finalReadingMonth(tx, oldMeter, date)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Final reading of the old meter, billed together with the new meter's reading
+	finalUsage, err := RecordWaterUsage(tx, WaterUsageInput{
+		TenantID:   oldMeter.TenantID,
+		CustomerID: oldMeter.CustomerID,
+		MeterID:    &oldMeter.ID,
+		UsageMonth: usageMonth,
+		MeterEnd:   replacement.FinalReading,
+		Notes:      fmt.Sprintf("Pembacaan akhir penggantian meter %s: %s", oldMeter.MeterNumber, replacement.Reason),
+		RecordedBy: &replacement.PerformedBy,
+		ReadAt:     date,
+		PhotoID:    replacement.FinalPhotoID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newMeter := models.Meter{
+		TenantID:       oldMeter.TenantID,
+		CustomerID:     oldMeter.CustomerID,
+		MeterNumber:    replacement.MeterNumber,
+		Brand:          brand,
+		Model:          model,
+		InstallDate:    date,
+		InitialReading: replacement.InitialReading,
+		RolloverAt:     replacement.RolloverAt,
+		Status:         models.MeterStatusActive,
+		ReplacesID:     &oldMeter.ID,
+		Notes:          replacement.Notes,
+		Latitude:       replacement.Latitude,
+		Longitude:      replacement.Longitude,
+	}
+	if err := tx.Omit("Customer").Create(&newMeter).Error; err != nil {
+		return nil, nil, err
+	}
+
+	finalReading := replacement.FinalReading
+	oldMeter.Status = models.MeterStatusReplaced
+	oldMeter.FinalReading = &finalReading
+	oldMeter.RemovedAt = &date
+	oldMeter.ReplacedByID = &newMeter.ID
+	if err := tx.Omit("Customer").Save(oldMeter).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := RecordMeterHistory(tx, oldMeter, models.MeterActionReplace,
+		fmt.Sprintf("%s (akhir %.2f)", oldMeter.MeterNumber, replacement.FinalReading),
+		fmt.Sprintf("%s (awal %.2f)", newMeter.MeterNumber, newMeter.InitialReading), replacement.PerformedBy, replacement.Reason); err != nil {
+		return nil, nil, err
+	}
+	if err := RecordMeterHistory(tx, &newMeter, models.MeterActionInstall, oldMeter.MeterNumber,
+		fmt.Sprintf("%s (awal %.2f)", newMeter.MeterNumber, newMeter.InitialReading), replacement.PerformedBy, replacement.Notes); err != nil {
+		return nil, nil, err
+	}
+
+	// Keep the customer's primary meter number in sync
+	if err := tx.Model(&models.Customer{}).
+		Where("id = ? AND tenant_id = ? AND meter_number = ?", oldMeter.CustomerID, oldMeter.TenantID, oldMeter.MeterNumber).
+		Update("meter_number", newMeter.MeterNumber).Error; err != nil {
+		return nil, nil, err
+	}
+	return &newMeter, finalUsage, nil
+}
+
+// finalReadingMonth returns the month a meter's final reading is billed in:
+// the month of the date, or the month after when that month is already read
+// or invoiced for the customer
+func finalReadingMonth(tx *gorm.DB, meter *models.Meter, date time.Time) (string, error) {
+	month := date.Format("2006-01")
+
+	var readings int64
+	if err := tx.Model(&models.WaterUsage{}).
+		Where("meter_id = ? AND tenant_id = ? AND usage_month = ?", meter.ID, meter.TenantID, month).
+		Count(&readings).Error; err != nil {
+		return "", err
+	}
+	var invoices int64
+	if err := tx.Model(&models.Invoice{}).
+		Where("customer_id = ? AND tenant_id = ? AND usage_month = ? AND type IN ?", meter.CustomerID, meter.TenantID, month, UsageInvoiceTypes).
+		Count(&invoices).Error; err != nil {
+		return "", err
+	}
+
+	if readings > 0 || invoices > 0 {
+		return date.AddDate(0, 0, 1-date.Day()).AddDate(0, 1, 0).Format("2006-01"), nil
+	}
+	return month, nil
+}
+
+var (
+	ErrMeterNumberTaken     = errors.New("Meter number already exists")
+	ErrMeterReplacedFinal   = errors.New("Replaced meters cannot change status")
+	ErrMeterCustomerClosed  = errors.New("Meters of a closed customer cannot be activated")
+	ErrMeterSuccessorActive = errors.New("Meter was replaced and its successor is active")
+)
+
+// CheckMeterStatusChange reports whether a meter may move to the given
+// status outside a replacement, which alone marks meters replaced. Replaced
+// meters keep their status, and a meter is only activated for a customer
+// still in service and when no active meter has taken its place. Other
+// active meters of the customer are separate connections and do not count.
+func CheckMeterStatusChange(tx *gorm.DB, meter *models.Meter, status string) error {
+	if status == meter.Status {
+		return nil
+	}
+	if meter.Status == models.MeterStatusReplaced {
+		return ErrMeterReplacedFinal
+	}
+	if status != models.MeterStatusActive {
+		return nil
+	}
+
+	var customer models.Customer
+	if err := tx.Select("status").Where("id = ? AND tenant_id = ?", meter.CustomerID, meter.TenantID).
+		First(&customer).Error; err != nil {
+		return err
+	}
+	if customer.Status == models.CustomerStatusClosed {
+		return ErrMeterCustomerClosed
+	}
+
+	if meter.ReplacedByID == nil {
+		return nil
+	}
+	var successors int64
+	if err := tx.Model(&models.Meter{}).
+		Where("id = ? AND tenant_id = ? AND status = ?", *meter.ReplacedByID, meter.TenantID, models.MeterStatusActive).
+		Count(&successors).Error; err != nil {
+		return err
+	}
+	if successors > 0 {
+		return ErrMeterSuccessorActive
+	}
+	return nil
+}
+
+// IsMeterStatusError reports whether err is a rule violation from CheckMeterStatusChange
+func IsMeterStatusError(err error) bool {
+	return errors.Is(err, ErrMeterReplacedFinal) || errors.Is(err, ErrMeterCustomerClosed) ||
+		errors.Is(err, ErrMeterSuccessorActive)
+}
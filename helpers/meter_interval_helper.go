@@ -0,0 +1,281 @@
+package helpers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxIntervalBatch is the largest number of readings accepted in one batch
+const MaxIntervalBatch = 1000
+
+// maxDeviceClockSkew is how far ahead of the server a device clock may run
+const maxDeviceClockSkew = 5 * time.Minute
+
+var (
+	ErrInvalidDeviceKey    = errors.New("Invalid or inactive device key")
+	ErrDeviceMeterInactive = errors.New("Meter of this device is not active")
+	ErrIntervalBatchSize   = fmt.Errorf("A batch holds at most %d readings", MaxIntervalBatch)
+)
+
+// IntervalReading is one register value reported by a device, or the pulse
+// count of a pulse counter that is converted with the device's pulse settings
+type IntervalReading struct {
+	RecordedAt time.Time
+	Reading    *float64
+	Pulses     *int64
+}
+
+// IngestResult summarises an ingested batch of interval readings
+type IngestResult struct {
+	Received   int      `json:"received"`
+	Stored     int      `json:"stored"`
+	Duplicates int      `json:"duplicates"`
+	Rejected   int      `json:"rejected"`
+	RolledUp   []string `json:"rolled_up_months"` // usage months created or updated
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// AuthenticateDevice returns the active device a key belongs to
+func AuthenticateDevice(db *gorm.DB, key string) (*models.MeterDevice, error) {
+	prefix, ok := utils.DeviceKeyPrefix(key)
+	if !ok {
+		return nil, ErrInvalidDeviceKey
+	}
+
+	var device models.MeterDevice
+	if err := db.Where("key_prefix = ? AND is_active = ?", prefix, true).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidDeviceKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(device.KeyHash), []byte(utils.HashDeviceKey(key))) != 1 {
+		return nil, ErrInvalidDeviceKey
+	}
+	return &device, nil
+}
+
+// IngestIntervalReadings stores the new readings of a device batch and rolls
+// completed months up into the meter's monthly WaterUsage. Readings already
+// stored for the same meter and device timestamp are skipped, so devices can
+// safely resend a batch.
+func IngestIntervalReadings(db *gorm.DB, device *models.MeterDevice, readings []IntervalReading) (*IngestResult, error) {
+	if len(readings) > MaxIntervalBatch {
+		return nil, ErrIntervalBatchSize
+	}
+	result := &IngestResult{Received: len(readings), RolledUp: []string{}}
+
+	var meter models.Meter
+	if err := db.Where("id = ? AND tenant_id = ?", device.MeterID, device.TenantID).First(&meter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceMeterInactive
+		}
+		return nil, err
+	}
+	if meter.Status != models.MeterStatusActive {
+		return nil, ErrDeviceMeterInactive
+	}
+
+	now := time.Now()
+	seen := make(map[int64]bool, len(readings))
+	var intervals []models.MeterInterval
+	for i, reading := range readings {
+		// Stored with second precision, so compare at that precision
+		recordedAt := reading.RecordedAt.Truncate(time.Second)
+		value, valueErr := device.RegisterValue(reading.Reading, reading.Pulses)
+		switch {
+		case recordedAt.IsZero():
+			result.Errors = append(result.Errors, fmt.Sprintf("Reading %d: missing timestamp", i+1))
+		case valueErr != nil:
+			result.Errors = append(result.Errors, fmt.Sprintf("Reading %d: %s", i+1, valueErr.Error()))
+		case value < 0 || value > MaxMeterReading:
+			result.Errors = append(result.Errors, fmt.Sprintf("Reading %d: value %.3f out of range", i+1, value))
+		case recordedAt.After(now.Add(maxDeviceClockSkew)):
+			result.Errors = append(result.Errors, fmt.Sprintf("Reading %d: timestamp %s is in the future", i+1, recordedAt.Format(time.RFC3339)))
+		case recordedAt.Before(meter.InstallDate):
+			result.Errors = append(result.Errors, fmt.Sprintf("Reading %d: timestamp %s is before the meter was installed", i+1, recordedAt.Format(time.RFC3339)))
+		case seen[recordedAt.Unix()]:
+			result.Duplicates++
+			continue
+		default:
+			seen[recordedAt.Unix()] = true
+			intervals = append(intervals, models.MeterInterval{
+				TenantID:   device.TenantID,
+				MeterID:    meter.ID,
+				DeviceID:   device.ID,
+				RecordedAt: recordedAt,
+				Reading:    value,
+			})
+			continue
+		}
+		result.Rejected++
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		lastSeen := now
+		if err := tx.Model(&models.MeterDevice{}).Where("id = ?", device.ID).Update("last_seen_at", &lastSeen).Error; err != nil {
+			return err
+		}
+		device.LastSeenAt = &lastSeen
+		if len(intervals) == 0 {
+			return nil
+		}
+
+		sort.Slice(intervals, func(i, j int) bool {
+			return intervals[i].RecordedAt.Before(intervals[j].RecordedAt)
+		})
+		first, last := intervals[0].RecordedAt, intervals[len(intervals)-1].RecordedAt
+
+		var stored []time.Time
+		if err := tx.Model(&models.MeterInterval{}).
+			Where("meter_id = ? AND recorded_at BETWEEN ? AND ?", meter.ID, first, last).
+			Pluck("recorded_at", &stored).Error; err != nil {
+			return err
+		}
+		storedAt := make(map[int64]bool, len(stored))
+		for _, at := range stored {
+			storedAt[at.Unix()] = true
+		}
+		fresh := intervals[:0]
+		for _, interval := range intervals {
+			if storedAt[interval.RecordedAt.Unix()] {
+				result.Duplicates++
+				continue
+			}
+			fresh = append(fresh, interval)
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+
+		// A concurrent resend of the same batch is caught by the unique index
+		if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).
+			CreateInBatches(&fresh, 200).Error; err != nil {
+			return err
+		}
+		result.Stored = len(fresh)
+
+		latest := fresh[len(fresh)-1]
+		if device.LastReadingAt == nil || latest.RecordedAt.After(*device.LastReadingAt) {
+			device.LastReadingAt = &latest.RecordedAt
+			device.LastReading = &latest.Reading
+			if err := tx.Model(&models.MeterDevice{}).Where("id = ?", device.ID).Updates(map[string]interface{}{
+				"last_reading_at": device.LastReadingAt,
+				"last_reading":    device.LastReading,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// A new interval can complete its own month or, as the first one
+		// after a month end, the month before
+		months := map[string]bool{}
+		for _, interval := range fresh {
+			local := interval.RecordedAt.In(time.Local)
+			months[local.Format("2006-01")] = true
+			months[local.AddDate(0, 0, -local.Day()).Format("2006-01")] = true
+		}
+		ordered := make([]string, 0, len(months))
+		for month := range months {
+			ordered = append(ordered, month)
+		}
+		sort.Strings(ordered)
+
+		for _, month := range ordered {
+			rolled, err := RollupIntervalMonth(tx, &meter, month)
+			if err != nil {
+				if !IsWaterUsageRuleError(err) {
+					return err
+				}
+				result.Errors = append(result.Errors, fmt.Sprintf("Month %s not rolled up: %s", month, err.Error()))
+				continue
+			}
+			if rolled {
+				result.RolledUp = append(result.RolledUp, month)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RollupIntervalMonth records the last interval of a completed month as the
+// meter's automatic reading for that month, or updates it when late intervals
+// arrive. A month is complete once the meter has an interval after its end.
+// Readings taken by hand and months already invoiced are left as they are.
+func RollupIntervalMonth(tx *gorm.DB, meter *models.Meter, month string) (bool, error) {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return false, ErrInvalidUsageMonth
+	}
+	end := start.AddDate(0, 1, 0)
+
+	var after int64
+	if err := tx.Model(&models.MeterInterval{}).
+		Where("meter_id = ? AND recorded_at >= ?", meter.ID, end).
+		Count(&after).Error; err != nil {
+		return false, err
+	}
+	if after == 0 {
+		return false, nil
+	}
+
+	var last models.MeterInterval
+	err = tx.Where("meter_id = ? AND recorded_at >= ? AND recorded_at < ?", meter.ID, start, end).
+		Order("recorded_at DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var usage models.WaterUsage
+	err = tx.Where("tenant_id = ? AND customer_id = ? AND meter_id = ? AND usage_month = ?",
+		meter.TenantID, meter.CustomerID, meter.ID, month).First(&usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_, err := RecordWaterUsage(tx, WaterUsageInput{
+			TenantID:      meter.TenantID,
+			CustomerID:    meter.CustomerID,
+			MeterID:       &meter.ID,
+			UsageMonth:    month,
+			MeterEnd:      last.Reading,
+			Notes:         "Smart meter",
+			ReadingMethod: models.ReadingMethodAutomatic,
+			ReadAt:        last.RecordedAt,
+		})
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if usage.ReadingMethod != models.ReadingMethodAutomatic || usage.MeterEnd == last.Reading {
+		return false, nil
+	}
+	var invoiced int64
+	if err := tx.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND customer_id = ? AND usage_month = ? AND type IN ?", meter.TenantID, meter.CustomerID, month, UsageInvoiceTypes).
+		Count(&invoiced).Error; err != nil {
+		return false, err
+	}
+	if invoiced > 0 {
+		return false, nil
+	}
+
+	if err := ReviseWaterUsage(tx, &usage, last.Reading, models.ReadingMethodAutomatic); err != nil {
+		return false, err
+	}
+	return true, tx.Model(&models.WaterUsage{}).Where("id = ?", usage.ID).Update("read_at", last.RecordedAt).Error
+}
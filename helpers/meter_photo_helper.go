@@ -0,0 +1,193 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxPhotoReadingGap is how far the EXIF capture time of a verified photo
+// may be from the time the reading was taken
+const MaxPhotoReadingGap = 48 * time.Hour
+
+// meterPhotoTypes are the accepted photo formats and their file extensions
+var meterPhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+var (
+	ErrPhotoTooLarge      = fmt.Errorf("Ukuran foto melebihi batas %d MB", utils.MaxImageSize/(1024*1024))
+	ErrPhotoInvalidType   = errors.New("Foto harus berformat JPEG atau PNG")
+	ErrPhotoTooManyPixels = fmt.Errorf("Resolusi foto melebihi batas %d megapiksel", utils.MaxImagePixels/1000000)
+	ErrUsagePhotoNotFound = errors.New("Foto meter tidak ditemukan atau belum diunggah")
+	ErrUsagePhotoInUse    = errors.New("Foto meter sudah dipakai untuk pencatatan lain")
+	ErrUsagePhotoRequired = errors.New("Foto meter wajib dilampirkan untuk pencatatan manual")
+	ErrUsageHasPhoto      = errors.New("Pencatatan sudah memiliki foto meter")
+)
+
+// StoreMeterPhoto saves the uploaded image of photo, reads its EXIF capture
+// time and GPS position and writes a thumbnail next to it. A photo without
+// an ID is created; otherwise it is a pre-signed upload being completed.
+func StoreMeterPhoto(db *gorm.DB, photo *models.MeterPhoto, src io.Reader) (err error) {
+	content, err := io.ReadAll(io.LimitReader(src, utils.MaxImageSize+1))
+	if err != nil {
+		return err
+	}
+	if len(content) > utils.MaxImageSize {
+		return ErrPhotoTooLarge
+	}
+
+	// The content decides the type, not the client's header
+	contentType := http.DetectContentType(content)
+	ext, ok := meterPhotoTypes[contentType]
+	if !ok {
+		return ErrPhotoInvalidType
+	}
+
+	// The file is named after the photo, so new photos get their ID first
+	if photo.ID == uuid.Nil {
+		photo.Status = models.MeterPhotoAwaitingUpload
+		if err := db.Omit("Tenant", "WaterUsage", "Uploader").Create(photo).Error; err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				db.Delete(&models.MeterPhoto{}, "id = ?", photo.ID)
+			}
+		}()
+	}
+
+	dir := filepath.Join("uploads", "tenants", photo.TenantID.String(), "meter-photos", time.Now().Format("2006-01"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	filePath := filepath.Join(dir, photo.ID.String()+ext)
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to save file: %v", err)
+	}
+
+	thumbnailPath := filepath.Join(dir, photo.ID.String()+"_thumb.jpg")
+	if err := utils.CreateThumbnail(filePath, thumbnailPath, utils.ThumbnailSize); err != nil {
+		utils.DeleteFile(filePath)
+		if errors.Is(err, utils.ErrImageTooManyPixels) {
+			return ErrPhotoTooManyPixels
+		}
+		return ErrPhotoInvalidType
+	}
+
+	exif := &utils.ExifData{}
+	if contentType == "image/jpeg" {
+		if data, err := utils.ReadExif(bytes.NewReader(content)); err == nil {
+			exif = data
+		}
+	}
+
+	photo.Status = models.MeterPhotoUploaded
+	photo.FilePath = filePath
+	photo.ThumbnailPath = thumbnailPath
+	photo.ContentType = contentType
+	photo.Size = int64(len(content))
+	photo.TakenAt = exif.TakenAt
+	photo.Latitude = exif.Latitude
+	photo.Longitude = exif.Longitude
+	photo.UploadExpiresAt = nil
+	if err := db.Omit("Tenant", "WaterUsage", "Uploader").Save(photo).Error; err != nil {
+		utils.DeleteFile(filePath)
+		utils.DeleteFile(thumbnailPath)
+		return err
+	}
+	return nil
+}
+
+// findReadingPhoto returns an uploaded photo of the tenant that is not yet
+// attached to a reading
+func findReadingPhoto(tx *gorm.DB, tenantID, photoID uuid.UUID) (*models.MeterPhoto, error) {
+	var photo models.MeterPhoto
+	err := tx.Where("id = ? AND tenant_id = ? AND status = ?", photoID, tenantID, models.MeterPhotoUploaded).
+		First(&photo).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUsagePhotoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if photo.WaterUsageID != nil {
+		return nil, ErrUsagePhotoInUse
+	}
+	return &photo, nil
+}
+
+// readingPhotoRequired reports whether the tenant requires a photo for a
+// reading taken with readingMethod
+func readingPhotoRequired(tx *gorm.DB, tenantID uuid.UUID, readingMethod string) (bool, error) {
+	if readingMethod != models.ReadingMethodManual {
+		return false, nil
+	}
+
+	var settings models.TenantSettings
+	err := tx.Select("require_reading_photo").Where("tenant_id = ?", tenantID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return settings.RequireReadingPhoto, err
+}
+
+// AttachPhotoToUsage attaches an uploaded photo to an existing reading that
+// was recorded without one
+func AttachPhotoToUsage(tx *gorm.DB, photoID uuid.UUID, usage *models.WaterUsage) (*models.MeterPhoto, error) {
+	photo, err := findReadingPhoto(tx, usage.TenantID, photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attached int64
+	if err := tx.Model(&models.MeterPhoto{}).Where("water_usage_id = ?", usage.ID).Count(&attached).Error; err != nil {
+		return nil, err
+	}
+	if attached > 0 {
+		return nil, ErrUsageHasPhoto
+	}
+	return photo, AttachReadingPhoto(tx, photo, usage)
+}
+
+// AttachReadingPhoto links photo to usage and verifies the photo's EXIF
+// capture time against the time the reading was taken
+func AttachReadingPhoto(tx *gorm.DB, photo *models.MeterPhoto, usage *models.WaterUsage) error {
+	photo.WaterUsageID = &usage.ID
+	photo.Verified = false
+	switch {
+	case photo.TakenAt == nil:
+		photo.VerificationNote = "Photo has no EXIF capture time"
+	case usage.ReadAt == nil:
+		photo.VerificationNote = "Reading has no read time"
+	default:
+		gap := photo.TakenAt.Sub(*usage.ReadAt)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > MaxPhotoReadingGap {
+			photo.VerificationNote = fmt.Sprintf("Photo taken %s from the reading time", gap.Round(time.Hour))
+		} else {
+			photo.Verified = true
+			photo.VerificationNote = ""
+		}
+	}
+
+	if err := tx.Model(photo).Select("water_usage_id", "verified", "verification_note").Updates(photo).Error; err != nil {
+		return err
+	}
+
+	usage.PhotoURL = photo.URL()
+	return tx.Model(&models.WaterUsage{}).Where("id = ?", usage.ID).Update("photo_url", usage.PhotoURL).Error
+}
@@ -0,0 +1,81 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CustomerNotification is a message to a customer. If the tenant has an
+// active template with TemplateCode it is used, otherwise Subject and Body.
+type CustomerNotification struct {
+	TemplateCode string
+	Subject      string
+	Body         string
+	Variables    map[string]interface{}
+}
+
+// QueueCustomerNotification records a notification for the customer in the
+// notification log. The template's channel is used when a template exists;
+// otherwise SMS when the customer has a phone number, else email. Customers
+// without contact details for the channel are skipped without error.
+func QueueCustomerNotification(tx *gorm.DB, customer *models.Customer, n CustomerNotification) (*models.NotificationLog, error) {
+	subject, body := n.Subject, n.Body
+	var channel models.NotificationChannel
+	var templateID *uuid.UUID
+
+	var tmpl models.NotificationTemplate
+	if n.TemplateCode != "" && tx.Where("tenant_id = ? AND code = ? AND is_active = ?", customer.TenantID, n.TemplateCode, true).
+		First(&tmpl).Error == nil {
+		subject, body = tmpl.Subject, tmpl.Body
+		channel = tmpl.Channel
+		templateID = &tmpl.ID
+	}
+
+	for key, value := range n.Variables {
+		placeholder := fmt.Sprintf("{{%s}}", key)
+		subject = strings.ReplaceAll(subject, placeholder, fmt.Sprint(value))
+		body = strings.ReplaceAll(body, placeholder, fmt.Sprint(value))
+	}
+
+	if channel == "" {
+		channel = models.ChannelSMS
+		if customer.Phone == "" {
+			channel = models.ChannelEmail
+		}
+	}
+
+	var destination string
+	switch channel {
+	case models.ChannelEmail:
+		destination = customer.Email
+	case models.ChannelSMS, models.ChannelWhatsApp:
+		destination = customer.Phone
+	case models.ChannelInApp:
+		destination = customer.ID.String()
+	}
+	if destination == "" {
+		return nil, nil
+	}
+
+	notificationLog := models.NotificationLog{
+		TenantID:      customer.TenantID,
+		TemplateID:    templateID,
+		RecipientType: "CUSTOMER",
+		RecipientID:   customer.ID,
+		RecipientName: customer.Name,
+		Channel:       channel,
+		Destination:   destination,
+		Subject:       subject,
+		Body:          body,
+		Status:        "PENDING",
+	}
+
+	if err := tx.Create(&notificationLog).Error; err != nil {
+		return nil, err
+	}
+	return &notificationLog, nil
+}
@@ -0,0 +1,275 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxPaymentAmount is the largest single payment accepted
+const MaxPaymentAmount = 999999
+
+var (
+	ErrInvoiceNotFound       = errors.New("Invoice tidak ditemukan")
+	ErrInvoiceAlreadyPaid    = errors.New("Tagihan sudah lunas")
+	ErrInvoiceCarriedOver    = errors.New("Saldo tagihan sudah dialihkan ke pemilik baru")
+	ErrInvalidPaymentAmount  = errors.New("Payment amount must be greater than zero")
+	ErrPaymentAmountTooLarge = errors.New("Payment amount exceeds maximum allowed limit")
+	ErrPaymentExceedsInvoice = errors.New("Pembayaran melebihi total tagihan")
+	ErrPaymentMethodNotFound = errors.New("Metode pembayaran tidak ditemukan")
+
+	ErrPaymentReversed      = errors.New("Pembayaran sudah direversal")
+	ErrPaymentSessionClosed = errors.New("Pembayaran termasuk sesi kas yang sudah ditutup; gunakan reversal")
+	ErrPaymentReceiptIssued = errors.New("Kuitansi pembayaran sudah diterbitkan; gunakan reversal")
+)
+
+// PaymentInput describes a payment to be applied to an invoice
+type PaymentInput struct {
+	TenantID        uuid.UUID
+	InvoiceID       uuid.UUID
+	Amount          float64
+	PaymentMethodID *uuid.UUID
+	ReferenceNumber string
+	Notes           string
+	ReceivedBy      *uuid.UUID
+	PaidAt          time.Time // zero means now
+}
+
+// ApplyPayment validates and records a payment inside tx, attaches it to the
+// collector's open cash session, recomputes the invoice and issues a receipt.
+// Business rule violations are returned as the Err* values above (possibly wrapped).
+func ApplyPayment(tx *gorm.DB, input PaymentInput) (*models.Payment, *models.Invoice, error) {
+	// Lock the invoice so concurrent payments cannot overpay it
+	var invoice models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", input.InvoiceID, input.TenantID).
+		First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvoiceNotFound
+		}
+		return nil, nil, err
+	}
+
+	if invoice.IsPaid {
+		return nil, &invoice, ErrInvoiceAlreadyPaid
+	}
+	if invoice.CarriedToID != nil {
+		return nil, &invoice, ErrInvoiceCarriedOver
+	}
+
+	if input.Amount <= 0 {
+		return nil, &invoice, ErrInvalidPaymentAmount
+	}
+
+	if input.Amount > MaxPaymentAmount {
+		return nil, &invoice, ErrPaymentAmountTooLarge
+	}
+
+	if invoice.TotalPaid+input.Amount > invoice.TotalAmount {
+		return nil, &invoice, fmt.Errorf("%w. Sisa tagihan: %.2f", ErrPaymentExceedsInvoice, invoice.TotalAmount-invoice.TotalPaid)
+	}
+
+	if input.PaymentMethodID != nil {
+		var method models.PaymentMethod
+		if err := tx.Where("id = ? AND tenant_id = ? AND is_active = ?", *input.PaymentMethodID, input.TenantID, true).
+			First(&method).Error; err != nil {
+			return nil, &invoice, ErrPaymentMethodNotFound
+		}
+	}
+
+	payment := models.Payment{
+		InvoiceID:       input.InvoiceID,
+		Amount:          input.Amount,
+		TenantID:        input.TenantID,
+		PaymentMethodID: input.PaymentMethodID,
+		ReferenceNumber: input.ReferenceNumber,
+		Notes:           input.Notes,
+		ReceivedBy:      input.ReceivedBy,
+		PaidAt:          input.PaidAt,
+	}
+
+	// Payments taken by a collector with an open cash session belong to that
+	// session. The shared lock makes a concurrent close wait for this payment,
+	// or this lookup wait for the close and then see the session closed.
+	if input.ReceivedBy != nil {
+		session, err := FindOpenCashSession(tx.Clauses(clause.Locking{Strength: "SHARE"}), input.TenantID, *input.ReceivedBy)
+		if err != nil {
+			return nil, &invoice, err
+		}
+		if session != nil {
+			payment.CashSessionID = &session.ID
+		}
+	}
+
+	if err := tx.Create(&payment).Error; err != nil {
+		return nil, &invoice, err
+	}
+
+	if err := RecalculateInvoicePayments(tx, &invoice); err != nil {
+		return nil, &invoice, err
+	}
+
+	if _, err := IssueReceipt(tx, &payment, &invoice); err != nil {
+		return nil, &invoice, err
+	}
+
+	return &payment, &invoice, nil
+}
+
+// IsPaymentRuleError reports whether err is a business rule violation from ApplyPayment
+func IsPaymentRuleError(err error) bool {
+	for _, target := range []error{ErrInvoiceNotFound, ErrInvoiceAlreadyPaid, ErrInvoiceCarriedOver, ErrInvalidPaymentAmount,
+		ErrPaymentAmountTooLarge, ErrPaymentExceedsInvoice, ErrPaymentMethodNotFound} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecalculateInvoicePayments menghitung ulang TotalPaid dan IsPaid dari seluruh
+// baris pembayaran invoice (termasuk reversal) lalu menyimpannya.
+// Untuk invoice pendaftaran, status pelanggan ikut disesuaikan: pemohon
+// diaktifkan saat lunas, dan kembali menjadi pemohon jika sebelumnya lunas
+// lalu tidak lagi. Lunasnya seluruh tagihan pemakaian membatalkan perintah
+// pemutusan dan membuat perintah penyambungan kembali (lihat settleArrearsOrders).
+func RecalculateInvoicePayments(tx *gorm.DB, invoice *models.Invoice) error {
+	var totalPaid float64
+	if err := tx.Model(&models.Payment{}).
+		Where("invoice_id = ?", invoice.ID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&totalPaid).Error; err != nil {
+		return err
+	}
+
+	wasPaid := invoice.IsPaid
+	invoice.TotalPaid = totalPaid
+	invoice.IsPaid = totalPaid >= invoice.TotalAmount
+	if err := tx.Save(invoice).Error; err != nil {
+		return err
+	}
+
+	if wasPaid == invoice.IsPaid {
+		return nil
+	}
+	if invoice.IsPaid && isArrearsInvoice(invoice) {
+		return settleArrearsOrders(tx, invoice)
+	}
+	if invoice.Type != "registration" {
+		return nil
+	}
+
+	var customer models.Customer
+	if err := tx.Where("id = ? AND tenant_id = ?", invoice.CustomerID, invoice.TenantID).First(&customer).Error; err != nil {
+		return err
+	}
+	switch {
+	case invoice.IsPaid && customer.Status == models.CustomerStatusApplicant:
+		_, err := recordCustomerStatus(tx, &customer, CustomerStatusChange{
+			Status: models.CustomerStatusActive,
+			Reason: "Registration paid",
+		}, &invoice.ID)
+		return err
+	case !invoice.IsPaid && customer.Status == models.CustomerStatusActive:
+		_, err := recordCustomerStatus(tx, &customer, CustomerStatusChange{
+			Status: models.CustomerStatusApplicant,
+			Reason: "Registration payment reversed",
+		}, &invoice.ID)
+		return err
+	}
+	return nil
+}
+
+// GetCustomerCreditBalance returns the current credit balance of a customer
+func GetCustomerCreditBalance(tx *gorm.DB, tenantID, customerID uuid.UUID) (float64, error) {
+	var balance float64
+	err := tx.Model(&models.CustomerCredit{}).
+		Where("tenant_id = ? AND customer_id = ?", tenantID, customerID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// CheckPaymentEditable reports whether a payment may still be edited or
+// deleted in place. Payments counted in a closed cash session or with an
+// issued receipt are part of the books and can only be reversed. The cash
+// session is share-locked so it cannot be closed while tx changes the payment.
+func CheckPaymentEditable(tx *gorm.DB, payment *models.Payment) error {
+	if payment.Status == models.PaymentStatusReversed || payment.Type == models.PaymentTypeReversal {
+		return ErrPaymentReversed
+	}
+	if payment.CashSessionID != nil {
+		var session models.CashSession
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("status").
+			Where("id = ?", *payment.CashSessionID).First(&session).Error; err != nil {
+			return err
+		}
+		if session.Status != models.CashSessionOpen {
+			return ErrPaymentSessionClosed
+		}
+	}
+
+	var receipts int64
+	if err := tx.Model(&models.PaymentReceipt{}).Where("payment_id = ?", payment.ID).Count(&receipts).Error; err != nil {
+		return err
+	}
+	if receipts > 0 {
+		return ErrPaymentReceiptIssued
+	}
+	return nil
+}
+
+// FindOpenCashSession returns the collector's open cash session, or nil if none
+func FindOpenCashSession(tx *gorm.DB, tenantID, collectorID uuid.UUID) (*models.CashSession, error) {
+	var session models.CashSession
+	err := tx.Where("tenant_id = ? AND collector_id = ? AND status = ?", tenantID, collectorID, models.CashSessionOpen).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CalculateCashSessionTotals sums the payments attached to a cash session per
+// payment method. Reversed payments are excluded: that money was not kept.
+func CalculateCashSessionTotals(tx *gorm.DB, session *models.CashSession) ([]models.CashSessionTotal, error) {
+	type row struct {
+		PaymentMethodID *uuid.UUID
+		PaymentCount    int
+		Expected        float64
+	}
+	var rows []row
+	if err := tx.Model(&models.Payment{}).
+		Select("payment_method_id, COUNT(*) AS payment_count, COALESCE(SUM(amount + penalty), 0) AS expected").
+		Where("cash_session_id = ? AND type = ? AND status = ?", session.ID, models.PaymentTypePayment, models.PaymentStatusCompleted).
+		Group("payment_method_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	totals := make([]models.CashSessionTotal, len(rows))
+	for i, r := range rows {
+		name := "Tidak ditentukan"
+		if r.PaymentMethodID != nil {
+			var method models.PaymentMethod
+			if err := tx.Select("name").Where("id = ?", *r.PaymentMethodID).First(&method).Error; err == nil {
+				name = method.Name
+			}
+		}
+		totals[i] = models.CashSessionTotal{
+			TenantID:        session.TenantID,
+			CashSessionID:   session.ID,
+			PaymentMethodID: r.PaymentMethodID,
+			MethodName:      name,
+			PaymentCount:    r.PaymentCount,
+			Expected:        r.Expected,
+		}
+	}
+	return totals, nil
+}
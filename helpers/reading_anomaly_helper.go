@@ -0,0 +1,263 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OpenAnomalyStatuses are the statuses of anomalies still awaiting review
+var OpenAnomalyStatuses = []string{models.AnomalyStatusPending, models.AnomalyStatusInvestigating}
+
+// LoadAnomalyConfig returns the tenant's anomaly thresholds, or the defaults
+// when the tenant has not configured any
+func LoadAnomalyConfig(tx *gorm.DB, tenantID uuid.UUID) (*models.AnomalyDetectionConfig, error) {
+	var cfg models.AnomalyDetectionConfig
+	err := tx.Where("tenant_id = ?", tenantID).First(&cfg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cfg = models.DefaultAnomalyDetectionConfig(tenantID)
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// CheckReadingAnomaly runs the tenant's detector on a stored reading. A
+// flagged reading is kept: the anomaly is recorded, the reading marked and,
+// when the tenant holds invoices, the customer's unpaid invoice of that month
+// is put on hold until the anomaly is reviewed.
+func CheckReadingAnomaly(tx *gorm.DB, usage *models.WaterUsage) (*models.ReadingAnomaly, error) {
+	cfg, err := LoadAnomalyConfig(tx, usage.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.IsEnabled {
+		return nil, nil
+	}
+
+	anomaly, err := DetectReadingAnomaly(tx, cfg, usage)
+	if err != nil || anomaly == nil {
+		return nil, err
+	}
+
+	if err := tx.Omit(clause.Associations).Create(anomaly).Error; err != nil {
+		return nil, err
+	}
+	usage.IsAnomaly = true
+	if err := tx.Model(&models.WaterUsage{}).Where("id = ?", usage.ID).Update("is_anomaly", true).Error; err != nil {
+		return nil, err
+	}
+
+	if cfg.HoldInvoices {
+		if err := holdMonthlyInvoice(tx, usage.TenantID, usage.CustomerID, usage.UsageMonth, anomalyHoldReason(anomaly.AnomalyType)); err != nil {
+			return nil, err
+		}
+	}
+	return anomaly, nil
+}
+
+// DetectReadingAnomaly compares a reading with the history of its meter and
+// returns the anomaly found, or nil. Nothing is written.
+//
+// A reading below its start that was accepted as a register rollover is
+// always flagged negative. Otherwise a reading is out of range when it leaves
+// the band around the moving average: the wider of mean ± k·σ and the
+// percentage thresholds, never narrower than MinDeviationM3. With seasonal
+// checking the band is widened to also cover the same month last year, so
+// regular seasonal peaks pass.
+func DetectReadingAnomaly(tx *gorm.DB, cfg *models.AnomalyDetectionConfig, usage *models.WaterUsage) (*models.ReadingAnomaly, error) {
+	limit := cfg.HistoryMonths
+	if cfg.StuckMonths-1 > limit {
+		limit = cfg.StuckMonths - 1
+	}
+	var previous []float64
+	if err := usageHistory(tx, usage).Order("usage_month DESC").Limit(limit).
+		Pluck("usage_m3", &previous).Error; err != nil {
+		return nil, err
+	}
+
+	window := previous
+	if len(window) > cfg.HistoryMonths {
+		window = window[:cfg.HistoryMonths]
+	}
+	mean, stdDev := meanStdDev(window)
+
+	newAnomaly := func(anomalyType string, expected float64, notes string) *models.ReadingAnomaly {
+		return &models.ReadingAnomaly{
+			TenantID:      usage.TenantID,
+			WaterUsageID:  usage.ID,
+			AnomalyType:   anomalyType,
+			ExpectedValue: round2(expected),
+			ActualValue:   usage.UsageM3,
+			Deviation:     round2(usage.UsageM3 - expected),
+			Status:        models.AnomalyStatusPending,
+			Notes:         notes,
+		}
+	}
+
+	// Register read below its start, billed as a wrap-around
+	if usage.IsRollover {
+		return newAnomaly(models.AnomalyTypeNegative, mean,
+			fmt.Sprintf("Meter end %.2f is below the start %.2f, taken as a register rollover", usage.MeterEnd, usage.MeterStart)), nil
+	}
+
+	// Meter register that has not moved for several readings
+	if usage.UsageM3 == 0 && cfg.StuckMonths > 1 && len(previous) >= cfg.StuckMonths-1 {
+		stuck := true
+		for _, past := range previous[:cfg.StuckMonths-1] {
+			if past != 0 {
+				stuck = false
+				break
+			}
+		}
+		if stuck {
+			return newAnomaly(models.AnomalyTypeStuck, mean,
+				fmt.Sprintf("No consumption for %d consecutive readings", cfg.StuckMonths)), nil
+		}
+	}
+
+	if usage.UsageM3 > cfg.MaxMonthlyUsageM3 {
+		expected := cfg.MaxMonthlyUsageM3
+		if len(window) > 0 {
+			expected = mean
+		}
+		return newAnomaly(models.AnomalyTypeHighUsage, expected,
+			fmt.Sprintf("Usage exceeds the monthly limit of %.2f m3", cfg.MaxMonthlyUsageM3)), nil
+	}
+
+	if len(window) < cfg.MinHistoryMonths || len(window) == 0 {
+		return nil, nil
+	}
+
+	upper := math.Max(mean+cfg.StdDevMultiplier*stdDev, mean*(1+cfg.HighThresholdPercent/100))
+	upper = math.Max(upper, mean+cfg.MinDeviationM3)
+	lower := math.Min(mean-cfg.StdDevMultiplier*stdDev, mean*(1-cfg.LowThresholdPercent/100))
+	lower = math.Max(math.Min(lower, mean-cfg.MinDeviationM3), 0)
+	notes := fmt.Sprintf("Average of last %d readings %.2f m3 (std dev %.2f), expected range %.2f-%.2f m3",
+		len(window), mean, stdDev, lower, upper)
+
+	if cfg.UseSeasonal {
+		lastYear, err := sameMonthLastYear(tx, usage)
+		if err != nil {
+			return nil, err
+		}
+		if lastYear != nil {
+			upper = math.Max(upper, *lastYear*(1+cfg.HighThresholdPercent/100))
+			lower = math.Min(lower, *lastYear*(1-cfg.LowThresholdPercent/100))
+			notes = fmt.Sprintf("%s, same month last year %.2f m3, seasonal range %.2f-%.2f m3", notes, *lastYear, lower, upper)
+		}
+	}
+
+	switch {
+	case usage.UsageM3 == 0 && mean >= cfg.MinDeviationM3:
+		return newAnomaly(models.AnomalyTypeNoUsage, mean, notes), nil
+	case usage.UsageM3 > upper:
+		return newAnomaly(models.AnomalyTypeHighUsage, mean, notes), nil
+	case usage.UsageM3 > 0 && usage.UsageM3 < lower:
+		return newAnomaly(models.AnomalyTypeLowUsage, mean, notes), nil
+	}
+	return nil, nil
+}
+
+// InvoiceHoldReason returns why the customer's invoice of usageMonth must be
+// held, or "" when it can be issued normally
+func InvoiceHoldReason(tx *gorm.DB, tenantID, customerID uuid.UUID, usageMonth string) (string, error) {
+	cfg, err := LoadAnomalyConfig(tx, tenantID)
+	if err != nil || !cfg.HoldInvoices {
+		return "", err
+	}
+
+	var anomaly models.ReadingAnomaly
+	err = tx.Select("reading_anomalies.*").Joins("JOIN water_usages ON water_usages.id = reading_anomalies.water_usage_id").
+		Where("reading_anomalies.tenant_id = ? AND reading_anomalies.status IN ?", tenantID, OpenAnomalyStatuses).
+		Where("water_usages.customer_id = ? AND water_usages.usage_month = ? AND water_usages.deleted_at IS NULL", customerID, usageMonth).
+		First(&anomaly).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return anomalyHoldReason(anomaly.AnomalyType), nil
+}
+
+// holdMonthlyInvoice puts the customer's unpaid monthly invoice on hold
+func holdMonthlyInvoice(tx *gorm.DB, tenantID, customerID uuid.UUID, usageMonth, reason string) error {
+	return tx.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND customer_id = ? AND usage_month = ? AND type IN ? AND is_paid = ? AND carried_to_id IS NULL",
+			tenantID, customerID, usageMonth, UsageInvoiceTypes, false).
+		Updates(map[string]interface{}{"on_hold": true, "hold_reason": reason}).Error
+}
+
+func anomalyHoldReason(anomalyType string) string {
+	return "Reading anomaly under review: " + anomalyType
+}
+
+// usageHistory selects the earlier readings of the usage's meter, including
+// the meter it replaced and readings taken before meters were registered.
+// Readings still under anomaly review are left out.
+func usageHistory(tx *gorm.DB, usage *models.WaterUsage) *gorm.DB {
+	query := tx.Model(&models.WaterUsage{}).
+		Where("tenant_id = ? AND customer_id = ? AND usage_month < ? AND id <> ?",
+			usage.TenantID, usage.CustomerID, usage.UsageMonth, usage.ID).
+		Where("id NOT IN (?)", tx.Model(&models.ReadingAnomaly{}).Select("water_usage_id").
+			Where("tenant_id = ? AND status IN ?", usage.TenantID, OpenAnomalyStatuses))
+
+	if usage.MeterID != nil {
+		meterIDs := []uuid.UUID{*usage.MeterID}
+		var meter models.Meter
+		if err := tx.Select("id", "replaces_id").Where("id = ?", *usage.MeterID).First(&meter).Error; err == nil && meter.ReplacesID != nil {
+			meterIDs = append(meterIDs, *meter.ReplacesID)
+		}
+		query = query.Where("(meter_id IN ? OR meter_id IS NULL)", meterIDs)
+	}
+	return query
+}
+
+// sameMonthLastYear returns the usage of the same meter a year earlier
+func sameMonthLastYear(tx *gorm.DB, usage *models.WaterUsage) (*float64, error) {
+	month, err := time.Parse("2006-01", usage.UsageMonth)
+	if err != nil {
+		return nil, nil
+	}
+	lastYear := month.AddDate(-1, 0, 0).Format("2006-01")
+
+	var past models.WaterUsage
+	err = usageHistory(tx, usage).Where("usage_month = ?", lastYear).First(&past).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &past.UsageM3, nil
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,133 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NextReceiptNumber reserves the next receipt number of the tenant for the
+// given year, e.g. DESA01-2026-000042. The sequence row stays locked until tx
+// ends, so numbers are gapless as long as the surrounding transaction commits.
+func NextReceiptNumber(tx *gorm.DB, tenant *models.Tenant, year int) (string, int64, error) {
+	seq := models.ReceiptSequence{TenantID: tenant.ID, Year: year}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seq).Error; err != nil {
+		return "", 0, err
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND year = ?", tenant.ID, year).
+		First(&seq).Error; err != nil {
+		return "", 0, err
+	}
+
+	seq.LastNumber++
+	if err := tx.Model(&seq).Update("last_number", seq.LastNumber).Error; err != nil {
+		return "", 0, err
+	}
+
+	prefix := strings.ToUpper(tenant.VillageCode)
+	if prefix == "" {
+		prefix = "KW"
+	}
+	return fmt.Sprintf("%s-%d-%06d", prefix, year, seq.LastNumber), seq.LastNumber, nil
+}
+
+// IssueReceipt numbers the payment and archives a snapshot of the receipt.
+// invoice must already reflect the payment (after RecalculateInvoicePayments).
+func IssueReceipt(tx *gorm.DB, payment *models.Payment, invoice *models.Invoice) (*models.PaymentReceipt, error) {
+	var tenant models.Tenant
+	if err := tx.Where("id = ?", payment.TenantID).First(&tenant).Error; err != nil {
+		return nil, err
+	}
+
+	var customer models.Customer
+	if err := tx.Where("id = ? AND tenant_id = ?", invoice.CustomerID, payment.TenantID).First(&customer).Error; err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now()
+	number, sequence, err := NextReceiptNumber(tx, &tenant, issuedAt.Year())
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := models.ReceiptSnapshot{
+		ReceiptNumber:   number,
+		IssuedAt:        issuedAt,
+		PaidAt:          payment.PaidAt,
+		PaymentID:       payment.ID,
+		Amount:          payment.Amount,
+		Penalty:         payment.Penalty,
+		Total:           payment.Amount + payment.Penalty,
+		PaymentMethodID: payment.PaymentMethodID,
+		PaymentMethod:   "Tidak ditentukan",
+		ReferenceNumber: payment.ReferenceNumber,
+		CollectorID:     payment.ReceivedBy,
+		Notes:           payment.Notes,
+	}
+	snapshot.Tenant.Name = tenant.Name
+	snapshot.Tenant.Address = tenant.Address
+	snapshot.Tenant.Phone = tenant.Phone
+	snapshot.Customer.ID = customer.ID
+	snapshot.Customer.Name = customer.Name
+	snapshot.Customer.MeterNumber = customer.MeterNumber
+	snapshot.Customer.Address = customer.Address
+	snapshot.Invoices = []models.ReceiptInvoiceLine{{
+		InvoiceID:   invoice.ID,
+		Type:        invoice.Type,
+		UsageMonth:  invoice.UsageMonth,
+		UsageM3:     invoice.UsageM3,
+		TotalAmount: invoice.TotalAmount,
+		PaidBefore:  invoice.TotalPaid - payment.Amount,
+		PaidNow:     payment.Amount,
+		Remaining:   invoice.TotalAmount - invoice.TotalPaid,
+	}}
+
+	if payment.PaymentMethodID != nil {
+		var method models.PaymentMethod
+		if err := tx.Select("name").Where("id = ?", *payment.PaymentMethodID).First(&method).Error; err == nil {
+			snapshot.PaymentMethod = method.Name
+		}
+	}
+	if payment.ReceivedBy != nil {
+		var collector models.User
+		if err := tx.Select("name").Where("id = ?", *payment.ReceivedBy).First(&collector).Error; err == nil {
+			snapshot.CollectorName = collector.Name
+		}
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(data)
+
+	receipt := models.PaymentReceipt{
+		TenantID:      payment.TenantID,
+		PaymentID:     payment.ID,
+		ReceiptNumber: number,
+		Year:          issuedAt.Year(),
+		Sequence:      sequence,
+		IssuedAt:      issuedAt,
+		Snapshot:      string(data),
+		Checksum:      hex.EncodeToString(checksum[:]),
+	}
+	if err := tx.Create(&receipt).Error; err != nil {
+		return nil, err
+	}
+
+	payment.ReceiptNumber = number
+	if err := tx.Model(payment).Update("receipt_number", number).Error; err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
@@ -0,0 +1,221 @@
+package helpers
+
+import (
+	"math"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultWalkingSpeedKmh = 4.0
+	DefaultMinutesPerStop  = 2.0
+
+	// Paths and alleys are longer than the straight line between two houses
+	routeDetourFactor = 1.3
+	maxTwoOptPasses   = 50
+)
+
+// RouteOptimizeOptions tunes the route optimisation. Start is where the reader
+// sets off from; without it the route may start at any stop.
+type RouteOptimizeOptions struct {
+	StartLat        *float64
+	StartLng        *float64
+	WalkingSpeedKmh float64
+	MinutesPerStop  float64
+}
+
+// RouteOptimization is a proposed visiting order for a reading route
+type RouteOptimization struct {
+	CustomerIDs      []uuid.UUID `json:"customer_ids"` // new visiting order
+	OptimizedStops   int         `json:"optimized_stops"`
+	UnlocatedStops   int         `json:"unlocated_stops"` // customers without coordinates, kept at the end in their current order
+	DistanceBeforeM  float64     `json:"distance_before_m"`
+	DistanceAfterM   float64     `json:"distance_after_m"`
+	EstDuration      int         `json:"est_duration"` // minutes
+	PrevEstDuration  int         `json:"prev_est_duration"`
+	WalkingSpeedKmh  float64     `json:"walking_speed_kmh"`
+	MinutesPerStop   float64     `json:"minutes_per_stop"`
+	StartsFromOrigin bool        `json:"starts_from_origin"`
+}
+
+// OptimizeReadingRoute orders the stops of a route by their customers'
+// coordinates to shorten the walk: a nearest-neighbour tour improved by 2-opt.
+// Distances are straight lines, so no map service is needed.
+func OptimizeReadingRoute(db *gorm.DB, route *models.ReadingRoute, opts RouteOptimizeOptions) (*RouteOptimization, error) {
+	if opts.WalkingSpeedKmh <= 0 {
+		opts.WalkingSpeedKmh = DefaultWalkingSpeedKmh
+	}
+
+	var customers []models.Customer
+	if err := db.Select("id", "latitude", "longitude", "route_sequence").
+		Where("tenant_id = ? AND reading_route_id = ?", route.TenantID, route.ID).
+		Order("route_sequence ASC, name ASC").
+		Find(&customers).Error; err != nil {
+		return nil, err
+	}
+
+	var located, unlocated []models.Customer
+	for _, customer := range customers {
+		if customer.Latitude != nil && customer.Longitude != nil {
+			located = append(located, customer)
+		} else {
+			unlocated = append(unlocated, customer)
+		}
+	}
+
+	// Points are the located stops, followed by the start when there is one
+	points := make([]utils.Position, 0, len(located)+1)
+	for _, customer := range located {
+		points = append(points, utils.Position{*customer.Longitude, *customer.Latitude})
+	}
+	hasStart := opts.StartLat != nil && opts.StartLng != nil
+	if hasStart {
+		points = append(points, utils.Position{*opts.StartLng, *opts.StartLat})
+	}
+	dist := distanceMatrix(points)
+
+	current := make([]int, len(located))
+	for i := range current {
+		current[i] = i
+	}
+	path := func(order []int) []int {
+		if hasStart {
+			return append([]int{len(located)}, order...)
+		}
+		return order
+	}
+
+	best := current
+	if len(located) > 1 {
+		best = nearestNeighbourOrder(dist, len(located), hasStart)
+		best = twoOpt(dist, path(best), hasStart)
+		if hasStart {
+			best = best[1:]
+		}
+		// Keep a hand-made order that is already at least as short
+		if pathLength(dist, path(best)) >= pathLength(dist, path(current)) {
+			best = current
+		}
+	}
+
+	result := &RouteOptimization{
+		CustomerIDs:      make([]uuid.UUID, 0, len(customers)),
+		OptimizedStops:   len(located),
+		UnlocatedStops:   len(unlocated),
+		DistanceBeforeM:  math.Round(pathLength(dist, path(current))),
+		DistanceAfterM:   math.Round(pathLength(dist, path(best))),
+		PrevEstDuration:  route.EstDuration,
+		WalkingSpeedKmh:  opts.WalkingSpeedKmh,
+		MinutesPerStop:   opts.MinutesPerStop,
+		StartsFromOrigin: hasStart,
+	}
+	for _, i := range best {
+		result.CustomerIDs = append(result.CustomerIDs, located[i].ID)
+	}
+	for _, customer := range unlocated {
+		result.CustomerIDs = append(result.CustomerIDs, customer.ID)
+	}
+
+	walkMinutes := result.DistanceAfterM * routeDetourFactor / (opts.WalkingSpeedKmh * 1000 / 60)
+	result.EstDuration = int(math.Ceil(walkMinutes + float64(len(customers))*opts.MinutesPerStop))
+	return result, nil
+}
+
+// ApplyRouteOrder saves a visiting order as the route's stop sequence
+// together with its new duration estimate
+func ApplyRouteOrder(tx *gorm.DB, route *models.ReadingRoute, result *RouteOptimization) error {
+	for i, customerID := range result.CustomerIDs {
+		if err := tx.Model(&models.Customer{}).
+			Where("id = ? AND tenant_id = ? AND reading_route_id = ?", customerID, route.TenantID, route.ID).
+			Update("route_sequence", i+1).Error; err != nil {
+			return err
+		}
+	}
+	route.EstDuration = result.EstDuration
+	return tx.Model(route).Update("est_duration", result.EstDuration).Error
+}
+
+func distanceMatrix(points []utils.Position) [][]float64 {
+	dist := make([][]float64, len(points))
+	for i := range points {
+		dist[i] = make([]float64, len(points))
+		for j := 0; j < i; j++ {
+			d := utils.DistanceMeters(points[i][1], points[i][0], points[j][1], points[j][0])
+			dist[i][j] = d
+			dist[j][i] = d
+		}
+	}
+	return dist
+}
+
+// nearestNeighbourOrder visits the nearest unvisited stop each time, setting
+// off from the start when there is one and otherwise from the current first stop
+func nearestNeighbourOrder(dist [][]float64, stops int, hasStart bool) []int {
+	visited := make([]bool, stops)
+	order := make([]int, 0, stops)
+	from := 0
+	if hasStart {
+		from = stops
+	} else {
+		visited[0] = true
+		order = append(order, 0)
+	}
+	for len(order) < stops {
+		next := -1
+		for j := 0; j < stops; j++ {
+			if !visited[j] && (next < 0 || dist[from][j] < dist[from][next]) {
+				next = j
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		from = next
+	}
+	return order
+}
+
+// twoOpt reverses stretches of an open path while that shortens it. The
+// first point stays in place when it is the start.
+func twoOpt(dist [][]float64, path []int, fixedStart bool) []int {
+	first := 0
+	if fixedStart {
+		first = 1
+	}
+	n := len(path)
+	edge := func(a, b int) float64 {
+		if a < 0 || b >= n {
+			return 0
+		}
+		return dist[path[a]][path[b]]
+	}
+
+	for pass := 0; pass < maxTwoOptPasses; pass++ {
+		improved := false
+		for i := first; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				delta := edge(i-1, j) + edge(i, j+1) - edge(i-1, i) - edge(j, j+1)
+				if delta < -1e-6 {
+					for l, r := i, j; l < r; l, r = l+1, r-1 {
+						path[l], path[r] = path[r], path[l]
+					}
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return path
+}
+
+func pathLength(dist [][]float64, path []int) float64 {
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		total += dist[path[i-1]][path[i]]
+	}
+	return total
+}
@@ -0,0 +1,100 @@
+package helpers
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/adipras/tirta-saas-backend/utils"
+)
+
+// lineMatrix places points on a line at the given positions
+func lineMatrix(positions ...float64) [][]float64 {
+	dist := make([][]float64, len(positions))
+	for i := range positions {
+		dist[i] = make([]float64, len(positions))
+		for j := range positions {
+			dist[i][j] = math.Abs(positions[i] - positions[j])
+		}
+	}
+	return dist
+}
+
+func TestNearestNeighbourOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		dist     [][]float64
+		stops    int
+		hasStart bool
+		want     []int
+	}{
+		{name: "from the first stop", dist: lineMatrix(0, 30, 10, 20), stops: 4, want: []int{0, 2, 3, 1}},
+		{name: "from the start", dist: lineMatrix(0, 30, 10, 20, 35), stops: 4, hasStart: true, want: []int{1, 3, 2, 0}},
+		{name: "single stop", dist: lineMatrix(5), stops: 1, want: []int{0}},
+		{name: "single stop with start", dist: lineMatrix(5, 0), stops: 1, hasStart: true, want: []int{0}},
+		{name: "same place", dist: lineMatrix(0, 0, 0), stops: 3, want: []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nearestNeighbourOrder(tt.dist, tt.stops, tt.hasStart); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTwoOpt(t *testing.T) {
+	// Corners of a 100 m square: 0 and 2 are opposite, so 0-2-1-3 crosses itself
+	square := distanceMatrix([]utils.Position{
+		{106.8000, -6.2000}, {106.8009, -6.2000}, {106.8009, -6.2009}, {106.8000, -6.2009},
+	})
+
+	tests := []struct {
+		name       string
+		dist       [][]float64
+		path       []int
+		fixedStart bool
+		want       []int // a shortest path; others of the same length pass
+	}{
+		{name: "line out of order", dist: lineMatrix(0, 10, 20, 30), path: []int{0, 2, 1, 3}, want: []int{0, 1, 2, 3}},
+		{name: "reversed tail", dist: lineMatrix(0, 10, 20, 30), path: []int{0, 3, 2, 1}, want: []int{0, 1, 2, 3}},
+		{name: "crossing square", dist: square, path: []int{0, 2, 1, 3}, want: []int{0, 1, 2, 3}},
+		{name: "already short", dist: lineMatrix(0, 10, 20), path: []int{0, 1, 2}, want: []int{0, 1, 2}},
+		// The start is 3 in the middle; it must stay first
+		{name: "fixed start", dist: lineMatrix(0, 10, 20, 15), path: []int{3, 0, 2, 1}, fixedStart: true, want: []int{3, 2, 1, 0}},
+		{name: "two points", dist: lineMatrix(0, 10), path: []int{1, 0}, want: []int{1, 0}},
+		{name: "empty", dist: nil, path: []int{}, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := twoOpt(tt.dist, append([]int{}, tt.path...), tt.fixedStart)
+			if len(got) != len(tt.path) {
+				t.Fatalf("got %v, a permutation of %v", got, tt.path)
+			}
+			if length, want := pathLength(tt.dist, got), pathLength(tt.dist, tt.want); math.Abs(length-want) > 1e-6 {
+				t.Fatalf("got %v of length %.2f, want %v of length %.2f", got, length, tt.want, want)
+			}
+			if tt.fixedStart && got[0] != tt.path[0] {
+				t.Fatalf("start moved from %d to %d", tt.path[0], got[0])
+			}
+		})
+	}
+}
+
+func TestPathLength(t *testing.T) {
+	dist := lineMatrix(0, 10, 25)
+	tests := []struct {
+		path []int
+		want float64
+	}{
+		{path: []int{0, 1, 2}, want: 25},
+		{path: []int{1, 0, 2}, want: 35},
+		{path: []int{2}, want: 0},
+		{path: nil, want: 0},
+	}
+	for _, tt := range tests {
+		if got := pathLength(dist, tt.path); got != tt.want {
+			t.Errorf("pathLength(%v) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
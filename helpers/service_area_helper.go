@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ServiceAreaTreeIDs returns areaID and the IDs of all areas below it
+func ServiceAreaTreeIDs(db *gorm.DB, tenantID, areaID uuid.UUID) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{areaID}
+	parents := []uuid.UUID{areaID}
+	seen := map[uuid.UUID]bool{areaID: true}
+
+	for len(parents) > 0 {
+		var children []uuid.UUID
+		if err := db.Model(&models.ServiceArea{}).
+			Where("tenant_id = ? AND parent_id IN ?", tenantID, parents).
+			Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+
+		parents = parents[:0]
+		for _, id := range children {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+			parents = append(parents, id)
+		}
+	}
+	return ids, nil
+}
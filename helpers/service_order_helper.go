@@ -0,0 +1,343 @@
+package helpers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrServiceOrderType            = errors.New("Unknown service order type")
+	ErrServiceOrderExists          = errors.New("Customer already has an open order of this type")
+	ErrServiceOrderNotApplicable   = errors.New("Order type does not apply to the customer's status")
+	ErrServiceOrderNotOpen         = errors.New("Service order is not open")
+	ErrServiceOrderReadingRequired = errors.New("A final meter reading is required to confirm the disconnection")
+)
+
+// CustomerArrears is the unpaid usage billing of a customer, including usage
+// taken over from a previous owner
+type CustomerArrears struct {
+	Invoices int     `json:"invoices"`
+	Amount   float64 `json:"amount"`
+}
+
+// GetCustomerArrears sums the customer's unpaid usage and transfer invoices
+// created before the cutoff; a zero cutoff counts all of them. Invoices on
+// hold are not collectable yet and are left out, as are invoices whose
+// balance was carried over to a new owner.
+func GetCustomerArrears(tx *gorm.DB, tenantID, customerID uuid.UUID, before time.Time) (*CustomerArrears, error) {
+	query := tx.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND customer_id = ? AND type IN ? AND is_paid = ? AND on_hold = ? AND carried_to_id IS NULL",
+			tenantID, customerID, ArrearsInvoiceTypes, false, false)
+	if !before.IsZero() {
+		query = query.Where("created_at < ?", before)
+	}
+
+	var arrears CustomerArrears
+	if err := query.Select("COUNT(*) AS invoices, COALESCE(SUM(total_amount - total_paid), 0) AS amount").
+		Scan(&arrears).Error; err != nil {
+		return nil, err
+	}
+	return &arrears, nil
+}
+
+// ServiceOrderInput describes a disconnection or reconnection order to raise
+type ServiceOrderInput struct {
+	OrderType string
+	Reason    string
+	CreatedBy *uuid.UUID       // nil when raised automatically
+	Arrears   *CustomerArrears // arrears that led to the order, if known
+
+	// ReconnectionFee overrides the subscription's fee billed with a
+	// reconnection order; 0 waives it
+	ReconnectionFee *float64
+}
+
+// RaiseServiceOrder opens a service order for the customer inside tx. A
+// disconnection order needs an active or suspended customer, a reconnection
+// order a disconnected one; the reconnection fee is billed when the order is
+// raised. The fee invoice, if any, is returned.
+func RaiseServiceOrder(tx *gorm.DB, customer *models.Customer, input ServiceOrderInput) (*models.ServiceOrder, *models.Invoice, error) {
+	switch input.OrderType {
+	case models.ServiceOrderDisconnection:
+		if customer.Status != models.CustomerStatusActive && customer.Status != models.CustomerStatusSuspended {
+			return nil, nil, ErrServiceOrderNotApplicable
+		}
+	case models.ServiceOrderReconnection:
+		if customer.Status != models.CustomerStatusDisconnected {
+			return nil, nil, ErrServiceOrderNotApplicable
+		}
+	default:
+		return nil, nil, ErrServiceOrderType
+	}
+
+	open, err := openServiceOrders(tx, customer, input.OrderType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(open) > 0 {
+		return nil, nil, ErrServiceOrderExists
+	}
+
+	order := models.ServiceOrder{
+		TenantID:   customer.TenantID,
+		CustomerID: customer.ID,
+		OrderType:  input.OrderType,
+		Status:     models.ServiceOrderOpen,
+		Reason:     input.Reason,
+		CreatedBy:  input.CreatedBy,
+	}
+	if input.Arrears != nil {
+		order.OverdueInvoices = input.Arrears.Invoices
+		order.ArrearsAmount = input.Arrears.Amount
+	}
+
+	var invoice *models.Invoice
+	if input.OrderType == models.ServiceOrderReconnection {
+		invoice, err = createReconnectionInvoice(tx, customer, CustomerStatusChange{
+			EffectiveDate:   truncateToDay(time.Now()),
+			ReconnectionFee: input.ReconnectionFee,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if invoice != nil {
+			order.InvoiceID = &invoice.ID
+		}
+	}
+
+	if err := tx.Omit("Tenant", "Customer", "Meter", "Assignee").Create(&order).Error; err != nil {
+		return nil, nil, err
+	}
+	return &order, invoice, nil
+}
+
+// ServiceOrderCompletion is the field report of a finished service order
+type ServiceOrderCompletion struct {
+	Date         time.Time  // zero means today
+	MeterID      *uuid.UUID // optional when the customer has at most one active meter
+	MeterReading *float64   // required for disconnections
+	PhotoID      *uuid.UUID // meter photo of the reading
+	CompletedBy  uuid.UUID
+	Notes        string
+}
+
+// CompleteServiceOrder confirms a service order inside tx and moves the
+// customer to disconnected or back to active. A disconnection records the
+// final reading as the month's water usage, unless the month already has a
+// reading; the reading is kept on the order either way. Returns the status
+// change and the recorded usage, if any.
+func CompleteServiceOrder(tx *gorm.DB, order *models.ServiceOrder, completion ServiceOrderCompletion) (*models.CustomerStatusHistory, *models.WaterUsage, error) {
+	if order.Status != models.ServiceOrderOpen {
+		return nil, nil, ErrServiceOrderNotOpen
+	}
+	if order.OrderType == models.ServiceOrderDisconnection && completion.MeterReading == nil {
+		return nil, nil, ErrServiceOrderReadingRequired
+	}
+
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND tenant_id = ?", order.CustomerID, order.TenantID).First(&customer).Error; err != nil {
+		return nil, nil, err
+	}
+
+	today := truncateToDay(time.Now())
+	date := today
+	readAt := time.Now()
+	if !completion.Date.IsZero() {
+		date = truncateToDay(completion.Date)
+		if date.Before(today) {
+			readAt = date
+		}
+	}
+
+	var meter *models.Meter
+	if completion.MeterReading != nil {
+		meterID := order.MeterID
+		if completion.MeterID != nil {
+			meterID = completion.MeterID
+		}
+		var err error
+		meter, err = ResolveUsageMeter(tx, order.TenantID, order.CustomerID, meterID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var usage *models.WaterUsage
+	if order.OrderType == models.ServiceOrderDisconnection {
+		var err error
+		input := WaterUsageInput{
+			TenantID:   order.TenantID,
+			CustomerID: order.CustomerID,
+			UsageMonth: date.Format("2006-01"),
+			MeterEnd:   *completion.MeterReading,
+			Notes:      "Final reading at disconnection",
+			RecordedBy: &completion.CompletedBy,
+			ReadAt:     readAt,
+			PhotoID:    completion.PhotoID,
+		}
+		if meter != nil {
+			input.MeterID = &meter.ID
+		}
+		usage, err = RecordWaterUsage(tx, input)
+		// The month's reading was already taken; the register is kept on the order
+		if errors.Is(err, ErrReadingAlreadyExists) {
+			usage, err = nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	order.Status = models.ServiceOrderCompleted
+	order.CompletedBy = &completion.CompletedBy
+	order.CompletedAt = &now
+	order.MeterReading = completion.MeterReading
+	order.Notes = completion.Notes
+	if meter != nil {
+		order.MeterID = &meter.ID
+	}
+	if usage != nil {
+		order.WaterUsageID = &usage.ID
+	}
+	if err := tx.Model(order).Updates(map[string]interface{}{
+		"status":         order.Status,
+		"completed_by":   order.CompletedBy,
+		"completed_at":   order.CompletedAt,
+		"meter_reading":  order.MeterReading,
+		"notes":          order.Notes,
+		"meter_id":       order.MeterID,
+		"water_usage_id": order.WaterUsageID,
+	}).Error; err != nil {
+		return nil, nil, err
+	}
+
+	change := CustomerStatusChange{
+		Status:        models.CustomerStatusDisconnected,
+		Reason:        order.Reason,
+		EffectiveDate: date,
+		ChangedBy:     &completion.CompletedBy,
+		Notes:         completion.Notes,
+	}
+	if order.OrderType == models.ServiceOrderReconnection {
+		// The fee was billed when the order was raised
+		noFee := 0.0
+		change.Status = models.CustomerStatusActive
+		change.ReconnectionFee = &noFee
+	}
+	history, _, err := ChangeCustomerStatus(tx, &customer, change)
+	if err != nil {
+		return nil, nil, err
+	}
+	return history, usage, nil
+}
+
+// CancelServiceOrder cancels an open service order inside tx. The fee invoice
+// of a reconnection order is left for staff to settle.
+func CancelServiceOrder(tx *gorm.DB, order *models.ServiceOrder, reason string) error {
+	if order.Status != models.ServiceOrderOpen {
+		return ErrServiceOrderNotOpen
+	}
+	order.Status = models.ServiceOrderCancelled
+	order.CancelReason = reason
+	return tx.Model(order).Updates(map[string]interface{}{
+		"status":        order.Status,
+		"cancel_reason": order.CancelReason,
+	}).Error
+}
+
+func openServiceOrders(tx *gorm.DB, customer *models.Customer, orderType string) ([]models.ServiceOrder, error) {
+	var orders []models.ServiceOrder
+	query := tx.Where("tenant_id = ? AND customer_id = ? AND status = ?", customer.TenantID, customer.ID, models.ServiceOrderOpen)
+	if orderType != "" {
+		query = query.Where("order_type = ?", orderType)
+	}
+	err := query.Find(&orders).Error
+	return orders, err
+}
+
+// settleServiceOrders closes the customer's open orders made moot by a status
+// change done without them: the matching order is completed, and closing the
+// account cancels all of them
+func settleServiceOrders(tx *gorm.DB, customer *models.Customer, fromStatus string, change CustomerStatusChange) error {
+	orderType := ""
+	switch {
+	case change.Status == models.CustomerStatusClosed:
+		orders, err := openServiceOrders(tx, customer, "")
+		if err != nil {
+			return err
+		}
+		for i := range orders {
+			if err := CancelServiceOrder(tx, &orders[i], "Account closed"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case change.Status == models.CustomerStatusDisconnected:
+		orderType = models.ServiceOrderDisconnection
+	case fromStatus == models.CustomerStatusDisconnected && change.Status == models.CustomerStatusActive:
+		orderType = models.ServiceOrderReconnection
+	default:
+		return nil
+	}
+
+	orders, err := openServiceOrders(tx, customer, orderType)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range orders {
+		if err := tx.Model(&orders[i]).Updates(map[string]interface{}{
+			"status":       models.ServiceOrderCompleted,
+			"completed_by": change.ChangedBy,
+			"completed_at": now,
+			"notes":        "Completed by customer status change",
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settleArrearsOrders reacts to a usage invoice being paid: once the customer
+// has no unpaid usage invoices left, open disconnection orders are cancelled
+// and a disconnected customer gets a reconnection order and fee invoice
+func settleArrearsOrders(tx *gorm.DB, invoice *models.Invoice) error {
+	arrears, err := GetCustomerArrears(tx, invoice.TenantID, invoice.CustomerID, time.Time{})
+	if err != nil || arrears.Invoices > 0 {
+		return err
+	}
+
+	var customer models.Customer
+	if err := tx.Where("id = ? AND tenant_id = ?", invoice.CustomerID, invoice.TenantID).First(&customer).Error; err != nil {
+		return err
+	}
+
+	orders, err := openServiceOrders(tx, &customer, models.ServiceOrderDisconnection)
+	if err != nil {
+		return err
+	}
+	for i := range orders {
+		if err := CancelServiceOrder(tx, &orders[i], "Arrears paid"); err != nil {
+			return err
+		}
+	}
+
+	if customer.Status != models.CustomerStatusDisconnected {
+		return nil
+	}
+	_, _, err = RaiseServiceOrder(tx, &customer, ServiceOrderInput{
+		OrderType: models.ServiceOrderReconnection,
+		Reason:    "Arrears paid",
+	})
+	if errors.Is(err, ErrServiceOrderExists) {
+		return nil
+	}
+	return err
+}
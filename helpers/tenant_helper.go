@@ -80,3 +80,16 @@ func RequireTenantID(c *gin.Context) (uuid.UUID, error) {
 
 	return tenantUUID, nil
 }
+
+// GetUserIDFromContext returns the authenticated user's ID, or nil when the
+// request was not made by a staff user (e.g. customer self-service)
+func GetUserIDFromContext(c *gin.Context) *uuid.UUID {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	if u, ok := userID.(uuid.UUID); ok {
+		return &u
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package helpers
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrBulkReadingAboveNext = errors.New("Reading is higher than the bulk meter's reading of a later month")
+
+// RecordBulkMeterReading records a bulk meter's reading for a month. Recording
+// a month again corrects it, and the following month's volume is recounted
+// from the corrected reading.
+func RecordBulkMeterReading(tx *gorm.DB, meter *models.BulkMeter, month string, readingEnd float64, readAt *time.Time, notes string, recordedBy *uuid.UUID) (*models.BulkMeterReading, error) {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, ErrInvalidUsageMonth
+	}
+	if readingEnd < 0 {
+		return nil, ErrMeterReadingNegative
+	}
+
+	start := meter.InitialReading
+	var prev models.BulkMeterReading
+	err := tx.Where("bulk_meter_id = ? AND reading_month < ?", meter.ID, month).Order("reading_month DESC").First(&prev).Error
+	if err == nil {
+		start = prev.ReadingEnd
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if readingEnd < start {
+		return nil, ErrMeterReadingBackward
+	}
+
+	var next models.BulkMeterReading
+	err = tx.Where("bulk_meter_id = ? AND reading_month > ?", meter.ID, month).Order("reading_month ASC").First(&next).Error
+	hasNext := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if hasNext && next.ReadingEnd < readingEnd {
+		return nil, ErrBulkReadingAboveNext
+	}
+
+	var reading models.BulkMeterReading
+	err = tx.Where("bulk_meter_id = ? AND reading_month = ?", meter.ID, month).First(&reading).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	reading.TenantID = meter.TenantID
+	reading.BulkMeterID = meter.ID
+	reading.ReadingMonth = month
+	reading.ReadingStart = start
+	reading.ReadingEnd = readingEnd
+	reading.VolumeM3 = readingEnd - start
+	reading.ReadAt = readAt
+	reading.RecordedBy = recordedBy
+	reading.Notes = notes
+	if reading.ID == uuid.Nil {
+		err = tx.Omit("BulkMeter").Create(&reading).Error
+	} else {
+		err = tx.Omit("BulkMeter").Save(&reading).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if hasNext {
+		if err := tx.Model(&models.BulkMeterReading{}).Where("id = ?", next.ID).Updates(map[string]interface{}{
+			"reading_start": readingEnd,
+			"volume_m3":     next.ReadingEnd - readingEnd,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &reading, nil
+}
+
+// AreaWaterBalance is the water balance of a service area and the areas below
+// it for one month. Supply is measured by the area's own bulk meters, or else
+// by those of the areas below it; only customer usage inside the measured
+// areas counts against the supply.
+type AreaWaterBalance struct {
+	ServiceAreaID   uuid.UUID           `json:"service_area_id"`
+	Code            string              `json:"code"`
+	Name            string              `json:"name"`
+	Type            string              `json:"type"`
+	ParentID        *uuid.UUID          `json:"parent_id"`
+	BulkMeters      int                 `json:"bulk_meters"`      // active bulk meters of the area itself
+	MissingReadings int                 `json:"missing_readings"` // active bulk meters without a reading for the month
+	Metered         bool                `json:"metered"`          // supply is measured for the area or some areas below it
+	Incomplete      bool                `json:"incomplete"`       // a bulk meter counted in the supply has no reading, so NRW is left out
+	SuppliedM3      float64             `json:"supplied_m3"`
+	BilledM3        float64             `json:"billed_m3"`   // usage of all customers in the area and below
+	MeasuredM3      float64             `json:"measured_m3"` // usage of customers inside the measured areas
+	Customers       int                 `json:"customers"`
+	NRWM3           *float64            `json:"nrw_m3"`
+	NRWPercent      *float64            `json:"nrw_percent"`
+	Children        []*AreaWaterBalance `json:"children,omitempty"`
+
+	ownSupplied float64
+	ownBilled   float64
+	ownReadings int
+}
+
+// WaterBalance is a tenant's water balance for one month
+type WaterBalance struct {
+	Month      string              `json:"month"`
+	SuppliedM3 float64             `json:"supplied_m3"`
+	MeasuredM3 float64             `json:"measured_m3"`
+	BilledM3   float64             `json:"billed_m3"` // including customers without a service area
+	Incomplete bool                `json:"incomplete"`
+	NRWM3      *float64            `json:"nrw_m3"`
+	NRWPercent *float64            `json:"nrw_percent"`
+	Areas      []*AreaWaterBalance `json:"areas"`
+	byID       map[uuid.UUID]*AreaWaterBalance
+}
+
+// Area returns the balance of one service area
+func (balance *WaterBalance) Area(areaID uuid.UUID) *AreaWaterBalance {
+	return balance.byID[areaID]
+}
+
+// ComputeWaterBalance balances the month's bulk meter volumes against the
+// customers' recorded usage of the same month, rolled up the service area
+// hierarchy
+func ComputeWaterBalance(db *gorm.DB, tenantID uuid.UUID, month string) (*WaterBalance, error) {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, ErrInvalidUsageMonth
+	}
+
+	var areas []models.ServiceArea
+	if err := db.Where("tenant_id = ?", tenantID).Order("code ASC").Find(&areas).Error; err != nil {
+		return nil, err
+	}
+	balance := &WaterBalance{Month: month, Areas: []*AreaWaterBalance{}, byID: make(map[uuid.UUID]*AreaWaterBalance, len(areas))}
+	for _, area := range areas {
+		balance.byID[area.ID] = &AreaWaterBalance{
+			ServiceAreaID: area.ID,
+			Code:          area.Code,
+			Name:          area.Name,
+			Type:          area.Type,
+			ParentID:      area.ParentID,
+		}
+	}
+
+	var meters []struct {
+		ServiceAreaID uuid.UUID
+		Meters        int
+	}
+	if err := db.Model(&models.BulkMeter{}).
+		Select("service_area_id, COUNT(*) AS meters").
+		Where("tenant_id = ? AND status = ?", tenantID, models.BulkMeterStatusActive).
+		Group("service_area_id").Scan(&meters).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range meters {
+		if area := balance.byID[row.ServiceAreaID]; area != nil {
+			area.BulkMeters = row.Meters
+		}
+	}
+
+	var supplied []struct {
+		ServiceAreaID uuid.UUID
+		VolumeM3      float64
+		Readings      int
+		ActiveRead    int
+	}
+	if err := db.Table("bulk_meter_readings").
+		Select("bulk_meters.service_area_id, SUM(bulk_meter_readings.volume_m3) AS volume_m3, COUNT(*) AS readings, "+
+			"SUM(CASE WHEN bulk_meters.status = ? THEN 1 ELSE 0 END) AS active_read", models.BulkMeterStatusActive).
+		Joins("JOIN bulk_meters ON bulk_meters.id = bulk_meter_readings.bulk_meter_id AND bulk_meters.deleted_at IS NULL").
+		Where("bulk_meter_readings.tenant_id = ? AND bulk_meter_readings.reading_month = ? AND bulk_meter_readings.deleted_at IS NULL", tenantID, month).
+		Group("bulk_meters.service_area_id").Scan(&supplied).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range supplied {
+		if area := balance.byID[row.ServiceAreaID]; area != nil {
+			area.ownSupplied = row.VolumeM3
+			area.ownReadings = row.Readings
+			area.MissingReadings = area.BulkMeters - row.ActiveRead
+		}
+	}
+	for _, area := range balance.byID {
+		if area.ownReadings == 0 {
+			area.MissingReadings = area.BulkMeters
+		}
+	}
+
+	var billed []struct {
+		ServiceAreaID *uuid.UUID
+		UsageM3       float64
+		Customers     int
+	}
+	if err := db.Table("water_usages").
+		Select("customers.service_area_id, SUM(water_usages.usage_m3) AS usage_m3, COUNT(DISTINCT water_usages.customer_id) AS customers").
+		Joins("JOIN customers ON customers.id = water_usages.customer_id").
+		Where("water_usages.tenant_id = ? AND water_usages.usage_month = ? AND water_usages.deleted_at IS NULL", tenantID, month).
+		Group("customers.service_area_id").Scan(&billed).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range billed {
+		balance.BilledM3 += row.UsageM3
+		if row.ServiceAreaID == nil {
+			continue
+		}
+		if area := balance.byID[*row.ServiceAreaID]; area != nil {
+			area.ownBilled = row.UsageM3
+			area.Customers = row.Customers
+		}
+	}
+
+	// Link the tree; areas whose parent is missing or part of a cycle are
+	// treated as top-level areas
+	for _, area := range areas {
+		node := balance.byID[area.ID]
+		if area.ParentID != nil && balance.byID[*area.ParentID] != nil && !reachesArea(balance.byID, *area.ParentID, area.ID) {
+			parent := balance.byID[*area.ParentID]
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		balance.Areas = append(balance.Areas, node)
+	}
+
+	for _, area := range balance.Areas {
+		area.rollup()
+		if area.Metered {
+			balance.SuppliedM3 += area.SuppliedM3
+			balance.MeasuredM3 += area.MeasuredM3
+			balance.Incomplete = balance.Incomplete || area.Incomplete
+		}
+	}
+	if balance.SuppliedM3 > 0 && !balance.Incomplete {
+		balance.NRWM3, balance.NRWPercent = nonRevenueWater(balance.SuppliedM3, balance.MeasuredM3)
+	}
+	return balance, nil
+}
+
+// reachesArea reports whether following the parents from areaID leads to target
+func reachesArea(byID map[uuid.UUID]*AreaWaterBalance, areaID, target uuid.UUID) bool {
+	seen := map[uuid.UUID]bool{}
+	for id := areaID; !seen[id]; {
+		if id == target {
+			return true
+		}
+		seen[id] = true
+		area := byID[id]
+		if area == nil || area.ParentID == nil {
+			return false
+		}
+		id = *area.ParentID
+	}
+	return false
+}
+
+func (area *AreaWaterBalance) rollup() {
+	area.BilledM3 = area.ownBilled
+	var childSupplied, childMeasured float64
+	childMetered, childIncomplete := false, false
+	for _, child := range area.Children {
+		child.rollup()
+		area.BilledM3 += child.BilledM3
+		area.Customers += child.Customers
+		if child.Metered {
+			childMetered = true
+			childSupplied += child.SuppliedM3
+			childMeasured += child.MeasuredM3
+			childIncomplete = childIncomplete || child.Incomplete
+		}
+	}
+
+	switch {
+	case area.ownReadings > 0:
+		// The area's own bulk meters measure everything flowing into it
+		area.Metered = true
+		area.SuppliedM3 = area.ownSupplied
+		area.MeasuredM3 = area.BilledM3
+		area.Incomplete = area.MissingReadings > 0
+	case childMetered:
+		area.Metered = true
+		area.SuppliedM3 = childSupplied
+		area.MeasuredM3 = childMeasured
+		area.Incomplete = childIncomplete
+	}
+	// Usage would be set against part of the supply only
+	if area.Metered && !area.Incomplete {
+		area.NRWM3, area.NRWPercent = nonRevenueWater(area.SuppliedM3, area.MeasuredM3)
+	}
+	sort.SliceStable(area.Children, func(i, j int) bool {
+		return area.Children[i].Code < area.Children[j].Code
+	})
+}
+
+func nonRevenueWater(supplied, billed float64) (*float64, *float64) {
+	nrw := supplied - billed
+	if supplied <= 0 {
+		return &nrw, nil
+	}
+	percent := nrw / supplied * 100
+	return &nrw, &percent
+}
+
+// WaterBalancePoint is one month of a water balance trend
+type WaterBalancePoint struct {
+	Month      string   `json:"month"`
+	Metered    bool     `json:"metered"`
+	Incomplete bool     `json:"incomplete"`
+	SuppliedM3 float64  `json:"supplied_m3"`
+	MeasuredM3 float64  `json:"measured_m3"`
+	NRWM3      *float64 `json:"nrw_m3"`
+	NRWPercent *float64 `json:"nrw_percent"`
+}
+
+// WaterBalanceTrend returns the balance of the months up to and including
+// until, oldest first, for one service area or, with a nil areaID, the
+// whole tenant
+func WaterBalanceTrend(db *gorm.DB, tenantID uuid.UUID, areaID *uuid.UUID, until string, months int) ([]WaterBalancePoint, error) {
+	end, err := time.Parse("2006-01", until)
+	if err != nil {
+		return nil, ErrInvalidUsageMonth
+	}
+
+	points := make([]WaterBalancePoint, 0, months)
+	for i := months - 1; i >= 0; i-- {
+		month := end.AddDate(0, -i, 0).Format("2006-01")
+		balance, err := ComputeWaterBalance(db, tenantID, month)
+		if err != nil {
+			return nil, err
+		}
+
+		point := WaterBalancePoint{
+			Month:      month,
+			Metered:    balance.SuppliedM3 > 0,
+			Incomplete: balance.Incomplete,
+			SuppliedM3: balance.SuppliedM3,
+			MeasuredM3: balance.MeasuredM3,
+			NRWM3:      balance.NRWM3,
+			NRWPercent: balance.NRWPercent,
+		}
+		if areaID != nil {
+			area := balance.Area(*areaID)
+			if area == nil {
+				return nil, gorm.ErrRecordNotFound
+			}
+			point = WaterBalancePoint{
+				Month:      month,
+				Metered:    area.Metered,
+				Incomplete: area.Incomplete,
+				SuppliedM3: area.SuppliedM3,
+				MeasuredM3: area.MeasuredM3,
+				NRWM3:      area.NRWM3,
+				NRWPercent: area.NRWPercent,
+			}
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
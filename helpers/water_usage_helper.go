@@ -0,0 +1,370 @@
+package helpers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxMeterReading is the largest reading an 8 digit meter can show
+const MaxMeterReading = 99999999
+
+// MaxMonthlyUsageM3 is the largest monthly consumption a rollover may imply
+const MaxMonthlyUsageM3 = 1000
+
+var (
+	ErrUsageCustomerNotFound = errors.New("Pelanggan tidak ditemukan")
+	ErrInvalidUsageMonth     = errors.New("Format bulan tidak valid. Gunakan YYYY-MM")
+	ErrMeterReadingNegative  = errors.New("Meter end reading cannot be negative")
+	ErrMeterReadingTooLarge  = errors.New("Meter reading exceeds maximum allowed value")
+	ErrMeterReadingBackward  = errors.New("Meter akhir lebih kecil dari meter sebelumnya")
+	ErrNoActiveWaterRate     = errors.New("Tarif air aktif tidak ditemukan")
+	ErrReadingAlreadyExists  = errors.New("Pencatatan meter untuk bulan tersebut sudah ada")
+	ErrUsageMeterNotFound    = errors.New("Meter tidak ditemukan atau tidak aktif")
+	ErrUsageMeterRequired    = errors.New("Pelanggan memiliki lebih dari satu meter aktif, meter_id wajib diisi")
+	ErrLaterReadingExists    = errors.New("Meter sudah memiliki pencatatan bulan berikutnya")
+)
+
+// WaterUsageInput describes a meter reading to be recorded
+type WaterUsageInput struct {
+	TenantID      uuid.UUID
+	CustomerID    uuid.UUID
+	MeterID       *uuid.UUID // optional when the customer has at most one active meter
+	UsageMonth    string     // YYYY-MM
+	MeterEnd      float64
+	Notes         string
+	RecordedBy    *uuid.UUID
+	ReadingMethod string    // manual (default), automatic, estimated
+	ReadAt        time.Time // zero means now
+
+	ReadingSessionID *uuid.UUID
+	PhotoURL         string
+	PhotoID          *uuid.UUID // uploaded meter photo to attach
+
+	// SkipPhotoPolicy records the reading without the photo the tenant may
+	// require, e.g. for imported paper readings; the photo can be attached
+	// later with AttachPhotoToUsage
+	SkipPhotoPolicy bool
+}
+
+// RecordWaterUsage validates a meter reading against the previous month,
+// prices it with the customer's active water rate and stores it inside tx.
+// Business rule violations are returned as the Err* values above; readings
+// that are merely unusual are stored and flagged by CheckReadingAnomaly.
+func RecordWaterUsage(tx *gorm.DB, input WaterUsageInput) (*models.WaterUsage, error) {
+	// Business rule validation: Check reasonable meter reading
+	if input.MeterEnd < 0 {
+		return nil, ErrMeterReadingNegative
+	}
+
+	if input.MeterEnd > MaxMeterReading {
+		return nil, ErrMeterReadingTooLarge
+	}
+
+	// Validasi format bulan
+	if _, err := time.Parse("2006-01", input.UsageMonth); err != nil {
+		return nil, ErrInvalidUsageMonth
+	}
+
+	// Ambil data customer
+	var customer models.Customer
+	if err := tx.Where("id = ? AND tenant_id = ?", input.CustomerID, input.TenantID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUsageCustomerNotFound
+		}
+		return nil, err
+	}
+	if customer.Status == models.CustomerStatusClosed {
+		return nil, ErrUsageCustomerClosed
+	}
+
+	meter, err := ResolveUsageMeter(tx, input.TenantID, input.CustomerID, input.MeterID)
+	if err != nil {
+		return nil, err
+	}
+	var meterID *uuid.UUID
+	if meter != nil {
+		meterID = &meter.ID
+	}
+
+	// Satu pencatatan per meter per bulan
+	existingQuery := tx.Model(&models.WaterUsage{}).
+		Where("customer_id = ? AND usage_month = ? AND tenant_id = ?", input.CustomerID, input.UsageMonth, input.TenantID)
+	if meterID != nil {
+		existingQuery = existingQuery.Where("meter_id = ?", *meterID)
+	}
+	var existingCount int64
+	if err := existingQuery.Count(&existingCount).Error; err != nil {
+		return nil, err
+	}
+	if existingCount > 0 {
+		return nil, ErrReadingAlreadyExists
+	}
+
+	meterStart, err := previousMeterEnd(tx, input.TenantID, input.CustomerID, meter, input.UsageMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	usageM3, rollover, err := ConsumptionBetween(meterStart, input.MeterEnd, meter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ambil tarif aktif untuk subscription pelanggan
+	var rate models.WaterRate
+	if err := tx.
+		Where("subscription_id = ? AND active = ?", customer.SubscriptionID, true).
+		Order("effective_date DESC").
+		First(&rate).Error; err != nil {
+		return nil, ErrNoActiveWaterRate
+	}
+
+	readingMethod := input.ReadingMethod
+	if readingMethod == "" {
+		readingMethod = models.ReadingMethodManual
+	}
+	readAt := input.ReadAt
+	if readAt.IsZero() {
+		readAt = time.Now()
+	}
+
+	// Tenant policy may require an uploaded photo of the meter register
+	var photo *models.MeterPhoto
+	if input.PhotoID != nil {
+		photo, err = findReadingPhoto(tx, input.TenantID, *input.PhotoID)
+		if err != nil {
+			return nil, err
+		}
+	} else if !input.SkipPhotoPolicy {
+		required, err := readingPhotoRequired(tx, input.TenantID, readingMethod)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, ErrUsagePhotoRequired
+		}
+	}
+
+	usage := models.WaterUsage{
+		CustomerID:       input.CustomerID,
+		MeterID:          meterID,
+		UsageMonth:       input.UsageMonth,
+		MeterStart:       meterStart,
+		MeterEnd:         input.MeterEnd,
+		UsageM3:          usageM3,
+		AmountCalculated: usageM3 * rate.Amount,
+		IsRollover:       rollover,
+		TenantID:         input.TenantID,
+		RecordedBy:       input.RecordedBy,
+		ReadingMethod:    readingMethod,
+		ReadingSessionID: input.ReadingSessionID,
+		PhotoURL:         input.PhotoURL,
+		Notes:            input.Notes,
+		ReadAt:           &readAt,
+	}
+
+	if err := tx.Create(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	if photo != nil {
+		if err := AttachReadingPhoto(tx, photo, &usage); err != nil {
+			return nil, err
+		}
+	}
+
+	// Unusual consumption is flagged for review instead of rejected
+	anomaly, err := CheckReadingAnomaly(tx, &usage)
+	if err != nil {
+		return nil, err
+	}
+	usage.AnomalyDetails = anomaly
+
+	return &usage, nil
+}
+
+// ReviseWaterUsage replaces the end reading of a stored reading, e.g. after a
+// re-read or with an estimate, and reprices it with the customer's active
+// water rate. Only the latest reading of a meter can be revised, since the
+// next reading starts from it.
+func ReviseWaterUsage(tx *gorm.DB, usage *models.WaterUsage, meterEnd float64, readingMethod string) error {
+	if meterEnd < 0 {
+		return ErrMeterReadingNegative
+	}
+	if meterEnd > MaxMeterReading {
+		return ErrMeterReadingTooLarge
+	}
+
+	laterQuery := tx.Model(&models.WaterUsage{}).
+		Where("tenant_id = ? AND customer_id = ? AND usage_month > ?", usage.TenantID, usage.CustomerID, usage.UsageMonth)
+	var meter *models.Meter
+	if usage.MeterID != nil {
+		laterQuery = laterQuery.Where("meter_id = ?", *usage.MeterID)
+		var m models.Meter
+		if err := tx.Where("id = ? AND tenant_id = ?", *usage.MeterID, usage.TenantID).First(&m).Error; err != nil {
+			return err
+		}
+		meter = &m
+	}
+	var later int64
+	if err := laterQuery.Count(&later).Error; err != nil {
+		return err
+	}
+	if later > 0 {
+		return ErrLaterReadingExists
+	}
+
+	usageM3, rollover, err := ConsumptionBetween(usage.MeterStart, meterEnd, meter)
+	if err != nil {
+		return err
+	}
+
+	var customer models.Customer
+	if err := tx.Where("id = ? AND tenant_id = ?", usage.CustomerID, usage.TenantID).First(&customer).Error; err != nil {
+		return ErrUsageCustomerNotFound
+	}
+	var rate models.WaterRate
+	if err := tx.
+		Where("subscription_id = ? AND active = ?", customer.SubscriptionID, true).
+		Order("effective_date DESC").
+		First(&rate).Error; err != nil {
+		return ErrNoActiveWaterRate
+	}
+
+	usage.MeterEnd = meterEnd
+	usage.UsageM3 = usageM3
+	usage.AmountCalculated = usageM3 * rate.Amount
+	usage.IsRollover = rollover
+	if readingMethod != "" {
+		usage.ReadingMethod = readingMethod
+	}
+	return tx.Model(&models.WaterUsage{}).Where("id = ?", usage.ID).Updates(map[string]interface{}{
+		"meter_end":         usage.MeterEnd,
+		"usage_m3":          usage.UsageM3,
+		"amount_calculated": usage.AmountCalculated,
+		"is_rollover":       usage.IsRollover,
+		"reading_method":    usage.ReadingMethod,
+	}).Error
+}
+
+// ConsumptionBetween returns the consumption between two readings of meter.
+// A reading lower than the start is only accepted when the meter has a
+// rollover value and the wrapped consumption stays within MaxMonthlyUsageM3;
+// rollover then reports that the register passed its maximum.
+func ConsumptionBetween(start, end float64, meter *models.Meter) (float64, bool, error) {
+	if meter != nil && meter.RolloverAt > 0 && end >= meter.RolloverAt {
+		return 0, false, ErrMeterReadingTooLarge
+	}
+	if end >= start {
+		return end - start, false, nil
+	}
+	if meter == nil || meter.RolloverAt <= 0 {
+		return 0, false, ErrMeterReadingBackward
+	}
+
+	usage := meter.RolloverAt - start + end
+	if usage > MaxMonthlyUsageM3 {
+		return 0, false, ErrMeterReadingBackward
+	}
+	return usage, true, nil
+}
+
+// ResolveUsageMeter returns the meter a reading belongs to. With meterID the
+// meter must be an active meter of the customer; without it the customer's
+// only active meter is used. Customers without registered meters get nil.
+func ResolveUsageMeter(tx *gorm.DB, tenantID, customerID uuid.UUID, meterID *uuid.UUID) (*models.Meter, error) {
+	if meterID != nil {
+		var meter models.Meter
+		if err := tx.Where("id = ? AND customer_id = ? AND tenant_id = ? AND status = ?", *meterID, customerID, tenantID, models.MeterStatusActive).
+			First(&meter).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrUsageMeterNotFound
+			}
+			return nil, err
+		}
+		return &meter, nil
+	}
+
+	var meters []models.Meter
+	if err := tx.Where("customer_id = ? AND tenant_id = ? AND status = ?", customerID, tenantID, models.MeterStatusActive).
+		Limit(2).Find(&meters).Error; err != nil {
+		return nil, err
+	}
+	switch len(meters) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &meters[0], nil
+	default:
+		return nil, ErrUsageMeterRequired
+	}
+}
+
+// previousMeterEnd returns the reading a new reading starts from: the latest
+// earlier reading of the meter, else the customer's earlier readings taken
+// before meters were registered when it is the customer's first meter, else
+// the meter's initial reading. A meter that replaced another always starts
+// from its own initial reading.
+func previousMeterEnd(tx *gorm.DB, tenantID, customerID uuid.UUID, meter *models.Meter, usageMonth string) (float64, error) {
+	var lastUsage models.WaterUsage
+	query := tx.Where("customer_id = ? AND tenant_id = ? AND usage_month < ?", customerID, tenantID, usageMonth).
+		Order("usage_month DESC")
+
+	if meter == nil {
+		err := query.First(&lastUsage).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return lastUsage.MeterEnd, err
+	}
+
+	err := query.Session(&gorm.Session{}).Where("meter_id = ?", meter.ID).First(&lastUsage).Error
+	if err == nil {
+		return lastUsage.MeterEnd, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	if meter.ReplacesID != nil {
+		return meter.InitialReading, nil
+	}
+
+	// Readings from before meters were registered belong to the customer's
+	// first meter; any later connection starts from its own register
+	var earlier int64
+	if err := tx.Model(&models.Meter{}).
+		Where("customer_id = ? AND tenant_id = ? AND id <> ?", customerID, tenantID, meter.ID).
+		Where("created_at < ? OR (created_at = ? AND id < ?)", meter.CreatedAt, meter.CreatedAt, meter.ID).
+		Count(&earlier).Error; err != nil {
+		return 0, err
+	}
+	if earlier > 0 {
+		return meter.InitialReading, nil
+	}
+
+	err = query.Session(&gorm.Session{}).Where("meter_id IS NULL").First(&lastUsage).Error
+	if err == nil {
+		return lastUsage.MeterEnd, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	return meter.InitialReading, nil
+}
+
+// IsWaterUsageRuleError reports whether err is a business rule violation from RecordWaterUsage
+func IsWaterUsageRuleError(err error) bool {
+	for _, target := range []error{ErrUsageCustomerNotFound, ErrInvalidUsageMonth, ErrMeterReadingNegative,
+		ErrMeterReadingTooLarge, ErrMeterReadingBackward, ErrNoActiveWaterRate, ErrReadingAlreadyExists,
+		ErrUsageMeterNotFound, ErrUsageMeterRequired, ErrLaterReadingExists, ErrUsagePhotoNotFound, ErrUsagePhotoInUse,
+		ErrUsagePhotoRequired, ErrUsageCustomerClosed} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/adipras/tirta-saas-backend/config"
 	_ "github.com/adipras/tirta-saas-backend/docs"
 	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/adipras/tirta-saas-backend/pkg/autodebit"
+	"github.com/adipras/tirta-saas-backend/pkg/calibration"
+	"github.com/adipras/tirta-saas-backend/pkg/disconnection"
+	"github.com/adipras/tirta-saas-backend/pkg/leakdetect"
 	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"github.com/adipras/tirta-saas-backend/pkg/mqttbridge"
+	"github.com/adipras/tirta-saas-backend/pkg/paymentprovider"
+	"github.com/adipras/tirta-saas-backend/pkg/readingsession"
 	"github.com/adipras/tirta-saas-backend/pkg/seeder"
 	"github.com/adipras/tirta-saas-backend/routes"
 
@@ -47,6 +56,36 @@ func main() {
 	config.ConnectDB()
 	config.Migrate()
 
+	// Auto-debit providers; real providers register themselves here
+	if os.Getenv("PAYMENT_PROVIDER_SANDBOX") == "true" {
+		paymentprovider.Register(paymentprovider.SandboxProvider{})
+	}
+
+	// Daily recompute of meter calibration due dates
+	go calibration.StartScheduler(24 * time.Hour)
+
+	// Hourly creation of the day's reading sessions from route schedules
+	go readingsession.StartScheduler(time.Hour)
+
+	// Daily leak check of smart meter intervals
+	go leakdetect.StartScheduler(24 * time.Hour)
+
+	// Daily check of arrears against the tenants' disconnection thresholds
+	go disconnection.StartScheduler(24 * time.Hour)
+
+	// Auto-debit runs queued by bill runs and released invoices
+	go autodebit.StartScheduler(time.Minute)
+
+	// MQTT telemetry bridge, unless it runs separately (cmd/mqtt-bridge)
+	if cfg, ok := mqttbridge.ConfigFromEnv(); ok && os.Getenv("MQTT_BRIDGE_ENABLED") == "true" {
+		bridge := &mqttbridge.Bridge{DB: config.DB, Config: cfg}
+		go func() {
+			if err := bridge.Run(context.Background()); err != nil {
+				log.Printf("⚠️  Warning: MQTT bridge stopped: %v", err)
+			}
+		}()
+	}
+
 	// Auto-seed default platform admin if none exists
 	if os.Getenv("AUTO_SEED_ADMIN") == "true" {
 		if err := seeder.SeedDefaultPlatformAdmin(); err != nil {
@@ -94,6 +133,22 @@ func main() {
 	routes.WaterUsageRoutes(r)
 	routes.InvoiceRoutes(r)
 	routes.PaymentRoutes(r)
+	routes.CashSessionRoutes(r)
+	routes.SyncRoutes(r)
+	routes.PaymentMandateRoutes(r)
+	routes.MeterRoutes(r)
+	routes.MeterIssueRoutes(r)
+	routes.CalibrationRoutes(r)
+	routes.ReadingRouteRoutes(r)
+	routes.ReadingSessionRoutes(r)
+	routes.ReadingAnomalyRoutes(r)
+	routes.MeterPhotoRoutes(r)
+	routes.MeterDeviceRoutes(r)
+	routes.LeakDetectionRoutes(r)
+	routes.WaterBalanceRoutes(r)
+	routes.GeoRoutes(r)
+	routes.ConnectionApplicationRoutes(r)
+	routes.ServiceOrderRoutes(r)
 	routes.RegisterTenantUserRoutes(r)
 	routes.PlatformRoutes(r)
 	routes.ReportRoutes(r)
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// AnomalyDetectionConfig holds a tenant's reading anomaly thresholds. Tenants
+// without a row use DefaultAnomalyDetectionConfig.
+type AnomalyDetectionConfig struct {
+	BaseModel
+	TenantID             uuid.UUID `gorm:"type:char(36);not null;uniqueIndex" json:"tenant_id"`
+	IsEnabled            bool      `gorm:"not null" json:"is_enabled"`
+	HistoryMonths        int       `gorm:"not null" json:"history_months"`                       // readings in the moving average
+	MinHistoryMonths     int       `gorm:"not null" json:"min_history_months"`                   // fewer readings only get the hard limit and stuck checks
+	StdDevMultiplier     float64   `gorm:"type:decimal(5,2);not null" json:"std_dev_multiplier"` // band of mean ± k·σ
+	HighThresholdPercent float64   `gorm:"type:decimal(7,2);not null" json:"high_threshold_percent"`
+	LowThresholdPercent  float64   `gorm:"type:decimal(5,2);not null" json:"low_threshold_percent"`
+	MinDeviationM3       float64   `gorm:"type:decimal(10,2);not null" json:"min_deviation_m3"` // smaller differences are never flagged
+	UseSeasonal          bool      `gorm:"not null" json:"use_seasonal"`                        // widen the band to the same month last year
+	StuckMonths          int       `gorm:"not null" json:"stuck_months"`                        // consecutive zero readings that mean a stuck meter
+	MaxMonthlyUsageM3    float64   `gorm:"type:decimal(10,2);not null" json:"max_monthly_usage_m3"`
+	HoldInvoices         bool      `gorm:"not null" json:"hold_invoices"` // hold the customer's invoice until anomalies are reviewed
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// DefaultAnomalyDetectionConfig returns the thresholds used until a tenant
+// configures its own
+func DefaultAnomalyDetectionConfig(tenantID uuid.UUID) AnomalyDetectionConfig {
+	return AnomalyDetectionConfig{
+		TenantID:             tenantID,
+		IsEnabled:            true,
+		HistoryMonths:        6,
+		MinHistoryMonths:     3,
+		StdDevMultiplier:     2,
+		HighThresholdPercent: 100,
+		LowThresholdPercent:  50,
+		MinDeviationM3:       5,
+		UseSeasonal:          true,
+		StuckMonths:          3,
+		MaxMonthlyUsageM3:    1000,
+		HoldInvoices:         true,
+	}
+}
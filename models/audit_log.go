@@ -23,6 +23,9 @@ const (
 	ActionRoleChange        AuditAction = "ROLE_CHANGE"
 	ActionActivation        AuditAction = "ACTIVATION"
 	ActionDeactivation      AuditAction = "DEACTIVATION"
+	ActionPaymentReversal   AuditAction = "PAYMENT_REVERSAL"
+	ActionRefund            AuditAction = "REFUND"
+	ActionReceiptReprint    AuditAction = "RECEIPT_REPRINT"
 )
 
 // AuditLevel represents the severity level of the audit event
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// CalibrationPolicy sets how often meters of a brand/model must be
+// calibrated. An empty Model applies to every model of the brand, an empty
+// Brand and Model is the tenant-wide default.
+type CalibrationPolicy struct {
+	BaseModel
+	TenantID       uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_tenant_calib_policy" json:"tenant_id"`
+	Brand          string    `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_tenant_calib_policy" json:"brand"`
+	Model          string    `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_tenant_calib_policy" json:"model"`
+	IntervalMonths int       `gorm:"not null" json:"interval_months"`
+	IsActive       bool      `gorm:"default:true;not null" json:"is_active"`
+	Notes          string    `gorm:"type:text" json:"notes"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Matches reports whether the policy covers meters of brand and model
+func (p *CalibrationPolicy) Matches(brand, model string) bool {
+	if p.Brand != "" && p.Brand != brand {
+		return false
+	}
+	return p.Model == "" || p.Model == model
+}
+
+// Calibration results
+const (
+	CalibrationPassed   = "passed"
+	CalibrationAdjusted = "adjusted"
+	CalibrationFailed   = "failed"
+)
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CashSession groups the payments a collector takes in the field between
+// opening and closing, so the cash handed in can be reconciled.
+type CashSession struct {
+	BaseModel
+	TenantID        uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_cash_session" json:"tenant_id"`
+	CollectorID     uuid.UUID  `gorm:"type:char(36);not null;index:idx_collector_cash_session" json:"collector_id"`
+	Status          string     `gorm:"type:varchar(20);default:'open';not null" json:"status"`
+	OpenedAt        time.Time  `gorm:"type:datetime;not null" json:"opened_at"`
+	ClosedAt        *time.Time `gorm:"type:datetime" json:"closed_at"`
+	TotalExpected   float64    `gorm:"type:decimal(15,2);default:0" json:"total_expected"`
+	TotalCounted    float64    `gorm:"type:decimal(15,2);default:0" json:"total_counted"`
+	Discrepancy     float64    `gorm:"type:decimal(15,2);default:0" json:"discrepancy"`
+	HasDiscrepancy  bool       `gorm:"default:false" json:"has_discrepancy"`
+	Notes           string     `gorm:"type:text" json:"notes"`
+	ClosingNotes    string     `gorm:"type:text" json:"closing_notes"`
+	SupervisorID    *uuid.UUID `gorm:"type:char(36)" json:"supervisor_id"`
+	SignedOffAt     *time.Time `gorm:"type:datetime" json:"signed_off_at"`
+	SupervisorNotes string     `gorm:"type:text" json:"supervisor_notes"`
+
+	// Relationships
+	Tenant     Tenant             `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Collector  User               `gorm:"foreignKey:CollectorID" json:"collector"`
+	Supervisor *User              `gorm:"foreignKey:SupervisorID" json:"supervisor,omitempty"`
+	Totals     []CashSessionTotal `gorm:"foreignKey:CashSessionID" json:"totals,omitempty"`
+	Payments   []Payment          `gorm:"foreignKey:CashSessionID" json:"-"`
+}
+
+// CashSessionTotal is the expected vs. counted amount for one payment method
+// at the moment a cash session is closed.
+type CashSessionTotal struct {
+	BaseModel
+	TenantID        uuid.UUID  `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	CashSessionID   uuid.UUID  `gorm:"type:char(36);not null;index:idx_cash_session_total" json:"cash_session_id"`
+	PaymentMethodID *uuid.UUID `gorm:"type:char(36)" json:"payment_method_id"`
+	MethodName      string     `gorm:"type:varchar(100)" json:"method_name"`
+	PaymentCount    int        `gorm:"default:0" json:"payment_count"`
+	Expected        float64    `gorm:"type:decimal(15,2);default:0" json:"expected"`
+	Counted         float64    `gorm:"type:decimal(15,2);default:0" json:"counted"`
+	Difference      float64    `gorm:"type:decimal(15,2);default:0" json:"difference"`
+}
+
+// Cash session status
+const (
+	CashSessionOpen      = "open"
+	CashSessionClosed    = "closed"
+	CashSessionSignedOff = "signed_off"
+)
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConnectionApplication is a request for a new water connection. It moves
+// from submission through a site survey and approval to installation, which
+// creates the customer, its meter and the registration invoice.
+type ConnectionApplication struct {
+	BaseModel
+	TenantID          uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_application;uniqueIndex:idx_tenant_application_number" json:"tenant_id"`
+	ApplicationNumber string     `gorm:"type:varchar(30);not null;uniqueIndex:idx_tenant_application_number" json:"application_number"`
+	Status            string     `gorm:"type:varchar(20);default:'submitted';not null;index" json:"status"`
+	Source            string     `gorm:"type:varchar(20);not null" json:"source"` // public, staff
+	CreatedBy         *uuid.UUID `gorm:"type:char(36)" json:"created_by"`         // staff who entered it
+
+	// Applicant
+	ApplicantName  string     `gorm:"type:varchar(100);not null" json:"applicant_name"`
+	IdentityNumber string     `gorm:"type:varchar(30)" json:"identity_number"` // NIK
+	Email          string     `gorm:"type:varchar(100)" json:"email"`
+	Phone          string     `gorm:"type:varchar(20);not null" json:"phone"`
+	Address        string     `gorm:"type:text;not null" json:"address"`
+	SubscriptionID uuid.UUID  `gorm:"type:char(36);not null" json:"subscription_id"` // requested subscription type
+	ServiceAreaID  *uuid.UUID `gorm:"type:char(36);index" json:"service_area_id"`
+	Latitude       *float64   `gorm:"type:decimal(10,7)" json:"latitude"`
+	Longitude      *float64   `gorm:"type:decimal(10,7)" json:"longitude"`
+	Notes          string     `gorm:"type:text" json:"notes"`
+
+	// Survey
+	SurveyedBy      *uuid.UUID `gorm:"type:char(36)" json:"surveyed_by"`
+	SurveyedAt      *time.Time `gorm:"type:datetime" json:"surveyed_at"`
+	Feasible        *bool      `json:"feasible"`
+	DistanceToMainM float64    `gorm:"type:decimal(10,2);default:0" json:"distance_to_main_m"` // pipe run from the nearest main
+	Materials       *string    `gorm:"type:json" json:"-"`                                     // required materials, a JSON array
+	SurveyNotes     string     `gorm:"type:text" json:"survey_notes"`
+
+	// Approval
+	DecidedBy       *uuid.UUID `gorm:"type:char(36)" json:"decided_by"`
+	DecidedAt       *time.Time `gorm:"type:datetime" json:"decided_at"`
+	ConnectionFee   float64    `gorm:"type:decimal(15,2);default:0" json:"connection_fee"` // billed as the registration invoice
+	RejectionReason string     `gorm:"type:text" json:"rejection_reason"`
+
+	// Installation
+	InstalledBy *uuid.UUID `gorm:"type:char(36)" json:"installed_by"`
+	InstalledAt *time.Time `gorm:"type:datetime" json:"installed_at"`
+	CustomerID  *uuid.UUID `gorm:"type:char(36);index" json:"customer_id"`
+	MeterID     *uuid.UUID `gorm:"type:char(36)" json:"meter_id"`
+	InvoiceID   *uuid.UUID `gorm:"type:char(36)" json:"invoice_id"`
+
+	// Relationships
+	Tenant       Tenant           `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Subscription SubscriptionType `gorm:"foreignKey:SubscriptionID" json:"-"`
+	ServiceArea  *ServiceArea     `gorm:"foreignKey:ServiceAreaID" json:"-"`
+	Customer     *Customer        `gorm:"foreignKey:CustomerID" json:"-"`
+}
+
+// ConnectionMaterial is an item the survey found to be needed for the connection
+type ConnectionMaterial struct {
+	Name     string  `json:"name" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"gt=0"`
+	Unit     string  `json:"unit"`
+}
+
+// Connection application status
+const (
+	ApplicationSubmitted = "submitted"
+	ApplicationSurveyed  = "surveyed"
+	ApplicationApproved  = "approved"
+	ApplicationRejected  = "rejected"
+	ApplicationInstalled = "installed"
+)
+
+// Application sources
+const (
+	ApplicationSourcePublic = "public"
+	ApplicationSourceStaff  = "staff"
+)
+
+// IsOpen reports whether the application is still being processed
+func (application *ConnectionApplication) IsOpen() bool {
+	switch application.Status {
+	case ApplicationSubmitted, ApplicationSurveyed, ApplicationApproved:
+		return true
+	}
+	return false
+}
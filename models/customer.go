@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -15,7 +17,9 @@ type Customer struct {
 	Phone          string           `json:"phone"`
 	SubscriptionID uuid.UUID        `gorm:"type:char(36);not null" json:"subscription_id"`
 	Subscription   SubscriptionType `gorm:"foreignKey:SubscriptionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"subscription"`
-	IsActive       bool             `gorm:"default:false" json:"is_active"`
+	IsActive       bool             `gorm:"default:false" json:"is_active"` // status is active, kept in sync by the lifecycle
+	Status         string           `gorm:"type:varchar(20);default:'applicant';not null;index" json:"status"`
+	StatusSince    *time.Time       `gorm:"type:date" json:"status_since"` // effective date of the current status
 	TenantID       uuid.UUID        `gorm:"type:char(36);not null;index" json:"tenant_id"`
 	
 	// Additional fields for Phase 6
@@ -23,7 +27,17 @@ type Customer struct {
 	ServiceArea    *ServiceArea `gorm:"foreignKey:ServiceAreaID" json:"service_area,omitempty"`
 	ReadingRouteID *uuid.UUID `gorm:"type:char(36);index" json:"reading_route_id"`
 	ReadingRoute   *ReadingRoute `gorm:"foreignKey:ReadingRouteID" json:"reading_route,omitempty"`
+	RouteSequence  int        `gorm:"default:0" json:"route_sequence"` // stop order on the reading route
+	Latitude       *float64   `gorm:"type:decimal(10,7)" json:"latitude"`
+	Longitude      *float64   `gorm:"type:decimal(10,7)" json:"longitude"`
 	
 	// Relationships
-	Meters []Meter `gorm:"foreignKey:CustomerID" json:"-"`
+	Meters        []Meter                 `gorm:"foreignKey:CustomerID" json:"-"`
+	StatusHistory []CustomerStatusHistory `gorm:"foreignKey:CustomerID" json:"-"`
+}
+
+// CanSignIn reports whether the customer may use the self-service portal:
+// from the first activation until the account is closed
+func (customer *Customer) CanSignIn() bool {
+	return customer.Status != CustomerStatusApplicant && customer.Status != CustomerStatusClosed
 }
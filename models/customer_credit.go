@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// CustomerCredit is a ledger entry on a customer's credit balance.
+// Positive amounts add credit, negative amounts consume it.
+type CustomerCredit struct {
+	BaseModel
+	TenantID   uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_customer_credit" json:"tenant_id"`
+	CustomerID uuid.UUID  `gorm:"type:char(36);not null;index:idx_customer_credit" json:"customer_id"`
+	Amount     float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Type       string     `gorm:"type:varchar(20);not null" json:"type"`
+	PaymentID  *uuid.UUID `gorm:"type:char(36);index" json:"payment_id,omitempty"`
+	// PaymentMethodID records how a refund was paid out
+	PaymentMethodID *uuid.UUID `gorm:"type:char(36)" json:"payment_method_id,omitempty"`
+	Reference       string     `gorm:"type:varchar(100)" json:"reference"`
+	Notes           string     `gorm:"type:text" json:"notes"`
+	CreatedBy       *uuid.UUID `gorm:"type:char(36)" json:"created_by"`
+
+	// Relationships
+	Tenant   Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Customer Customer `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE" json:"-"`
+	Payment  *Payment `gorm:"foreignKey:PaymentID" json:"-"`
+}
+
+// Customer credit entry types
+const (
+	CreditTypeReversal   = "reversal"
+	CreditTypeRefund     = "refund"
+	CreditTypeApplied    = "applied"
+	CreditTypeAdjustment = "adjustment"
+)
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerStatusHistory records each lifecycle transition of a customer
+type CustomerStatusHistory struct {
+	BaseModel
+	TenantID      uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_customer_status" json:"tenant_id"`
+	CustomerID    uuid.UUID  `gorm:"type:char(36);not null;index:idx_customer_status_history" json:"customer_id"`
+	FromStatus    string     `gorm:"type:varchar(20);not null" json:"from_status"`
+	ToStatus      string     `gorm:"type:varchar(20);not null" json:"to_status"`
+	Reason        string     `gorm:"type:varchar(255);not null" json:"reason"`
+	EffectiveDate time.Time  `gorm:"type:date;not null;index" json:"effective_date"`
+	ChangedBy     *uuid.UUID `gorm:"type:char(36)" json:"changed_by"` // nil for automatic changes
+	InvoiceID     *uuid.UUID `gorm:"type:char(36)" json:"invoice_id"` // final bill or reconnection fee raised by the change
+	Notes         string     `gorm:"type:text" json:"notes"`
+
+	// Relationships
+	Tenant   Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Customer Customer `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE" json:"-"`
+	User     *User    `gorm:"foreignKey:ChangedBy" json:"user,omitempty"`
+}
+
+// Customer lifecycle status
+const (
+	CustomerStatusApplicant    = "applicant"    // registered, connection not yet paid or installed
+	CustomerStatusActive       = "active"       // connected and billed
+	CustomerStatusSuspended    = "suspended"    // service suspended, still billed
+	CustomerStatusDisconnected = "disconnected" // cut off, no abonemen
+	CustomerStatusClosed       = "closed"       // account ended with a final bill
+)
+
+// CustomerStatusTransitions lists the statuses a customer can move to from each status
+var CustomerStatusTransitions = map[string][]string{
+	CustomerStatusApplicant:    {CustomerStatusActive, CustomerStatusClosed},
+	CustomerStatusActive:       {CustomerStatusSuspended, CustomerStatusDisconnected, CustomerStatusClosed},
+	CustomerStatusSuspended:    {CustomerStatusActive, CustomerStatusDisconnected, CustomerStatusClosed},
+	CustomerStatusDisconnected: {CustomerStatusActive, CustomerStatusClosed},
+	CustomerStatusClosed:       {},
+}
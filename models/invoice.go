@@ -16,6 +16,14 @@ type Invoice struct {
 	TotalAmount float64   `json:"total_amount"`
 	IsPaid      bool      `gorm:"default:false" json:"is_paid"`
 	TotalPaid   float64   `gorm:"default:0" json:"total_paid"`
-	Type        string    `gorm:"type:enum('registration','monthly');not null" json:"type"`
+	Type        string    `gorm:"type:enum('registration','monthly','final','reconnection','transfer');not null" json:"type"`
 	TenantID    uuid.UUID `gorm:"type:char(36);index" json:"tenant_id"`
+
+	// Held invoices wait for review of anomalous readings before collection
+	OnHold     bool   `gorm:"default:false;index" json:"on_hold"`
+	HoldReason string `gorm:"type:varchar(255)" json:"hold_reason"`
+
+	// Balance carried over to the transfer invoice of a new owner; the
+	// invoice counts as settled on this account
+	CarriedToID *uuid.UUID `gorm:"type:char(36)" json:"carried_to_id,omitempty"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// LeakDetectionConfig holds a tenant's thresholds for spotting leaks in smart
+// meter intervals. Tenants without a row use DefaultLeakDetectionConfig.
+type LeakDetectionConfig struct {
+	BaseModel
+	TenantID        uuid.UUID `gorm:"type:char(36);not null;uniqueIndex" json:"tenant_id"`
+	IsEnabled       bool      `gorm:"not null" json:"is_enabled"`
+	NightStartHour  int       `gorm:"not null" json:"night_start_hour"` // night window in local time
+	NightEndHour    int       `gorm:"not null" json:"night_end_hour"`
+	MinNightFlowLPH float64   `gorm:"type:decimal(10,2);not null" json:"min_night_flow_lph"` // lowest night flow that still counts as running water
+	LeakNights      int       `gorm:"not null" json:"leak_nights"`                           // consecutive nights without a zero-flow period
+	BaselineDays    int       `gorm:"not null" json:"baseline_days"`                         // days averaged for the usual daily usage
+	IncreasePercent float64   `gorm:"type:decimal(7,2);not null" json:"increase_percent"`
+	MinIncreaseM3   float64   `gorm:"type:decimal(10,3);not null" json:"min_increase_m3"` // smaller daily increases are never flagged
+	SustainedDays   int       `gorm:"not null" json:"sustained_days"`                     // consecutive days above the baseline
+	NotifyCustomer  bool      `gorm:"not null" json:"notify_customer"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// DefaultLeakDetectionConfig returns the thresholds used until a tenant
+// configures its own
+func DefaultLeakDetectionConfig(tenantID uuid.UUID) LeakDetectionConfig {
+	return LeakDetectionConfig{
+		TenantID:        tenantID,
+		IsEnabled:       true,
+		NightStartHour:  1,
+		NightEndHour:    5,
+		MinNightFlowLPH: 5,
+		LeakNights:      3,
+		BaselineDays:    14,
+		IncreasePercent: 100,
+		MinIncreaseM3:   0.3,
+		SustainedDays:   3,
+		NotifyCustomer:  true,
+	}
+}
@@ -17,8 +17,17 @@ type Meter struct {
 	LastCalibDate  *time.Time `gorm:"type:date" json:"last_calib_date"`
 	NextCalibDate  *time.Time `gorm:"type:date" json:"next_calib_date"`
 	InitialReading float64    `gorm:"type:decimal(10,2);default:0" json:"initial_reading"`
+	RolloverAt     float64    `gorm:"type:decimal(12,2);default:0" json:"rollover_at"` // register wraps to 0 at this value (e.g. 100000 for 5 digits), 0 = never
 	Status         string     `gorm:"type:varchar(20);default:'active';not null" json:"status"`
 	Notes          string     `gorm:"type:text" json:"notes"`
+	Latitude       *float64   `gorm:"type:decimal(10,7)" json:"latitude"` // where the meter is installed
+	Longitude      *float64   `gorm:"type:decimal(10,7)" json:"longitude"`
+
+	// Replacement
+	ReplacesID     *uuid.UUID `gorm:"type:char(36);index" json:"replaces_id"` // meter this one replaced
+	ReplacedByID   *uuid.UUID `gorm:"type:char(36)" json:"replaced_by_id"` // meter that replaced this one
+	FinalReading   *float64   `gorm:"type:decimal(10,2)" json:"final_reading"` // last reading when removed
+	RemovedAt      *time.Time `gorm:"type:date" json:"removed_at"`
 
 	// Relationships
 	Tenant      Tenant            `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
@@ -30,23 +39,31 @@ type Meter struct {
 
 type MeterIssue struct {
 	BaseModel
-	TenantID    uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_meter_issue" json:"tenant_id"`
-	MeterID     uuid.UUID  `gorm:"type:char(36);not null;index:idx_meter_issue" json:"meter_id"`
-	ReportedBy  uuid.UUID  `gorm:"type:char(36);not null" json:"reported_by"`
-	IssueType   string     `gorm:"type:varchar(50);not null" json:"issue_type"` // broken, leak, stuck, incorrect
-	Description string     `gorm:"type:text;not null" json:"description"`
-	Status      string     `gorm:"type:varchar(20);default:'open';not null" json:"status"`
-	Priority    string     `gorm:"type:varchar(20);default:'normal';not null" json:"priority"`
-	ResolvedBy  *uuid.UUID `gorm:"type:char(36)" json:"resolved_by"`
-	ResolvedAt  *time.Time `gorm:"type:datetime" json:"resolved_at"`
-	Resolution  string     `gorm:"type:text" json:"resolution"`
-	PhotoURL    string     `gorm:"type:varchar(500)" json:"photo_url"`
+	TenantID             uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_meter_issue" json:"tenant_id"`
+	MeterID              uuid.UUID  `gorm:"type:char(36);not null;index:idx_meter_issue" json:"meter_id"`
+	ReportedBy           *uuid.UUID `gorm:"type:char(36)" json:"reported_by"`             // staff reporter
+	ReportedByCustomerID *uuid.UUID `gorm:"type:char(36)" json:"reported_by_customer_id"` // customer reporter (self-service)
+	IssueType            string     `gorm:"type:varchar(50);not null" json:"issue_type"`  // broken, leak, stuck, incorrect
+	Description          string     `gorm:"type:text;not null" json:"description"`
+	Status               string     `gorm:"type:varchar(20);default:'open';not null" json:"status"`
+	Priority             string     `gorm:"type:varchar(20);default:'normal';not null" json:"priority"`
+	AssignedTo           *uuid.UUID `gorm:"type:char(36);index" json:"assigned_to"`
+	AssignedAt           *time.Time `gorm:"type:datetime" json:"assigned_at"`
+	DueAt                time.Time  `gorm:"type:datetime;index" json:"due_at"` // SLA deadline, from priority
+	StartedAt            *time.Time `gorm:"type:datetime" json:"started_at"`
+	ResolvedBy           *uuid.UUID `gorm:"type:char(36)" json:"resolved_by"`
+	ResolvedAt           *time.Time `gorm:"type:datetime" json:"resolved_at"`
+	Resolution           string     `gorm:"type:text" json:"resolution"`
+	ClosedAt             *time.Time `gorm:"type:datetime" json:"closed_at"`
+	PhotoURL             string     `gorm:"type:varchar(500)" json:"photo_url"`
 
 	// Relationships
-	Tenant   Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
-	Meter    Meter    `gorm:"foreignKey:MeterID;constraint:OnDelete:CASCADE" json:"meter"`
-	Reporter User     `gorm:"foreignKey:ReportedBy" json:"reporter"`
-	Resolver *User    `gorm:"foreignKey:ResolvedBy" json:"resolver,omitempty"`
+	Tenant            Tenant    `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Meter             Meter     `gorm:"foreignKey:MeterID;constraint:OnDelete:CASCADE" json:"meter"`
+	Reporter          *User     `gorm:"foreignKey:ReportedBy" json:"reporter,omitempty"`
+	ReportingCustomer *Customer `gorm:"foreignKey:ReportedByCustomerID" json:"reporting_customer,omitempty"`
+	Assignee          *User     `gorm:"foreignKey:AssignedTo" json:"assignee,omitempty"`
+	Resolver          *User     `gorm:"foreignKey:ResolvedBy" json:"resolver,omitempty"`
 }
 
 type MeterHistory struct {
@@ -67,6 +84,18 @@ type MeterHistory struct {
 	User      User     `gorm:"foreignKey:PerformedBy" json:"user"`
 }
 
+// Meter history actions
+const (
+	MeterActionInstall       = "install"
+	MeterActionReplace       = "replace"
+	MeterActionRemove        = "remove"
+	MeterActionCalibrate     = "calibrate"
+	MeterActionStatusChange  = "status_change"
+	MeterActionUpdate        = "update"
+	MeterActionIssueResolved = "issue_resolved"
+	MeterActionTransfer      = "transfer"
+)
+
 // Meter status
 const (
 	MeterStatusActive    = "active"
@@ -99,3 +128,40 @@ const (
 	MeterIssuePriorityHigh     = "high"
 	MeterIssuePriorityCritical = "critical"
 )
+
+// MeterIssueSLA is the time allowed from report to resolution per priority
+var MeterIssueSLA = map[string]time.Duration{
+	MeterIssuePriorityCritical: 4 * time.Hour,
+	MeterIssuePriorityHigh:     24 * time.Hour,
+	MeterIssuePriorityNormal:   3 * 24 * time.Hour,
+	MeterIssuePriorityLow:      7 * 24 * time.Hour,
+}
+
+// meterIssueTransitions maps each issue status to the status it may move to next
+var meterIssueTransitions = map[string]string{
+	MeterIssueStatusOpen:       MeterIssueStatusInProgress,
+	MeterIssueStatusInProgress: MeterIssueStatusResolved,
+	MeterIssueStatusResolved:   MeterIssueStatusClosed,
+}
+
+// CanTransitionTo reports whether the issue may move to status
+func (issue *MeterIssue) CanTransitionTo(status string) bool {
+	return meterIssueTransitions[issue.Status] == status
+}
+
+// SetDueAt sets the SLA deadline from the report time and priority
+func (issue *MeterIssue) SetDueAt() {
+	reportedAt := issue.CreatedAt
+	if reportedAt.IsZero() {
+		reportedAt = time.Now()
+	}
+	issue.DueAt = reportedAt.Add(MeterIssueSLA[issue.Priority])
+}
+
+// IsOverdue reports whether an unresolved issue has passed its SLA deadline
+func (issue *MeterIssue) IsOverdue() bool {
+	if issue.ResolvedAt != nil {
+		return issue.ResolvedAt.After(issue.DueAt)
+	}
+	return time.Now().After(issue.DueAt)
+}
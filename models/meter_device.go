@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MeterDevice is a smart meter (AMR/AMI) or telemetry module that pushes
+// readings of one Meter. Devices authenticate with their own key; only the
+// key's prefix and hash are stored.
+type MeterDevice struct {
+	BaseModel
+	TenantID     uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	MeterID      uuid.UUID `gorm:"type:char(36);not null;index" json:"meter_id"`
+	Name         string    `gorm:"type:varchar(100)" json:"name"`
+	SerialNumber string    `gorm:"type:varchar(100)" json:"serial_number"`
+	KeyPrefix    string    `gorm:"type:varchar(16);not null;uniqueIndex" json:"key_prefix"` // identifies the key, shown to staff
+	KeyHash      string    `gorm:"type:char(64);not null" json:"-"`
+	IsActive     bool      `gorm:"default:true" json:"is_active"`
+
+	// Pulse counters report a pulse count that is converted to a register value
+	LitersPerPulse float64 `gorm:"type:decimal(10,4);default:0" json:"liters_per_pulse"` // 0 = device reports m³
+	PulseOffset    float64 `gorm:"type:decimal(12,3);default:0" json:"pulse_offset"`     // register value at pulse count 0
+
+	LastSeenAt    *time.Time `gorm:"type:datetime" json:"last_seen_at"`
+	LastReadingAt *time.Time `gorm:"type:datetime" json:"last_reading_at"`
+	LastReading   *float64   `gorm:"type:decimal(12,3)" json:"last_reading"`
+	CreatedBy     *uuid.UUID `gorm:"type:char(36)" json:"created_by"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Meter  Meter  `gorm:"foreignKey:MeterID;constraint:OnDelete:CASCADE" json:"meter"`
+}
+
+// RegisterValue returns the register value in m³ of a device report, which
+// carries either the value itself or a pulse count
+func (device *MeterDevice) RegisterValue(reading *float64, pulses *int64) (float64, error) {
+	switch {
+	case reading != nil:
+		return *reading, nil
+	case pulses == nil:
+		return 0, errors.New("reading or pulses is required")
+	case device.LitersPerPulse <= 0:
+		return 0, errors.New("device is not configured for pulse counts")
+	default:
+		return device.PulseOffset + float64(*pulses)*device.LitersPerPulse/1000, nil
+	}
+}
+
+// MeterInterval is a raw register value reported by a device. Intervals are
+// unique per meter and device timestamp, so resent batches are ignored; the
+// last interval of a month becomes the meter's monthly WaterUsage.
+type MeterInterval struct {
+	BaseModel
+	TenantID   uuid.UUID `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	MeterID    uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_meter_interval_time" json:"meter_id"`
+	DeviceID   uuid.UUID `gorm:"type:char(36);not null;index" json:"device_id"`
+	RecordedAt time.Time `gorm:"type:datetime;not null;uniqueIndex:idx_meter_interval_time" json:"recorded_at"` // device timestamp
+	Reading    float64   `gorm:"type:decimal(12,3);not null" json:"reading"`                                    // register value in m³
+
+	// Relationships
+	Tenant Tenant      `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Meter  Meter       `gorm:"foreignKey:MeterID;constraint:OnDelete:CASCADE" json:"-"`
+	Device MeterDevice `gorm:"foreignKey:DeviceID;constraint:OnDelete:CASCADE" json:"-"`
+}
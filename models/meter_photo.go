@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MeterPhoto is a photo of a meter register taken when reading it. Photos are
+// uploaded first, directly or through a pre-signed URL, and then attached to
+// the reading they document.
+type MeterPhoto struct {
+	BaseModel
+	TenantID         uuid.UUID  `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	WaterUsageID     *uuid.UUID `gorm:"type:char(36);index" json:"water_usage_id"`
+	UploadedBy       *uuid.UUID `gorm:"type:char(36)" json:"uploaded_by"`
+	Status           string     `gorm:"type:varchar(20);not null" json:"status"` // awaiting_upload, uploaded
+	FilePath         string     `gorm:"type:varchar(500)" json:"-"`
+	ThumbnailPath    string     `gorm:"type:varchar(500)" json:"-"`
+	ContentType      string     `gorm:"type:varchar(50)" json:"content_type"`
+	Size             int64      `json:"size"`
+	UploadExpiresAt  *time.Time `gorm:"type:datetime" json:"upload_expires_at,omitempty"` // pre-signed uploads only
+	TakenAt          *time.Time `gorm:"type:datetime" json:"taken_at"`                    // EXIF capture time
+	Latitude         *float64   `gorm:"type:decimal(10,7)" json:"latitude"`               // EXIF GPS
+	Longitude        *float64   `gorm:"type:decimal(10,7)" json:"longitude"`
+	Verified         bool       `gorm:"default:false" json:"verified"` // EXIF time matches the reading
+	VerificationNote string     `gorm:"type:varchar(255)" json:"verification_note"`
+
+	// Relationships
+	Tenant     Tenant      `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	WaterUsage *WaterUsage `gorm:"foreignKey:WaterUsageID" json:"-"`
+	Uploader   *User       `gorm:"foreignKey:UploadedBy" json:"uploader,omitempty"`
+}
+
+// Meter photo status
+const (
+	MeterPhotoAwaitingUpload = "awaiting_upload"
+	MeterPhotoUploaded       = "uploaded"
+)
+
+// URL is the API path serving the photo
+func (photo *MeterPhoto) URL() string {
+	return "/api/meter-photos/" + photo.ID.String() + "/file"
+}
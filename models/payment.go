@@ -27,14 +27,44 @@ type Payment struct {
 	VerifiedBy      *uuid.UUID     `gorm:"type:char(36)" json:"verified_by"`
 	VerifiedAt      *time.Time     `gorm:"type:datetime" json:"verified_at"`
 	Status          string         `gorm:"type:varchar(20);default:'completed';not null" json:"status"`
+	CashSessionID   *uuid.UUID     `gorm:"type:char(36);index" json:"cash_session_id,omitempty"`
+	ReceiptNumber   string         `gorm:"type:varchar(50);index" json:"receipt_number,omitempty"`
+
+	// Reversal tracking. A reversal is stored as its own negative payment row
+	// linked to the original, so SUM(amount) per invoice stays correct.
+	Type           string     `gorm:"type:varchar(20);default:'payment';not null" json:"type"`
+	ReversalOfID   *uuid.UUID `gorm:"type:char(36);index" json:"reversal_of_id,omitempty"`
+	ReversalReason string     `gorm:"type:text" json:"reversal_reason,omitempty"`
+	ReversedAt     *time.Time `gorm:"type:datetime" json:"reversed_at,omitempty"`
+	ReversedBy     *uuid.UUID `gorm:"type:char(36)" json:"reversed_by,omitempty"`
 
 	BaseModel
 }
 
+// Payment status
+const (
+	PaymentStatusCompleted = "completed"
+	PaymentStatusReversed  = "reversed"
+)
+
+// Payment types
+const (
+	PaymentTypePayment  = "payment"
+	PaymentTypeReversal = "reversal"
+)
+
+// Reversal dispositions: what happens to the money of a reversed payment
+const (
+	ReversalDispositionReturned = "returned" // bounced / never received
+	ReversalDispositionCredit   = "credit"   // kept as customer credit
+)
+
 func (p *Payment) BeforeCreate(tx *gorm.DB) (err error) {
 	if err = p.BaseModel.BeforeCreate(tx); err != nil {
 		return
 	}
-	p.PaidAt = time.Now()
+	if p.PaidAt.IsZero() {
+		p.PaidAt = time.Now()
+	}
 	return
 }
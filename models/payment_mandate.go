@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentMandate is a customer's standing instruction to have bills charged
+// automatically through an external payment provider.
+type PaymentMandate struct {
+	BaseModel
+	TenantID            uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_mandate" json:"tenant_id"`
+	CustomerID          uuid.UUID  `gorm:"type:char(36);not null;index" json:"customer_id"`
+	Provider            string     `gorm:"type:varchar(50);not null" json:"provider"`
+	AccountToken        string     `gorm:"type:varchar(255);not null" json:"-"`            // provider token, never returned
+	AccountMask         string     `gorm:"type:varchar(50)" json:"account_mask"`           // e.g. ****1234, for display
+	MaxAmount           float64    `gorm:"type:decimal(15,2);default:0" json:"max_amount"` // per charge limit, 0 = unlimited
+	PaymentMethodID     *uuid.UUID `gorm:"type:char(36)" json:"payment_method_id"`
+	Status              string     `gorm:"type:varchar(20);default:'active';not null;index" json:"status"`
+	ConsecutiveFailures int        `gorm:"default:0" json:"consecutive_failures"`
+	LastAttemptAt       *time.Time `gorm:"type:datetime" json:"last_attempt_at"`
+	RevokedAt           *time.Time `gorm:"type:datetime" json:"revoked_at"`
+	Notes               string     `gorm:"type:text" json:"notes"`
+	CreatedBy           *uuid.UUID `gorm:"type:char(36)" json:"created_by"`
+
+	// Relationships
+	Tenant        Tenant         `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Customer      Customer       `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE" json:"customer"`
+	PaymentMethod *PaymentMethod `gorm:"foreignKey:PaymentMethodID" json:"payment_method,omitempty"`
+}
+
+// AutoDebitAttempt records one try to collect an invoice through a mandate
+type AutoDebitAttempt struct {
+	BaseModel
+	TenantID      uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_auto_debit" json:"tenant_id"`
+	MandateID     uuid.UUID  `gorm:"type:char(36);not null;index" json:"mandate_id"`
+	InvoiceID     uuid.UUID  `gorm:"type:char(36);not null;index" json:"invoice_id"`
+	CustomerID    uuid.UUID  `gorm:"type:char(36);not null;index" json:"customer_id"`
+	Amount        float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Status        string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Reference     string     `gorm:"type:varchar(100);uniqueIndex" json:"reference"`
+	ProviderRef   string     `gorm:"type:varchar(255)" json:"provider_ref"`
+	FailureReason string     `gorm:"type:text" json:"failure_reason"`
+	PaymentID     *uuid.UUID `gorm:"type:char(36)" json:"payment_id"`
+	AttemptedAt   time.Time  `gorm:"type:datetime;not null" json:"attempted_at"`
+
+	// Relationships
+	Tenant  Tenant         `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Mandate PaymentMandate `gorm:"foreignKey:MandateID;constraint:OnDelete:CASCADE" json:"-"`
+	Invoice Invoice        `gorm:"foreignKey:InvoiceID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Mandate statuses
+const (
+	MandateStatusActive    = "active"
+	MandateStatusSuspended = "suspended" // paused by staff or after repeated failures
+	MandateStatusRevoked   = "revoked"
+)
+
+// Auto-debit attempt statuses
+const (
+	AutoDebitPending   = "pending" // charge sent, outcome not yet known
+	AutoDebitSucceeded = "succeeded"
+	AutoDebitFailed    = "failed"
+	AutoDebitSkipped   = "skipped"   // not charged, e.g. amount above the mandate limit
+	AutoDebitAbandoned = "abandoned" // outcome never confirmed by the provider, left for manual follow-up
+)
+
+// AutoDebitRun is a queued collection run, picked up by the auto-debit
+// scheduler. A run with an InvoiceID collects only that invoice.
+type AutoDebitRun struct {
+	BaseModel
+	TenantID   uuid.UUID  `gorm:"type:char(36);not null;index" json:"tenant_id"`
+	UsageMonth string     `gorm:"type:varchar(7);not null" json:"usage_month"`
+	InvoiceID  *uuid.UUID `gorm:"type:char(36)" json:"invoice_id"`
+	Status     string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts   int        `gorm:"default:0" json:"attempts"`
+	Error      string     `gorm:"type:text" json:"error"`
+	StartedAt  *time.Time `gorm:"type:datetime" json:"started_at"`
+	FinishedAt *time.Time `gorm:"type:datetime" json:"finished_at"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Auto-debit run statuses
+const (
+	AutoDebitRunQueued   = "queued"
+	AutoDebitRunRunning  = "running"
+	AutoDebitRunFinished = "finished"
+	AutoDebitRunFailed   = "failed" // gave up after repeated errors
+)
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrReceiptImmutable is returned when an issued receipt would be changed
+var ErrReceiptImmutable = errors.New("receipt has been issued and cannot be changed")
+
+// PaymentReceipt is the archived copy of a receipt exactly as it was issued.
+// It is not linked to the payment by a foreign key and cannot be updated or
+// deleted, so it survives later edits to the customer, invoice or payment.
+type PaymentReceipt struct {
+	BaseModel
+	TenantID      uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_tenant_receipt_number" json:"tenant_id"`
+	PaymentID     uuid.UUID `gorm:"type:char(36);not null;uniqueIndex" json:"payment_id"`
+	ReceiptNumber string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_tenant_receipt_number" json:"receipt_number"`
+	Year          int       `gorm:"not null" json:"year"`
+	Sequence      int64     `gorm:"not null" json:"sequence"`
+	IssuedAt      time.Time `gorm:"type:datetime;not null" json:"issued_at"`
+	Snapshot      string    `gorm:"type:json;not null" json:"snapshot"`     // ReceiptSnapshot as JSON
+	Checksum      string    `gorm:"type:char(64);not null" json:"checksum"` // SHA-256 of Snapshot
+}
+
+// ReceiptSequence holds the last receipt number issued per tenant and year
+type ReceiptSequence struct {
+	BaseModel
+	TenantID   uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_tenant_receipt_year" json:"tenant_id"`
+	Year       int       `gorm:"not null;uniqueIndex:idx_tenant_receipt_year" json:"year"`
+	LastNumber int64     `gorm:"not null;default:0" json:"last_number"`
+}
+
+// ReceiptSnapshot is everything printed on a receipt
+type ReceiptSnapshot struct {
+	ReceiptNumber string    `json:"receipt_number"`
+	IssuedAt      time.Time `json:"issued_at"`
+	PaidAt        time.Time `json:"paid_at"`
+
+	Tenant struct {
+		Name    string `json:"name"`
+		Address string `json:"address,omitempty"`
+		Phone   string `json:"phone,omitempty"`
+	} `json:"tenant"`
+
+	Customer struct {
+		ID          uuid.UUID `json:"id"`
+		Name        string    `json:"name"`
+		MeterNumber string    `json:"meter_number"`
+		Address     string    `json:"address,omitempty"`
+	} `json:"customer"`
+
+	Invoices []ReceiptInvoiceLine `json:"invoices"`
+
+	PaymentID       uuid.UUID  `json:"payment_id"`
+	Amount          float64    `json:"amount"`
+	Penalty         float64    `json:"penalty"`
+	Total           float64    `json:"total"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id,omitempty"`
+	PaymentMethod   string     `json:"payment_method"`
+	ReferenceNumber string     `json:"reference_number,omitempty"`
+	CollectorID     *uuid.UUID `json:"collector_id,omitempty"`
+	CollectorName   string     `json:"collector_name,omitempty"`
+	Notes           string     `json:"notes,omitempty"`
+}
+
+// ReceiptInvoiceLine is an invoice covered by a receipt
+type ReceiptInvoiceLine struct {
+	InvoiceID   uuid.UUID `json:"invoice_id"`
+	Type        string    `json:"type"`
+	UsageMonth  string    `json:"usage_month"`
+	UsageM3     float64   `json:"usage_m3"`
+	TotalAmount float64   `json:"total_amount"`
+	PaidBefore  float64   `json:"paid_before"`
+	PaidNow     float64   `json:"paid_now"`
+	Remaining   float64   `json:"remaining"`
+}
+
+func (PaymentReceipt) BeforeUpdate(tx *gorm.DB) error {
+	return ErrReceiptImmutable
+}
+
+func (PaymentReceipt) BeforeDelete(tx *gorm.DB) error {
+	return ErrReceiptImmutable
+}
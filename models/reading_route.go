@@ -25,19 +25,31 @@ type ReadingRoute struct {
 	Sessions     []ReadingSession      `gorm:"foreignKey:RouteID" json:"-"`
 }
 
+// ScheduledOn reports whether the route is read on date. A ScheduleDay past
+// the end of a short month falls on the month's last day.
+func (route *ReadingRoute) ScheduledOn(date time.Time) bool {
+	if route.ScheduleDay == date.Day() {
+		return true
+	}
+	lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	return date.Day() == lastDay && route.ScheduleDay > lastDay
+}
+
 type ReadingSession struct {
 	BaseModel
-	TenantID        uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_reading_session" json:"tenant_id"`
-	RouteID         uuid.UUID  `gorm:"type:char(36);not null;index:idx_route_session" json:"route_id"`
-	ReaderID        uuid.UUID  `gorm:"type:char(36);not null" json:"reader_id"`
-	ScheduledDate   time.Time  `gorm:"type:date;not null" json:"scheduled_date"`
-	StartTime       *time.Time `gorm:"type:datetime" json:"start_time"`
-	EndTime         *time.Time `gorm:"type:datetime" json:"end_time"`
-	Status          string     `gorm:"type:varchar(20);default:'scheduled';not null" json:"status"`
-	TotalCustomers  int        `gorm:"default:0" json:"total_customers"`
-	CompletedCount  int        `gorm:"default:0" json:"completed_count"`
-	AnomalyCount    int        `gorm:"default:0" json:"anomaly_count"`
-	Notes           string     `gorm:"type:text" json:"notes"`
+	TenantID          uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_reading_session" json:"tenant_id"`
+	RouteID           uuid.UUID  `gorm:"type:char(36);not null;index:idx_route_session" json:"route_id"`
+	ReaderID          uuid.UUID  `gorm:"type:char(36);not null" json:"reader_id"`
+	ScheduledDate     time.Time  `gorm:"type:date;not null" json:"scheduled_date"`
+	UsageMonth        string     `gorm:"type:varchar(7);index" json:"usage_month"`  // month being read, YYYY-MM
+	CarriedOverFromID *uuid.UUID `gorm:"type:char(36)" json:"carried_over_from_id"` // session whose unread stops this one continues
+	StartTime         *time.Time `gorm:"type:datetime" json:"start_time"`
+	EndTime           *time.Time `gorm:"type:datetime" json:"end_time"`
+	Status            string     `gorm:"type:varchar(20);default:'scheduled';not null" json:"status"`
+	TotalCustomers    int        `gorm:"default:0" json:"total_customers"`
+	CompletedCount    int        `gorm:"default:0" json:"completed_count"`
+	AnomalyCount      int        `gorm:"default:0" json:"anomaly_count"`
+	Notes             string     `gorm:"type:text" json:"notes"`
 
 	// Relationships
 	Tenant  Tenant        `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
@@ -50,20 +62,22 @@ type ReadingAnomaly struct {
 	BaseModel
 	TenantID       uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_anomaly" json:"tenant_id"`
 	WaterUsageID   uuid.UUID  `gorm:"type:char(36);not null;index:idx_usage_anomaly" json:"water_usage_id"`
-	AnomalyType    string     `gorm:"type:varchar(50);not null" json:"anomaly_type"` // high_usage, low_usage, no_usage, negative
+	AnomalyType    string     `gorm:"type:varchar(50);not null" json:"anomaly_type"` // high_usage, low_usage, no_usage, negative, stuck
 	ExpectedValue  float64    `gorm:"type:decimal(10,2)" json:"expected_value"`
 	ActualValue    float64    `gorm:"type:decimal(10,2)" json:"actual_value"`
 	Deviation      float64    `gorm:"type:decimal(10,2)" json:"deviation"`
 	Status         string     `gorm:"type:varchar(20);default:'pending';not null" json:"status"`
 	ResolvedBy     *uuid.UUID `gorm:"type:char(36)" json:"resolved_by"`
 	ResolvedAt     *time.Time `gorm:"type:datetime" json:"resolved_at"`
-	Resolution     string     `gorm:"type:text" json:"resolution"`
+	Resolution     string     `gorm:"type:text" json:"resolution"` // confirm, correct, estimate, raise_issue
+	MeterIssueID   *uuid.UUID `gorm:"type:char(36)" json:"meter_issue_id"` // issue raised during review
 	Notes          string     `gorm:"type:text" json:"notes"`
 
 	// Relationships
 	Tenant     Tenant      `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
 	WaterUsage WaterUsage  `gorm:"foreignKey:WaterUsageID;constraint:OnDelete:CASCADE" json:"water_usage"`
 	Resolver   *User       `gorm:"foreignKey:ResolvedBy" json:"resolver,omitempty"`
+	MeterIssue *MeterIssue `gorm:"foreignKey:MeterIssueID" json:"meter_issue,omitempty"`
 }
 
 // Reading session status
@@ -15,9 +15,10 @@ type ServiceArea struct {
 	IsActive    bool      `gorm:"default:true;not null" json:"is_active"`
 
 	// Additional information
-	Population    int    `gorm:"default:0" json:"population"`
-	CustomerCount int    `gorm:"default:0" json:"customer_count"`
-	CoverageArea  string `gorm:"type:varchar(200)" json:"coverage_area"`
+	Population    int     `gorm:"default:0" json:"population"`
+	CustomerCount int     `gorm:"default:0" json:"customer_count"`
+	CoverageArea  string  `gorm:"type:varchar(200)" json:"coverage_area"`
+	Boundary      *string `gorm:"type:json" json:"-"` // GeoJSON Polygon or MultiPolygon
 
 	// Relationships
 	Tenant    Tenant         `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServiceOrder is a field work order to disconnect a customer in arrears or
+// to reconnect one who has paid. Completing the order moves the customer to
+// disconnected or back to active.
+type ServiceOrder struct {
+	BaseModel
+	TenantID        uuid.UUID  `gorm:"type:char(36);not null;index:idx_tenant_service_order" json:"tenant_id"`
+	CustomerID      uuid.UUID  `gorm:"type:char(36);not null;index" json:"customer_id"`
+	MeterID         *uuid.UUID `gorm:"type:char(36)" json:"meter_id"`
+	OrderType       string     `gorm:"type:varchar(20);not null;index:idx_tenant_service_order" json:"order_type"` // disconnection, reconnection
+	Status          string     `gorm:"type:varchar(20);default:'open';not null;index:idx_tenant_service_order" json:"status"`
+	Reason          string     `gorm:"type:text;not null" json:"reason"`
+	OverdueInvoices int        `gorm:"default:0" json:"overdue_invoices"` // arrears when the order was raised
+	ArrearsAmount   float64    `gorm:"type:decimal(15,2);default:0" json:"arrears_amount"`
+	CreatedBy       *uuid.UUID `gorm:"type:char(36)" json:"created_by"` // nil when raised automatically
+	AssignedTo      *uuid.UUID `gorm:"type:char(36);index" json:"assigned_to"`
+	AssignedAt      *time.Time `gorm:"type:datetime" json:"assigned_at"`
+	InvoiceID       *uuid.UUID `gorm:"type:char(36)" json:"invoice_id"` // reconnection fee
+
+	// Completion
+	CompletedBy  *uuid.UUID `gorm:"type:char(36)" json:"completed_by"`
+	CompletedAt  *time.Time `gorm:"type:datetime" json:"completed_at"`
+	MeterReading *float64   `gorm:"type:decimal(10,2)" json:"meter_reading"` // register when the water was cut off or restored
+	WaterUsageID *uuid.UUID `gorm:"type:char(36)" json:"water_usage_id"`     // reading recorded for the month, if any
+	Notes        string     `gorm:"type:text" json:"notes"`
+	CancelReason string     `gorm:"type:text" json:"cancel_reason"`
+
+	// Relationships
+	Tenant   Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Customer Customer `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE" json:"-"`
+	Meter    *Meter   `gorm:"foreignKey:MeterID" json:"-"`
+	Assignee *User    `gorm:"foreignKey:AssignedTo" json:"-"`
+}
+
+// Service order types
+const (
+	ServiceOrderDisconnection = "disconnection"
+	ServiceOrderReconnection  = "reconnection"
+)
+
+// Service order status
+const (
+	ServiceOrderOpen      = "open"
+	ServiceOrderCompleted = "completed"
+	ServiceOrderCancelled = "cancelled"
+)
+
+// DisconnectionPolicy holds a tenant's arrears thresholds for raising
+// disconnection orders. Tenants without a row use DefaultDisconnectionPolicy.
+type DisconnectionPolicy struct {
+	BaseModel
+	TenantID         uuid.UUID `gorm:"type:char(36);not null;uniqueIndex" json:"tenant_id"`
+	IsEnabled        bool      `gorm:"not null" json:"is_enabled"`
+	GraceDays        int       `gorm:"not null" json:"grace_days"`                            // days after billing before an invoice is overdue
+	MinOverdueMonths int       `gorm:"not null" json:"min_overdue_months"`                    // overdue usage invoices that trigger an order, 0 = not used
+	MinArrearsAmount float64   `gorm:"type:decimal(15,2);not null" json:"min_arrears_amount"` // overdue amount that triggers an order, 0 = not used
+	NotifyCustomer   bool      `gorm:"not null" json:"notify_customer"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// DefaultDisconnectionPolicy returns the thresholds used until a tenant
+// configures its own. Enforcement is off until the tenant turns it on.
+func DefaultDisconnectionPolicy(tenantID uuid.UUID) DisconnectionPolicy {
+	return DisconnectionPolicy{
+		TenantID:         tenantID,
+		IsEnabled:        false,
+		GraceDays:        30,
+		MinOverdueMonths: 3,
+		MinArrearsAmount: 0,
+		NotifyCustomer:   true,
+	}
+}
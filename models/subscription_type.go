@@ -14,5 +14,6 @@ type SubscriptionType struct {
 	MaintenanceFee  float64   `json:"maintenance_fee"`  // Opsional
 	LateFeePerDay   float64   `json:"late_fee_per_day"` // Denda
 	MaxLateFee      float64   `json:"max_late_fee"`     // Batas maksimal denda
+	ReconnectionFee float64   `json:"reconnection_fee"` // Biaya penyambungan kembali
 	TenantID        uuid.UUID `gorm:"type:char(36);index" json:"tenant_id"`
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SyncItem records the outcome of an item captured offline and pushed through
+// the sync endpoint, keyed by the client-generated ID so retries are idempotent.
+type SyncItem struct {
+	BaseModel
+	TenantID     uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_tenant_sync_client" json:"tenant_id"`
+	ClientID     string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_tenant_sync_client" json:"client_id"`
+	DeviceID     string     `gorm:"type:varchar(100);index" json:"device_id"`
+	ItemType     string     `gorm:"type:varchar(20);not null" json:"item_type"` // payment, reading
+	CapturedAt   time.Time  `gorm:"type:datetime;not null" json:"captured_at"`
+	Status       string     `gorm:"type:varchar(20);not null" json:"status"` // applied, conflict
+	ResourceID   *uuid.UUID `gorm:"type:char(36)" json:"resource_id"`
+	ConflictCode string     `gorm:"type:varchar(50)" json:"conflict_code"`
+	Message      string     `gorm:"type:varchar(255)" json:"message"`
+	SubmittedBy  *uuid.UUID `gorm:"type:char(36)" json:"submitted_by"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Sync item types
+const (
+	SyncItemPayment = "payment"
+	SyncItemReading = "reading"
+)
+
+// Sync item statuses
+const (
+	SyncStatusApplied   = "applied"
+	SyncStatusConflict  = "conflict"
+	SyncStatusError     = "error"     // transient, not stored so the device can retry
+	SyncStatusDuplicate = "duplicate" // client_id already processed
+)
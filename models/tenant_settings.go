@@ -48,6 +48,9 @@ type TenantSettings struct {
 	Language        string `gorm:"type:varchar(10);default:'id'" json:"language"`
 	Currency        string `gorm:"type:varchar(3);default:'IDR'" json:"currency"`
 	
+	// Meter Reading Policy
+	RequireReadingPhoto bool `gorm:"default:false" json:"require_reading_photo"` // manual readings must attach a meter photo
+	
 	// Additional Settings (JSON for flexible configuration)
 	CustomSettings string `gorm:"type:json" json:"custom_settings"`
 	
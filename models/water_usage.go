@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -23,13 +25,22 @@ type WaterUsage struct {
 	Recorder          *User             `gorm:"foreignKey:RecordedBy" json:"recorder,omitempty"`
 	PhotoURL          string            `gorm:"type:varchar(500)" json:"photo_url"`
 	ReadingMethod     string            `gorm:"type:varchar(20);default:'manual'" json:"reading_method"` // manual, automatic, estimated
+	ReadAt            *time.Time        `gorm:"type:datetime" json:"read_at"`                            // when the meter was actually read
 	Notes             string            `gorm:"type:text" json:"notes"`
 	IsAnomaly         bool              `gorm:"default:false" json:"is_anomaly"`
+	IsRollover        bool              `gorm:"default:false" json:"is_rollover"` // register wrapped past its maximum
 	AnomalyDetails    *ReadingAnomaly   `gorm:"foreignKey:WaterUsageID" json:"anomaly_details,omitempty"`
 
 	BaseModel
 }
 
+// Reading methods
+const (
+	ReadingMethodManual    = "manual"
+	ReadingMethodAutomatic = "automatic"
+	ReadingMethodEstimated = "estimated"
+)
+
 // TableName overrides the table name for GORM
 func (WaterUsage) TableName() string {
 	return "water_usages"
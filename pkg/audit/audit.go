@@ -129,8 +129,13 @@ func (s *AuditService) Log(c *gin.Context, entry AuditEntry) error {
 		CreatedAt:    time.Now(),
 	}
 
-	// Save to database
-	if err := s.db.Create(&auditLog).Error; err != nil {
+	// Save to database. The global service is created during package init,
+	// before config.ConnectDB runs, so fall back to the live connection.
+	db := s.db
+	if db == nil {
+		db = config.DB
+	}
+	if err := db.Create(&auditLog).Error; err != nil {
 		logger.Error("Failed to create audit log", err, map[string]interface{}{
 			"audit_entry": entry,
 			"tenant_id":   tenantID,
@@ -263,6 +268,42 @@ func LogPayment(c *gin.Context, invoiceID, paymentID uuid.UUID, amount float64,
 	})
 }
 
+// LogPaymentReversal audits the reversal of a payment
+func LogPaymentReversal(c *gin.Context, invoiceID, paymentID, reversalID uuid.UUID, amount float64, reason, disposition string) {
+	auditService.Log(c, AuditEntry{
+		Action:      models.ActionPaymentReversal,
+		Resource:    "payment",
+		ResourceID:  &paymentID,
+		Level:       models.LevelCritical,
+		Description: "Payment reversed",
+		Success:     true,
+		Metadata: map[string]interface{}{
+			"invoice_id":  invoiceID,
+			"reversal_id": reversalID,
+			"amount":      amount,
+			"reason":      reason,
+			"disposition": disposition,
+		},
+	})
+}
+
+// LogRefund audits a refund paid out of customer credit
+func LogRefund(c *gin.Context, customerID, creditID uuid.UUID, amount float64, reason string) {
+	auditService.Log(c, AuditEntry{
+		Action:      models.ActionRefund,
+		Resource:    "customer_credit",
+		ResourceID:  &creditID,
+		Level:       models.LevelCritical,
+		Description: "Customer credit refunded",
+		Success:     true,
+		Metadata: map[string]interface{}{
+			"customer_id": customerID,
+			"amount":      amount,
+			"reason":      reason,
+		},
+	})
+}
+
 // LogPasswordChange audits password changes
 func LogPasswordChange(c *gin.Context, userType string, userID uuid.UUID, success bool) {
 	level := models.LevelWarning
@@ -0,0 +1,543 @@
+// Package autodebit collects unpaid bills from customers with an active
+// payment mandate. Runs are queued after each bill run and after a held
+// invoice is released, and worked off by the scheduler; they can also be
+// run manually. Invoices that cannot be collected stay open for normal
+// dunning.
+package autodebit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"github.com/adipras/tirta-saas-backend/pkg/paymentprovider"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MaxAttemptsPerInvoice stops retrying an invoice after this many failed charges
+	MaxAttemptsPerInvoice = 3
+	// MaxConsecutiveFailures suspends a mandate after this many failed charges in a row
+	MaxConsecutiveFailures = 3
+	// MaxRunAttempts marks a queued run failed after this many errors
+	MaxRunAttempts = 3
+
+	runBatchSize = 100
+
+	// PendingCheckAfter is how old a pending attempt must be before its
+	// outcome is looked up with the provider, well past chargeTimeout
+	PendingCheckAfter = 15 * time.Minute
+	// PendingExpiry abandons a pending attempt whose outcome is still unknown
+	PendingExpiry = 72 * time.Hour
+
+	chargeTimeout = 30 * time.Second
+)
+
+// Summary reports the outcome of one run
+type Summary struct {
+	UsageMonth      string  `json:"usage_month"`
+	Invoices        int     `json:"invoices"`
+	Succeeded       int     `json:"succeeded"`
+	Failed          int     `json:"failed"`
+	Skipped         int     `json:"skipped"`
+	Pending         int     `json:"pending"` // provider outcome unknown, needs checking before retry
+	AmountCollected float64 `json:"amount_collected"`
+}
+
+var errAttemptExists = errors.New("attempt already in progress or collected")
+
+// Enqueue queues a run for the scheduler. With invoiceID set only that
+// invoice is collected, e.g. after it is released from an anomaly hold.
+func Enqueue(db *gorm.DB, tenantID uuid.UUID, usageMonth string, invoiceID *uuid.UUID) error {
+	return db.Create(&models.AutoDebitRun{
+		TenantID:   tenantID,
+		UsageMonth: usageMonth,
+		InvoiceID:  invoiceID,
+		Status:     models.AutoDebitRunQueued,
+	}).Error
+}
+
+// StartScheduler works through queued runs and reconciles pending attempts
+// now and then once per interval.
+// Runs left running by a stopped process are queued again first; attempts
+// already reserved for an invoice keep it from being charged twice. It
+// blocks, so start it in a goroutine.
+func StartScheduler(interval time.Duration) {
+	if err := config.DB.Model(&models.AutoDebitRun{}).Where("status = ?", models.AutoDebitRunRunning).
+		Update("status", models.AutoDebitRunQueued).Error; err != nil {
+		logger.Error("Auto-debit scheduler failed to requeue interrupted runs", err, nil)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var runs []models.AutoDebitRun
+		if err := config.DB.Where("status = ?", models.AutoDebitRunQueued).
+			Order("created_at ASC").Limit(runBatchSize).Find(&runs).Error; err != nil {
+			logger.Error("Auto-debit scheduler failed to load queued runs", err, nil)
+		}
+		for i := range runs {
+			processRun(config.DB, &runs[i])
+		}
+		if err := ReconcilePending(config.DB, time.Now()); err != nil {
+			logger.Error("Auto-debit scheduler failed to reconcile pending attempts", err, nil)
+		}
+		<-ticker.C
+	}
+}
+
+// processRun claims a queued run and executes it. A failed run is retried on
+// the next tick until MaxRunAttempts is reached.
+func processRun(db *gorm.DB, run *models.AutoDebitRun) {
+	now := time.Now()
+	// Claim the run so a second instance of the scheduler skips it
+	claim := db.Model(&models.AutoDebitRun{}).
+		Where("id = ? AND status = ?", run.ID, models.AutoDebitRunQueued).
+		Updates(map[string]interface{}{
+			"status":     models.AutoDebitRunRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+		})
+	if claim.Error != nil {
+		logger.Error("Auto-debit scheduler failed to claim run", claim.Error, map[string]interface{}{"run_id": run.ID})
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+	run.Attempts++
+
+	var summary *Summary
+	var err error
+	if run.InvoiceID != nil {
+		summary, err = RunInvoice(db, run.TenantID, *run.InvoiceID)
+	} else {
+		summary, err = Run(db, run.TenantID, run.UsageMonth)
+	}
+
+	finished := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.AutoDebitRunFinished,
+		"error":       "",
+		"finished_at": finished,
+	}
+	if err != nil {
+		updates["status"] = models.AutoDebitRunQueued
+		if run.Attempts >= MaxRunAttempts {
+			updates["status"] = models.AutoDebitRunFailed
+		}
+		updates["error"] = err.Error()
+		logger.Error("Auto-debit run failed", err, map[string]interface{}{
+			"run_id":      run.ID,
+			"tenant_id":   run.TenantID,
+			"usage_month": run.UsageMonth,
+			"attempts":    run.Attempts,
+		})
+	} else {
+		logger.Info("Auto-debit run finished", map[string]interface{}{
+			"run_id":           run.ID,
+			"tenant_id":        run.TenantID,
+			"usage_month":      summary.UsageMonth,
+			"invoices":         summary.Invoices,
+			"succeeded":        summary.Succeeded,
+			"failed":           summary.Failed,
+			"skipped":          summary.Skipped,
+			"pending":          summary.Pending,
+			"amount_collected": summary.AmountCollected,
+		})
+	}
+	if err := db.Model(run).Updates(updates).Error; err != nil {
+		logger.Error("Auto-debit scheduler failed to record run", err, map[string]interface{}{"run_id": run.ID})
+	}
+}
+
+// Run attempts collection of the tenant's unpaid monthly invoices for
+// usageMonth from every customer with an active mandate.
+func Run(db *gorm.DB, tenantID uuid.UUID, usageMonth string) (*Summary, error) {
+	summary := &Summary{UsageMonth: usageMonth}
+
+	var mandates []models.PaymentMandate
+	if err := db.Preload("Customer").
+		Where("tenant_id = ? AND status = ?", tenantID, models.MandateStatusActive).
+		Find(&mandates).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range mandates {
+		mandate := &mandates[i]
+
+		var invoices []models.Invoice
+		// Held invoices wait for anomaly review and are collected once released
+		if err := db.Where("tenant_id = ? AND customer_id = ? AND usage_month = ? AND type = ? AND is_paid = ? AND on_hold = ? AND carried_to_id IS NULL",
+			tenantID, mandate.CustomerID, usageMonth, "monthly", false, false).
+			Find(&invoices).Error; err != nil {
+			return summary, err
+		}
+
+		if err := collectInvoices(db, mandate, invoices, summary); err != nil {
+			return summary, err
+		}
+	}
+
+	return summary, nil
+}
+
+// RunInvoice attempts collection of a single unpaid monthly invoice through
+// the customer's active mandate. Invoices without one are left alone.
+func RunInvoice(db *gorm.DB, tenantID, invoiceID uuid.UUID) (*Summary, error) {
+	var invoice models.Invoice
+	if err := db.Where("id = ? AND tenant_id = ?", invoiceID, tenantID).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	summary := &Summary{UsageMonth: invoice.UsageMonth}
+	if invoice.Type != "monthly" || invoice.IsPaid || invoice.OnHold || invoice.CarriedToID != nil {
+		return summary, nil
+	}
+
+	var mandate models.PaymentMandate
+	err := db.Preload("Customer").
+		Where("tenant_id = ? AND customer_id = ? AND status = ?", tenantID, invoice.CustomerID, models.MandateStatusActive).
+		First(&mandate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return summary, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, collectInvoices(db, &mandate, []models.Invoice{invoice}, summary)
+}
+
+// collectInvoices charges the mandate's invoices in turn and adds the
+// outcomes to summary
+func collectInvoices(db *gorm.DB, mandate *models.PaymentMandate, invoices []models.Invoice, summary *Summary) error {
+	for j := range invoices {
+		// A suspension after repeated failures applies to the remaining invoices too
+		if mandate.Status != models.MandateStatusActive {
+			break
+		}
+		status, amount, err := collectInvoice(db, mandate, &invoices[j])
+		if errors.Is(err, errAttemptExists) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		summary.Invoices++
+		switch status {
+		case models.AutoDebitSucceeded:
+			summary.Succeeded++
+			summary.AmountCollected += amount
+		case models.AutoDebitFailed:
+			summary.Failed++
+		case models.AutoDebitSkipped:
+			summary.Skipped++
+		case models.AutoDebitPending:
+			summary.Pending++
+		}
+	}
+	return nil
+}
+
+// collectInvoice charges one invoice through the mandate and records the attempt
+func collectInvoice(db *gorm.DB, mandate *models.PaymentMandate, invoice *models.Invoice) (string, float64, error) {
+	provider, ok := paymentprovider.Get(mandate.Provider)
+
+	attempt, err := reserveAttempt(db, mandate, invoice)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if !ok {
+		return models.AutoDebitFailed, 0, failAttempt(db, mandate, invoice, attempt, "Provider "+mandate.Provider+" tidak tersedia")
+	}
+
+	reason := ""
+	if mandate.MaxAmount > 0 && attempt.Amount > mandate.MaxAmount {
+		reason = fmt.Sprintf("Tagihan %.2f melebihi limit mandat %.2f", attempt.Amount, mandate.MaxAmount)
+	} else if mandate.PaymentMethodID != nil {
+		// Money collected for an inactive method could not be booked against the invoice
+		var active int64
+		if err := db.Model(&models.PaymentMethod{}).
+			Where("id = ? AND tenant_id = ? AND is_active = ?", *mandate.PaymentMethodID, mandate.TenantID, true).
+			Count(&active).Error; err != nil {
+			return "", 0, err
+		}
+		if active == 0 {
+			reason = "Metode pembayaran mandat tidak aktif"
+		}
+	}
+	if reason != "" {
+		attempt.Status = models.AutoDebitSkipped
+		if err := db.Model(attempt).Updates(map[string]interface{}{
+			"status":         models.AutoDebitSkipped,
+			"failure_reason": reason,
+		}).Error; err != nil {
+			return "", 0, err
+		}
+		notifyFallback(db, mandate, invoice, reason)
+		return models.AutoDebitSkipped, 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chargeTimeout)
+	result, err := provider.Charge(ctx, paymentprovider.ChargeRequest{
+		TenantID:     mandate.TenantID,
+		MandateID:    mandate.ID,
+		InvoiceID:    invoice.ID,
+		AccountToken: mandate.AccountToken,
+		Amount:       attempt.Amount,
+		Reference:    attempt.Reference,
+		Description:  "Tagihan air " + invoice.UsageMonth,
+	})
+	cancel()
+
+	now := time.Now()
+	mandate.LastAttemptAt = &now
+
+	if err != nil {
+		// Outcome unknown: leave the attempt pending so the invoice is not charged twice
+		if err := db.Model(attempt).Update("failure_reason", "Status dari provider tidak diketahui: "+err.Error()).Error; err != nil {
+			return "", 0, err
+		}
+		return models.AutoDebitPending, 0, db.Model(mandate).Update("last_attempt_at", now).Error
+	}
+
+	if !result.Approved {
+		attempt.ProviderRef = result.ProviderRef
+		return models.AutoDebitFailed, 0, failAttempt(db, mandate, invoice, attempt, result.FailureReason)
+	}
+
+	if err := recordCollection(db, mandate, invoice, attempt, result.ProviderRef); err != nil {
+		return "", 0, err
+	}
+	return models.AutoDebitSucceeded, attempt.Amount, nil
+}
+
+// ReconcilePending settles attempts left pending because the provider's
+// answer was lost or the process stopped before charging. Providers that
+// support it are asked for the outcome; attempts still unknown after
+// PendingExpiry are abandoned and the customer is told to pay manually, which
+// frees the invoice for a later run.
+func ReconcilePending(db *gorm.DB, now time.Time) error {
+	var attempts []models.AutoDebitAttempt
+	if err := db.Preload("Mandate.Customer").Preload("Invoice").
+		Where("status = ? AND attempted_at < ?", models.AutoDebitPending, now.Add(-PendingCheckAfter)).
+		Order("attempted_at ASC").Limit(runBatchSize).Find(&attempts).Error; err != nil {
+		return err
+	}
+
+	for i := range attempts {
+		if err := reconcileAttempt(db, &attempts[i], now); err != nil {
+			logger.Error("Failed to reconcile auto-debit attempt", err, map[string]interface{}{
+				"attempt_id": attempts[i].ID,
+				"invoice_id": attempts[i].InvoiceID,
+			})
+		}
+	}
+	return nil
+}
+
+func reconcileAttempt(db *gorm.DB, attempt *models.AutoDebitAttempt, now time.Time) error {
+	mandate, invoice := &attempt.Mandate, &attempt.Invoice
+
+	if provider, ok := paymentprovider.Get(mandate.Provider); ok {
+		if checker, ok := provider.(paymentprovider.StatusChecker); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), chargeTimeout)
+			result, err := checker.ChargeStatus(ctx, paymentprovider.ChargeRequest{
+				TenantID:     mandate.TenantID,
+				MandateID:    mandate.ID,
+				InvoiceID:    invoice.ID,
+				AccountToken: mandate.AccountToken,
+				Amount:       attempt.Amount,
+				Reference:    attempt.Reference,
+				Description:  "Tagihan air " + invoice.UsageMonth,
+			})
+			cancel()
+			if err == nil {
+				if !result.Approved {
+					attempt.ProviderRef = result.ProviderRef
+					return failAttempt(db, mandate, invoice, attempt, result.FailureReason)
+				}
+				return recordCollection(db, mandate, invoice, attempt, result.ProviderRef)
+			}
+		}
+	}
+
+	if now.Sub(attempt.AttemptedAt) < PendingExpiry {
+		return nil
+	}
+	reason := "Status dari provider tidak dapat dipastikan"
+	if err := db.Model(attempt).Updates(map[string]interface{}{
+		"status":         models.AutoDebitAbandoned,
+		"failure_reason": reason,
+	}).Error; err != nil {
+		return err
+	}
+	notifyFallback(db, mandate, invoice, reason)
+	return nil
+}
+
+// reserveAttempt creates a pending attempt while holding the invoice lock, so
+// concurrent runs cannot charge the same invoice twice
+func reserveAttempt(db *gorm.DB, mandate *models.PaymentMandate, invoice *models.Invoice) (*models.AutoDebitAttempt, error) {
+	var attempt *models.AutoDebitAttempt
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(invoice, "id = ?", invoice.ID).Error; err != nil {
+			return err
+		}
+		if invoice.IsPaid || invoice.CarriedToID != nil {
+			return errAttemptExists
+		}
+
+		var open int64
+		if err := tx.Model(&models.AutoDebitAttempt{}).
+			Where("invoice_id = ? AND status IN ?", invoice.ID, []string{models.AutoDebitPending, models.AutoDebitSucceeded}).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		// A skipped invoice is retried only once the mandate limit allows it
+		if mandate.MaxAmount > 0 {
+			var skipped int64
+			if err := tx.Model(&models.AutoDebitAttempt{}).
+				Where("invoice_id = ? AND status = ? AND amount > ?", invoice.ID, models.AutoDebitSkipped, mandate.MaxAmount).
+				Count(&skipped).Error; err != nil {
+				return err
+			}
+			open += skipped
+		}
+		var failed int64
+		if err := tx.Model(&models.AutoDebitAttempt{}).
+			Where("invoice_id = ? AND status = ?", invoice.ID, models.AutoDebitFailed).
+			Count(&failed).Error; err != nil {
+			return err
+		}
+		if open > 0 || failed >= MaxAttemptsPerInvoice {
+			return errAttemptExists
+		}
+
+		attempt = &models.AutoDebitAttempt{
+			TenantID:    mandate.TenantID,
+			MandateID:   mandate.ID,
+			InvoiceID:   invoice.ID,
+			CustomerID:  invoice.CustomerID,
+			Amount:      invoice.TotalAmount - invoice.TotalPaid,
+			Status:      models.AutoDebitPending,
+			Reference:   "AD-" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+			AttemptedAt: time.Now(),
+		}
+		return tx.Create(attempt).Error
+	})
+	return attempt, err
+}
+
+// recordCollection books the collected money against the invoice. If the
+// invoice was settled in the meantime the money goes to customer credit.
+func recordCollection(db *gorm.DB, mandate *models.PaymentMandate, invoice *models.Invoice, attempt *models.AutoDebitAttempt, providerRef string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":       models.AutoDebitSucceeded,
+			"provider_ref": providerRef,
+		}
+
+		payment, _, err := helpers.ApplyPayment(tx, helpers.PaymentInput{
+			TenantID:        mandate.TenantID,
+			InvoiceID:       invoice.ID,
+			Amount:          attempt.Amount,
+			PaymentMethodID: mandate.PaymentMethodID,
+			ReferenceNumber: providerRef,
+			Notes:           "Auto-debit via " + mandate.Provider,
+		})
+		switch {
+		case err == nil:
+			updates["payment_id"] = payment.ID
+		case helpers.IsPaymentRuleError(err):
+			credit := models.CustomerCredit{
+				TenantID:   mandate.TenantID,
+				CustomerID: invoice.CustomerID,
+				Amount:     attempt.Amount,
+				Type:       models.CreditTypeAdjustment,
+				Reference:  providerRef,
+				Notes:      "Auto-debit " + attempt.Reference + " tidak dapat dibukukan: " + err.Error(),
+			}
+			if err := tx.Create(&credit).Error; err != nil {
+				return err
+			}
+			updates["failure_reason"] = "Dibukukan sebagai kredit pelanggan: " + err.Error()
+		default:
+			return err
+		}
+
+		if err := tx.Model(attempt).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Model(mandate).Updates(map[string]interface{}{
+			"consecutive_failures": 0,
+			"last_attempt_at":      mandate.LastAttemptAt,
+		}).Error
+	})
+}
+
+// failAttempt marks the attempt failed, suspends the mandate after repeated
+// failures and tells the customer to pay through the usual channels
+func failAttempt(db *gorm.DB, mandate *models.PaymentMandate, invoice *models.Invoice, attempt *models.AutoDebitAttempt, reason string) error {
+	if reason == "" {
+		reason = "Ditolak oleh provider"
+	}
+	attempt.Status = models.AutoDebitFailed
+
+	mandate.ConsecutiveFailures++
+	if mandate.ConsecutiveFailures >= MaxConsecutiveFailures {
+		mandate.Status = models.MandateStatusSuspended
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(attempt).Updates(map[string]interface{}{
+			"status":         models.AutoDebitFailed,
+			"provider_ref":   attempt.ProviderRef,
+			"failure_reason": reason,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(mandate).Updates(map[string]interface{}{
+			"consecutive_failures": mandate.ConsecutiveFailures,
+			"status":               mandate.Status,
+			"last_attempt_at":      mandate.LastAttemptAt,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	notifyFallback(db, mandate, invoice, reason)
+	return nil
+}
+
+// notifyFallback lets the customer know the bill was not debited and must be
+// paid manually; the invoice itself stays open for normal dunning
+func notifyFallback(db *gorm.DB, mandate *models.PaymentMandate, invoice *models.Invoice, reason string) {
+	_, err := helpers.QueueCustomerNotification(db, &mandate.Customer, helpers.CustomerNotification{
+		TemplateCode: "AUTO_DEBIT_FAILED",
+		Subject:      "Auto-debit tagihan {{usage_month}} gagal",
+		Body:         "Yth. {{customer_name}}, tagihan air {{usage_month}} sebesar Rp {{amount}} tidak dapat didebet otomatis ({{reason}}). Silakan lakukan pembayaran melalui loket atau metode pembayaran lain.",
+		Variables: map[string]interface{}{
+			"customer_name": mandate.Customer.Name,
+			"usage_month":   invoice.UsageMonth,
+			"amount":        fmt.Sprintf("%.0f", invoice.TotalAmount-invoice.TotalPaid),
+			"reason":        reason,
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to queue auto-debit notification", err, map[string]interface{}{
+			"invoice_id": invoice.ID,
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package calibration keeps Meter.NextCalibDate in line with the tenant's
+// calibration policies. The job runs daily and after every policy change;
+// meters without a matching policy keep their manually set date.
+package calibration
+
+import (
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const batchSize = 500
+
+// Summary reports the outcome of one run
+type Summary struct {
+	Meters        int `json:"meters"`
+	Updated       int `json:"updated"`
+	WithoutPolicy int `json:"without_policy"`
+}
+
+// LoadPolicies returns the tenant's active calibration policies
+func LoadPolicies(db *gorm.DB, tenantID uuid.UUID) ([]models.CalibrationPolicy, error) {
+	var policies []models.CalibrationPolicy
+	err := db.Where("tenant_id = ? AND is_active = ?", tenantID, true).Find(&policies).Error
+	return policies, err
+}
+
+// PolicyFor returns the most specific policy covering the meter: brand and
+// model, then brand, then model, then the tenant default
+func PolicyFor(policies []models.CalibrationPolicy, meter *models.Meter) *models.CalibrationPolicy {
+	var best *models.CalibrationPolicy
+	bestScore := -1
+	for i := range policies {
+		policy := &policies[i]
+		if !policy.Matches(meter.Brand, meter.Model) {
+			continue
+		}
+		score := 0
+		if policy.Brand != "" {
+			score += 2
+		}
+		if policy.Model != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = policy, score
+		}
+	}
+	return best
+}
+
+// NextDate returns when the meter is next due: the policy interval counted
+// from the last calibration, or from installation if it was never calibrated
+func NextDate(meter *models.Meter, policy *models.CalibrationPolicy) time.Time {
+	base := meter.InstallDate
+	if meter.LastCalibDate != nil {
+		base = *meter.LastCalibDate
+	}
+	return base.AddDate(0, policy.IntervalMonths, 0)
+}
+
+// Run recomputes NextCalibDate for the tenant's meters in service
+func Run(db *gorm.DB, tenantID uuid.UUID) (*Summary, error) {
+	policies, err := LoadPolicies(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{}
+	var meters []models.Meter
+	result := db.Where("tenant_id = ? AND status <> ?", tenantID, models.MeterStatusReplaced).
+		FindInBatches(&meters, batchSize, func(tx *gorm.DB, batch int) error {
+			for i := range meters {
+				meter := &meters[i]
+				summary.Meters++
+
+				policy := PolicyFor(policies, meter)
+				if policy == nil {
+					summary.WithoutPolicy++
+					continue
+				}
+
+				next := NextDate(meter, policy)
+				if meter.NextCalibDate != nil && sameDay(*meter.NextCalibDate, next) {
+					continue
+				}
+				if err := db.Model(&models.Meter{}).Where("id = ?", meter.ID).Update("next_calib_date", next).Error; err != nil {
+					return err
+				}
+				summary.Updated++
+			}
+			return nil
+		})
+	return summary, result.Error
+}
+
+// RunForTenant is the background hook used after policy changes
+func RunForTenant(tenantID uuid.UUID) {
+	summary, err := Run(config.DB, tenantID)
+	if err != nil {
+		logger.Error("Calibration schedule run failed", err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return
+	}
+	logger.Info("Calibration schedule run finished", map[string]interface{}{
+		"tenant_id":      tenantID,
+		"meters":         summary.Meters,
+		"updated":        summary.Updated,
+		"without_policy": summary.WithoutPolicy,
+	})
+}
+
+// StartScheduler recomputes calibration dates for every active tenant now
+// and then once per interval. It blocks, so start it in a goroutine.
+func StartScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var tenantIDs []uuid.UUID
+		if err := config.DB.Model(&models.Tenant{}).Where("status = ?", models.TenantStatusActive).
+			Pluck("id", &tenantIDs).Error; err != nil {
+			logger.Error("Calibration scheduler failed to load tenants", err, nil)
+		}
+		for _, tenantID := range tenantIDs {
+			RunForTenant(tenantID)
+		}
+		<-ticker.C
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
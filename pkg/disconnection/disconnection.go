@@ -0,0 +1,183 @@
+// Package disconnection enforces a tenant's arrears policy. Customers whose
+// overdue usage invoices reach the tenant's thresholds get a disconnection
+// service order for field staff, and a notice when the tenant wants one.
+// Paying the arrears in full cancels the order again, or, once the customer
+// is disconnected, raises the reconnection order (see helpers.RecalculateInvoicePayments).
+package disconnection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Summary reports the outcome of one run
+type Summary struct {
+	Customers int `json:"customers"` // customers with overdue invoices
+	Raised    int `json:"raised"`    // new disconnection orders
+	Skipped   int `json:"skipped"`   // customers that already have an open order
+	Notified  int `json:"notified"`  // customers notified
+}
+
+// customerArrears is the overdue billing of one customer
+type customerArrears struct {
+	CustomerID uuid.UUID
+	Invoices   int
+	Amount     float64
+}
+
+// LoadPolicy returns the tenant's arrears thresholds, or the defaults when the
+// tenant has not configured any
+func LoadPolicy(db *gorm.DB, tenantID uuid.UUID) (*models.DisconnectionPolicy, error) {
+	var policy models.DisconnectionPolicy
+	err := db.Where("tenant_id = ?", tenantID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		policy = models.DefaultDisconnectionPolicy(tenantID)
+		return &policy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Exceeds reports whether overdue arrears reach either threshold of the policy
+func Exceeds(policy *models.DisconnectionPolicy, arrears helpers.CustomerArrears) bool {
+	if arrears.Invoices == 0 {
+		return false
+	}
+	if policy.MinOverdueMonths > 0 && arrears.Invoices >= policy.MinOverdueMonths {
+		return true
+	}
+	return policy.MinArrearsAmount > 0 && arrears.Amount >= policy.MinArrearsAmount
+}
+
+// OverdueCutoff returns the time before which an unpaid invoice is overdue
+func OverdueCutoff(policy *models.DisconnectionPolicy, now time.Time) time.Time {
+	return now.AddDate(0, 0, -policy.GraceDays)
+}
+
+// Run raises disconnection orders for the tenant's active and suspended
+// customers whose overdue arrears exceed the policy
+func Run(db *gorm.DB, tenantID uuid.UUID) (*Summary, error) {
+	policy, err := LoadPolicy(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	if !policy.IsEnabled {
+		return summary, nil
+	}
+
+	var overdue []customerArrears
+	if err := db.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND type IN ? AND is_paid = ? AND on_hold = ? AND carried_to_id IS NULL AND created_at < ?",
+			tenantID, helpers.ArrearsInvoiceTypes, false, false, OverdueCutoff(policy, time.Now())).
+		Select("customer_id, COUNT(*) AS invoices, COALESCE(SUM(total_amount - total_paid), 0) AS amount").
+		Group("customer_id").
+		Scan(&overdue).Error; err != nil {
+		return nil, err
+	}
+	summary.Customers = len(overdue)
+
+	for _, entry := range overdue {
+		arrears := helpers.CustomerArrears{Invoices: entry.Invoices, Amount: entry.Amount}
+		if !Exceeds(policy, arrears) {
+			continue
+		}
+
+		var customer models.Customer
+		err := db.Where("id = ? AND tenant_id = ? AND status IN ?", entry.CustomerID, tenantID,
+			[]string{models.CustomerStatusActive, models.CustomerStatusSuspended}).First(&customer).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return summary, err
+		}
+
+		notified := false
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if _, _, err := helpers.RaiseServiceOrder(tx, &customer, helpers.ServiceOrderInput{
+				OrderType: models.ServiceOrderDisconnection,
+				Reason:    fmt.Sprintf("Arrears of %d overdue invoices totalling %.2f", arrears.Invoices, arrears.Amount),
+				Arrears:   &arrears,
+			}); err != nil {
+				return err
+			}
+			if !policy.NotifyCustomer {
+				return nil
+			}
+
+			log, err := helpers.QueueCustomerNotification(tx, &customer, helpers.CustomerNotification{
+				TemplateCode: "DISCONNECTION_NOTICE",
+				Subject:      "Pemberitahuan pemutusan sambungan air {{meter_number}}",
+				Body:         "Yth. {{customer_name}}, tunggakan tagihan air Anda sebesar Rp {{amount}} ({{invoices}} tagihan) telah melewati batas. Sambungan air Anda akan diputus oleh petugas kami. Segera lunasi tunggakan untuk menghindari pemutusan.",
+				Variables: map[string]interface{}{
+					"customer_name": customer.Name,
+					"meter_number":  customer.MeterNumber,
+					"amount":        fmt.Sprintf("%.0f", arrears.Amount),
+					"invoices":      arrears.Invoices,
+				},
+			})
+			notified = log != nil
+			return err
+		})
+		if errors.Is(err, helpers.ErrServiceOrderExists) {
+			summary.Skipped++
+			continue
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.Raised++
+		if notified {
+			summary.Notified++
+		}
+	}
+	return summary, nil
+}
+
+// RunForTenant runs the policy and logs the outcome
+func RunForTenant(tenantID uuid.UUID) {
+	summary, err := Run(config.DB, tenantID)
+	if err != nil {
+		logger.Error("Disconnection run failed", err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return
+	}
+	logger.Info("Disconnection run finished", map[string]interface{}{
+		"tenant_id": tenantID,
+		"customers": summary.Customers,
+		"raised":    summary.Raised,
+		"skipped":   summary.Skipped,
+		"notified":  summary.Notified,
+	})
+}
+
+// StartScheduler checks every active tenant now and then once per interval.
+// It blocks, so start it in a goroutine.
+func StartScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var tenantIDs []uuid.UUID
+		if err := config.DB.Model(&models.Tenant{}).Where("status = ?", models.TenantStatusActive).
+			Pluck("id", &tenantIDs).Error; err != nil {
+			logger.Error("Disconnection scheduler failed to load tenants", err, nil)
+		}
+		for _, tenantID := range tenantIDs {
+			RunForTenant(tenantID)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,365 @@
+// Package leakdetect looks for leaks in the interval readings of smart
+// meters. Two signs are checked: water that keeps running through the night
+// window on consecutive nights, and daily usage jumping well above the
+// meter's usual level for several days in a row. A flagged meter gets a leak
+// MeterIssue and its customer a notification; while that issue is open the
+// meter is not flagged again.
+package leakdetect
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Leak signs
+const (
+	ReasonContinuousFlow = "continuous_flow"
+	ReasonFlowIncrease   = "flow_increase"
+)
+
+const (
+	// alertCooldown keeps a leak that was just reported and fixed, but still
+	// shows in the last days of intervals, from being flagged again
+	alertCooldown = 7 * 24 * time.Hour
+	// maxBoundaryGap is how long before midnight the last interval of a day
+	// may be for the day's usage to be known
+	maxBoundaryGap = 6 * time.Hour
+)
+
+// Summary reports the outcome of one run
+type Summary struct {
+	Meters   int `json:"meters"`   // meters with recent intervals
+	Flagged  int `json:"flagged"`  // new leak issues
+	Skipped  int `json:"skipped"`  // suspected leaks already reported
+	Notified int `json:"notified"` // customers notified
+}
+
+// Finding is a suspected leak
+type Finding struct {
+	Reason      string
+	Description string // for staff, stored on the issue
+	CustomerMsg string // for the customer notification
+}
+
+// LoadConfig returns the tenant's leak thresholds, or the defaults when the
+// tenant has not configured any
+func LoadConfig(db *gorm.DB, tenantID uuid.UUID) (*models.LeakDetectionConfig, error) {
+	var cfg models.LeakDetectionConfig
+	err := db.Where("tenant_id = ?", tenantID).First(&cfg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cfg = models.DefaultLeakDetectionConfig(tenantID)
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Detect checks a meter's intervals, sorted by time, for a leak as of now
+func Detect(cfg *models.LeakDetectionConfig, meter *models.Meter, intervals []models.MeterInterval, now time.Time) *Finding {
+	if finding := detectContinuousFlow(cfg, meter, intervals, now); finding != nil {
+		return finding
+	}
+	return detectFlowIncrease(cfg, meter, intervals, now)
+}
+
+// detectContinuousFlow flags meters whose hourly flow never dropped below
+// MinNightFlowLPH during the night window of each of the last LeakNights
+// nights. Households normally have an hour without any use at night.
+func detectContinuousFlow(cfg *models.LeakDetectionConfig, meter *models.Meter, intervals []models.MeterInterval, now time.Time) *Finding {
+	last := startOfDay(now)
+	if last.Add(hours(cfg.NightEndHour)).After(now) {
+		last = last.AddDate(0, 0, -1)
+	}
+
+	lowest := math.MaxFloat64
+	for night := 0; night < cfg.LeakNights; night++ {
+		day := last.AddDate(0, 0, -night)
+		flow, ok := minHourlyFlow(meter, intervals, day.Add(hours(cfg.NightStartHour)), day.Add(hours(cfg.NightEndHour)))
+		if !ok || flow < cfg.MinNightFlowLPH {
+			return nil
+		}
+		lowest = math.Min(lowest, flow)
+	}
+
+	return &Finding{
+		Reason: ReasonContinuousFlow,
+		Description: fmt.Sprintf("Possible leak: water kept running between %02d:00 and %02d:00 on %d consecutive nights, lowest flow %.1f L/h",
+			cfg.NightStartHour, cfg.NightEndHour, cfg.LeakNights, lowest),
+		CustomerMsg: fmt.Sprintf("air terus mengalir pada malam hari selama %d malam berturut-turut, minimal %.1f liter per jam",
+			cfg.LeakNights, lowest),
+	}
+}
+
+// detectFlowIncrease flags meters whose usage on each of the last
+// SustainedDays days is above the average of the BaselineDays before by at
+// least IncreasePercent and MinIncreaseM3
+func detectFlowIncrease(cfg *models.LeakDetectionConfig, meter *models.Meter, intervals []models.MeterInterval, now time.Time) *Finding {
+	today := startOfDay(now)
+
+	lowest := math.MaxFloat64
+	for n := 1; n <= cfg.SustainedDays; n++ {
+		usage, ok := dailyUsage(meter, intervals, today.AddDate(0, 0, -n))
+		if !ok {
+			return nil
+		}
+		lowest = math.Min(lowest, usage)
+	}
+
+	var total float64
+	known := 0
+	for n := cfg.SustainedDays + 1; n <= cfg.SustainedDays+cfg.BaselineDays; n++ {
+		if usage, ok := dailyUsage(meter, intervals, today.AddDate(0, 0, -n)); ok {
+			total += usage
+			known++
+		}
+	}
+	// A meter with too little history has no usual level yet
+	if known == 0 || known*2 < cfg.BaselineDays {
+		return nil
+	}
+	baseline := total / float64(known)
+
+	threshold := math.Max(baseline*(1+cfg.IncreasePercent/100), baseline+cfg.MinIncreaseM3)
+	if lowest <= threshold {
+		return nil
+	}
+
+	return &Finding{
+		Reason: ReasonFlowIncrease,
+		Description: fmt.Sprintf("Possible leak: daily usage of at least %.3f m3 on %d consecutive days against a usual %.3f m3 per day",
+			lowest, cfg.SustainedDays, baseline),
+		CustomerMsg: fmt.Sprintf("pemakaian harian naik dari rata-rata %.2f m3 menjadi minimal %.2f m3 selama %d hari berturut-turut",
+			baseline, lowest, cfg.SustainedDays),
+	}
+}
+
+// minHourlyFlow returns the lowest flow in L/h measured over stretches of at
+// least an hour between from and to. Windows that are less than half covered
+// by intervals, or where the register went backwards, are not evaluated.
+func minHourlyFlow(meter *models.Meter, intervals []models.MeterInterval, from, to time.Time) (float64, bool) {
+	start := sort.Search(len(intervals), func(i int) bool { return !intervals[i].RecordedAt.Before(from) })
+	end := sort.Search(len(intervals), func(i int) bool { return intervals[i].RecordedAt.After(to) })
+	window := intervals[start:end]
+	if len(window) < 2 || window[len(window)-1].RecordedAt.Sub(window[0].RecordedAt) < to.Sub(from)/2 {
+		return 0, false
+	}
+
+	lowest := math.MaxFloat64
+	prev := window[0]
+	for _, interval := range window[1:] {
+		elapsed := interval.RecordedAt.Sub(prev.RecordedAt)
+		// Short stretches are merged, a register resolution of one litre
+		// would otherwise show zero flow for slow leaks
+		if elapsed < time.Hour {
+			continue
+		}
+		consumed, _, err := helpers.ConsumptionBetween(prev.Reading, interval.Reading, meter)
+		if err != nil {
+			return 0, false
+		}
+		lowest = math.Min(lowest, consumed*1000/elapsed.Hours())
+		prev = interval
+	}
+	if lowest == math.MaxFloat64 {
+		return 0, false
+	}
+	return lowest, true
+}
+
+// dailyUsage returns the usage of the day starting at day, from the last
+// intervals before the day's start and end
+func dailyUsage(meter *models.Meter, intervals []models.MeterInterval, day time.Time) (float64, bool) {
+	start, ok := registerAt(intervals, day)
+	if !ok {
+		return 0, false
+	}
+	end, ok := registerAt(intervals, day.AddDate(0, 0, 1))
+	if !ok {
+		return 0, false
+	}
+	usage, _, err := helpers.ConsumptionBetween(start, end, meter)
+	return usage, err == nil
+}
+
+// registerAt returns the register value at t from the last interval up to t
+func registerAt(intervals []models.MeterInterval, t time.Time) (float64, bool) {
+	i := sort.Search(len(intervals), func(i int) bool { return intervals[i].RecordedAt.After(t) })
+	if i == 0 || t.Sub(intervals[i-1].RecordedAt) > maxBoundaryGap {
+		return 0, false
+	}
+	return intervals[i-1].Reading, true
+}
+
+// lookback returns the start of the intervals the checks need
+func lookback(cfg *models.LeakDetectionConfig, now time.Time) time.Time {
+	days := max(cfg.SustainedDays+cfg.BaselineDays, cfg.LeakNights+1)
+	return startOfDay(now).AddDate(0, 0, -days).Add(-maxBoundaryGap)
+}
+
+// Run checks the tenant's active meters with recent intervals
+func Run(db *gorm.DB, tenantID uuid.UUID) (*Summary, error) {
+	cfg, err := LoadConfig(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	if !cfg.IsEnabled {
+		return summary, nil
+	}
+
+	now := time.Now()
+	since := lookback(cfg, now)
+	var meterIDs []uuid.UUID
+	if err := db.Model(&models.MeterInterval{}).
+		Where("tenant_id = ? AND recorded_at >= ?", tenantID, since).
+		Distinct().Pluck("meter_id", &meterIDs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, meterID := range meterIDs {
+		var meter models.Meter
+		err := db.Preload("Customer").Where("id = ? AND tenant_id = ? AND status = ?", meterID, tenantID, models.MeterStatusActive).
+			First(&meter).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.Meters++
+
+		var intervals []models.MeterInterval
+		if err := db.Where("meter_id = ? AND recorded_at >= ?", meter.ID, since).
+			Order("recorded_at").Find(&intervals).Error; err != nil {
+			return summary, err
+		}
+		finding := Detect(cfg, &meter, intervals, now)
+		if finding == nil {
+			continue
+		}
+
+		reported, err := alreadyReported(db, meter.ID, now)
+		if err != nil {
+			return summary, err
+		}
+		if reported {
+			summary.Skipped++
+			continue
+		}
+
+		notified, err := raiseLeakIssue(db, cfg, &meter, finding)
+		if err != nil {
+			return summary, err
+		}
+		summary.Flagged++
+		if notified {
+			summary.Notified++
+		}
+	}
+	return summary, nil
+}
+
+// alreadyReported reports whether the meter has an unresolved leak issue or
+// one reported within the cooldown
+func alreadyReported(db *gorm.DB, meterID uuid.UUID, now time.Time) (bool, error) {
+	var count int64
+	err := db.Model(&models.MeterIssue{}).
+		Where("meter_id = ? AND issue_type = ?", meterID, models.MeterIssueLeak).
+		Where("(status IN ? OR created_at >= ?)", []string{models.MeterIssueStatusOpen, models.MeterIssueStatusInProgress}, now.Add(-alertCooldown)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// raiseLeakIssue opens a leak issue for the meter and notifies its customer
+func raiseLeakIssue(db *gorm.DB, cfg *models.LeakDetectionConfig, meter *models.Meter, finding *Finding) (bool, error) {
+	notified := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		issue := models.MeterIssue{
+			TenantID:    meter.TenantID,
+			MeterID:     meter.ID,
+			IssueType:   models.MeterIssueLeak,
+			Description: finding.Description,
+			Status:      models.MeterIssueStatusOpen,
+			Priority:    models.MeterIssuePriorityHigh,
+		}
+		issue.SetDueAt()
+		if err := tx.Omit("Meter").Create(&issue).Error; err != nil {
+			return err
+		}
+		if !cfg.NotifyCustomer {
+			return nil
+		}
+
+		log, err := helpers.QueueCustomerNotification(tx, &meter.Customer, helpers.CustomerNotification{
+			TemplateCode: "LEAK_ALERT",
+			Subject:      "Dugaan kebocoran air pada meter {{meter_number}}",
+			Body:         "Yth. {{customer_name}}, meter air {{meter_number}} mencatat {{reason}}. Hal ini dapat menandakan kebocoran pada instalasi Anda. Mohon periksa keran, toilet dan pipa Anda; petugas kami juga akan menindaklanjuti.",
+			Variables: map[string]interface{}{
+				"customer_name": meter.Customer.Name,
+				"meter_number":  meter.MeterNumber,
+				"reason":        finding.CustomerMsg,
+			},
+		})
+		notified = log != nil
+		return err
+	})
+	return notified, err
+}
+
+// RunForTenant runs the detector and logs the outcome
+func RunForTenant(tenantID uuid.UUID) {
+	summary, err := Run(config.DB, tenantID)
+	if err != nil {
+		logger.Error("Leak detection run failed", err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return
+	}
+	logger.Info("Leak detection run finished", map[string]interface{}{
+		"tenant_id": tenantID,
+		"meters":    summary.Meters,
+		"flagged":   summary.Flagged,
+		"skipped":   summary.Skipped,
+		"notified":  summary.Notified,
+	})
+}
+
+// StartScheduler checks every active tenant now and then once per interval.
+// It blocks, so start it in a goroutine.
+func StartScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var tenantIDs []uuid.UUID
+		if err := config.DB.Model(&models.Tenant{}).Where("status = ?", models.TenantStatusActive).
+			Pluck("id", &tenantIDs).Error; err != nil {
+			logger.Error("Leak detection scheduler failed to load tenants", err, nil)
+		}
+		for _, tenantID := range tenantIDs {
+			RunForTenant(tenantID)
+		}
+		<-ticker.C
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func hours(n int) time.Duration {
+	return time.Duration(n) * time.Hour
+}
@@ -0,0 +1,343 @@
+// Package mqttbridge feeds meter telemetry published over MQTT, e.g. by
+// LoRa gateways or ESP32 pulse counters, into the same interval ingestion
+// as the HTTP device API. Topics name the tenant's village code and the
+// meter number, and every payload carries the device key, checked like the
+// HTTP API's X-Device-Key: the key must belong to an active device
+// registered on that meter. Disabling the device or rotating its key stops
+// its MQTT telemetry.
+package mqttbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/helpers"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Topic pattern placeholders
+const (
+	tenantPlaceholder = "{tenant}"
+	meterPlaceholder  = "{meter}"
+)
+
+// DefaultTopicPattern is used when MQTT_TOPIC_PATTERN is not set
+const DefaultTopicPattern = "tirta/{tenant}/meters/{meter}/readings"
+
+var (
+	errUnknownTopic     = errors.New("topic does not match the pattern")
+	errMissingDeviceKey = errors.New("payload has no device_key")
+)
+
+// Config configures the bridge
+type Config struct {
+	BrokerURL    string
+	ClientID     string
+	Username     string
+	Password     string
+	TopicPattern string // {tenant} is the tenant's village code, {meter} the meter number
+	Tenant       string // village code for patterns without {tenant}
+	QoS          byte   // 0 or 1
+	CleanSession bool
+	KeepAlive    time.Duration
+}
+
+// ConfigFromEnv reads the bridge configuration from MQTT_* environment
+// variables. It reports false when no broker is configured.
+func ConfigFromEnv() (Config, bool) {
+	cfg := Config{
+		BrokerURL:    os.Getenv("MQTT_BROKER_URL"),
+		ClientID:     os.Getenv("MQTT_CLIENT_ID"),
+		Username:     os.Getenv("MQTT_USERNAME"),
+		Password:     os.Getenv("MQTT_PASSWORD"),
+		TopicPattern: os.Getenv("MQTT_TOPIC_PATTERN"),
+		Tenant:       os.Getenv("MQTT_TENANT"),
+		QoS:          1,
+		CleanSession: os.Getenv("MQTT_CLEAN_SESSION") == "true",
+		KeepAlive:    60 * time.Second,
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "tirta-saas-bridge"
+	}
+	if cfg.TopicPattern == "" {
+		cfg.TopicPattern = DefaultTopicPattern
+	}
+	if os.Getenv("MQTT_QOS") == "0" {
+		cfg.QoS = 0
+	}
+	if seconds, err := strconv.Atoi(os.Getenv("MQTT_KEEPALIVE")); err == nil && seconds >= 10 {
+		cfg.KeepAlive = time.Duration(seconds) * time.Second
+	}
+	return cfg, cfg.BrokerURL != ""
+}
+
+// Validate checks the topic pattern can identify a meter
+func (cfg Config) Validate() error {
+	if !strings.Contains(cfg.TopicPattern, meterPlaceholder) {
+		return fmt.Errorf("topic pattern %q has no %s placeholder", cfg.TopicPattern, meterPlaceholder)
+	}
+	if !strings.Contains(cfg.TopicPattern, tenantPlaceholder) && cfg.Tenant == "" {
+		return fmt.Errorf("topic pattern %q has no %s placeholder and MQTT_TENANT is not set", cfg.TopicPattern, tenantPlaceholder)
+	}
+	if strings.ContainsAny(cfg.TopicPattern, "+#") {
+		return errors.New("topic pattern must not contain MQTT wildcards, use the placeholders")
+	}
+	return nil
+}
+
+// filter is the subscription filter of the pattern
+func (cfg Config) filter() string {
+	return strings.NewReplacer(tenantPlaceholder, "+", meterPlaceholder, "+").Replace(cfg.TopicPattern)
+}
+
+// match extracts the village code and meter number from a topic
+func (cfg Config) match(topic string) (tenant, meter string, err error) {
+	patternLevels := strings.Split(cfg.TopicPattern, "/")
+	topicLevels := strings.Split(topic, "/")
+	if len(patternLevels) != len(topicLevels) {
+		return "", "", errUnknownTopic
+	}
+
+	tenant = cfg.Tenant
+	for i, level := range patternLevels {
+		switch level {
+		case tenantPlaceholder:
+			tenant = topicLevels[i]
+		case meterPlaceholder:
+			meter = topicLevels[i]
+		default:
+			if level != topicLevels[i] {
+				return "", "", errUnknownTopic
+			}
+		}
+	}
+	if tenant == "" || meter == "" {
+		return "", "", errUnknownTopic
+	}
+	return tenant, meter, nil
+}
+
+// Bridge subscribes to the broker and ingests the readings it receives
+type Bridge struct {
+	DB     *gorm.DB
+	Config Config
+}
+
+// Run connects to the broker and processes messages until ctx is done,
+// reconnecting with backoff when the connection drops
+func (b *Bridge) Run(ctx context.Context) error {
+	if err := b.Config.Validate(); err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := b.session(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		logger.Warn("MQTT bridge disconnected, reconnecting", map[string]interface{}{
+			"broker": b.Config.BrokerURL,
+			"error":  fmt.Sprint(err),
+			"retry":  backoff.String(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// session runs one broker connection
+func (b *Bridge) session(ctx context.Context) error {
+	c, err := dial(b.Config)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, c.close)
+	defer stop()
+	defer c.conn.Close()
+
+	filter := b.Config.filter()
+	if err := c.subscribe(filter, b.Config.QoS); err != nil {
+		return err
+	}
+	logger.Info("MQTT bridge subscribed", map[string]interface{}{
+		"broker": b.Config.BrokerURL,
+		"topic":  filter,
+	})
+
+	var handleErr error
+	err = c.run(func(msg message) {
+		if handleErr != nil {
+			return
+		}
+		if err := b.handle(msg.Topic, msg.Payload); err != nil {
+			// Not acknowledged: the broker redelivers after the reconnect
+			handleErr = err
+			c.conn.Close()
+			return
+		}
+		if err := c.ack(msg); err != nil {
+			handleErr = err
+		}
+	})
+	if handleErr != nil {
+		return handleErr
+	}
+	return err
+}
+
+// handle ingests one message. Only failures worth a redelivery, such as the
+// database being unavailable, are returned; bad messages are logged and
+// dropped.
+func (b *Bridge) handle(topic string, payload []byte) error {
+	villageCode, meterNumber, err := b.Config.match(topic)
+	if err != nil {
+		logger.Warn("MQTT bridge ignored message", map[string]interface{}{"topic": topic, "error": err.Error()})
+		return nil
+	}
+
+	readings, deviceKey, err := parsePayload(payload, time.Now())
+	if err != nil {
+		logger.Warn("MQTT bridge ignored malformed payload", map[string]interface{}{"topic": topic, "error": err.Error()})
+		return nil
+	}
+
+	device, err := b.authenticateDevice(deviceKey, villageCode, meterNumber)
+	if err != nil {
+		if errors.Is(err, helpers.ErrInvalidDeviceKey) {
+			logger.Warn("MQTT bridge rejected device key for meter", map[string]interface{}{
+				"tenant": villageCode,
+				"meter":  meterNumber,
+			})
+			return nil
+		}
+		return err
+	}
+
+	for start := 0; start < len(readings); start += helpers.MaxIntervalBatch {
+		end := min(start+helpers.MaxIntervalBatch, len(readings))
+		result, err := helpers.IngestIntervalReadings(b.DB, device, readings[start:end])
+		if errors.Is(err, helpers.ErrDeviceMeterInactive) {
+			logger.Warn("MQTT bridge dropped readings of inactive meter", map[string]interface{}{"meter": meterNumber})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(result.Errors) > 0 {
+			logger.Warn("MQTT bridge rejected readings", map[string]interface{}{
+				"meter":  meterNumber,
+				"errors": result.Errors,
+			})
+		}
+	}
+	return nil
+}
+
+// authenticateDevice checks the key belongs to an active device registered
+// on the topic's meter, an active meter of an active tenant
+func (b *Bridge) authenticateDevice(key, villageCode, meterNumber string) (*models.MeterDevice, error) {
+	device, err := helpers.AuthenticateDevice(b.DB, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := b.DB.Model(&models.Meter{}).
+		Joins("JOIN tenants ON tenants.id = meters.tenant_id AND tenants.deleted_at IS NULL").
+		Where("meters.id = ? AND meters.tenant_id = ?", device.MeterID, device.TenantID).
+		Where("tenants.village_code = ? AND tenants.status = ?", villageCode, models.TenantStatusActive).
+		Where("meters.meter_number = ? AND meters.status = ?", meterNumber, models.MeterStatusActive).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, helpers.ErrInvalidDeviceKey
+	}
+	return device, nil
+}
+
+// telemetry is one reading as published by a device. Pulse counters may send
+// their pulse count instead of the register value.
+type telemetry struct {
+	Timestamp *timestamp `json:"timestamp"`
+	Reading   *float64   `json:"reading"`
+	Pulses    *int64     `json:"pulses"`
+}
+
+// timestamp accepts RFC3339 strings and Unix seconds
+type timestamp struct {
+	time.Time
+}
+
+func (t *timestamp) UnmarshalJSON(data []byte) error {
+	if seconds, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		t.Time = time.Unix(seconds, 0)
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// parsePayload accepts an object with the device key and either a single
+// reading or a "readings" array. Readings without a timestamp are taken at
+// receivedAt.
+func parsePayload(payload []byte, receivedAt time.Time) ([]helpers.IntervalReading, string, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 {
+		return nil, "", errors.New("empty payload")
+	}
+	var batch struct {
+		telemetry
+		DeviceKey string      `json:"device_key"`
+		Readings  []telemetry `json:"readings"`
+	}
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return nil, "", err
+	}
+	if batch.DeviceKey == "" {
+		return nil, "", errMissingDeviceKey
+	}
+	items := batch.Readings
+	if items == nil {
+		items = []telemetry{batch.telemetry}
+	}
+
+	readings := make([]helpers.IntervalReading, 0, len(items))
+	for _, item := range items {
+		reading := helpers.IntervalReading{RecordedAt: receivedAt, Reading: item.Reading, Pulses: item.Pulses}
+		if item.Timestamp != nil {
+			reading.RecordedAt = item.Timestamp.Time
+		}
+		readings = append(readings, reading)
+	}
+	return readings, batch.DeviceKey, nil
+}
@@ -0,0 +1,185 @@
+package mqttbridge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts connections on a local port, completes the CONNECT and
+// SUBSCRIBE handshakes and hands each session to serve
+func fakeBroker(t *testing.T, serve func(n int, broker *client)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for n := 1; ; n++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			broker := &client{conn: conn, reader: bufio.NewReader(conn)}
+			go func(n int) {
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				if header, _, err := broker.readPacket(); err != nil || header>>4 != packetConnect {
+					return
+				}
+				broker.write(packetConnack<<4, []byte{0x00, 0x00})
+				header, body, err := broker.readPacket()
+				if err != nil || header>>4 != packetSubscribe || len(body) < 2 {
+					return
+				}
+				broker.write(packetSuback<<4, []byte{body[0], body[1], 0x01})
+				serve(n, broker)
+			}(n)
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestBridgeReconnects(t *testing.T) {
+	sessions := make(chan int, 4)
+	url := fakeBroker(t, func(n int, broker *client) {
+		sessions <- n
+		if n == 1 {
+			// Drop the first connection; the bridge must come back
+			return
+		}
+		broker.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		for {
+			if _, _, err := broker.readPacket(); err != nil {
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bridge := &Bridge{Config: Config{
+		BrokerURL:    url,
+		ClientID:     "test",
+		TopicPattern: DefaultTopicPattern,
+		QoS:          1,
+		KeepAlive:    10 * time.Second,
+	}}
+	done := make(chan error, 1)
+	go func() { done <- bridge.Run(ctx) }()
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case n := <-sessions:
+			if n != want {
+				t.Fatalf("got session %d, want %d", n, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("session %d was not established", want)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v after cancel", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
+
+func TestBridgeRejectsInvalidConfig(t *testing.T) {
+	bridge := &Bridge{Config: Config{BrokerURL: "tcp://127.0.0.1:1", TopicPattern: "tirta/{tenant}/readings"}}
+	if err := bridge.Run(context.Background()); err == nil {
+		t.Fatal("expected an error for a pattern without {meter}")
+	}
+}
+
+func TestDialUnsupportedScheme(t *testing.T) {
+	if _, err := dial(Config{BrokerURL: "http://localhost"}); err == nil {
+		t.Fatal("expected an error for an http broker URL")
+	}
+}
+
+func TestConfigMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		topic      string
+		wantTenant string
+		wantMeter  string
+		wantErr    bool
+	}{
+		{name: "default pattern", cfg: Config{TopicPattern: DefaultTopicPattern},
+			topic: "tirta/DESA01/meters/M-001/readings", wantTenant: "DESA01", wantMeter: "M-001"},
+		{name: "fixed tenant", cfg: Config{TopicPattern: "meters/{meter}", Tenant: "DESA02"},
+			topic: "meters/M-9", wantTenant: "DESA02", wantMeter: "M-9"},
+		{name: "wrong literal", cfg: Config{TopicPattern: DefaultTopicPattern},
+			topic: "tirta/DESA01/pumps/M-001/readings", wantErr: true},
+		{name: "too few levels", cfg: Config{TopicPattern: DefaultTopicPattern},
+			topic: "tirta/DESA01/meters/M-001", wantErr: true},
+		{name: "empty meter", cfg: Config{TopicPattern: DefaultTopicPattern},
+			topic: "tirta/DESA01/meters//readings", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, meter, err := tt.cfg.match(tt.topic)
+			if tt.wantErr {
+				if !errors.Is(err, errUnknownTopic) {
+					t.Fatalf("got %v, want errUnknownTopic", err)
+				}
+				return
+			}
+			if err != nil || tenant != tt.wantTenant || meter != tt.wantMeter {
+				t.Fatalf("got %q %q %v, want %q %q", tenant, meter, err, tt.wantTenant, tt.wantMeter)
+			}
+		})
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		payload      string
+		wantReadings int
+		wantErr      bool
+	}{
+		{name: "single reading", payload: `{"device_key":"k","reading":12.5}`, wantReadings: 1},
+		{name: "batch", payload: `{"device_key":"k","readings":[{"timestamp":1700000000,"pulses":3},{"timestamp":"2026-03-01T11:00:00Z","reading":1}]}`, wantReadings: 2},
+		{name: "missing key", payload: `{"reading":1}`, wantErr: true},
+		{name: "array form", payload: `[{"reading":1}]`, wantErr: true},
+		{name: "empty", payload: "  ", wantErr: true},
+		{name: "bad timestamp", payload: `{"device_key":"k","timestamp":"yesterday","reading":1}`, wantErr: true},
+		{name: "not json", payload: `reading=1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readings, key, err := parsePayload([]byte(tt.payload), receivedAt)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d readings", len(readings))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key != "k" || len(readings) != tt.wantReadings {
+				t.Fatalf("got key %q and %d readings", key, len(readings))
+			}
+		})
+	}
+
+	readings, _, _ := parsePayload([]byte(`{"device_key":"k","reading":1}`), receivedAt)
+	if !readings[0].RecordedAt.Equal(receivedAt) {
+		t.Fatalf("reading without timestamp recorded at %v, want %v", readings[0].RecordedAt, receivedAt)
+	}
+}
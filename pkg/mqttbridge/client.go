@@ -0,0 +1,306 @@
+package mqttbridge
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	maxRemainingBytes = 268435455
+)
+
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// message is a PUBLISH received from the broker
+type message struct {
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	PacketID uint16
+}
+
+// client is a minimal MQTT 3.1.1 subscriber: it connects, subscribes to one
+// topic filter and delivers PUBLISH packets. QoS 1 messages are acknowledged
+// only when the caller calls ack, so unprocessed messages are redelivered
+// after a reconnect.
+type client struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	keepAlive time.Duration
+
+	writeMu sync.Mutex
+}
+
+// dial connects to brokerURL (tcp://, mqtt://, ssl://, tls:// or mqtts://)
+// and performs the MQTT handshake
+func dial(cfg Config) (*client, error) {
+	broker, err := url.Parse(cfg.BrokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL: %v", err)
+	}
+
+	host := broker.Host
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	switch broker.Scheme {
+	case "tcp", "mqtt":
+		if broker.Port() == "" {
+			host = net.JoinHostPort(broker.Hostname(), "1883")
+		}
+		conn, err = dialer.Dial("tcp", host)
+	case "ssl", "tls", "mqtts":
+		if broker.Port() == "" {
+			host = net.JoinHostPort(broker.Hostname(), "8883")
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: broker.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", broker.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{conn: conn, reader: bufio.NewReader(conn), keepAlive: cfg.KeepAlive}
+	if err := c.connect(cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *client) connect(cfg Config) error {
+	var flags byte
+	if cfg.CleanSession {
+		flags |= 0x02
+	}
+	payload := encodeString(cfg.ClientID)
+	if cfg.Username != "" {
+		flags |= 0x80
+		payload = append(payload, encodeString(cfg.Username)...)
+		if cfg.Password != "" {
+			flags |= 0x40
+			payload = append(payload, encodeString(cfg.Password)...)
+		}
+	}
+
+	body := encodeString("MQTT")
+	body = append(body, 4, flags) // protocol level 4 = MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(c.keepAlive/time.Second))
+	body = append(body, payload...)
+	if err := c.write(packetConnect<<4, body); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	header, body, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if header>>4 != packetConnack || len(body) != 2 {
+		return errors.New("broker did not acknowledge the connection")
+	}
+	if body[1] != 0 {
+		return fmt.Errorf("broker refused the connection: %s", connackErrors[body[1]])
+	}
+	return nil
+}
+
+// subscribe subscribes to filter and waits for the broker's SUBACK
+func (c *client) subscribe(filter string, qos byte) error {
+	body := binary.BigEndian.AppendUint16(nil, 1)
+	body = append(body, encodeString(filter)...)
+	body = append(body, qos)
+	if err := c.write(packetSubscribe<<4|0x02, body); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		header, body, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		if header>>4 != packetSuback {
+			continue
+		}
+		if len(body) < 3 || body[2] == 0x80 {
+			return fmt.Errorf("broker rejected the subscription to %s", filter)
+		}
+		return nil
+	}
+}
+
+// run reads packets until the connection fails, passing each PUBLISH to
+// handle. It keeps the connection alive with PINGREQ.
+func (c *client) run(handle func(message)) error {
+	done := make(chan struct{})
+	defer close(done)
+	go c.ping(done)
+
+	for {
+		// The broker answers our pings, so silence means the link is gone
+		c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		header, body, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+
+		switch header >> 4 {
+		case packetPublish:
+			msg, err := decodePublish(header, body)
+			if err != nil {
+				return err
+			}
+			if msg.QoS == 2 {
+				// Exactly-once is not needed: ingestion deduplicates by timestamp
+				if err := c.write(packetPubrec<<4, binary.BigEndian.AppendUint16(nil, msg.PacketID)); err != nil {
+					return err
+				}
+			}
+			handle(msg)
+		case packetPubrel:
+			if len(body) >= 2 {
+				if err := c.write(packetPubcomp<<4, body[:2]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// ack acknowledges a processed QoS 1 message
+func (c *client) ack(msg message) error {
+	if msg.QoS != 1 {
+		return nil
+	}
+	return c.write(packetPuback<<4, binary.BigEndian.AppendUint16(nil, msg.PacketID))
+}
+
+func (c *client) ping(done <-chan struct{}) {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := c.write(packetPingreq<<4, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close sends DISCONNECT and closes the connection
+func (c *client) close() {
+	c.write(packetDisconnect<<4, nil)
+	c.conn.Close()
+}
+
+func (c *client) write(header byte, body []byte) error {
+	if len(body) > maxRemainingBytes {
+		return errors.New("packet too large")
+	}
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+func (c *client) readPacket() (byte, []byte, error) {
+	header, err := c.reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		digit, err := c.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func decodePublish(header byte, body []byte) (message, error) {
+	msg := message{QoS: (header >> 1) & 0x03}
+	if len(body) < 2 {
+		return msg, errors.New("malformed PUBLISH")
+	}
+	topicLength := int(binary.BigEndian.Uint16(body))
+	offset := 2 + topicLength
+	if len(body) < offset {
+		return msg, errors.New("malformed PUBLISH")
+	}
+	msg.Topic = string(body[2:offset])
+	if msg.QoS > 0 {
+		if len(body) < offset+2 {
+			return msg, errors.New("malformed PUBLISH")
+		}
+		msg.PacketID = binary.BigEndian.Uint16(body[offset:])
+		offset += 2
+	}
+	msg.Payload = body[offset:]
+	return msg, nil
+}
+
+func encodeString(value string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(value))), value...)
+}
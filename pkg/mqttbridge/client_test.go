@@ -0,0 +1,356 @@
+package mqttbridge
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pipe returns a client and the broker's end of an in-memory connection. The
+// broker end is a client too, so tests can use readPacket and write on it.
+func pipe(t *testing.T, keepAlive time.Duration) (*client, *client) {
+	t.Helper()
+	clientConn, brokerConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		brokerConn.Close()
+	})
+	c := &client{conn: clientConn, reader: bufio.NewReader(clientConn), keepAlive: keepAlive}
+	broker := &client{conn: brokerConn, reader: bufio.NewReader(brokerConn), keepAlive: keepAlive}
+	return c, broker
+}
+
+// expectPacket reads one packet on the broker side and compares it with want
+func expectPacket(t *testing.T, broker *client, wantHeader byte, wantBody []byte) {
+	t.Helper()
+	broker.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	header, body, err := broker.readPacket()
+	if err != nil {
+		t.Fatalf("reading packet: %v", err)
+	}
+	if header != wantHeader || !bytes.Equal(body, wantBody) {
+		t.Fatalf("got packet %#x % x, want %#x % x", header, body, wantHeader, wantBody)
+	}
+}
+
+func TestWriteRemainingLength(t *testing.T) {
+	tests := []struct {
+		length int
+		want   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7F}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xFF, 0x7F}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{2097151, []byte{0xFF, 0xFF, 0x7F}},
+		{2097152, []byte{0x80, 0x80, 0x80, 0x01}},
+	}
+	for _, tt := range tests {
+		c, broker := pipe(t, time.Minute)
+		body := bytes.Repeat([]byte{'x'}, tt.length)
+		errs := make(chan error, 1)
+		go func() { errs <- c.write(packetPublish<<4, body) }()
+
+		got := make([]byte, 1+len(tt.want)+tt.length)
+		if _, err := io.ReadFull(broker.conn, got); err != nil {
+			t.Fatalf("length %d: reading packet: %v", tt.length, err)
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("length %d: write: %v", tt.length, err)
+		}
+		if got[0] != packetPublish<<4 {
+			t.Errorf("length %d: header %#x", tt.length, got[0])
+		}
+		if prefix := got[1 : 1+len(tt.want)]; !bytes.Equal(prefix, tt.want) {
+			t.Errorf("length %d: remaining length % x, want % x", tt.length, prefix, tt.want)
+		}
+	}
+}
+
+func TestWriteRejectsOversizedPacket(t *testing.T) {
+	c := &client{}
+	if err := c.write(packetPublish<<4, make([]byte, maxRemainingBytes+1)); err == nil {
+		t.Fatal("expected an error for a packet above the MQTT size limit")
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantHeader byte
+		wantBody   []byte
+		wantErr    bool
+	}{
+		{name: "empty body", data: []byte{0xD0, 0x00}, wantHeader: 0xD0, wantBody: []byte{}},
+		{name: "short body", data: []byte{0x30, 0x03, 'a', 'b', 'c'}, wantHeader: 0x30, wantBody: []byte("abc")},
+		{name: "two byte length", data: append([]byte{0x30, 0x80, 0x01}, bytes.Repeat([]byte{'y'}, 128)...),
+			wantHeader: 0x30, wantBody: bytes.Repeat([]byte{'y'}, 128)},
+		{name: "no data", data: nil, wantErr: true},
+		{name: "missing length", data: []byte{0x30}, wantErr: true},
+		{name: "unterminated length", data: []byte{0x30, 0x80, 0x80}, wantErr: true},
+		{name: "five byte length", data: []byte{0x30, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}, wantErr: true},
+		{name: "truncated body", data: []byte{0x30, 0x05, 'a', 'b'}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{reader: bufio.NewReader(bytes.NewReader(tt.data))}
+			header, body, err := c.readPacket()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got packet %#x % x", header, body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if header != tt.wantHeader || !bytes.Equal(body, tt.wantBody) {
+				t.Fatalf("got %#x % x, want %#x % x", header, body, tt.wantHeader, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestDecodePublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  byte
+		body    []byte
+		want    message
+		wantErr bool
+	}{
+		{
+			name:   "qos 0",
+			header: packetPublish << 4,
+			body:   []byte{0x00, 0x01, 't', 'p', 'a', 'y'},
+			want:   message{Topic: "t", Payload: []byte("pay")},
+		},
+		{
+			name:   "qos 1 with packet id",
+			header: packetPublish<<4 | 0x02,
+			body:   []byte{0x00, 0x02, 'a', 'b', 0x12, 0x34, '{', '}'},
+			want:   message{Topic: "ab", Payload: []byte("{}"), QoS: 1, PacketID: 0x1234},
+		},
+		{
+			name:   "qos 2 empty payload",
+			header: packetPublish<<4 | 0x04,
+			body:   []byte{0x00, 0x01, 'x', 0x00, 0x09},
+			want:   message{Topic: "x", Payload: []byte{}, QoS: 2, PacketID: 9},
+		},
+		{name: "empty body", header: packetPublish << 4, body: nil, wantErr: true},
+		{name: "one byte", header: packetPublish << 4, body: []byte{0x00}, wantErr: true},
+		{name: "topic longer than body", header: packetPublish << 4, body: []byte{0xFF, 0xFF, 'a'}, wantErr: true},
+		{name: "qos 1 without packet id", header: packetPublish<<4 | 0x02, body: []byte{0x00, 0x01, 't', 0x01}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePublish(tt.header, tt.body)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Topic != tt.want.Topic || got.QoS != tt.want.QoS || got.PacketID != tt.want.PacketID ||
+				!bytes.Equal(got.Payload, tt.want.Payload) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnect(t *testing.T) {
+	cfg := Config{ClientID: "bridge", Username: "user", Password: "secret", CleanSession: true}
+	wantBody := []byte{0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0xC2, 0x00, 0x3C}
+	wantBody = append(wantBody, encodeString("bridge")...)
+	wantBody = append(wantBody, encodeString("user")...)
+	wantBody = append(wantBody, encodeString("secret")...)
+
+	tests := []struct {
+		name    string
+		reply   []byte
+		wantErr string
+	}{
+		{name: "accepted", reply: []byte{0x00, 0x00}},
+		{name: "not authorized", reply: []byte{0x00, 0x05}, wantErr: "not authorized"},
+		{name: "bad user name or password", reply: []byte{0x00, 0x04}, wantErr: "bad user name or password"},
+		{name: "malformed connack", reply: []byte{0x00}, wantErr: "did not acknowledge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, broker := pipe(t, 60*time.Second)
+			errs := make(chan error, 1)
+			go func() { errs <- c.connect(cfg) }()
+
+			expectPacket(t, broker, packetConnect<<4, wantBody)
+			if err := broker.write(packetConnack<<4, tt.reply); err != nil {
+				t.Fatalf("writing CONNACK: %v", err)
+			}
+
+			err := <-errs
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnectWithoutCredentials(t *testing.T) {
+	c, broker := pipe(t, 30*time.Second)
+	errs := make(chan error, 1)
+	go func() { errs <- c.connect(Config{ClientID: "b"}) }()
+
+	wantBody := append([]byte{0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x00, 0x00, 0x1E}, encodeString("b")...)
+	expectPacket(t, broker, packetConnect<<4, wantBody)
+	broker.write(packetConnack<<4, []byte{0x00, 0x00})
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	filter := "tirta/+/meters/+/readings"
+	wantBody := append([]byte{0x00, 0x01}, encodeString(filter)...)
+	wantBody = append(wantBody, 0x01)
+
+	tests := []struct {
+		name    string
+		suback  []byte
+		wantErr bool
+	}{
+		{name: "granted qos 1", suback: []byte{0x00, 0x01, 0x01}},
+		{name: "granted qos 0", suback: []byte{0x00, 0x01, 0x00}},
+		{name: "rejected", suback: []byte{0x00, 0x01, 0x80}, wantErr: true},
+		{name: "malformed", suback: []byte{0x00, 0x01}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, broker := pipe(t, time.Minute)
+			errs := make(chan error, 1)
+			go func() { errs <- c.subscribe(filter, 1) }()
+
+			expectPacket(t, broker, packetSubscribe<<4|0x02, wantBody)
+			// Packets before the SUBACK are skipped
+			broker.write(packetPingresp<<4, nil)
+			broker.write(packetSuback<<4, tt.suback)
+
+			err := <-errs
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunAcknowledgements(t *testing.T) {
+	c, broker := pipe(t, time.Minute)
+
+	received := make(chan message, 3)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.run(func(msg message) {
+			received <- msg
+			if err := c.ack(msg); err != nil {
+				t.Errorf("ack: %v", err)
+			}
+		})
+	}()
+
+	// QoS 0: delivered, nothing to acknowledge
+	broker.write(packetPublish<<4, []byte{0x00, 0x01, 'a', '0'})
+	if msg := <-received; msg.Topic != "a" || string(msg.Payload) != "0" {
+		t.Fatalf("unexpected QoS 0 message %+v", msg)
+	}
+
+	// QoS 1: PUBACK only after the handler acknowledged it
+	broker.write(packetPublish<<4|0x02, []byte{0x00, 0x01, 'b', 0x00, 0x07, '1'})
+	expectPacket(t, broker, packetPuback<<4, []byte{0x00, 0x07})
+	if msg := <-received; msg.QoS != 1 || msg.PacketID != 7 {
+		t.Fatalf("unexpected QoS 1 message %+v", msg)
+	}
+
+	// QoS 2: PUBREC on receipt, PUBCOMP for the broker's PUBREL
+	broker.write(packetPublish<<4|0x04, []byte{0x00, 0x01, 'c', 0x00, 0x09, '2'})
+	expectPacket(t, broker, packetPubrec<<4, []byte{0x00, 0x09})
+	if msg := <-received; msg.QoS != 2 || msg.PacketID != 9 {
+		t.Fatalf("unexpected QoS 2 message %+v", msg)
+	}
+	broker.write(packetPubrel<<4|0x02, []byte{0x00, 0x09})
+	expectPacket(t, broker, packetPubcomp<<4, []byte{0x00, 0x09})
+
+	broker.conn.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("run returned without error after the connection closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("run did not return after the connection closed")
+	}
+}
+
+func TestRunStopsOnMalformedPublish(t *testing.T) {
+	c, broker := pipe(t, time.Minute)
+	done := make(chan error, 1)
+	go func() { done <- c.run(func(message) { t.Error("handler called for a malformed PUBLISH") }) }()
+
+	broker.write(packetPublish<<4|0x02, []byte{0x00, 0x05, 'a'})
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error for a malformed PUBLISH")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("run did not return")
+	}
+}
+
+func TestRunKeepAlive(t *testing.T) {
+	keepAlive := 200 * time.Millisecond
+	c, broker := pipe(t, keepAlive)
+	done := make(chan error, 1)
+	go func() { done <- c.run(func(message) {}) }()
+
+	// Pings arrive every half keepalive and are answered
+	for i := 0; i < 2; i++ {
+		expectPacket(t, broker, packetPingreq<<4, []byte{})
+		broker.write(packetPingresp<<4, nil)
+	}
+
+	// A broker that goes silent is detected after one and a half keepalives
+	go io.Copy(io.Discard, broker.conn)
+	select {
+	case err := <-done:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Fatalf("got %v, want a read timeout", err)
+		}
+	case <-time.After(5 * keepAlive):
+		t.Fatal("run did not notice the silent broker")
+	}
+}
+
+func TestAckIgnoresOtherQoS(t *testing.T) {
+	c := &client{}
+	for _, qos := range []byte{0, 2} {
+		if err := c.ack(message{QoS: qos, PacketID: 1}); err != nil {
+			t.Fatalf("qos %d: unexpected error %v", qos, err)
+		}
+	}
+}
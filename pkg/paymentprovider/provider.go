@@ -0,0 +1,78 @@
+// Package paymentprovider defines the interface external payment providers
+// (bank direct debit, e-wallet, card tokenisation) implement so auto-debit
+// can charge customers without knowing the provider's API.
+package paymentprovider
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ChargeRequest is a single debit against a customer's tokenised account
+type ChargeRequest struct {
+	TenantID     uuid.UUID
+	MandateID    uuid.UUID
+	InvoiceID    uuid.UUID
+	AccountToken string
+	Amount       float64
+	Reference    string // unique per attempt, providers use it for idempotency
+	Description  string
+}
+
+// ChargeResult is the provider's answer to a charge
+type ChargeResult struct {
+	Approved      bool
+	ProviderRef   string
+	FailureReason string
+}
+
+// Provider charges tokenised accounts. Charge returns an error only when the
+// outcome is unknown (timeout, transport failure); a decline is a result with
+// Approved false.
+type Provider interface {
+	Name() string
+	Charge(ctx context.Context, req ChargeRequest) (*ChargeResult, error)
+}
+
+// StatusChecker is implemented by providers that can look up a charge whose
+// outcome was unknown, by its Reference. It returns an error while the
+// outcome is still unknown; a charge the provider never received is a result
+// with Approved false.
+type StatusChecker interface {
+	ChargeStatus(ctx context.Context, req ChargeRequest) (*ChargeResult, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// Register makes a provider available by its name, replacing any previous one
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Get returns the provider registered under name
+func Get(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names lists the registered providers
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,33 @@
+package paymentprovider
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// SandboxProvider approves every charge except for account tokens starting
+// with "decline", for development and staging environments.
+type SandboxProvider struct{}
+
+func (SandboxProvider) Name() string {
+	return "sandbox"
+}
+
+func (SandboxProvider) Charge(ctx context.Context, req ChargeRequest) (*ChargeResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(req.AccountToken, "decline") {
+		return &ChargeResult{Approved: false, FailureReason: "Saldo tidak mencukupi"}, nil
+	}
+	return &ChargeResult{
+		Approved:    true,
+		ProviderRef: "SBX-" + time.Now().Format("20060102150405") + "-" + req.Reference,
+	}, nil
+}
+
+// ChargeStatus answers as Charge would have; the sandbox keeps no state
+func (p SandboxProvider) ChargeStatus(ctx context.Context, req ChargeRequest) (*ChargeResult, error) {
+	return p.Charge(ctx, req)
+}
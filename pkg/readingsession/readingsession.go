@@ -0,0 +1,168 @@
+// Package readingsession creates the field reading sessions of each route on
+// its schedule day. Routes without an assigned reader are skipped until one
+// is assigned; sessions can also be scheduled by hand.
+package readingsession
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/adipras/tirta-saas-backend/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrCarryOverDateTaken is returned when the route's session on the
+// carry-over date is closed or belongs to another month
+var ErrCarryOverDateTaken = errors.New("Route already has a closed session or one for another month on the carry-over date")
+
+// Summary reports the outcome of one scheduling run
+type Summary struct {
+	Date     string `json:"date"`
+	Routes   int    `json:"routes"`    // routes scheduled on the date
+	Created  int    `json:"created"`   // new sessions
+	Existing int    `json:"existing"`  // already had a session that day
+	NoReader int    `json:"no_reader"` // skipped, no reader assigned
+}
+
+// Schedule creates a session for every active route of the tenant that is
+// read on date. It is idempotent per route and date.
+func Schedule(db *gorm.DB, tenantID uuid.UUID, date time.Time) (*Summary, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	summary := &Summary{Date: day.Format("2006-01-02")}
+
+	var routes []models.ReadingRoute
+	if err := db.Where("tenant_id = ? AND is_active = ?", tenantID, true).Find(&routes).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range routes {
+		route := &routes[i]
+		if !route.ScheduledOn(day) {
+			continue
+		}
+		summary.Routes++
+
+		if route.AssignedTo == nil {
+			summary.NoReader++
+			continue
+		}
+
+		session, err := CreateSession(db, route, *route.AssignedTo, day, day.Format("2006-01"), nil)
+		if err != nil {
+			return summary, err
+		}
+		if session != nil {
+			summary.Created++
+		} else {
+			summary.Existing++
+		}
+	}
+
+	return summary, nil
+}
+
+// CreateSession schedules a session of route for reader on date. It returns
+// nil without error when the route already has a session that day, including
+// a cancelled one, so a cancelled session is not scheduled again.
+func CreateSession(db *gorm.DB, route *models.ReadingRoute, readerID uuid.UUID, date time.Time, usageMonth string, carriedOverFrom *uuid.UUID) (*models.ReadingSession, error) {
+	var existing int64
+	if err := db.Model(&models.ReadingSession{}).
+		Where("route_id = ? AND scheduled_date = ?", route.ID, date.Format("2006-01-02")).
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, nil
+	}
+
+	var total int64
+	if err := db.Model(&models.Customer{}).
+		Where("tenant_id = ? AND reading_route_id = ?", route.TenantID, route.ID).
+		Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	session := models.ReadingSession{
+		TenantID:          route.TenantID,
+		RouteID:           route.ID,
+		ReaderID:          readerID,
+		ScheduledDate:     date,
+		UsageMonth:        usageMonth,
+		CarriedOverFromID: carriedOverFrom,
+		Status:            models.ReadingSessionScheduled,
+		TotalCustomers:    int(total),
+	}
+	if err := db.Omit("Route", "Reader").Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CarryOver schedules the unread stops of session on date. Stops are the
+// route's customers still unread for the month, so an open session of the
+// route for the same month on that date already lists them and is returned
+// with merged set instead of creating a new one.
+func CarryOver(db *gorm.DB, session *models.ReadingSession, date time.Time) (carryOver *models.ReadingSession, merged bool, err error) {
+	var existing models.ReadingSession
+	err = db.Where("route_id = ? AND scheduled_date = ?", session.RouteID, date.Format("2006-01-02")).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		carryOver, err = CreateSession(db, &session.Route, session.ReaderID, date, session.UsageMonth, &session.ID)
+		return carryOver, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	open := existing.Status == models.ReadingSessionScheduled || existing.Status == models.ReadingSessionInProgress
+	if !open || existing.UsageMonth != session.UsageMonth {
+		return nil, false, ErrCarryOverDateTaken
+	}
+	return &existing, true, nil
+}
+
+// ScheduleForTenant is the background hook for the daily run
+func ScheduleForTenant(tenantID uuid.UUID, date time.Time) {
+	summary, err := Schedule(config.DB, tenantID, date)
+	if err != nil {
+		logger.Error("Reading session scheduling failed", err, map[string]interface{}{
+			"tenant_id": tenantID,
+			"date":      date.Format("2006-01-02"),
+		})
+		return
+	}
+	if summary.Routes == 0 {
+		return
+	}
+	logger.Info("Reading sessions scheduled", map[string]interface{}{
+		"tenant_id": tenantID,
+		"date":      summary.Date,
+		"routes":    summary.Routes,
+		"created":   summary.Created,
+		"existing":  summary.Existing,
+		"no_reader": summary.NoReader,
+	})
+}
+
+// StartScheduler schedules today's sessions for every active tenant now and
+// then once per interval. It blocks, so start it in a goroutine.
+func StartScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var tenantIDs []uuid.UUID
+		if err := config.DB.Model(&models.Tenant{}).Where("status = ?", models.TenantStatusActive).
+			Pluck("id", &tenantIDs).Error; err != nil {
+			logger.Error("Reading session scheduler failed to load tenants", err, nil)
+		}
+		today := time.Now()
+		for _, tenantID := range tenantIDs {
+			ScheduleForTenant(tenantID, today)
+		}
+		<-ticker.C
+	}
+}
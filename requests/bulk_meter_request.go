@@ -0,0 +1,28 @@
+package requests
+
+type CreateBulkMeterRequest struct {
+	ServiceAreaID  string  `json:"service_area_id" binding:"required"`
+	MeterNumber    string  `json:"meter_number" binding:"required,max=50"`
+	Name           string  `json:"name" binding:"max=100"`
+	Brand          string  `json:"brand" binding:"max=100"`
+	Model          string  `json:"model" binding:"max=100"`
+	InstallDate    string  `json:"install_date" binding:"required"` // YYYY-MM-DD
+	InitialReading float64 `json:"initial_reading" binding:"gte=0"`
+	Notes          string  `json:"notes"`
+}
+
+type UpdateBulkMeterRequest struct {
+	ServiceAreaID *string `json:"service_area_id"`
+	Name          *string `json:"name" binding:"omitempty,max=100"`
+	Brand         *string `json:"brand" binding:"omitempty,max=100"`
+	Model         *string `json:"model" binding:"omitempty,max=100"`
+	Status        *string `json:"status" binding:"omitempty,oneof=active inactive"`
+	Notes         *string `json:"notes"`
+}
+
+type RecordBulkMeterReadingRequest struct {
+	ReadingMonth string   `json:"reading_month" binding:"required"` // YYYY-MM
+	ReadingEnd   *float64 `json:"reading_end" binding:"required,gte=0"`
+	ReadAt       string   `json:"read_at"` // RFC3339, defaults to now
+	Notes        string   `json:"notes"`
+}
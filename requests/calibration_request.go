@@ -0,0 +1,22 @@
+package requests
+
+type CreateCalibrationPolicyRequest struct {
+	Brand          string `json:"brand" binding:"max=100"`
+	Model          string `json:"model" binding:"max=100"`
+	IntervalMonths int    `json:"interval_months" binding:"required,min=1,max=240"`
+	Notes          string `json:"notes"`
+}
+
+type UpdateCalibrationPolicyRequest struct {
+	IntervalMonths *int    `json:"interval_months" binding:"omitempty,min=1,max=240"`
+	IsActive       *bool   `json:"is_active"`
+	Notes          *string `json:"notes"`
+}
+
+type RecordCalibrationRequest struct {
+	CalibrationDate string `json:"calibration_date"` // YYYY-MM-DD, defaults to today
+	Result          string `json:"result" binding:"required,oneof=passed adjusted failed"`
+	CertificateNo   string `json:"certificate_no" binding:"max=100"`
+	NextCalibDate   string `json:"next_calib_date"` // YYYY-MM-DD, defaults to the policy interval
+	Notes           string `json:"notes"`
+}
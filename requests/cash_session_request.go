@@ -0,0 +1,21 @@
+package requests
+
+import "github.com/google/uuid"
+
+type OpenCashSessionRequest struct {
+	Notes string `json:"notes"`
+}
+
+type CashCountRequest struct {
+	PaymentMethodID *uuid.UUID `json:"payment_method_id"`
+	CountedAmount   float64    `json:"counted_amount" binding:"gte=0"`
+}
+
+type CloseCashSessionRequest struct {
+	Counts []CashCountRequest `json:"counts" binding:"dive"`
+	Notes  string             `json:"notes"`
+}
+
+type SignOffCashSessionRequest struct {
+	Notes string `json:"notes"`
+}
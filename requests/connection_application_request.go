@@ -0,0 +1,58 @@
+package requests
+
+import (
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+// SubmitConnectionApplicationRequest is filed by the public; the village code
+// identifies the tenant
+type SubmitConnectionApplicationRequest struct {
+	VillageCode string `json:"village_code" binding:"required"`
+	ConnectionApplicationRequest
+}
+
+type ConnectionApplicationRequest struct {
+	ApplicantName  string     `json:"applicant_name" binding:"required"`
+	IdentityNumber string     `json:"identity_number"`
+	Email          string     `json:"email" binding:"omitempty,email"`
+	Phone          string     `json:"phone" binding:"required"`
+	Address        string     `json:"address" binding:"required"`
+	SubscriptionID uuid.UUID  `json:"subscription_id" binding:"required"`
+	ServiceAreaID  *uuid.UUID `json:"service_area_id"`
+	Latitude       *float64   `json:"latitude"`
+	Longitude      *float64   `json:"longitude"`
+	Notes          string     `json:"notes"`
+}
+
+type SurveyConnectionApplicationRequest struct {
+	Feasible        *bool                       `json:"feasible" binding:"required"`
+	DistanceToMainM float64                     `json:"distance_to_main_m" binding:"gte=0"`
+	Materials       []models.ConnectionMaterial `json:"materials" binding:"dive"`
+	ServiceAreaID   *uuid.UUID                  `json:"service_area_id"` // corrects the applicant's choice
+	Latitude        *float64                    `json:"latitude"`        // measured on site
+	Longitude       *float64                    `json:"longitude"`
+	Notes           string                      `json:"notes"`
+}
+
+type ApproveConnectionApplicationRequest struct {
+	ConnectionFee  *float64   `json:"connection_fee" binding:"omitempty,gte=0"` // defaults to the subscription's registration fee
+	SubscriptionID *uuid.UUID `json:"subscription_id"`                          // changes the requested subscription type
+}
+
+type RejectConnectionApplicationRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type InstallConnectionApplicationRequest struct {
+	MeterNumber    string   `json:"meter_number" binding:"required"`
+	Brand          string   `json:"brand"`
+	Model          string   `json:"model"`
+	InstallDate    string   `json:"install_date"` // YYYY-MM-DD, defaults to today
+	InitialReading float64  `json:"initial_reading" binding:"gte=0"`
+	RolloverAt     float64  `json:"rollover_at" binding:"gte=0"`
+	Latitude       *float64 `json:"latitude"` // defaults to the surveyed location
+	Longitude      *float64 `json:"longitude"`
+	Password       string   `json:"password" binding:"omitempty,min=6"` // customer portal password, optional
+	Notes          string   `json:"notes"`
+}
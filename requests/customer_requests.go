@@ -10,6 +10,9 @@ type CreateCustomerRequest struct {
 	SubscriptionID uuid.UUID `json:"subscription_id" binding:"required" format:"uuid" doc:"ID of the subscription type/plan" example:"123e4567-e89b-12d3-a456-426614174000"`
 	Phone          string    `json:"phone,omitempty" pattern:"^[0-9+\\-\\s()]{10,20}$" doc:"Phone number for contact" example:"081234567890"`
 	Address        string    `json:"address,omitempty" maxLength:"500" doc:"Full address of the customer" example:"Jl. Merdeka No. 123, Jakarta"`
+	ServiceAreaID  *uuid.UUID `json:"service_area_id,omitempty" format:"uuid" doc:"Service area (RT/RW) of the connection" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Latitude       *float64   `json:"latitude,omitempty" doc:"Latitude of the connection, must lie in the service area's boundary" example:"-7.2575"`
+	Longitude      *float64   `json:"longitude,omitempty" doc:"Longitude of the connection" example:"112.7521"`
 }
 
 type UpdateCustomerRequest struct {
@@ -17,4 +20,57 @@ type UpdateCustomerRequest struct {
 	SubscriptionID uuid.UUID `json:"subscription_id" binding:"required" format:"uuid" doc:"ID of the subscription type/plan" example:"123e4567-e89b-12d3-a456-426614174000"`
 	Phone          string    `json:"phone,omitempty" pattern:"^[0-9+\\-\\s()]{10,20}$" doc:"Phone number for contact" example:"081234567890"`
 	Address        string    `json:"address,omitempty" maxLength:"500" doc:"Full address of the customer" example:"Jl. Merdeka No. 123, Jakarta Selatan"`
+	ServiceAreaID  *uuid.UUID `json:"service_area_id,omitempty" format:"uuid" doc:"Service area (RT/RW) of the connection, omitted keeps the current one" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Latitude       *float64   `json:"latitude,omitempty" doc:"Latitude of the connection, omitted keeps the current location" example:"-7.2575"`
+	Longitude      *float64   `json:"longitude,omitempty" doc:"Longitude of the connection" example:"112.7521"`
+}
+
+type ChangeCustomerStatusRequest struct {
+	Status          string   `json:"status" binding:"required,oneof=applicant active suspended disconnected closed" enum:"applicant,active,suspended,disconnected,closed" doc:"New lifecycle status" example:"suspended"`
+	Reason          string   `json:"reason" binding:"required,max=255" maxLength:"255" doc:"Why the status changes" example:"Rumah kosong, permintaan pelanggan"`
+	EffectiveDate   string   `json:"effective_date,omitempty" format:"date" doc:"Date the change takes effect (YYYY-MM-DD), defaults to today; cannot be in the future" example:"2025-01-15"`
+	Notes           string   `json:"notes,omitempty" maxLength:"1000" doc:"Additional notes"`
+	ReconnectionFee *float64 `json:"reconnection_fee,omitempty" binding:"omitempty,gte=0" minimum:"0" doc:"Reconnection fee to bill when reactivating a disconnected customer, defaults to the subscription's; 0 waives it" example:"150000"`
+}
+
+type FinalMeterReadingRequest struct {
+	MeterID      *uuid.UUID `json:"meter_id,omitempty" format:"uuid" doc:"Meter read, optional when the customer has one active meter"`
+	MeterReading float64    `json:"meter_reading" binding:"gte=0" minimum:"0" doc:"Final register of the meter" example:"1250.5"`
+	PhotoID      *uuid.UUID `json:"photo_id,omitempty" format:"uuid" doc:"Uploaded meter photo of the reading"`
+}
+
+type TransferCustomerRequest struct {
+	Name            string                     `json:"name" binding:"required,min=3,max=100" minLength:"3" maxLength:"100" doc:"Full name of the new owner" example:"Siti Aminah"`
+	Email           string                     `json:"email,omitempty" binding:"omitempty,email" format:"email" doc:"Email of the new owner" example:"siti@example.com"`
+	Phone           string                     `json:"phone,omitempty" pattern:"^[0-9+\\-\\s()]{10,20}$" doc:"Phone number of the new owner" example:"081234567890"`
+	Password        string                     `json:"password,omitempty" binding:"omitempty,min=6" minLength:"6" doc:"Portal password of the new owner, can be set later"`
+	SubscriptionID  *uuid.UUID                 `json:"subscription_id,omitempty" format:"uuid" doc:"Subscription type of the new account, defaults to the old account's"`
+	FinalReadings   []FinalMeterReadingRequest `json:"final_readings" binding:"required,min=1,dive" doc:"Final reading of every active meter"`
+	BalanceHandling string                     `json:"balance_handling,omitempty" binding:"omitempty,oneof=settle carry_over" enum:"settle,carry_over" doc:"settle leaves unpaid bills with the previous owner, carry_over bills them to the new owner; defaults to settle" example:"settle"`
+	ArchivedNumber  string                     `json:"archived_number,omitempty" maxLength:"50" doc:"Account number kept by the closed account, defaults to the current one with the transfer date appended" example:"MTR-001-20250115"`
+	TransferDate    string                     `json:"transfer_date,omitempty" format:"date" doc:"Date of the handover (YYYY-MM-DD), defaults to today; cannot be in the future" example:"2025-01-15"`
+	Reason          string                     `json:"reason,omitempty" maxLength:"255" doc:"Why the account is transferred" example:"Rumah dijual"`
+	Notes           string                     `json:"notes,omitempty" maxLength:"1000" doc:"Additional notes"`
+}
+
+type RelocationMeterRequest struct {
+	MeterNumber    string     `json:"meter_number" binding:"required" doc:"Number of the meter fitted at the new address" example:"MTR-002"`
+	Brand          string     `json:"brand,omitempty" doc:"Defaults to the old meter's brand"`
+	Model          string     `json:"model,omitempty" doc:"Defaults to the old meter's model"`
+	InitialReading float64    `json:"initial_reading" binding:"gte=0" minimum:"0" doc:"Register of the new meter when installed"`
+	RolloverAt     float64    `json:"rollover_at" binding:"gte=0" minimum:"0" doc:"Register value the new meter rolls over at, 0 if unknown"`
+	FinalReading   float64    `json:"final_reading" binding:"gte=0" minimum:"0" doc:"Final register of the meter at the old address" example:"1250.5"`
+	FinalPhotoID   *uuid.UUID `json:"final_photo_id,omitempty" format:"uuid" doc:"Uploaded meter photo of the final reading"`
+	Notes          string     `json:"notes,omitempty" doc:"Installation notes"`
+}
+
+type RelocateCustomerRequest struct {
+	Address        string                  `json:"address" binding:"required,max=500" maxLength:"500" doc:"New address of the customer" example:"Jl. Kenanga No. 7"`
+	ServiceAreaID  *uuid.UUID              `json:"service_area_id,omitempty" format:"uuid" doc:"Service area of the new address, omitted keeps the current one"`
+	Latitude       *float64                `json:"latitude,omitempty" doc:"Latitude of the new address" example:"-7.2575"`
+	Longitude      *float64                `json:"longitude,omitempty" doc:"Longitude of the new address" example:"112.7521"`
+	MeterID        *uuid.UUID              `json:"meter_id,omitempty" format:"uuid" doc:"Meter at the old address, optional when the customer has one active meter"`
+	NewMeter       *RelocationMeterRequest `json:"new_meter,omitempty" doc:"Meter fitted at the new address; omitted moves the meter along with the customer"`
+	RelocationDate string                  `json:"relocation_date,omitempty" format:"date" doc:"Date of the move (YYYY-MM-DD), defaults to today" example:"2025-01-15"`
+	Notes          string                  `json:"notes,omitempty" maxLength:"1000" doc:"Additional notes"`
 }
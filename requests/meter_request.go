@@ -1,31 +1,43 @@
 package requests
 
+import "time"
+
 type CreateMeterRequest struct {
-	CustomerID     string  `json:"customer_id" binding:"required"`
-	MeterNumber    string  `json:"meter_number" binding:"required"`
-	Brand          string  `json:"brand"`
-	Model          string  `json:"model"`
-	InstallDate    string  `json:"install_date" binding:"required"`
-	InitialReading float64 `json:"initial_reading" binding:"gte=0"`
-	Notes          string  `json:"notes"`
+	CustomerID     string   `json:"customer_id" binding:"required"`
+	MeterNumber    string   `json:"meter_number" binding:"required"`
+	Brand          string   `json:"brand"`
+	Model          string   `json:"model"`
+	InstallDate    string   `json:"install_date" binding:"required"`
+	InitialReading float64  `json:"initial_reading" binding:"gte=0"`
+	RolloverAt     float64  `json:"rollover_at" binding:"gte=0"`
+	Notes          string   `json:"notes"`
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
 }
 
 type UpdateMeterRequest struct {
-	Brand         string  `json:"brand"`
-	Model         string  `json:"model"`
-	LastCalibDate *string `json:"last_calib_date"`
-	NextCalibDate *string `json:"next_calib_date"`
-	Status        string  `json:"status" binding:"omitempty,oneof=active inactive broken replaced"`
-	Notes         string  `json:"notes"`
+	Brand         string   `json:"brand"`
+	Model         string   `json:"model"`
+	LastCalibDate *string  `json:"last_calib_date"`
+	NextCalibDate *string  `json:"next_calib_date"`
+	RolloverAt    *float64 `json:"rollover_at" binding:"omitempty,gte=0"`
+	Status        string   `json:"status" binding:"omitempty,oneof=active inactive broken"` // replaced is set by replacing the meter
+	Notes         string   `json:"notes"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
 }
 
 type ReplaceMeterRequest struct {
-	NewMeterNumber string  `json:"new_meter_number" binding:"required"`
-	NewBrand       string  `json:"new_brand"`
-	NewModel       string  `json:"new_model"`
-	FinalReading   float64 `json:"final_reading" binding:"required,gte=0"`
-	Reason         string  `json:"reason" binding:"required"`
-	Notes          string  `json:"notes"`
+	NewMeterNumber    string  `json:"new_meter_number" binding:"required"`
+	NewBrand          string  `json:"new_brand"`
+	NewModel          string  `json:"new_model"`
+	NewInitialReading float64 `json:"new_initial_reading" binding:"gte=0"`
+	NewRolloverAt     float64 `json:"new_rollover_at" binding:"gte=0"`
+	FinalReading      float64 `json:"final_reading" binding:"gte=0"`
+	FinalPhotoID      *string `json:"final_photo_id"`   // meter photo of the final reading
+	ReplacementDate   string  `json:"replacement_date"` // YYYY-MM-DD, defaults to today
+	Reason            string  `json:"reason" binding:"required"`
+	Notes             string  `json:"notes"`
 }
 
 type ReportMeterIssueRequest struct {
@@ -36,7 +48,63 @@ type ReportMeterIssueRequest struct {
 	PhotoURL    string `json:"photo_url"`
 }
 
+type CustomerReportMeterIssueRequest struct {
+	MeterID     string `json:"meter_id"` // optional when the customer has one active meter
+	IssueType   string `json:"issue_type" binding:"required,oneof=broken leak stuck incorrect other"`
+	Description string `json:"description" binding:"required"`
+	PhotoURL    string `json:"photo_url"`
+}
+
+type UpdateMeterIssueRequest struct {
+	AssignedTo *string `json:"assigned_to"` // empty string unassigns
+	Priority   string  `json:"priority" binding:"omitempty,oneof=low normal high critical"`
+}
+
 type ResolveMeterIssueRequest struct {
-	Resolution string `json:"resolution" binding:"required"`
-	Notes      string `json:"notes"`
+	Resolution  string `json:"resolution" binding:"required"`
+	MeterStatus string `json:"meter_status" binding:"omitempty,oneof=active inactive broken"` // defaults to the current status
+	Notes       string `json:"notes"`
+}
+
+type CloseMeterIssueRequest struct {
+	Notes string `json:"notes"`
+}
+
+type CreateMeterDeviceRequest struct {
+	MeterID        string  `json:"meter_id" binding:"required"`
+	Name           string  `json:"name"`
+	SerialNumber   string  `json:"serial_number"`
+	LitersPerPulse float64 `json:"liters_per_pulse" binding:"gte=0"` // pulse counters only
+	PulseOffset    float64 `json:"pulse_offset" binding:"gte=0"`     // register value at pulse count 0
+}
+
+type UpdateMeterDeviceRequest struct {
+	Name           *string  `json:"name"`
+	SerialNumber   *string  `json:"serial_number"`
+	IsActive       *bool    `json:"is_active"`
+	LitersPerPulse *float64 `json:"liters_per_pulse" binding:"omitempty,gte=0"`
+	PulseOffset    *float64 `json:"pulse_offset" binding:"omitempty,gte=0"`
+}
+
+type DeviceReadingItem struct {
+	Timestamp time.Time `json:"timestamp" binding:"required"`     // RFC3339 device time
+	Reading   *float64  `json:"reading"`                          // register value in m³
+	Pulses    *int64    `json:"pulses" binding:"omitempty,gte=0"` // pulse count, instead of reading
+}
+
+type DeviceReadingBatchRequest struct {
+	Readings []DeviceReadingItem `json:"readings" binding:"required,min=1,dive"`
+}
+
+type UpdateLeakSettingsRequest struct {
+	IsEnabled       *bool    `json:"is_enabled"`
+	NightStartHour  *int     `json:"night_start_hour" binding:"omitempty,min=0,max=23"`
+	NightEndHour    *int     `json:"night_end_hour" binding:"omitempty,min=1,max=24"`
+	MinNightFlowLPH *float64 `json:"min_night_flow_lph" binding:"omitempty,gt=0"`
+	LeakNights      *int     `json:"leak_nights" binding:"omitempty,min=1,max=14"`
+	BaselineDays    *int     `json:"baseline_days" binding:"omitempty,min=2,max=60"`
+	IncreasePercent *float64 `json:"increase_percent" binding:"omitempty,gt=0,max=10000"`
+	MinIncreaseM3   *float64 `json:"min_increase_m3" binding:"omitempty,gte=0"`
+	SustainedDays   *int     `json:"sustained_days" binding:"omitempty,min=1,max=14"`
+	NotifyCustomer  *bool    `json:"notify_customer"`
 }
@@ -0,0 +1,28 @@
+package requests
+
+import "github.com/google/uuid"
+
+type CreatePaymentMandateRequest struct {
+	CustomerID      uuid.UUID  `json:"customer_id" binding:"required"`
+	Provider        string     `json:"provider" binding:"required,max=50"`
+	AccountToken    string     `json:"account_token" binding:"required,max=255"`
+	AccountMask     string     `json:"account_mask" binding:"max=50"`
+	MaxAmount       float64    `json:"max_amount" binding:"gte=0"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id"`
+	Notes           string     `json:"notes"`
+}
+
+type UpdatePaymentMandateRequest struct {
+	MaxAmount       *float64   `json:"max_amount" binding:"omitempty,gte=0"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id"`
+	Status          string     `json:"status" binding:"omitempty,oneof=active suspended"`
+	Notes           *string    `json:"notes"`
+}
+
+type RevokePaymentMandateRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type RunAutoDebitRequest struct {
+	UsageMonth string `json:"usage_month" binding:"required,len=7"`
+}
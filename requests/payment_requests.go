@@ -3,9 +3,24 @@ package requests
 import "github.com/google/uuid"
 
 type CreatePaymentRequest struct {
-	InvoiceID     uuid.UUID `json:"invoice_id" binding:"required" format:"uuid" doc:"Invoice ID to pay" example:"123e4567-e89b-12d3-a456-426614174000"`
-	Amount        float64   `json:"amount" binding:"required" minimum:"0" doc:"Payment amount in IDR" example:"150000"`
-	PaymentMethod string    `json:"payment_method" binding:"omitempty" enum:"CASH,BANK_TRANSFER,E_WALLET,CREDIT_CARD" doc:"Method of payment" example:"CASH"`
-	PaymentDate   string    `json:"payment_date,omitempty" format:"date" doc:"Payment date (ISO format)" example:"2025-01-15"`
-	Notes         string    `json:"notes,omitempty" maxLength:"500" doc:"Additional notes for this payment" example:"Paid in full"`
+	InvoiceID       uuid.UUID  `json:"invoice_id" binding:"required" format:"uuid" doc:"Invoice ID to pay" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Amount          float64    `json:"amount" binding:"required" minimum:"0" doc:"Payment amount in IDR" example:"150000"`
+	PaymentMethod   string     `json:"payment_method" binding:"omitempty" enum:"CASH,BANK_TRANSFER,E_WALLET,CREDIT_CARD" doc:"Method of payment" example:"CASH"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id,omitempty" format:"uuid" doc:"Tenant payment method used"`
+	ReferenceNumber string     `json:"reference_number,omitempty" maxLength:"100" doc:"Transfer or receipt reference" example:"TRF-0001"`
+	PaymentDate     string     `json:"payment_date,omitempty" format:"date" doc:"Payment date (ISO format)" example:"2025-01-15"`
+	Notes           string     `json:"notes,omitempty" maxLength:"500" doc:"Additional notes for this payment" example:"Paid in full"`
+}
+
+type ReversePaymentRequest struct {
+	Reason      string `json:"reason" binding:"required" maxLength:"500" doc:"Why the payment is reversed" example:"Transfer bounced"`
+	Disposition string `json:"disposition" binding:"omitempty,oneof=returned credit" enum:"returned,credit" doc:"returned = money never received, credit = keep as customer credit" example:"returned"`
+	Notes       string `json:"notes,omitempty" maxLength:"500" doc:"Additional notes" example:"Bank notice 2025-01-20"`
+}
+
+type RefundCustomerCreditRequest struct {
+	Amount          float64    `json:"amount" binding:"required,gt=0" minimum:"0" doc:"Refund amount in IDR" example:"50000"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id,omitempty" format:"uuid" doc:"Method used to pay out the refund"`
+	ReferenceNumber string     `json:"reference_number,omitempty" maxLength:"100" doc:"Transfer or voucher reference" example:"TRF-0001"`
+	Reason          string     `json:"reason" binding:"required" maxLength:"500" doc:"Why the refund is made" example:"Customer moved out"`
 }
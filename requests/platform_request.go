@@ -102,6 +102,9 @@ type UpdateTenantSettingsRequest struct {
 	ServiceArea    string `json:"service_area"`
 	TimeZone       string `json:"timezone"`
 	Language       string `json:"language" binding:"omitempty,oneof=id en"`
+	
+	// Meter Reading Policy
+	RequireReadingPhoto *bool `json:"require_reading_photo"`
 }
 
 // BulkCustomerImportRequest represents request for bulk customer import
@@ -18,9 +18,43 @@ type UpdateReadingRouteRequest struct {
 	IsActive    *bool   `json:"is_active"`
 }
 
+type SetRouteStopsRequest struct {
+	CustomerIDs []string `json:"customer_ids" binding:"required,dive,uuid"` // in visiting order
+}
+
+type AssignRouteCustomersRequest struct {
+	CustomerIDs []string `json:"customer_ids" binding:"required,min=1,dive,uuid"`
+}
+
 type StartReadingSessionRequest struct {
-	RouteID       string `json:"route_id" binding:"required"`
-	ScheduledDate string `json:"scheduled_date" binding:"required"`
+	RouteID       string  `json:"route_id" binding:"required"`
+	ScheduledDate string  `json:"scheduled_date" binding:"required"`
+	ReaderID      *string `json:"reader_id"`   // defaults to the route's reader
+	UsageMonth    string  `json:"usage_month"` // defaults to the month of scheduled_date
+}
+
+type ScheduleReadingSessionsRequest struct {
+	Date string `json:"date"` // YYYY-MM-DD, defaults to today
+}
+
+type SessionReadingItem struct {
+	CustomerID    string  `json:"customer_id" binding:"required,uuid"`
+	MeterID       *string `json:"meter_id"`
+	MeterReading  float64 `json:"meter_reading" binding:"gte=0"`
+	PhotoURL      string  `json:"photo_url"`
+	PhotoID       *string `json:"photo_id"` // uploaded meter photo, see /api/meter-photos
+	ReadingMethod string  `json:"reading_method" binding:"omitempty,oneof=manual automatic estimated"`
+	ReadAt        string  `json:"read_at"` // RFC3339, defaults to now
+	Notes         string  `json:"notes"`
+}
+
+type SubmitSessionReadingsRequest struct {
+	Readings []SessionReadingItem `json:"readings" binding:"required,min=1,max=200,dive"`
+}
+
+type CloseReadingSessionRequest struct {
+	Notes         string `json:"notes"`
+	CarryOverDate string `json:"carry_over_date"` // YYYY-MM-DD for the session with the unread stops, defaults to the next day
 }
 
 type RecordMeterReadingRequest struct {
@@ -40,6 +74,41 @@ type BatchRecordReadingsRequest struct {
 }
 
 type ResolveAnomalyRequest struct {
-	Resolution string `json:"resolution" binding:"required"`
-	Notes      string `json:"notes"`
+	Resolution     string   `json:"resolution" binding:"required,oneof=confirm correct estimate raise_issue"`
+	MeterReading   *float64 `json:"meter_reading" binding:"omitempty,gte=0"`   // correct: the re-read meter value
+	PhotoURL       string   `json:"photo_url"`                                 // correct: photo of the re-read
+	EstimatedUsage *float64 `json:"estimated_usage" binding:"omitempty,gte=0"` // estimate: m3, defaults to the expected usage
+	IssueType      string   `json:"issue_type" binding:"omitempty,oneof=broken leak stuck incorrect other"`
+	Priority       string   `json:"priority" binding:"omitempty,oneof=low normal high critical"`
+	Notes          string   `json:"notes"`
+}
+
+type UpdateAnomalySettingsRequest struct {
+	IsEnabled            *bool    `json:"is_enabled"`
+	HistoryMonths        *int     `json:"history_months" binding:"omitempty,min=1,max=24"`
+	MinHistoryMonths     *int     `json:"min_history_months" binding:"omitempty,min=1,max=24"`
+	StdDevMultiplier     *float64 `json:"std_dev_multiplier" binding:"omitempty,gt=0,max=10"`
+	HighThresholdPercent *float64 `json:"high_threshold_percent" binding:"omitempty,gt=0,max=10000"`
+	LowThresholdPercent  *float64 `json:"low_threshold_percent" binding:"omitempty,gt=0,max=100"`
+	MinDeviationM3       *float64 `json:"min_deviation_m3" binding:"omitempty,gte=0"`
+	UseSeasonal          *bool    `json:"use_seasonal"`
+	StuckMonths          *int     `json:"stuck_months" binding:"omitempty,min=2,max=24"`
+	MaxMonthlyUsageM3    *float64 `json:"max_monthly_usage_m3" binding:"omitempty,gt=0"`
+	HoldInvoices         *bool    `json:"hold_invoices"`
+}
+
+type RunAnomalyDetectionRequest struct {
+	UsageMonth string `json:"usage_month" binding:"required"` // YYYY-MM
+}
+
+type AttachMeterPhotoRequest struct {
+	WaterUsageID string `json:"water_usage_id" binding:"required"`
+}
+
+type OptimizeRouteRequest struct {
+	StartLatitude   *float64 `json:"start_latitude"` // where the reader sets off, e.g. the office
+	StartLongitude  *float64 `json:"start_longitude"`
+	WalkingSpeedKmh *float64 `json:"walking_speed_kmh" binding:"omitempty,gt=0,lte=30"` // default 4
+	MinutesPerStop  *float64 `json:"minutes_per_stop" binding:"omitempty,gte=0,lte=60"` // default 2
+	DryRun          bool     `json:"dry_run"`                                           // only propose the order
 }
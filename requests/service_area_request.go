@@ -1,19 +1,23 @@
 package requests
 
+import "encoding/json"
+
 type CreateServiceAreaRequest struct {
-	Code         string  `json:"code" binding:"required"`
-	Name         string  `json:"name" binding:"required"`
-	Type         string  `json:"type" binding:"required,oneof=RT RW Blok Zone"`
-	ParentID     *string `json:"parent_id"`
-	Description  string  `json:"description"`
-	Population   int     `json:"population"`
-	CoverageArea string  `json:"coverage_area"`
+	Code         string          `json:"code" binding:"required"`
+	Name         string          `json:"name" binding:"required"`
+	Type         string          `json:"type" binding:"required,oneof=RT RW Blok Zone"`
+	ParentID     *string         `json:"parent_id"`
+	Description  string          `json:"description"`
+	Population   int             `json:"population"`
+	CoverageArea string          `json:"coverage_area"`
+	Boundary     json.RawMessage `json:"boundary"` // GeoJSON Polygon or MultiPolygon
 }
 
 type UpdateServiceAreaRequest struct {
-	Name         string  `json:"name" binding:"required"`
-	Description  string  `json:"description"`
-	Population   int     `json:"population"`
-	CoverageArea string  `json:"coverage_area"`
-	IsActive     *bool   `json:"is_active"`
+	Name         string          `json:"name" binding:"required"`
+	Description  string          `json:"description"`
+	Population   int             `json:"population"`
+	CoverageArea string          `json:"coverage_area"`
+	IsActive     *bool           `json:"is_active"`
+	Boundary     json.RawMessage `json:"boundary"` // GeoJSON Polygon or MultiPolygon; null removes it, omitted keeps it
 }
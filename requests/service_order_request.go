@@ -0,0 +1,34 @@
+package requests
+
+import "github.com/google/uuid"
+
+type UpdateDisconnectionSettingsRequest struct {
+	IsEnabled        *bool    `json:"is_enabled"`
+	GraceDays        *int     `json:"grace_days" binding:"omitempty,min=0,max=365"`
+	MinOverdueMonths *int     `json:"min_overdue_months" binding:"omitempty,min=0,max=36"`
+	MinArrearsAmount *float64 `json:"min_arrears_amount" binding:"omitempty,gte=0"`
+	NotifyCustomer   *bool    `json:"notify_customer"`
+}
+
+type CreateServiceOrderRequest struct {
+	CustomerID      uuid.UUID `json:"customer_id" binding:"required"`
+	OrderType       string    `json:"order_type" binding:"required,oneof=disconnection reconnection"`
+	Reason          string    `json:"reason" binding:"required"`
+	ReconnectionFee *float64  `json:"reconnection_fee" binding:"omitempty,gte=0"` // overrides the subscription's fee, 0 waives it
+}
+
+type AssignServiceOrderRequest struct {
+	AssignedTo string `json:"assigned_to"` // empty unassigns
+}
+
+type CompleteServiceOrderRequest struct {
+	Date         string     `json:"date"` // YYYY-MM-DD, defaults to today
+	MeterID      *uuid.UUID `json:"meter_id"`
+	MeterReading *float64   `json:"meter_reading" binding:"omitempty,gte=0"` // required for disconnections
+	PhotoID      *uuid.UUID `json:"photo_id"`
+	Notes        string     `json:"notes"`
+}
+
+type CancelServiceOrderRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
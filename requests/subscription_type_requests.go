@@ -8,6 +8,7 @@ type CreateSubscriptionTypeRequest struct {
 	MaintenanceFee  float64 `json:"maintenance_fee" minimum:"0" doc:"Monthly maintenance fee in IDR" example:"10000"`
 	LateFeePerDay   float64 `json:"late_fee_per_day" minimum:"0" doc:"Daily late payment fee in IDR" example:"5000"`
 	MaxLateFee      float64 `json:"max_late_fee" minimum:"0" doc:"Maximum late fee cap in IDR" example:"100000"`
+	ReconnectionFee float64 `json:"reconnection_fee" minimum:"0" doc:"Fee billed when a disconnected customer is reconnected, in IDR" example:"150000"`
 }
 
 type UpdateSubscriptionTypeRequest struct {
@@ -18,4 +19,5 @@ type UpdateSubscriptionTypeRequest struct {
 	MaintenanceFee  float64 `json:"maintenance_fee" minimum:"0" doc:"Monthly maintenance fee in IDR" example:"10000"`
 	LateFeePerDay   float64 `json:"late_fee_per_day" minimum:"0" doc:"Daily late payment fee in IDR" example:"5000"`
 	MaxLateFee      float64 `json:"max_late_fee" minimum:"0" doc:"Maximum late fee cap in IDR" example:"100000"`
+	ReconnectionFee float64 `json:"reconnection_fee" minimum:"0" doc:"Fee billed when a disconnected customer is reconnected, in IDR" example:"150000"`
 }
@@ -0,0 +1,35 @@
+package requests
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SyncPaymentItem struct {
+	ClientID        string     `json:"client_id" binding:"required,max=64"`
+	CapturedAt      time.Time  `json:"captured_at" binding:"required"`
+	InvoiceID       uuid.UUID  `json:"invoice_id" binding:"required"`
+	Amount          float64    `json:"amount" binding:"required,gt=0"`
+	PaymentMethodID *uuid.UUID `json:"payment_method_id"`
+	ReferenceNumber string     `json:"reference_number"`
+	Notes           string     `json:"notes"`
+}
+
+type SyncReadingItem struct {
+	ClientID   string     `json:"client_id" binding:"required,max=64"`
+	CapturedAt time.Time  `json:"captured_at" binding:"required"`
+	CustomerID uuid.UUID  `json:"customer_id" binding:"required"`
+	MeterID    *uuid.UUID `json:"meter_id"`
+	UsageMonth string     `json:"usage_month" binding:"required,len=7"`
+	MeterEnd   float64    `json:"meter_end" binding:"gte=0"`
+	PhotoID    *uuid.UUID `json:"photo_id"` // meter photo uploaded before syncing
+	Notes      string     `json:"notes"`
+}
+
+type SyncRequest struct {
+	DeviceID  string            `json:"device_id" binding:"required,max=100"`
+	SyncToken string            `json:"sync_token"` // next_sync_token from the previous sync, empty on first sync
+	Payments  []SyncPaymentItem `json:"payments" binding:"dive"`
+	Readings  []SyncReadingItem `json:"readings" binding:"dive"`
+}
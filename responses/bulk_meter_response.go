@@ -0,0 +1,65 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+type BulkMeterResponse struct {
+	ID              uuid.UUID                 `json:"id"`
+	ServiceAreaID   uuid.UUID                 `json:"service_area_id"`
+	ServiceAreaCode string                    `json:"service_area_code,omitempty"`
+	ServiceAreaName string                    `json:"service_area_name,omitempty"`
+	MeterNumber     string                    `json:"meter_number"`
+	Name            string                    `json:"name"`
+	Brand           string                    `json:"brand"`
+	Model           string                    `json:"model"`
+	InstallDate     time.Time                 `json:"install_date"`
+	InitialReading  float64                   `json:"initial_reading"`
+	Status          string                    `json:"status"`
+	Notes           string                    `json:"notes"`
+	LastReading     *BulkMeterReadingResponse `json:"last_reading,omitempty"`
+}
+
+type BulkMeterReadingResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	ReadingMonth string     `json:"reading_month"`
+	ReadingStart float64    `json:"reading_start"`
+	ReadingEnd   float64    `json:"reading_end"`
+	VolumeM3     float64    `json:"volume_m3"`
+	ReadAt       *time.Time `json:"read_at"`
+	RecordedBy   *uuid.UUID `json:"recorded_by"`
+	Notes        string     `json:"notes"`
+}
+
+func ToBulkMeterResponse(meter *models.BulkMeter) BulkMeterResponse {
+	return BulkMeterResponse{
+		ID:              meter.ID,
+		ServiceAreaID:   meter.ServiceAreaID,
+		ServiceAreaCode: meter.ServiceArea.Code,
+		ServiceAreaName: meter.ServiceArea.Name,
+		MeterNumber:     meter.MeterNumber,
+		Name:            meter.Name,
+		Brand:           meter.Brand,
+		Model:           meter.Model,
+		InstallDate:     meter.InstallDate,
+		InitialReading:  meter.InitialReading,
+		Status:          meter.Status,
+		Notes:           meter.Notes,
+	}
+}
+
+func ToBulkMeterReadingResponse(reading *models.BulkMeterReading) BulkMeterReadingResponse {
+	return BulkMeterReadingResponse{
+		ID:           reading.ID,
+		ReadingMonth: reading.ReadingMonth,
+		ReadingStart: reading.ReadingStart,
+		ReadingEnd:   reading.ReadingEnd,
+		VolumeM3:     reading.VolumeM3,
+		ReadAt:       reading.ReadAt,
+		RecordedBy:   reading.RecordedBy,
+		Notes:        reading.Notes,
+	}
+}
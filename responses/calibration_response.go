@@ -0,0 +1,57 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+type CalibrationPolicyResponse struct {
+	ID             uuid.UUID `json:"id"`
+	Brand          string    `json:"brand"`
+	Model          string    `json:"model"`
+	IntervalMonths int       `json:"interval_months"`
+	IsActive       bool      `json:"is_active"`
+	Notes          string    `json:"notes"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CalibrationDueMeter is one meter on the calibration due list
+type CalibrationDueMeter struct {
+	MeterID       uuid.UUID  `json:"meter_id"`
+	MeterNumber   string     `json:"meter_number"`
+	Brand         string     `json:"brand"`
+	Model         string     `json:"model"`
+	CustomerID    uuid.UUID  `json:"customer_id"`
+	CustomerName  string     `json:"customer_name"`
+	Address       string     `json:"address"`
+	LastCalibDate *time.Time `json:"last_calib_date"`
+	NextCalibDate time.Time  `json:"next_calib_date"`
+	DaysUntilDue  int        `json:"days_until_due"` // negative when overdue
+	Overdue       bool       `json:"overdue"`
+}
+
+// CalibrationDueGroup lists the due meters of one service area
+type CalibrationDueGroup struct {
+	ServiceAreaID   *uuid.UUID            `json:"service_area_id"`
+	ServiceAreaCode string                `json:"service_area_code"`
+	ServiceAreaName string                `json:"service_area_name"`
+	OverdueCount    int                   `json:"overdue_count"`
+	DueCount        int                   `json:"due_count"`
+	Meters          []CalibrationDueMeter `json:"meters"`
+}
+
+func ToCalibrationPolicyResponse(policy *models.CalibrationPolicy) CalibrationPolicyResponse {
+	return CalibrationPolicyResponse{
+		ID:             policy.ID,
+		Brand:          policy.Brand,
+		Model:          policy.Model,
+		IntervalMonths: policy.IntervalMonths,
+		IsActive:       policy.IsActive,
+		Notes:          policy.Notes,
+		CreatedAt:      policy.CreatedAt,
+		UpdatedAt:      policy.UpdatedAt,
+	}
+}
@@ -0,0 +1,79 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+type CashSessionTotalResponse struct {
+	PaymentMethodID *uuid.UUID `json:"payment_method_id"`
+	MethodName      string     `json:"method_name"`
+	PaymentCount    int        `json:"payment_count"`
+	Expected        float64    `json:"expected"`
+	Counted         float64    `json:"counted"`
+	Difference      float64    `json:"difference"`
+}
+
+type CashSessionResponse struct {
+	ID              uuid.UUID                  `json:"id"`
+	CollectorID     uuid.UUID                  `json:"collector_id"`
+	CollectorName   string                     `json:"collector_name,omitempty"`
+	Status          string                     `json:"status"`
+	OpenedAt        time.Time                  `json:"opened_at"`
+	ClosedAt        *time.Time                 `json:"closed_at"`
+	TotalExpected   float64                    `json:"total_expected"`
+	TotalCounted    float64                    `json:"total_counted"`
+	Discrepancy     float64                    `json:"discrepancy"`
+	HasDiscrepancy  bool                       `json:"has_discrepancy"`
+	Notes           string                     `json:"notes,omitempty"`
+	ClosingNotes    string                     `json:"closing_notes,omitempty"`
+	SupervisorID    *uuid.UUID                 `json:"supervisor_id,omitempty"`
+	SupervisorName  string                     `json:"supervisor_name,omitempty"`
+	SignedOffAt     *time.Time                 `json:"signed_off_at,omitempty"`
+	SupervisorNotes string                     `json:"supervisor_notes,omitempty"`
+	Totals          []CashSessionTotalResponse `json:"totals"`
+}
+
+func ToCashSessionTotalResponse(total *models.CashSessionTotal) CashSessionTotalResponse {
+	return CashSessionTotalResponse{
+		PaymentMethodID: total.PaymentMethodID,
+		MethodName:      total.MethodName,
+		PaymentCount:    total.PaymentCount,
+		Expected:        total.Expected,
+		Counted:         total.Counted,
+		Difference:      total.Difference,
+	}
+}
+
+func ToCashSessionResponse(session *models.CashSession) CashSessionResponse {
+	response := CashSessionResponse{
+		ID:              session.ID,
+		CollectorID:     session.CollectorID,
+		CollectorName:   session.Collector.Name,
+		Status:          session.Status,
+		OpenedAt:        session.OpenedAt,
+		ClosedAt:        session.ClosedAt,
+		TotalExpected:   session.TotalExpected,
+		TotalCounted:    session.TotalCounted,
+		Discrepancy:     session.Discrepancy,
+		HasDiscrepancy:  session.HasDiscrepancy,
+		Notes:           session.Notes,
+		ClosingNotes:    session.ClosingNotes,
+		SupervisorID:    session.SupervisorID,
+		SignedOffAt:     session.SignedOffAt,
+		SupervisorNotes: session.SupervisorNotes,
+		Totals:          make([]CashSessionTotalResponse, len(session.Totals)),
+	}
+
+	if session.Supervisor != nil {
+		response.SupervisorName = session.Supervisor.Name
+	}
+
+	for i := range session.Totals {
+		response.Totals[i] = ToCashSessionTotalResponse(&session.Totals[i])
+	}
+
+	return response
+}
@@ -0,0 +1,132 @@
+package responses
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+type ConnectionApplicationResponse struct {
+	ID                uuid.UUID                   `json:"id"`
+	ApplicationNumber string                      `json:"application_number"`
+	Status            string                      `json:"status"`
+	Source            string                      `json:"source"`
+	ApplicantName     string                      `json:"applicant_name"`
+	IdentityNumber    string                      `json:"identity_number"`
+	Email             string                      `json:"email"`
+	Phone             string                      `json:"phone"`
+	Address           string                      `json:"address"`
+	SubscriptionID    uuid.UUID                   `json:"subscription_id"`
+	SubscriptionName  string                      `json:"subscription_name,omitempty"`
+	ServiceAreaID     *uuid.UUID                  `json:"service_area_id,omitempty"`
+	ServiceAreaName   string                      `json:"service_area_name,omitempty"`
+	Latitude          *float64                    `json:"latitude,omitempty"`
+	Longitude         *float64                    `json:"longitude,omitempty"`
+	Notes             string                      `json:"notes"`
+	SurveyedBy        *uuid.UUID                  `json:"surveyed_by,omitempty"`
+	SurveyedAt        *time.Time                  `json:"surveyed_at,omitempty"`
+	Feasible          *bool                       `json:"feasible,omitempty"`
+	DistanceToMainM   float64                     `json:"distance_to_main_m"`
+	Materials         []models.ConnectionMaterial `json:"materials"`
+	SurveyNotes       string                      `json:"survey_notes,omitempty"`
+	DecidedBy         *uuid.UUID                  `json:"decided_by,omitempty"`
+	DecidedAt         *time.Time                  `json:"decided_at,omitempty"`
+	ConnectionFee     float64                     `json:"connection_fee"`
+	RejectionReason   string                      `json:"rejection_reason,omitempty"`
+	InstalledBy       *uuid.UUID                  `json:"installed_by,omitempty"`
+	InstalledAt       *time.Time                  `json:"installed_at,omitempty"`
+	CustomerID        *uuid.UUID                  `json:"customer_id,omitempty"`
+	MeterID           *uuid.UUID                  `json:"meter_id,omitempty"`
+	InvoiceID         *uuid.UUID                  `json:"invoice_id,omitempty"`
+	CreatedAt         time.Time                   `json:"created_at"`
+	UpdatedAt         time.Time                   `json:"updated_at"`
+}
+
+// ConnectionApplicationStatusResponse is what an applicant sees when tracking
+// an application
+type ConnectionApplicationStatusResponse struct {
+	ApplicationNumber string     `json:"application_number"`
+	Status            string     `json:"status"`
+	ApplicantName     string     `json:"applicant_name"`
+	SubmittedAt       time.Time  `json:"submitted_at"`
+	SurveyedAt        *time.Time `json:"surveyed_at,omitempty"`
+	DecidedAt         *time.Time `json:"decided_at,omitempty"`
+	ConnectionFee     float64    `json:"connection_fee,omitempty"`
+	RejectionReason   string     `json:"rejection_reason,omitempty"`
+	InstalledAt       *time.Time `json:"installed_at,omitempty"`
+	MeterNumber       string     `json:"meter_number,omitempty"`
+}
+
+// ConnectionInstallationResponse is returned when an application is installed
+type ConnectionInstallationResponse struct {
+	Application ConnectionApplicationResponse `json:"application"`
+	Customer    CustomerResponse              `json:"customer"`
+	Meter       MeterResponse                 `json:"meter"`
+	Invoice     *InvoiceResponse              `json:"invoice,omitempty"`
+}
+
+func ToConnectionApplicationResponse(application *models.ConnectionApplication) ConnectionApplicationResponse {
+	response := ConnectionApplicationResponse{
+		ID:                application.ID,
+		ApplicationNumber: application.ApplicationNumber,
+		Status:            application.Status,
+		Source:            application.Source,
+		ApplicantName:     application.ApplicantName,
+		IdentityNumber:    application.IdentityNumber,
+		Email:             application.Email,
+		Phone:             application.Phone,
+		Address:           application.Address,
+		SubscriptionID:    application.SubscriptionID,
+		SubscriptionName:  application.Subscription.Name,
+		ServiceAreaID:     application.ServiceAreaID,
+		Latitude:          application.Latitude,
+		Longitude:         application.Longitude,
+		Notes:             application.Notes,
+		SurveyedBy:        application.SurveyedBy,
+		SurveyedAt:        application.SurveyedAt,
+		Feasible:          application.Feasible,
+		DistanceToMainM:   application.DistanceToMainM,
+		Materials:         []models.ConnectionMaterial{},
+		SurveyNotes:       application.SurveyNotes,
+		DecidedBy:         application.DecidedBy,
+		DecidedAt:         application.DecidedAt,
+		ConnectionFee:     application.ConnectionFee,
+		RejectionReason:   application.RejectionReason,
+		InstalledBy:       application.InstalledBy,
+		InstalledAt:       application.InstalledAt,
+		CustomerID:        application.CustomerID,
+		MeterID:           application.MeterID,
+		InvoiceID:         application.InvoiceID,
+		CreatedAt:         application.CreatedAt,
+		UpdatedAt:         application.UpdatedAt,
+	}
+	if application.ServiceArea != nil {
+		response.ServiceAreaName = application.ServiceArea.Name
+	}
+	if application.Materials != nil {
+		_ = json.Unmarshal([]byte(*application.Materials), &response.Materials)
+	}
+	return response
+}
+
+func ToConnectionApplicationStatusResponse(application *models.ConnectionApplication) ConnectionApplicationStatusResponse {
+	response := ConnectionApplicationStatusResponse{
+		ApplicationNumber: application.ApplicationNumber,
+		Status:            application.Status,
+		ApplicantName:     application.ApplicantName,
+		SubmittedAt:       application.CreatedAt,
+		SurveyedAt:        application.SurveyedAt,
+		DecidedAt:         application.DecidedAt,
+		RejectionReason:   application.RejectionReason,
+		InstalledAt:       application.InstalledAt,
+	}
+	if application.Status == models.ApplicationApproved || application.Status == models.ApplicationInstalled {
+		response.ConnectionFee = application.ConnectionFee
+	}
+	if application.Customer != nil {
+		response.MeterNumber = application.Customer.MeterNumber
+	}
+	return response
+}
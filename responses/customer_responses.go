@@ -2,6 +2,8 @@ package responses
 
 import (
 	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
 
@@ -14,6 +16,11 @@ type CustomerResponse struct {
 	Address        string     `json:"address,omitempty" doc:"Full address" example:"Jl. Merdeka No. 123"`
 	SubscriptionID uuid.UUID  `json:"subscription_id" format:"uuid" doc:"Subscription type ID" example:"123e4567-e89b-12d3-a456-426614174000"`
 	IsActive       bool       `json:"is_active" doc:"Active status" example:"true"`
+	Status         string     `json:"status" enum:"applicant,active,suspended,disconnected,closed" doc:"Lifecycle status" example:"active"`
+	StatusSince    *time.Time `json:"status_since,omitempty" format:"date" doc:"Effective date of the current status"`
+	ServiceAreaID  *uuid.UUID `json:"service_area_id,omitempty" format:"uuid" doc:"Service area ID"`
+	Latitude       *float64   `json:"latitude,omitempty" doc:"Latitude of the connection" example:"-7.2575"`
+	Longitude      *float64   `json:"longitude,omitempty" doc:"Longitude of the connection" example:"112.7521"`
 	CreatedAt      time.Time  `json:"created_at" format:"date-time" doc:"Registration date" example:"2025-01-01T00:00:00Z"`
 }
 
@@ -21,3 +28,59 @@ type CustomerListResponse struct {
 	Customers []CustomerResponse `json:"customers" doc:"List of customers"`
 	Total     int                `json:"total" doc:"Total number of customers" example:"150"`
 }
+
+type CustomerStatusHistoryResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	FromStatus    string     `json:"from_status"`
+	ToStatus      string     `json:"to_status"`
+	Reason        string     `json:"reason"`
+	EffectiveDate time.Time  `json:"effective_date"`
+	ChangedBy     *uuid.UUID `json:"changed_by"`
+	ChangedByName string     `json:"changed_by_name,omitempty"`
+	InvoiceID     *uuid.UUID `json:"invoice_id,omitempty"`
+	Notes         string     `json:"notes,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type CustomerStatusChangeResponse struct {
+	CustomerID         uuid.UUID                     `json:"customer_id"`
+	Status             string                        `json:"status"`
+	Change             CustomerStatusHistoryResponse `json:"change"`
+	Invoice            *InvoiceResponse              `json:"invoice,omitempty"` // final bill or reconnection fee
+	AllowedTransitions []string                      `json:"allowed_transitions"`
+}
+
+type CustomerTransferResponse struct {
+	OldCustomer   CustomerResponse     `json:"old_customer"` // closed, under its archived account number
+	NewCustomer   CustomerResponse     `json:"new_customer"`
+	Meters        []MeterResponse      `json:"meters"`
+	FinalReadings []WaterUsageResponse `json:"final_readings"`
+	FinalBills    []InvoiceResponse    `json:"final_bills"`
+	CarriedOver   *InvoiceResponse     `json:"carried_over,omitempty"` // transfer invoice of the new owner
+	Outstanding   float64              `json:"outstanding"`            // unpaid usage balance left on the closed account
+}
+
+type CustomerRelocationResponse struct {
+	Customer     CustomerResponse    `json:"customer"`
+	Meter        *MeterResponse      `json:"meter,omitempty"`         // meter in service at the new address
+	FinalReading *WaterUsageResponse `json:"final_reading,omitempty"` // last reading of a replaced meter
+}
+
+func ToCustomerResponse(customer *models.Customer) CustomerResponse {
+	return CustomerResponse{
+		ID:             customer.ID,
+		MeterNumber:    customer.MeterNumber,
+		Name:           customer.Name,
+		Email:          customer.Email,
+		Address:        customer.Address,
+		Phone:          customer.Phone,
+		SubscriptionID: customer.SubscriptionID,
+		IsActive:       customer.IsActive,
+		Status:         customer.Status,
+		StatusSince:    customer.StatusSince,
+		ServiceAreaID:  customer.ServiceAreaID,
+		Latitude:       customer.Latitude,
+		Longitude:      customer.Longitude,
+		CreatedAt:      customer.CreatedAt,
+	}
+}
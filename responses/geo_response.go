@@ -0,0 +1,40 @@
+package responses
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FeatureCollection is a GeoJSON FeatureCollection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature. Geometry is null for features without a
+// location.
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+func NewFeature(id string, geometry json.RawMessage, properties map[string]interface{}) Feature {
+	if geometry == nil {
+		geometry = json.RawMessage("null")
+	}
+	return Feature{Type: "Feature", ID: id, Geometry: geometry, Properties: properties}
+}
+
+// PointGeometry is a GeoJSON Point at the given latitude and longitude
+func PointGeometry(lat, lng float64) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"type":"Point","coordinates":[%v,%v]}`, lng, lat))
+}
@@ -2,24 +2,48 @@ package responses
 
 import (
 	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
 
 type InvoiceResponse struct {
-	ID          uuid.UUID `json:"id"`
-	CustomerID  uuid.UUID `json:"customer_id"`
-	UsageMonth  string    `json:"usage_month"`
-	UsageM3     float64   `json:"usage_m3"`
-	Abonemen    float64   `json:"abonemen"`
-	PricePerM3  float64   `json:"price_per_m3"`
-	TotalAmount float64   `json:"total_amount"`
-	TotalPaid   float64   `json:"total_paid"`
-	IsPaid      bool      `json:"is_paid"`
-	Type        string    `json:"type"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uuid.UUID  `json:"id"`
+	CustomerID  uuid.UUID  `json:"customer_id"`
+	UsageMonth  string     `json:"usage_month"`
+	UsageM3     float64    `json:"usage_m3"`
+	Abonemen    float64    `json:"abonemen"`
+	PricePerM3  float64    `json:"price_per_m3"`
+	TotalAmount float64    `json:"total_amount"`
+	TotalPaid   float64    `json:"total_paid"`
+	IsPaid      bool       `json:"is_paid"`
+	Type        string     `json:"type"`
+	OnHold      bool       `json:"on_hold"`
+	HoldReason  string     `json:"hold_reason,omitempty"`
+	CarriedToID *uuid.UUID `json:"carried_to_id,omitempty"` // transfer invoice that took over the balance
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 type InvoiceListResponse struct {
 	Invoices []InvoiceResponse `json:"invoices"`
 	Total    int               `json:"total"`
-}
\ No newline at end of file
+}
+
+func ToInvoiceResponse(invoice *models.Invoice) InvoiceResponse {
+	return InvoiceResponse{
+		ID:          invoice.ID,
+		CustomerID:  invoice.CustomerID,
+		UsageMonth:  invoice.UsageMonth,
+		UsageM3:     invoice.UsageM3,
+		Abonemen:    invoice.Abonemen,
+		PricePerM3:  invoice.PricePerM3,
+		TotalAmount: invoice.TotalAmount,
+		TotalPaid:   invoice.TotalPaid,
+		IsPaid:      invoice.IsPaid,
+		Type:        invoice.Type,
+		OnHold:      invoice.OnHold,
+		HoldReason:  invoice.HoldReason,
+		CarriedToID: invoice.CarriedToID,
+		CreatedAt:   invoice.CreatedAt,
+	}
+}
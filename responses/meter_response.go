@@ -9,6 +9,7 @@ import (
 
 type MeterResponse struct {
 	ID             uuid.UUID  `json:"id"`
+	CustomerID     uuid.UUID  `json:"customer_id"`
 	MeterNumber    string     `json:"meter_number"`
 	Brand          string     `json:"brand"`
 	Model          string     `json:"model"`
@@ -16,24 +17,38 @@ type MeterResponse struct {
 	LastCalibDate  *time.Time `json:"last_calib_date"`
 	NextCalibDate  *time.Time `json:"next_calib_date"`
 	InitialReading float64    `json:"initial_reading"`
+	RolloverAt     float64    `json:"rollover_at"`
 	Status         string     `json:"status"`
+	ReplacesID     *uuid.UUID `json:"replaces_id,omitempty"`
+	ReplacedByID   *uuid.UUID `json:"replaced_by_id,omitempty"`
+	FinalReading   *float64   `json:"final_reading,omitempty"`
+	RemovedAt      *time.Time `json:"removed_at,omitempty"`
 	Notes          string     `json:"notes"`
+	Latitude       *float64   `json:"latitude,omitempty"`
+	Longitude      *float64   `json:"longitude,omitempty"`
 	CustomerName   string     `json:"customer_name,omitempty"`
 }
 
 type MeterIssueResponse struct {
-	ID          uuid.UUID  `json:"id"`
-	Meter       MeterResponse `json:"meter"`
-	IssueType   string     `json:"issue_type"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	Priority    string     `json:"priority"`
-	PhotoURL    string     `json:"photo_url,omitempty"`
-	ReportedBy  string     `json:"reported_by"`
-	ResolvedBy  *string    `json:"resolved_by,omitempty"`
-	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
-	Resolution  string     `json:"resolution,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID           uuid.UUID     `json:"id"`
+	Meter        MeterResponse `json:"meter"`
+	IssueType    string        `json:"issue_type"`
+	Description  string        `json:"description"`
+	Status       string        `json:"status"`
+	Priority     string        `json:"priority"`
+	PhotoURL     string        `json:"photo_url,omitempty"`
+	ReportedBy   string        `json:"reported_by"`
+	AssignedTo   *uuid.UUID    `json:"assigned_to,omitempty"`
+	AssigneeName string        `json:"assignee_name,omitempty"`
+	AssignedAt   *time.Time    `json:"assigned_at,omitempty"`
+	DueAt        time.Time     `json:"due_at"`
+	Overdue      bool          `json:"overdue"`
+	StartedAt    *time.Time    `json:"started_at,omitempty"`
+	ResolvedBy   *string       `json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time    `json:"resolved_at,omitempty"`
+	Resolution   string        `json:"resolution,omitempty"`
+	ClosedAt     *time.Time    `json:"closed_at,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
 }
 
 type MeterHistoryResponse struct {
@@ -51,6 +66,7 @@ type MeterHistoryResponse struct {
 func ToMeterResponse(meter *models.Meter) MeterResponse {
 	response := MeterResponse{
 		ID:             meter.ID,
+		CustomerID:     meter.CustomerID,
 		MeterNumber:    meter.MeterNumber,
 		Brand:          meter.Brand,
 		Model:          meter.Model,
@@ -58,8 +74,15 @@ func ToMeterResponse(meter *models.Meter) MeterResponse {
 		LastCalibDate:  meter.LastCalibDate,
 		NextCalibDate:  meter.NextCalibDate,
 		InitialReading: meter.InitialReading,
+		RolloverAt:     meter.RolloverAt,
 		Status:         meter.Status,
+		ReplacesID:     meter.ReplacesID,
+		ReplacedByID:   meter.ReplacedByID,
+		FinalReading:   meter.FinalReading,
+		RemovedAt:      meter.RemovedAt,
 		Notes:          meter.Notes,
+		Latitude:       meter.Latitude,
+		Longitude:      meter.Longitude,
 	}
 	
 	if meter.Customer.Name != "" {
@@ -78,9 +101,23 @@ func ToMeterIssueResponse(issue *models.MeterIssue) MeterIssueResponse {
 		Status:      issue.Status,
 		Priority:    issue.Priority,
 		PhotoURL:    issue.PhotoURL,
-		ReportedBy:  issue.Reporter.Email,
+		AssignedTo:  issue.AssignedTo,
+		AssignedAt:  issue.AssignedAt,
+		DueAt:       issue.DueAt,
+		Overdue:     issue.IsOverdue(),
+		StartedAt:   issue.StartedAt,
+		ClosedAt:    issue.ClosedAt,
 		CreatedAt:   issue.CreatedAt,
 	}
+
+	if issue.Reporter != nil {
+		response.ReportedBy = issue.Reporter.Email
+	} else if issue.ReportingCustomer != nil {
+		response.ReportedBy = issue.ReportingCustomer.Name
+	}
+	if issue.Assignee != nil {
+		response.AssigneeName = issue.Assignee.Name
+	}
 	
 	if issue.Resolver != nil {
 		resolvedBy := issue.Resolver.Email
@@ -91,3 +128,47 @@ func ToMeterIssueResponse(issue *models.MeterIssue) MeterIssueResponse {
 	
 	return response
 }
+
+func ToMeterHistoryResponse(history *models.MeterHistory) MeterHistoryResponse {
+	return MeterHistoryResponse{
+		ID:           history.ID,
+		MeterNumber:  history.Meter.MeterNumber,
+		CustomerName: history.Customer.Name,
+		Action:       history.Action,
+		OldValue:     history.OldValue,
+		NewValue:     history.NewValue,
+		PerformedBy:  history.User.Email,
+		Notes:        history.Notes,
+		CreatedAt:    history.CreatedAt,
+	}
+}
+
+type LeakSettingsResponse struct {
+	IsEnabled       bool    `json:"is_enabled"`
+	NightStartHour  int     `json:"night_start_hour"`
+	NightEndHour    int     `json:"night_end_hour"`
+	MinNightFlowLPH float64 `json:"min_night_flow_lph"`
+	LeakNights      int     `json:"leak_nights"`
+	BaselineDays    int     `json:"baseline_days"`
+	IncreasePercent float64 `json:"increase_percent"`
+	MinIncreaseM3   float64 `json:"min_increase_m3"`
+	SustainedDays   int     `json:"sustained_days"`
+	NotifyCustomer  bool    `json:"notify_customer"`
+	IsDefault       bool    `json:"is_default"` // tenant has not configured its own thresholds
+}
+
+func ToLeakSettingsResponse(cfg *models.LeakDetectionConfig) LeakSettingsResponse {
+	return LeakSettingsResponse{
+		IsEnabled:       cfg.IsEnabled,
+		NightStartHour:  cfg.NightStartHour,
+		NightEndHour:    cfg.NightEndHour,
+		MinNightFlowLPH: cfg.MinNightFlowLPH,
+		LeakNights:      cfg.LeakNights,
+		BaselineDays:    cfg.BaselineDays,
+		IncreasePercent: cfg.IncreasePercent,
+		MinIncreaseM3:   cfg.MinIncreaseM3,
+		SustainedDays:   cfg.SustainedDays,
+		NotifyCustomer:  cfg.NotifyCustomer,
+		IsDefault:       cfg.ID == uuid.Nil,
+	}
+}
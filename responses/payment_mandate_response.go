@@ -0,0 +1,72 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+type PaymentMandateResponse struct {
+	ID                  uuid.UUID  `json:"id"`
+	CustomerID          uuid.UUID  `json:"customer_id"`
+	CustomerName        string     `json:"customer_name,omitempty"`
+	Provider            string     `json:"provider"`
+	AccountMask         string     `json:"account_mask"`
+	MaxAmount           float64    `json:"max_amount"`
+	PaymentMethodID     *uuid.UUID `json:"payment_method_id"`
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastAttemptAt       *time.Time `json:"last_attempt_at"`
+	RevokedAt           *time.Time `json:"revoked_at,omitempty"`
+	Notes               string     `json:"notes,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+type AutoDebitAttemptResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	MandateID     uuid.UUID  `json:"mandate_id"`
+	InvoiceID     uuid.UUID  `json:"invoice_id"`
+	CustomerID    uuid.UUID  `json:"customer_id"`
+	Amount        float64    `json:"amount"`
+	Status        string     `json:"status"`
+	Reference     string     `json:"reference"`
+	ProviderRef   string     `json:"provider_ref,omitempty"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	PaymentID     *uuid.UUID `json:"payment_id,omitempty"`
+	AttemptedAt   time.Time  `json:"attempted_at"`
+}
+
+func ToPaymentMandateResponse(mandate *models.PaymentMandate) PaymentMandateResponse {
+	return PaymentMandateResponse{
+		ID:                  mandate.ID,
+		CustomerID:          mandate.CustomerID,
+		CustomerName:        mandate.Customer.Name,
+		Provider:            mandate.Provider,
+		AccountMask:         mandate.AccountMask,
+		MaxAmount:           mandate.MaxAmount,
+		PaymentMethodID:     mandate.PaymentMethodID,
+		Status:              mandate.Status,
+		ConsecutiveFailures: mandate.ConsecutiveFailures,
+		LastAttemptAt:       mandate.LastAttemptAt,
+		RevokedAt:           mandate.RevokedAt,
+		Notes:               mandate.Notes,
+		CreatedAt:           mandate.CreatedAt,
+	}
+}
+
+func ToAutoDebitAttemptResponse(attempt *models.AutoDebitAttempt) AutoDebitAttemptResponse {
+	return AutoDebitAttemptResponse{
+		ID:            attempt.ID,
+		MandateID:     attempt.MandateID,
+		InvoiceID:     attempt.InvoiceID,
+		CustomerID:    attempt.CustomerID,
+		Amount:        attempt.Amount,
+		Status:        attempt.Status,
+		Reference:     attempt.Reference,
+		ProviderRef:   attempt.ProviderRef,
+		FailureReason: attempt.FailureReason,
+		PaymentID:     attempt.PaymentID,
+		AttemptedAt:   attempt.AttemptedAt,
+	}
+}
@@ -1,14 +1,70 @@
 package responses
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
 
 type PaymentResponse struct {
-	ID        uuid.UUID `json:"id"`
-	InvoiceID uuid.UUID `json:"invoice_id"`
-	Amount    float64   `json:"amount"`
-	PaidAt    time.Time `json:"paid_at"`
+	ID            uuid.UUID  `json:"id"`
+	InvoiceID     uuid.UUID  `json:"invoice_id"`
+	Amount        float64    `json:"amount"`
+	PaidAt        time.Time  `json:"paid_at"`
+	CashSessionID *uuid.UUID `json:"cash_session_id,omitempty"`
+	ReceiptNumber string     `json:"receipt_number,omitempty"`
+}
+
+type PaymentReversalResponse struct {
+	OriginalPaymentID uuid.UUID `json:"original_payment_id"`
+	ReversalPaymentID uuid.UUID `json:"reversal_payment_id"`
+	InvoiceID         uuid.UUID `json:"invoice_id"`
+	Amount            float64   `json:"amount"`
+	Reason            string    `json:"reason"`
+	Disposition       string    `json:"disposition"`
+	InvoiceTotalPaid  float64   `json:"invoice_total_paid"`
+	InvoiceIsPaid     bool      `json:"invoice_is_paid"`
+	CustomerActive    bool      `json:"customer_active"`
+	CreditBalance     float64   `json:"credit_balance"`
+	ReversedAt        time.Time `json:"reversed_at"`
+}
+
+type CustomerCreditEntryResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Amount    float64    `json:"amount"`
+	Type      string     `json:"type"`
+	PaymentID *uuid.UUID `json:"payment_id,omitempty"`
+	Reference string     `json:"reference,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type CustomerCreditResponse struct {
+	CustomerID uuid.UUID                     `json:"customer_id"`
+	Balance    float64                       `json:"balance"`
+	Entries    []CustomerCreditEntryResponse `json:"entries"`
+}
+
+type PaymentReceiptResponse struct {
+	ReceiptNumber string                 `json:"receipt_number"`
+	PaymentID     uuid.UUID              `json:"payment_id"`
+	IssuedAt      time.Time              `json:"issued_at"`
+	Checksum      string                 `json:"checksum"`
+	IsCopy        bool                   `json:"is_copy"` // true for reprints
+	Receipt       models.ReceiptSnapshot `json:"receipt"`
+}
+
+// ToPaymentReceiptResponse decodes the archived snapshot exactly as issued
+func ToPaymentReceiptResponse(receipt *models.PaymentReceipt, isCopy bool) (PaymentReceiptResponse, error) {
+	response := PaymentReceiptResponse{
+		ReceiptNumber: receipt.ReceiptNumber,
+		PaymentID:     receipt.PaymentID,
+		IssuedAt:      receipt.IssuedAt,
+		Checksum:      receipt.Checksum,
+		IsCopy:        isCopy,
+	}
+	err := json.Unmarshal([]byte(receipt.Snapshot), &response.Receipt)
+	return response, err
 }
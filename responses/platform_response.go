@@ -202,6 +202,9 @@ type TenantSettingsResponse struct {
 	Language       string `json:"language"`
 	Currency       string `json:"currency"`
 	
+	// Meter Reading Policy
+	RequireReadingPhoto bool `json:"require_reading_photo"`
+	
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -255,6 +258,33 @@ type BulkOperationResponse struct {
 	DurationMs       int64    `json:"duration_ms"`
 }
 
+// ReadingImportResponse represents result of a bulk meter reading import
+type ReadingImportResponse struct {
+	DryRun       bool                    `json:"dry_run"`
+	Applied      bool                    `json:"applied"`
+	TotalRecords int                     `json:"total_records"`
+	ValidCount   int                     `json:"valid_count"`
+	ErrorCount   int                     `json:"error_count"`
+	AnomalyCount int                     `json:"anomaly_count"`
+	Rows         []ReadingImportRowResult `json:"rows"`
+	ProcessedAt  time.Time               `json:"processed_at"`
+	DurationMs   int64                   `json:"duration_ms"`
+}
+
+// ReadingImportRowResult is the validation result of one imported reading
+type ReadingImportRowResult struct {
+	Line        int      `json:"line"`
+	MeterNumber string   `json:"meter_number"`
+	UsageMonth  string   `json:"usage_month"`
+	Reading     *float64 `json:"reading"`
+	Status      string   `json:"status"`          // ok, anomaly, error
+	Issue       string   `json:"issue,omitempty"` // unknown_meter, backward_reading, duplicate, invalid, or the anomaly type
+	Message     string   `json:"message,omitempty"`
+	MeterStart  *float64 `json:"meter_start,omitempty"`
+	UsageM3     *float64 `json:"usage_m3,omitempty"`
+	ExpectedM3  *float64 `json:"expected_m3,omitempty"`
+}
+
 // TenantGrowthAnalyticsResponse represents tenant growth analytics
 type TenantGrowthAnalyticsResponse struct {
 	Period              string                  `json:"period"`
@@ -8,36 +8,94 @@ import (
 )
 
 type ReadingRouteResponse struct {
-	ID            uuid.UUID `json:"id"`
-	Code          string    `json:"code"`
-	Name          string    `json:"name"`
-	Description   string    `json:"description"`
-	AssignedUser  *string   `json:"assigned_user,omitempty"`
-	ScheduleDay   int       `json:"schedule_day"`
-	EstDuration   int       `json:"est_duration"`
-	CustomerCount int       `json:"customer_count"`
-	IsActive      bool      `json:"is_active"`
+	ID            uuid.UUID  `json:"id"`
+	Code          string     `json:"code"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	AssignedTo    *uuid.UUID `json:"assigned_to,omitempty"`
+	AssignedUser  *string    `json:"assigned_user,omitempty"`
+	ScheduleDay   int        `json:"schedule_day"`
+	EstDuration   int        `json:"est_duration"`
+	CustomerCount int        `json:"customer_count"`
+	IsActive      bool       `json:"is_active"`
+}
+
+// ReadingStopMeter is one meter to read at a route stop
+type ReadingStopMeter struct {
+	MeterID            *uuid.UUID `json:"meter_id"` // nil for customers without registered meters
+	MeterNumber        string     `json:"meter_number"`
+	PreviousReading    float64    `json:"previous_reading"`
+	PreviousUsageMonth string     `json:"previous_usage_month,omitempty"`
+	PreviousUsageM3    *float64   `json:"previous_usage_m3,omitempty"`
+	CurrentReading     *float64   `json:"current_reading,omitempty"` // already recorded for the month
+}
+
+// ReadingRouteStopResponse is one customer along a reading route
+type ReadingRouteStopResponse struct {
+	Sequence     int                `json:"sequence"`
+	CustomerID   uuid.UUID          `json:"customer_id"`
+	CustomerName string             `json:"customer_name"`
+	Address      string             `json:"address"`
+	Phone        string             `json:"phone,omitempty"`
+	Meters       []ReadingStopMeter `json:"meters"`
+	Completed    bool               `json:"completed"` // every meter read for the month
+}
+
+// ReadingRouteDetailResponse is a route with its ordered stops
+type ReadingRouteDetailResponse struct {
+	ReadingRouteResponse
+	UsageMonth     string                     `json:"usage_month"`
+	CompletedCount int                        `json:"completed_count"`
+	Stops          []ReadingRouteStopResponse `json:"stops"`
 }
 
 type ReadingSessionResponse struct {
-	ID             uuid.UUID  `json:"id"`
-	Route          ReadingRouteResponse `json:"route"`
-	ReaderName     string     `json:"reader_name"`
-	ScheduledDate  time.Time  `json:"scheduled_date"`
-	StartTime      *time.Time `json:"start_time"`
-	EndTime        *time.Time `json:"end_time"`
-	Status         string     `json:"status"`
-	TotalCustomers int        `json:"total_customers"`
-	CompletedCount int        `json:"completed_count"`
-	AnomalyCount   int        `json:"anomaly_count"`
-	Notes          string     `json:"notes,omitempty"`
+	ID                uuid.UUID            `json:"id"`
+	Route             ReadingRouteResponse `json:"route"`
+	ReaderID          uuid.UUID            `json:"reader_id"`
+	ReaderName        string               `json:"reader_name"`
+	ScheduledDate     time.Time            `json:"scheduled_date"`
+	UsageMonth        string               `json:"usage_month"`
+	CarriedOverFromID *uuid.UUID           `json:"carried_over_from_id,omitempty"`
+	StartTime         *time.Time           `json:"start_time"`
+	EndTime           *time.Time           `json:"end_time"`
+	Status            string               `json:"status"`
+	TotalCustomers    int                  `json:"total_customers"`
+	CompletedCount    int                  `json:"completed_count"`
+	AnomalyCount      int                  `json:"anomaly_count"`
+	Notes             string               `json:"notes,omitempty"`
+}
+
+// ReadingSessionDetailResponse is a session with the stops still to read
+type ReadingSessionDetailResponse struct {
+	ReadingSessionResponse
+	RemainingCount  int                        `json:"remaining_count"`
+	ProgressPercent float64                    `json:"progress_percent"`
+	Stops           []ReadingRouteStopResponse `json:"stops"`
+}
+
+// SessionReadingResult is the outcome of one submitted reading
+type SessionReadingResult struct {
+	Index      int                 `json:"index"`
+	CustomerID string              `json:"customer_id"`
+	Status     string              `json:"status"` // recorded, rejected
+	Reading    *WaterUsageResponse `json:"reading,omitempty"`
+	Error      string              `json:"error,omitempty"`
 }
 
 type ReadingAnomalyResponse struct {
 	ID            uuid.UUID  `json:"id"`
+	WaterUsageID  uuid.UUID  `json:"water_usage_id"`
+	CustomerID    uuid.UUID  `json:"customer_id"`
 	CustomerName  string     `json:"customer_name"`
+	MeterID       *uuid.UUID `json:"meter_id,omitempty"`
 	MeterNumber   string     `json:"meter_number"`
+	RouteID       *uuid.UUID `json:"route_id,omitempty"`
 	UsageMonth    string     `json:"usage_month"`
+	MeterStart    float64    `json:"meter_start"`
+	MeterEnd      float64    `json:"meter_end"`
+	ReadingMethod string     `json:"reading_method"`
+	PhotoURL      string     `json:"photo_url,omitempty"`
 	AnomalyType   string     `json:"anomaly_type"`
 	ExpectedValue float64    `json:"expected_value"`
 	ActualValue   float64    `json:"actual_value"`
@@ -46,16 +104,47 @@ type ReadingAnomalyResponse struct {
 	ResolvedBy    *string    `json:"resolved_by,omitempty"`
 	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
 	Resolution    string     `json:"resolution,omitempty"`
+	MeterIssueID  *uuid.UUID `json:"meter_issue_id,omitempty"`
 	Notes         string     `json:"notes,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 }
 
+// AnomalyHistoryPoint is one month of the chart shown next to an anomaly
+type AnomalyHistoryPoint struct {
+	UsageMonth string  `json:"usage_month"`
+	UsageM3    float64 `json:"usage_m3"`
+	IsAnomaly  bool    `json:"is_anomaly"`
+}
+
+type ReadingAnomalyDetailResponse struct {
+	ReadingAnomalyResponse
+	RecordedBy *string               `json:"recorded_by,omitempty"`
+	ReadAt     *time.Time            `json:"read_at,omitempty"`
+	History    []AnomalyHistoryPoint `json:"history"`
+}
+
+type AnomalySettingsResponse struct {
+	IsEnabled            bool    `json:"is_enabled"`
+	HistoryMonths        int     `json:"history_months"`
+	MinHistoryMonths     int     `json:"min_history_months"`
+	StdDevMultiplier     float64 `json:"std_dev_multiplier"`
+	HighThresholdPercent float64 `json:"high_threshold_percent"`
+	LowThresholdPercent  float64 `json:"low_threshold_percent"`
+	MinDeviationM3       float64 `json:"min_deviation_m3"`
+	UseSeasonal          bool    `json:"use_seasonal"`
+	StuckMonths          int     `json:"stuck_months"`
+	MaxMonthlyUsageM3    float64 `json:"max_monthly_usage_m3"`
+	HoldInvoices         bool    `json:"hold_invoices"`
+	IsDefault            bool    `json:"is_default"` // tenant has not configured its own thresholds
+}
+
 func ToReadingRouteResponse(route *models.ReadingRoute) ReadingRouteResponse {
 	response := ReadingRouteResponse{
 		ID:            route.ID,
 		Code:          route.Code,
 		Name:          route.Name,
 		Description:   route.Description,
+		AssignedTo:    route.AssignedTo,
 		ScheduleDay:   route.ScheduleDay,
 		EstDuration:   route.EstDuration,
 		CustomerCount: route.CustomerCount,
@@ -72,41 +161,75 @@ func ToReadingRouteResponse(route *models.ReadingRoute) ReadingRouteResponse {
 
 func ToReadingSessionResponse(session *models.ReadingSession) ReadingSessionResponse {
 	return ReadingSessionResponse{
-		ID:             session.ID,
-		Route:          ToReadingRouteResponse(&session.Route),
-		ReaderName:     session.Reader.Email,
-		ScheduledDate:  session.ScheduledDate,
-		StartTime:      session.StartTime,
-		EndTime:        session.EndTime,
-		Status:         session.Status,
-		TotalCustomers: session.TotalCustomers,
-		CompletedCount: session.CompletedCount,
-		AnomalyCount:   session.AnomalyCount,
-		Notes:          session.Notes,
+		ID:                session.ID,
+		Route:             ToReadingRouteResponse(&session.Route),
+		ReaderID:          session.ReaderID,
+		ReaderName:        session.Reader.Email,
+		ScheduledDate:     session.ScheduledDate,
+		UsageMonth:        session.UsageMonth,
+		CarriedOverFromID: session.CarriedOverFromID,
+		StartTime:         session.StartTime,
+		EndTime:           session.EndTime,
+		Status:            session.Status,
+		TotalCustomers:    session.TotalCustomers,
+		CompletedCount:    session.CompletedCount,
+		AnomalyCount:      session.AnomalyCount,
+		Notes:             session.Notes,
 	}
 }
 
 func ToReadingAnomalyResponse(anomaly *models.ReadingAnomaly) ReadingAnomalyResponse {
+	usage := &anomaly.WaterUsage
 	response := ReadingAnomalyResponse{
 		ID:            anomaly.ID,
-		CustomerName:  anomaly.WaterUsage.Customer.Name,
-		MeterNumber:   anomaly.WaterUsage.Customer.MeterNumber,
-		UsageMonth:    anomaly.WaterUsage.UsageMonth,
+		WaterUsageID:  anomaly.WaterUsageID,
+		CustomerID:    usage.CustomerID,
+		CustomerName:  usage.Customer.Name,
+		MeterID:       usage.MeterID,
+		MeterNumber:   usage.Customer.MeterNumber,
+		RouteID:       usage.Customer.ReadingRouteID,
+		UsageMonth:    usage.UsageMonth,
+		MeterStart:    usage.MeterStart,
+		MeterEnd:      usage.MeterEnd,
+		ReadingMethod: usage.ReadingMethod,
+		PhotoURL:      usage.PhotoURL,
 		AnomalyType:   anomaly.AnomalyType,
 		ExpectedValue: anomaly.ExpectedValue,
 		ActualValue:   anomaly.ActualValue,
 		Deviation:     anomaly.Deviation,
 		Status:        anomaly.Status,
+		Resolution:    anomaly.Resolution,
+		MeterIssueID:  anomaly.MeterIssueID,
 		Notes:         anomaly.Notes,
 		CreatedAt:     anomaly.CreatedAt,
 	}
 	
+	if usage.Meter != nil {
+		response.MeterNumber = usage.Meter.MeterNumber
+	}
+	
 	if anomaly.Resolver != nil {
 		resolvedBy := anomaly.Resolver.Email
 		response.ResolvedBy = &resolvedBy
 		response.ResolvedAt = anomaly.ResolvedAt
-		response.Resolution = anomaly.Resolution
 	}
 	
 	return response
 }
+
+func ToAnomalySettingsResponse(cfg *models.AnomalyDetectionConfig) AnomalySettingsResponse {
+	return AnomalySettingsResponse{
+		IsEnabled:            cfg.IsEnabled,
+		HistoryMonths:        cfg.HistoryMonths,
+		MinHistoryMonths:     cfg.MinHistoryMonths,
+		StdDevMultiplier:     cfg.StdDevMultiplier,
+		HighThresholdPercent: cfg.HighThresholdPercent,
+		LowThresholdPercent:  cfg.LowThresholdPercent,
+		MinDeviationM3:       cfg.MinDeviationM3,
+		UseSeasonal:          cfg.UseSeasonal,
+		StuckMonths:          cfg.StuckMonths,
+		MaxMonthlyUsageM3:    cfg.MaxMonthlyUsageM3,
+		HoldInvoices:         cfg.HoldInvoices,
+		IsDefault:            cfg.ID == uuid.Nil,
+	}
+}
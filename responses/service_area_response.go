@@ -1,6 +1,8 @@
 package responses
 
 import (
+	"encoding/json"
+
 	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
@@ -15,6 +17,7 @@ type ServiceAreaResponse struct {
 	CustomerCount int                    `json:"customer_count"`
 	CoverageArea  string                 `json:"coverage_area"`
 	IsActive      bool                   `json:"is_active"`
+	Boundary      json.RawMessage        `json:"boundary,omitempty"` // GeoJSON geometry
 	Parent        *ServiceAreaResponse   `json:"parent,omitempty"`
 	Children      []ServiceAreaResponse  `json:"children,omitempty"`
 }
@@ -32,6 +35,10 @@ func ToServiceAreaResponse(area *models.ServiceArea) ServiceAreaResponse {
 		IsActive:      area.IsActive,
 	}
 
+	if area.Boundary != nil {
+		response.Boundary = json.RawMessage(*area.Boundary)
+	}
+
 	if area.Parent != nil {
 		parent := ToServiceAreaResponse(area.Parent)
 		response.Parent = &parent
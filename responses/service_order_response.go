@@ -0,0 +1,88 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+type DisconnectionSettingsResponse struct {
+	IsEnabled        bool    `json:"is_enabled"`
+	GraceDays        int     `json:"grace_days"`
+	MinOverdueMonths int     `json:"min_overdue_months"`
+	MinArrearsAmount float64 `json:"min_arrears_amount"`
+	NotifyCustomer   bool    `json:"notify_customer"`
+	IsDefault        bool    `json:"is_default"` // tenant has not configured its own thresholds
+}
+
+type ServiceOrderResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	CustomerID      uuid.UUID  `json:"customer_id"`
+	CustomerName    string     `json:"customer_name,omitempty"`
+	MeterNumber     string     `json:"meter_number,omitempty"`
+	Address         string     `json:"address,omitempty"`
+	MeterID         *uuid.UUID `json:"meter_id,omitempty"`
+	OrderType       string     `json:"order_type"`
+	Status          string     `json:"status"`
+	Reason          string     `json:"reason"`
+	OverdueInvoices int        `json:"overdue_invoices"`
+	ArrearsAmount   float64    `json:"arrears_amount"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty"`
+	AssignedTo      *uuid.UUID `json:"assigned_to,omitempty"`
+	AssigneeName    string     `json:"assignee_name,omitempty"`
+	AssignedAt      *time.Time `json:"assigned_at,omitempty"`
+	InvoiceID       *uuid.UUID `json:"invoice_id,omitempty"`
+	CompletedBy     *uuid.UUID `json:"completed_by,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	MeterReading    *float64   `json:"meter_reading,omitempty"`
+	WaterUsageID    *uuid.UUID `json:"water_usage_id,omitempty"`
+	Notes           string     `json:"notes,omitempty"`
+	CancelReason    string     `json:"cancel_reason,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func ToDisconnectionSettingsResponse(policy *models.DisconnectionPolicy) DisconnectionSettingsResponse {
+	return DisconnectionSettingsResponse{
+		IsEnabled:        policy.IsEnabled,
+		GraceDays:        policy.GraceDays,
+		MinOverdueMonths: policy.MinOverdueMonths,
+		MinArrearsAmount: policy.MinArrearsAmount,
+		NotifyCustomer:   policy.NotifyCustomer,
+		IsDefault:        policy.ID == uuid.Nil,
+	}
+}
+
+func ToServiceOrderResponse(order *models.ServiceOrder) ServiceOrderResponse {
+	response := ServiceOrderResponse{
+		ID:              order.ID,
+		CustomerID:      order.CustomerID,
+		CustomerName:    order.Customer.Name,
+		MeterNumber:     order.Customer.MeterNumber,
+		Address:         order.Customer.Address,
+		MeterID:         order.MeterID,
+		OrderType:       order.OrderType,
+		Status:          order.Status,
+		Reason:          order.Reason,
+		OverdueInvoices: order.OverdueInvoices,
+		ArrearsAmount:   order.ArrearsAmount,
+		CreatedBy:       order.CreatedBy,
+		AssignedTo:      order.AssignedTo,
+		AssignedAt:      order.AssignedAt,
+		InvoiceID:       order.InvoiceID,
+		CompletedBy:     order.CompletedBy,
+		CompletedAt:     order.CompletedAt,
+		MeterReading:    order.MeterReading,
+		WaterUsageID:    order.WaterUsageID,
+		Notes:           order.Notes,
+		CancelReason:    order.CancelReason,
+		CreatedAt:       order.CreatedAt,
+	}
+	if order.Meter != nil {
+		response.MeterNumber = order.Meter.MeterNumber
+	}
+	if order.Assignee != nil {
+		response.AssigneeName = order.Assignee.Name
+	}
+	return response
+}
@@ -15,5 +15,6 @@ type SubscriptionTypeResponse struct {
 	MaintenanceFee  float64   `json:"maintenance_fee"`
 	LateFeePerDay   float64   `json:"late_fee_per_day"`
 	MaxLateFee      float64   `json:"max_late_fee"`
+	ReconnectionFee float64   `json:"reconnection_fee"`
 	CreatedAt       time.Time `json:"created_at"`
 }
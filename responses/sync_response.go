@@ -0,0 +1,86 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
+	"github.com/google/uuid"
+)
+
+type SyncItemResult struct {
+	ClientID     string     `json:"client_id"`
+	Type         string     `json:"type"`
+	Status       string     `json:"status"` // applied, conflict, error, duplicate
+	ResourceID   *uuid.UUID `json:"resource_id,omitempty"`
+	ConflictCode string     `json:"conflict_code,omitempty"`
+	Message      string     `json:"message,omitempty"`
+}
+
+type SyncCustomerDelta struct {
+	ID          uuid.UUID `json:"id"`
+	MeterNumber string    `json:"meter_number"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address,omitempty"`
+	Phone       string    `json:"phone,omitempty"`
+	IsActive    bool      `json:"is_active"`
+	Status      string    `json:"status"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SyncInvoiceDelta struct {
+	ID          uuid.UUID `json:"id"`
+	CustomerID  uuid.UUID `json:"customer_id"`
+	UsageMonth  string    `json:"usage_month"`
+	Type        string    `json:"type"`
+	TotalAmount float64   `json:"total_amount"`
+	TotalPaid   float64   `json:"total_paid"`
+	IsPaid      bool      `json:"is_paid"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SyncResponse struct {
+	Results            []SyncItemResult    `json:"results"`
+	Customers          []SyncCustomerDelta `json:"customers"`
+	Invoices           []SyncInvoiceDelta  `json:"invoices"`
+	DeletedCustomerIDs []uuid.UUID         `json:"deleted_customer_ids"`
+	DeletedInvoiceIDs  []uuid.UUID         `json:"deleted_invoice_ids"`
+	NextSyncToken      string              `json:"next_sync_token"`
+	ServerTime         time.Time           `json:"server_time"`
+}
+
+func ToSyncItemResult(item *models.SyncItem) SyncItemResult {
+	return SyncItemResult{
+		ClientID:     item.ClientID,
+		Type:         item.ItemType,
+		Status:       item.Status,
+		ResourceID:   item.ResourceID,
+		ConflictCode: item.ConflictCode,
+		Message:      item.Message,
+	}
+}
+
+func ToSyncCustomerDelta(customer *models.Customer) SyncCustomerDelta {
+	return SyncCustomerDelta{
+		ID:          customer.ID,
+		MeterNumber: customer.MeterNumber,
+		Name:        customer.Name,
+		Address:     customer.Address,
+		Phone:       customer.Phone,
+		IsActive:    customer.IsActive,
+		Status:      customer.Status,
+		UpdatedAt:   customer.UpdatedAt,
+	}
+}
+
+func ToSyncInvoiceDelta(invoice *models.Invoice) SyncInvoiceDelta {
+	return SyncInvoiceDelta{
+		ID:          invoice.ID,
+		CustomerID:  invoice.CustomerID,
+		UsageMonth:  invoice.UsageMonth,
+		Type:        invoice.Type,
+		TotalAmount: invoice.TotalAmount,
+		TotalPaid:   invoice.TotalPaid,
+		IsPaid:      invoice.IsPaid,
+		UpdatedAt:   invoice.UpdatedAt,
+	}
+}
@@ -2,21 +2,49 @@ package responses
 
 import (
 	"time"
+
+	"github.com/adipras/tirta-saas-backend/models"
 	"github.com/google/uuid"
 )
 
 type WaterUsageResponse struct {
-	ID               uuid.UUID `json:"id"`
-	CustomerID       uuid.UUID `json:"customer_id"`
-	UsageMonth       string    `json:"usage_month"`
-	MeterStart       float64   `json:"meter_start"`
-	MeterEnd         float64   `json:"meter_end"`
-	UsageM3          float64   `json:"usage_m3"`
-	AmountCalculated float64   `json:"amount_calculated"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID               uuid.UUID  `json:"id"`
+	CustomerID       uuid.UUID  `json:"customer_id"`
+	MeterID          *uuid.UUID `json:"meter_id,omitempty"`
+	UsageMonth       string     `json:"usage_month"`
+	MeterStart       float64    `json:"meter_start"`
+	MeterEnd         float64    `json:"meter_end"`
+	UsageM3          float64    `json:"usage_m3"`
+	AmountCalculated float64    `json:"amount_calculated"`
+	IsRollover       bool       `json:"is_rollover,omitempty"`
+	IsAnomaly        bool       `json:"is_anomaly,omitempty"`
+	AnomalyType      string     `json:"anomaly_type,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
 }
 
 type WaterUsageListResponse struct {
 	UsageRecords []WaterUsageResponse `json:"usage_records"`
 	Total        int                  `json:"total"`
-}
\ No newline at end of file
+}
+
+func ToWaterUsageResponse(usage *models.WaterUsage) WaterUsageResponse {
+	response := WaterUsageResponse{
+		ID:               usage.ID,
+		CustomerID:       usage.CustomerID,
+		MeterID:          usage.MeterID,
+		UsageMonth:       usage.UsageMonth,
+		MeterStart:       usage.MeterStart,
+		MeterEnd:         usage.MeterEnd,
+		UsageM3:          usage.UsageM3,
+		AmountCalculated: usage.AmountCalculated,
+		IsRollover:       usage.IsRollover,
+		IsAnomaly:        usage.IsAnomaly,
+		CreatedAt:        usage.CreatedAt,
+	}
+
+	if usage.AnomalyDetails != nil {
+		response.AnomalyType = usage.AnomalyDetails.AnomalyType
+	}
+
+	return response
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func CalibrationRoutes(r *gin.Engine) {
+	calibrationController := controllers.NewCalibrationController(config.DB)
+
+	api := r.Group("/api/calibrations")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("/due", middleware.RequirePermission(constants.PermViewCustomers), calibrationController.GetCalibrationDueList)
+		api.GET("/policies", middleware.RequirePermission(constants.PermViewCustomers), calibrationController.GetCalibrationPolicies)
+
+		api.POST("/policies", middleware.RequirePermission(constants.PermManageInstallations), calibrationController.CreateCalibrationPolicy)
+		api.PUT("/policies/:id", middleware.RequirePermission(constants.PermManageInstallations), calibrationController.UpdateCalibrationPolicy)
+		api.DELETE("/policies/:id", middleware.RequirePermission(constants.PermManageInstallations), calibrationController.DeleteCalibrationPolicy)
+		api.POST("/run", middleware.RequirePermission(constants.PermManageInstallations), calibrationController.RunCalibrationSchedule)
+	}
+}
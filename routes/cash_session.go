@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func CashSessionRoutes(r *gin.Engine) {
+	cashSessionController := controllers.NewCashSessionController(config.DB)
+
+	api := r.Group("/api/cash-sessions")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		// Collector operations
+		api.POST("/open", middleware.RequirePermission(constants.PermRecordPayments), cashSessionController.OpenCashSession)
+		api.GET("/current", middleware.RequirePermission(constants.PermRecordPayments), cashSessionController.GetCurrentCashSession)
+		api.POST("/:id/close", middleware.RequirePermission(constants.PermRecordPayments), cashSessionController.CloseCashSession)
+
+		// Supervisor operations
+		api.GET("", middleware.RequirePermission(constants.PermViewPayments), cashSessionController.GetCashSessions)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewPayments), cashSessionController.GetCashSession)
+		api.POST("/:id/sign-off", middleware.RequirePermission(constants.PermManagePayments), cashSessionController.SignOffCashSession)
+	}
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ConnectionApplicationRoutes(r *gin.Engine) {
+	applicationController := controllers.NewConnectionApplicationController(config.DB)
+
+	// Public application form and tracking, no login
+	public := r.Group("/api/public/connection-applications")
+	public.Use(middleware.EndpointRateLimitMiddleware("connection_application", 10))
+	{
+		public.POST("", applicationController.SubmitPublicApplication)
+		public.GET("/:number", applicationController.TrackPublicApplication)
+	}
+
+	applications := r.Group("/api/connection-applications")
+	applications.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		applications.GET("", middleware.RequirePermission(constants.PermViewCustomers), applicationController.GetApplications)
+		applications.GET("/:id", middleware.RequirePermission(constants.PermViewCustomers), applicationController.GetApplication)
+		applications.POST("", middleware.RequirePermission(constants.PermManageCustomers), applicationController.CreateApplication)
+		applications.POST("/:id/survey", middleware.RequirePermission(constants.PermManageInstallations), applicationController.SurveyApplication)
+		applications.POST("/:id/approve", middleware.RequirePermission(constants.PermManageCustomers), applicationController.ApproveApplication)
+		applications.POST("/:id/reject", middleware.RequirePermission(constants.PermManageCustomers), applicationController.RejectApplication)
+		applications.POST("/:id/install", middleware.RequirePermission(constants.PermManageInstallations), applicationController.InstallApplication)
+	}
+}
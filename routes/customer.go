@@ -15,4 +15,16 @@ func CustomerRoutes(r *gin.Engine) {
 	group.GET(":id", controllers.GetCustomer)
 	group.PUT(":id", controllers.UpdateCustomer)
 	group.DELETE(":id", controllers.DeleteCustomer)
+
+	// Lifecycle: applicant → active → suspended → disconnected → closed
+	group.POST(":id/status", controllers.ChangeCustomerStatus)
+	group.GET(":id/status-history", controllers.GetCustomerStatusHistory)
+
+	// Ownership transfer closes the account and opens one for the new owner
+	group.POST(":id/transfer", controllers.TransferCustomer)
+	group.POST(":id/relocate", controllers.RelocateCustomer)
+
+	// Customer credit & refunds
+	group.GET(":id/credit", controllers.GetCustomerCredit)
+	group.POST(":id/refunds", controllers.RefundCustomerCredit)
 }
@@ -1,12 +1,15 @@
 package routes
 
 import (
+	"github.com/adipras/tirta-saas-backend/config"
 	"github.com/adipras/tirta-saas-backend/controllers"
 	"github.com/adipras/tirta-saas-backend/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 func CustomerSelfServiceRoutes(r *gin.Engine) {
+	meterIssueController := controllers.NewMeterIssueController(config.DB)
+
 	group := r.Group("/api/customer")
 	group.Use(middleware.CustomerJWTAuthMiddleware())
 
@@ -18,8 +21,13 @@ func CustomerSelfServiceRoutes(r *gin.Engine) {
 	// Data access
 	group.GET("/invoices", controllers.GetCustomerInvoices)
 	group.GET("/payments", controllers.GetCustomerPayments)
+	group.GET("/payments/:id/receipt", controllers.CustomerGetPaymentReceipt)
 	group.GET("/water-usage", controllers.GetCustomerWaterUsage)
 
 	// Payment
 	group.POST("/payments", controllers.CustomerMakePayment)
+
+	// Meter problems
+	group.GET("/meter-issues", meterIssueController.CustomerGetMeterIssues)
+	group.POST("/meter-issues", meterIssueController.CustomerReportMeterIssue)
 }
\ No newline at end of file
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func GeoRoutes(r *gin.Engine) {
+	geoController := controllers.NewGeoController(config.DB)
+
+	geo := r.Group("/api/geo")
+	geo.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		geo.GET("/service-areas", middleware.RequirePermission(constants.PermViewCustomers), geoController.GetServiceAreaFeatures)
+		geo.GET("/customers", middleware.RequirePermission(constants.PermViewCustomers), geoController.GetCustomerFeatures)
+		geo.GET("/meters", middleware.RequirePermission(constants.PermViewCustomers), geoController.GetMeterFeatures)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func LeakDetectionRoutes(r *gin.Engine) {
+	leakController := controllers.NewLeakDetectionController(config.DB)
+
+	// Suspected leaks are listed as meter issues of type leak
+	api := r.Group("/api/leak-detection")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("/settings", middleware.RequirePermission(constants.PermViewWaterUsage), leakController.GetLeakSettings)
+		api.PUT("/settings", middleware.RequirePermission(constants.PermManageWaterRates), leakController.UpdateLeakSettings)
+		api.POST("/run", middleware.RequirePermission(constants.PermManageRepairs), leakController.RunLeakDetection)
+	}
+}
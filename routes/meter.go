@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func MeterRoutes(r *gin.Engine) {
+	meterController := controllers.NewMeterController(config.DB)
+
+	api := r.Group("/api/meters")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("", middleware.RequirePermission(constants.PermViewCustomers), meterController.GetMeters)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewCustomers), meterController.GetMeter)
+		api.GET("/:id/readings", middleware.RequirePermission(constants.PermViewWaterUsage), meterController.GetMeterReadings)
+		api.GET("/:id/history", middleware.RequirePermission(constants.PermViewCustomers), meterController.GetMeterHistory)
+
+		api.POST("", middleware.RequirePermission(constants.PermManageInstallations), meterController.CreateMeter)
+		api.PUT("/:id", middleware.RequirePermission(constants.PermManageInstallations), meterController.UpdateMeter)
+		api.POST("/:id/replace", middleware.RequirePermission(constants.PermManageInstallations), meterController.ReplaceMeter)
+		api.POST("/:id/calibrate", middleware.RequirePermission(constants.PermManageInstallations, constants.PermManageRepairs), meterController.CalibrateMeter)
+	}
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func MeterDeviceRoutes(r *gin.Engine) {
+	deviceController := controllers.NewMeterDeviceController(config.DB)
+
+	// Devices authenticate with their own key instead of a user token
+	devices := r.Group("/api/devices")
+	devices.Use(deviceController.DeviceAuth())
+	{
+		devices.POST("/readings", deviceController.IngestDeviceReadings)
+	}
+
+	api := r.Group("/api/meter-devices")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("", middleware.RequirePermission(constants.PermViewCustomers), deviceController.GetMeterDevices)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewCustomers), deviceController.GetMeterDevice)
+		api.GET("/:id/intervals", middleware.RequirePermission(constants.PermViewWaterUsage), deviceController.GetMeterIntervals)
+
+		api.POST("", middleware.RequirePermission(constants.PermManageInstallations), deviceController.CreateMeterDevice)
+		api.PUT("/:id", middleware.RequirePermission(constants.PermManageInstallations), deviceController.UpdateMeterDevice)
+		api.POST("/:id/rotate-key", middleware.RequirePermission(constants.PermManageInstallations), deviceController.RotateDeviceKey)
+		api.DELETE("/:id", middleware.RequirePermission(constants.PermManageInstallations), deviceController.DeleteMeterDevice)
+	}
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func MeterIssueRoutes(r *gin.Engine) {
+	issueController := controllers.NewMeterIssueController(config.DB)
+
+	api := r.Group("/api/meter-issues")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("", middleware.RequirePermission(constants.PermViewCustomers), issueController.GetMeterIssues)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewCustomers), issueController.GetMeterIssue)
+
+		// Readers and field staff report, repair staff work the ticket
+		api.POST("", middleware.RequirePermission(constants.PermRecordWaterUsage, constants.PermManageRepairs), issueController.ReportMeterIssue)
+		api.PUT("/:id", middleware.RequirePermission(constants.PermManageRepairs), issueController.UpdateMeterIssue)
+		api.POST("/:id/start", middleware.RequirePermission(constants.PermManageRepairs), issueController.StartMeterIssue)
+		api.POST("/:id/resolve", middleware.RequirePermission(constants.PermManageRepairs), issueController.ResolveMeterIssue)
+		api.POST("/:id/close", middleware.RequirePermission(constants.PermManageRepairs), issueController.CloseMeterIssue)
+	}
+}
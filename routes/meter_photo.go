@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func MeterPhotoRoutes(r *gin.Engine) {
+	photoController := controllers.NewMeterPhotoController(config.DB)
+
+	// Pre-signed uploads are authorised by the token in the URL
+	r.PUT("/api/meter-photos/upload/:token", photoController.CompletePhotoUpload)
+
+	api := r.Group("/api/meter-photos")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.POST("", middleware.RequirePermission(constants.PermRecordWaterUsage), photoController.UploadMeterPhoto)
+		api.POST("/upload-url", middleware.RequirePermission(constants.PermRecordWaterUsage), photoController.CreatePhotoUploadURL)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), photoController.GetMeterPhoto)
+		api.GET("/:id/file", middleware.RequirePermission(constants.PermViewWaterUsage), photoController.GetMeterPhotoFile)
+		api.GET("/:id/thumbnail", middleware.RequirePermission(constants.PermViewWaterUsage), photoController.GetMeterPhotoThumbnail)
+		api.POST("/:id/attach", middleware.RequirePermission(constants.PermRecordWaterUsage), photoController.AttachMeterPhoto)
+	}
+}
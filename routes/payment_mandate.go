@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func PaymentMandateRoutes(r *gin.Engine) {
+	mandateController := controllers.NewPaymentMandateController(config.DB)
+
+	api := r.Group("/api/mandates")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("", middleware.RequirePermission(constants.PermViewPayments), mandateController.GetPaymentMandates)
+		api.GET("/attempts", middleware.RequirePermission(constants.PermViewPayments), mandateController.GetAutoDebitAttempts)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewPayments), mandateController.GetPaymentMandate)
+
+		api.POST("", middleware.RequirePermission(constants.PermManagePayments), mandateController.CreatePaymentMandate)
+		api.PUT("/:id", middleware.RequirePermission(constants.PermManagePayments), mandateController.UpdatePaymentMandate)
+		api.POST("/:id/revoke", middleware.RequirePermission(constants.PermManagePayments), mandateController.RevokePaymentMandate)
+		api.POST("/run", middleware.RequirePermission(constants.PermManagePayments), mandateController.RunAutoDebit)
+	}
+}
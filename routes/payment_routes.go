@@ -16,5 +16,9 @@ func PaymentRoutes(r *gin.Engine) {
 	group.GET(":id", controllers.GetPayment)
 	group.PUT(":id", controllers.UpdatePayment)
 	group.DELETE(":id", controllers.DeletePayment)
+	group.POST(":id/reverse", controllers.ReversePayment)
+	group.GET(":id/receipt", controllers.GetPaymentReceipt)
+	group.POST(":id/receipt/reprint", controllers.ReprintPaymentReceipt)
+	group.GET("receipts/:number", controllers.GetReceiptByNumber)
 	group.GET("customer/:customer_id", controllers.GetPaymentHistoryByCustomerID)
 }
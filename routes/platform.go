@@ -65,6 +65,9 @@ func PlatformRoutes(r *gin.Engine) {
 		tenant.POST("/customers/bulk-activate", controllers.BulkActivateCustomers)
 		tenant.GET("/customers/export", controllers.ExportCustomers)
 		
+		// Meter Reading Bulk Import
+		tenant.POST("/readings/bulk-import", controllers.BulkImportReadings)
+		
 		// TODO: Reports
 		// tenant.GET("/reports/monthly-collection", controllers.MonthlyCollectionReport)
 		// tenant.GET("/reports/outstanding-payments", controllers.OutstandingPaymentsReport)
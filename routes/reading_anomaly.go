@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ReadingAnomalyRoutes(r *gin.Engine) {
+	anomalyController := controllers.NewReadingAnomalyController(config.DB)
+
+	api := r.Group("/api/reading-anomalies")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("/settings", middleware.RequirePermission(constants.PermViewWaterUsage), anomalyController.GetAnomalySettings)
+		api.PUT("/settings", middleware.RequirePermission(constants.PermManageWaterRates), anomalyController.UpdateAnomalySettings)
+		api.POST("/detect", middleware.RequirePermission(constants.PermEditWaterUsage), anomalyController.RunAnomalyDetection)
+
+		// Review queue
+		api.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), anomalyController.GetReadingAnomalies)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), anomalyController.GetReadingAnomaly)
+		// Resolving reprices the customer's invoice, so readers cannot clear their own anomalies
+		api.POST("/:id/resolve", middleware.RequirePermission(constants.PermEditInvoices), anomalyController.ResolveReadingAnomaly)
+	}
+}
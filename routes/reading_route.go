@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ReadingRouteRoutes(r *gin.Engine) {
+	routeController := controllers.NewReadingRouteController(config.DB)
+
+	api := r.Group("/api/reading-routes")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		// Meter reader's daily worklist
+		api.GET("/my/today", middleware.RequirePermission(constants.PermRecordWaterUsage), routeController.GetMyRouteToday)
+
+		api.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), routeController.GetReadingRoutes)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), routeController.GetReadingRoute)
+
+		api.POST("", middleware.RequirePermission(constants.PermManageCustomers), routeController.CreateReadingRoute)
+		api.PUT("/:id", middleware.RequirePermission(constants.PermManageCustomers), routeController.UpdateReadingRoute)
+		api.DELETE("/:id", middleware.RequirePermission(constants.PermManageCustomers), routeController.DeleteReadingRoute)
+		api.PUT("/:id/stops", middleware.RequirePermission(constants.PermManageCustomers), routeController.SetRouteStops)
+		api.POST("/:id/customers", middleware.RequirePermission(constants.PermManageCustomers), routeController.AssignRouteCustomers)
+		api.POST("/:id/customers/remove", middleware.RequirePermission(constants.PermManageCustomers), routeController.RemoveRouteCustomers)
+		api.POST("/:id/optimize", middleware.RequirePermission(constants.PermManageCustomers), routeController.OptimizeRoute)
+	}
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ReadingSessionRoutes(r *gin.Engine) {
+	sessionController := controllers.NewReadingSessionController(config.DB)
+
+	api := r.Group("/api/reading-sessions")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		api.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), sessionController.GetReadingSessions)
+		api.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), sessionController.GetReadingSession)
+
+		// Reader workflow
+		api.POST("/:id/start", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.StartReadingSession)
+		api.POST("/:id/readings", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.SubmitSessionReadings)
+		api.POST("/:id/close", middleware.RequirePermission(constants.PermRecordWaterUsage), sessionController.CloseReadingSession)
+
+		api.POST("", middleware.RequirePermission(constants.PermManageCustomers), sessionController.CreateReadingSession)
+		api.POST("/schedule", middleware.RequirePermission(constants.PermManageCustomers), sessionController.ScheduleReadingSessions)
+		api.POST("/:id/cancel", middleware.RequirePermission(constants.PermManageCustomers), sessionController.CancelReadingSession)
+	}
+}
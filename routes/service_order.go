@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func ServiceOrderRoutes(r *gin.Engine) {
+	orderController := controllers.NewServiceOrderController(config.DB)
+
+	// Arrears policy that raises disconnection orders
+	policy := r.Group("/api/disconnection")
+	policy.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		policy.GET("/settings", middleware.RequirePermission(constants.PermViewCustomers), orderController.GetDisconnectionSettings)
+		policy.PUT("/settings", middleware.RequirePermission(constants.PermManageWaterRates), orderController.UpdateDisconnectionSettings)
+		policy.POST("/run", middleware.RequirePermission(constants.PermManageCustomers), orderController.RunDisconnection)
+	}
+
+	orders := r.Group("/api/service-orders")
+	orders.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		orders.GET("", middleware.RequirePermission(constants.PermViewCustomers), orderController.GetServiceOrders)
+		orders.GET("/:id", middleware.RequirePermission(constants.PermViewCustomers), orderController.GetServiceOrder)
+		orders.POST("", middleware.RequirePermission(constants.PermManageCustomers), orderController.CreateServiceOrder)
+		orders.POST("/:id/assign", middleware.RequirePermission(constants.PermManageCustomers), orderController.AssignServiceOrder)
+		orders.POST("/:id/complete", middleware.RequirePermission(constants.PermManageInstallations), orderController.CompleteServiceOrder)
+		orders.POST("/:id/cancel", middleware.RequirePermission(constants.PermManageCustomers), orderController.CancelServiceOrder)
+	}
+}
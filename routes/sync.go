@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func SyncRoutes(r *gin.Engine) {
+	syncController := controllers.NewSyncController(config.DB)
+
+	api := r.Group("/api/sync")
+	api.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		// Per-item permissions (record payments / record water usage) are checked while applying
+		api.POST("", middleware.RequirePermission(constants.PermRecordPayments, constants.PermRecordWaterUsage), syncController.Sync)
+	}
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/adipras/tirta-saas-backend/config"
+	"github.com/adipras/tirta-saas-backend/constants"
+	"github.com/adipras/tirta-saas-backend/controllers"
+	"github.com/adipras/tirta-saas-backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func WaterBalanceRoutes(r *gin.Engine) {
+	bulkMeterController := controllers.NewBulkMeterController(config.DB)
+	balanceController := controllers.NewWaterBalanceController(config.DB)
+
+	bulkMeters := r.Group("/api/bulk-meters")
+	bulkMeters.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		bulkMeters.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), bulkMeterController.GetBulkMeters)
+		bulkMeters.GET("/:id", middleware.RequirePermission(constants.PermViewWaterUsage), bulkMeterController.GetBulkMeter)
+		bulkMeters.GET("/:id/readings", middleware.RequirePermission(constants.PermViewWaterUsage), bulkMeterController.GetBulkMeterReadings)
+		bulkMeters.POST("/:id/readings", middleware.RequirePermission(constants.PermRecordWaterUsage), bulkMeterController.RecordBulkMeterReading)
+
+		bulkMeters.POST("", middleware.RequirePermission(constants.PermManageInstallations), bulkMeterController.CreateBulkMeter)
+		bulkMeters.PUT("/:id", middleware.RequirePermission(constants.PermManageInstallations), bulkMeterController.UpdateBulkMeter)
+		bulkMeters.DELETE("/:id", middleware.RequirePermission(constants.PermManageInstallations), bulkMeterController.DeleteBulkMeter)
+	}
+
+	balance := r.Group("/api/water-balance")
+	balance.Use(middleware.JWTAuthMiddleware(), middleware.RequireTenantUser())
+	{
+		balance.GET("", middleware.RequirePermission(constants.PermViewWaterUsage), balanceController.GetWaterBalance)
+		balance.GET("/trend", middleware.RequirePermission(constants.PermViewWaterUsage), balanceController.GetWaterBalanceTrend)
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// deviceKeyScheme starts every meter device key so leaked keys are recognisable
+const deviceKeyScheme = "tmd_"
+
+// GenerateDeviceKey returns a new meter device key with its lookup prefix and
+// the hash to store. The key itself is shown once and never stored.
+func GenerateDeviceKey() (key, prefix, hash string, err error) {
+	prefixBytes := make([]byte, 4)
+	secretBytes := make([]byte, 24)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	key = deviceKeyScheme + prefix + "." + hex.EncodeToString(secretBytes)
+	return key, prefix, HashDeviceKey(key), nil
+}
+
+// DeviceKeyPrefix returns the lookup prefix of a device key
+func DeviceKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, deviceKeyScheme) {
+		return "", false
+	}
+	prefix, secret, found := strings.Cut(strings.TrimPrefix(key, deviceKeyScheme), ".")
+	if !found || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// HashDeviceKey returns the stored hash of a device key
+func HashDeviceKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ExifData holds the EXIF fields used to verify meter photos
+type ExifData struct {
+	TakenAt   *time.Time
+	Latitude  *float64
+	Longitude *float64
+}
+
+// EXIF tags
+const (
+	exifTagExifIFD          = 0x8769
+	exifTagGPSIFD           = 0x8825
+	exifTagDateTime         = 0x0132
+	exifTagDateTimeOriginal = 0x9003
+	exifTagGPSLatitudeRef   = 0x0001
+	exifTagGPSLatitude      = 0x0002
+	exifTagGPSLongitudeRef  = 0x0003
+	exifTagGPSLongitude     = 0x0004
+)
+
+var errNoExif = errors.New("no EXIF data")
+
+// ReadExif extracts the capture time and GPS position from a JPEG. Photos
+// without EXIF return empty data; only malformed EXIF is an error.
+func ReadExif(r io.Reader) (*ExifData, error) {
+	payload, err := findExifSegment(r)
+	if errors.Is(err, errNoExif) {
+		return &ExifData{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tiff := payload[6:]
+	if len(tiff) < 8 {
+		return nil, errors.New("invalid EXIF header")
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid EXIF byte order")
+	}
+
+	ifd0, err := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+	if err != nil {
+		return nil, err
+	}
+
+	data := &ExifData{}
+	dateTime := ifd0.ascii(tiff, order, exifTagDateTime)
+	if offset, ok := ifd0.long(order, exifTagExifIFD); ok {
+		if exifIFD, err := readIFD(tiff, order, offset); err == nil {
+			if original := exifIFD.ascii(tiff, order, exifTagDateTimeOriginal); original != "" {
+				dateTime = original
+			}
+		}
+	}
+	if takenAt, err := time.ParseInLocation("2006:01:02 15:04:05", dateTime, time.Local); err == nil {
+		data.TakenAt = &takenAt
+	}
+
+	if offset, ok := ifd0.long(order, exifTagGPSIFD); ok {
+		if gps, err := readIFD(tiff, order, offset); err == nil {
+			data.Latitude = gps.coordinate(tiff, order, exifTagGPSLatitude, exifTagGPSLatitudeRef, "S")
+			data.Longitude = gps.coordinate(tiff, order, exifTagGPSLongitude, exifTagGPSLongitudeRef, "W")
+		}
+	}
+	return data, nil
+}
+
+// findExifSegment returns the APP1 Exif payload of a JPEG stream
+func findExifSegment(r io.Reader) ([]byte, error) {
+	var marker [2]byte
+	if _, err := io.ReadFull(r, marker[:]); err != nil || marker != [2]byte{0xFF, 0xD8} {
+		return nil, errNoExif
+	}
+
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xFF {
+			return nil, errNoExif
+		}
+		// Start of scan: no metadata segments follow
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, errNoExif
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return nil, errNoExif
+		}
+		size := int(binary.BigEndian.Uint16(length[:])) - 2
+		if size < 0 {
+			return nil, errNoExif
+		}
+		segment := make([]byte, size)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, errNoExif
+		}
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment, nil
+		}
+	}
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte // the 4 byte value/offset field
+}
+
+type ifd map[uint16]ifdEntry
+
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) (ifd, error) {
+	if int(offset)+2 > len(tiff) {
+		return nil, errors.New("EXIF IFD out of range")
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(tiff) {
+		return nil, errors.New("EXIF IFD out of range")
+	}
+
+	entries := ifd{}
+	for i := 0; i < count; i++ {
+		raw := tiff[start+i*12 : start+(i+1)*12]
+		entries[order.Uint16(raw)] = ifdEntry{
+			typ:   order.Uint16(raw[2:]),
+			count: order.Uint32(raw[4:]),
+			value: raw[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// data returns the bytes of an entry, inline or at its offset
+func (entries ifd) data(tiff []byte, order binary.ByteOrder, tag uint16, unitSize int) []byte {
+	entry, ok := entries[tag]
+	if !ok {
+		return nil
+	}
+	size := int(entry.count) * unitSize
+	if size <= 4 {
+		return entry.value[:size]
+	}
+	offset := int(order.Uint32(entry.value))
+	if offset < 0 || offset+size > len(tiff) {
+		return nil
+	}
+	return tiff[offset : offset+size]
+}
+
+func (entries ifd) long(order binary.ByteOrder, tag uint16) (uint32, bool) {
+	entry, ok := entries[tag]
+	if !ok {
+		return 0, false
+	}
+	if entry.typ == 3 { // SHORT
+		return uint32(order.Uint16(entry.value)), true
+	}
+	return order.Uint32(entry.value), true
+}
+
+func (entries ifd) ascii(tiff []byte, order binary.ByteOrder, tag uint16) string {
+	return strings.TrimRight(string(entries.data(tiff, order, tag, 1)), "\x00 ")
+}
+
+// coordinate converts a degrees/minutes/seconds GPS value to signed decimal degrees
+func (entries ifd) coordinate(tiff []byte, order binary.ByteOrder, tag, refTag uint16, negativeRef string) *float64 {
+	raw := entries.data(tiff, order, tag, 8)
+	if len(raw) < 24 {
+		return nil
+	}
+
+	parts := [3]float64{}
+	for i := range parts {
+		numerator := order.Uint32(raw[i*8:])
+		denominator := order.Uint32(raw[i*8+4:])
+		if denominator == 0 {
+			return nil
+		}
+		parts[i] = float64(numerator) / float64(denominator)
+	}
+	value := parts[0] + parts[1]/60 + parts[2]/3600
+	if entries.ascii(tiff, order, refTag) == negativeRef {
+		value = -value
+	}
+	return &value
+}
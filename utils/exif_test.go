@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// tiffEntry is an IFD entry of a test TIFF. Data longer than 4 bytes is
+// stored after the IFDs; link points the entry at another IFD instead.
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+	link  int
+}
+
+// buildTIFF lays out the IFDs one after another, IFD0 first
+func buildTIFF(order binary.ByteOrder, ifds ...[]tiffEntry) []byte {
+	offsets := make([]uint32, len(ifds))
+	size := uint32(8)
+	for i, entries := range ifds {
+		offsets[i] = size
+		size += 2 + 12*uint32(len(entries)) + 4
+	}
+
+	buf := make([]byte, size)
+	copy(buf, "II")
+	if order == binary.BigEndian {
+		copy(buf, "MM")
+	}
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+	for i, entries := range ifds {
+		order.PutUint16(buf[offsets[i]:], uint16(len(entries)))
+		for k, entry := range entries {
+			at := offsets[i] + 2 + 12*uint32(k)
+			order.PutUint16(buf[at:], entry.tag)
+			order.PutUint16(buf[at+2:], entry.typ)
+			order.PutUint32(buf[at+4:], entry.count)
+			switch {
+			case entry.link > 0:
+				order.PutUint32(buf[at+8:], offsets[entry.link])
+			case len(entry.data) <= 4:
+				copy(buf[at+8:at+12], entry.data)
+			default:
+				order.PutUint32(buf[at+8:], uint32(len(buf)))
+				buf = append(buf, entry.data...)
+			}
+		}
+	}
+	return buf
+}
+
+func asciiEntry(tag uint16, value string) tiffEntry {
+	return tiffEntry{tag: tag, typ: 2, count: uint32(len(value) + 1), data: []byte(value + "\x00")}
+}
+
+func rationalEntry(order binary.ByteOrder, tag uint16, values ...uint32) tiffEntry {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		order.PutUint32(data[4*i:], v)
+	}
+	return tiffEntry{tag: tag, typ: 5, count: uint32(len(values) / 2), data: data}
+}
+
+// jpegWithExif wraps a TIFF block in a JPEG with a JFIF segment before the
+// Exif one, as cameras write them
+func jpegWithExif(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+	buf.Write([]byte{0xFF, 0xE0, 0x00, 0x07})
+	buf.WriteString("JFIF\x00")
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	buf.Write([]byte{0xFF, 0xE1})
+	binary.Write(&buf, binary.BigEndian, uint16(len(payload)+2))
+	buf.Write(payload)
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+// cameraTIFF is a photo taken in Jakarta: 6°12'30"S 106°49'12"E
+func cameraTIFF(order binary.ByteOrder) []byte {
+	return buildTIFF(order,
+		[]tiffEntry{
+			asciiEntry(exifTagDateTime, "2026:03:01 08:00:00"),
+			{tag: exifTagExifIFD, typ: 4, count: 1, link: 1},
+			{tag: exifTagGPSIFD, typ: 4, count: 1, link: 2},
+		},
+		[]tiffEntry{asciiEntry(exifTagDateTimeOriginal, "2026:03:01 09:30:00")},
+		[]tiffEntry{
+			asciiEntry(exifTagGPSLatitudeRef, "S"),
+			rationalEntry(order, exifTagGPSLatitude, 6, 1, 12, 1, 3000, 100),
+			asciiEntry(exifTagGPSLongitudeRef, "E"),
+			rationalEntry(order, exifTagGPSLongitude, 106, 1, 49, 1, 12, 1),
+		},
+	)
+}
+
+func TestReadExif(t *testing.T) {
+	wantTaken := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			data, err := ReadExif(bytes.NewReader(jpegWithExif(cameraTIFF(order))))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data.TakenAt == nil || !data.TakenAt.Equal(wantTaken) {
+				t.Errorf("taken at %v, want %v", data.TakenAt, wantTaken)
+			}
+			if data.Latitude == nil || math.Abs(*data.Latitude-(-6.208333)) > 1e-6 {
+				t.Errorf("latitude %v, want -6.208333", data.Latitude)
+			}
+			if data.Longitude == nil || math.Abs(*data.Longitude-106.82) > 1e-6 {
+				t.Errorf("longitude %v, want 106.82", data.Longitude)
+			}
+		})
+	}
+}
+
+func TestReadExifFallsBackToDateTime(t *testing.T) {
+	tiff := buildTIFF(binary.LittleEndian, []tiffEntry{asciiEntry(exifTagDateTime, "2026:03:01 08:00:00")})
+	data, err := ReadExif(bytes.NewReader(jpegWithExif(tiff)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local)
+	if data.TakenAt == nil || !data.TakenAt.Equal(want) {
+		t.Fatalf("taken at %v, want %v", data.TakenAt, want)
+	}
+	if data.Latitude != nil || data.Longitude != nil {
+		t.Fatal("photo without GPS has a position")
+	}
+}
+
+// Photos without usable EXIF are accepted with empty data
+func TestReadExifWithoutExif(t *testing.T) {
+	truncated := jpegWithExif(cameraTIFF(binary.LittleEndian))
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "empty", input: nil},
+		{name: "png", input: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")},
+		{name: "jpeg without exif", input: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00, 0xFF, 0xDA}},
+		{name: "segment cut short", input: truncated[:len(truncated)/2]},
+		{name: "segment length below 2", input: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01}},
+		{name: "garbage after soi", input: []byte{0xFF, 0xD8, 0x12, 0x34}},
+		{name: "other app1", input: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x06, 'h', 't', 't', 'p', 0xFF, 0xD9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ReadExif(bytes.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data.TakenAt != nil || data.Latitude != nil || data.Longitude != nil {
+				t.Fatalf("got %+v, want empty data", data)
+			}
+		})
+	}
+}
+
+func TestReadExifMalformed(t *testing.T) {
+	order := binary.LittleEndian
+	tooManyEntries := buildTIFF(order, []tiffEntry{asciiEntry(exifTagDateTime, "2026:03:01 08:00:00")})
+	order.PutUint16(tooManyEntries[8:], 0xFFFF)
+	ifdOutOfRange := buildTIFF(order, []tiffEntry{})
+	order.PutUint32(ifdOutOfRange[4:], 0xFFFFFFF0)
+
+	tests := []struct {
+		name string
+		tiff []byte
+	}{
+		{name: "header too short", tiff: []byte("II*\x00")},
+		{name: "unknown byte order", tiff: []byte("XX*\x00\x08\x00\x00\x00\x00\x00")},
+		{name: "ifd0 out of range", tiff: ifdOutOfRange},
+		{name: "entry count beyond data", tiff: tooManyEntries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadExif(bytes.NewReader(jpegWithExif(tt.tiff))); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+// Broken sub-IFDs and values are skipped without failing the photo
+func TestReadExifIgnoresBrokenValues(t *testing.T) {
+	order := binary.BigEndian
+	tests := []struct {
+		name    string
+		entries []tiffEntry
+	}{
+		{name: "gps ifd out of range", entries: []tiffEntry{
+			{tag: exifTagGPSIFD, typ: 4, count: 1, data: []byte{0x7F, 0xFF, 0xFF, 0xFF}},
+		}},
+		{name: "date offset out of range", entries: []tiffEntry{
+			{tag: exifTagDateTime, typ: 2, count: 20, data: []byte{0xFF, 0xFF, 0xFF, 0xF0}},
+		}},
+		{name: "huge date count", entries: []tiffEntry{
+			{tag: exifTagDateTime, typ: 2, count: 0xFFFFFFFF, data: []byte{0x00, 0x00, 0x00, 0x08}},
+		}},
+		{name: "unparsable date", entries: []tiffEntry{asciiEntry(exifTagDateTime, "yesterday")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ReadExif(bytes.NewReader(jpegWithExif(buildTIFF(order, tt.entries))))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data.TakenAt != nil || data.Latitude != nil {
+				t.Fatalf("got %+v, want empty data", data)
+			}
+		})
+	}
+
+	zeroDenominator := buildTIFF(order,
+		[]tiffEntry{{tag: exifTagGPSIFD, typ: 4, count: 1, link: 1}},
+		[]tiffEntry{
+			rationalEntry(order, exifTagGPSLatitude, 6, 0, 12, 1, 30, 1),
+			rationalEntry(order, exifTagGPSLongitude, 106, 1, 49, 1),
+		},
+	)
+	data, err := ReadExif(bytes.NewReader(jpegWithExif(zeroDenominator)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data.Latitude != nil || data.Longitude != nil {
+		t.Fatalf("got %v %v, want no position", data.Latitude, data.Longitude)
+	}
+}
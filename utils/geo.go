@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrCoordinatesOutOfRange = errors.New("coordinates out of range")
+
+const earthRadiusMeters = 6371000
+
+// Position is a GeoJSON position: longitude, then latitude
+type Position [2]float64
+
+// Polygon is a GeoJSON polygon: the outer ring followed by its holes
+type Polygon [][]Position
+
+// geometry is a GeoJSON geometry or a Feature wrapping one
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geometry       `json:"geometry"`
+}
+
+// ParseBoundary parses a GeoJSON Polygon or MultiPolygon, given as a
+// geometry or a Feature, into its polygons. The returned JSON is the bare
+// geometry to store.
+func ParseBoundary(raw []byte) ([]Polygon, []byte, error) {
+	var geom geometry
+	if err := json.Unmarshal(raw, &geom); err != nil {
+		return nil, nil, errors.New("boundary must be a GeoJSON Polygon or MultiPolygon")
+	}
+	if geom.Type == "Feature" {
+		if geom.Geometry == nil {
+			return nil, nil, errors.New("boundary feature has no geometry")
+		}
+		geom = *geom.Geometry
+	}
+
+	var polygons []Polygon
+	switch geom.Type {
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(geom.Coordinates, &coords); err != nil {
+			return nil, nil, errors.New("invalid Polygon coordinates")
+		}
+		polygon, err := toPolygon(coords)
+		if err != nil {
+			return nil, nil, err
+		}
+		polygons = []Polygon{polygon}
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(geom.Coordinates, &coords); err != nil {
+			return nil, nil, errors.New("invalid MultiPolygon coordinates")
+		}
+		if len(coords) == 0 {
+			return nil, nil, errors.New("MultiPolygon has no polygons")
+		}
+		for _, polygonCoords := range coords {
+			polygon, err := toPolygon(polygonCoords)
+			if err != nil {
+				return nil, nil, err
+			}
+			polygons = append(polygons, polygon)
+		}
+	default:
+		return nil, nil, fmt.Errorf("boundary must be a Polygon or MultiPolygon, got %q", geom.Type)
+	}
+
+	stored, err := json.Marshal(struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}{geom.Type, geom.Coordinates})
+	if err != nil {
+		return nil, nil, err
+	}
+	return polygons, stored, nil
+}
+
+func toPolygon(coords [][][]float64) (Polygon, error) {
+	if len(coords) == 0 {
+		return nil, errors.New("polygon has no rings")
+	}
+	polygon := make(Polygon, len(coords))
+	for i, ringCoords := range coords {
+		// A closed ring repeats its first position at the end
+		if len(ringCoords) < 4 {
+			return nil, errors.New("polygon rings need at least 4 positions")
+		}
+		ring := make([]Position, len(ringCoords))
+		for j, coord := range ringCoords {
+			if len(coord) < 2 {
+				return nil, errors.New("positions need a longitude and a latitude")
+			}
+			if err := ValidateCoordinates(coord[1], coord[0]); err != nil {
+				return nil, err
+			}
+			ring[j] = Position{coord[0], coord[1]}
+		}
+		if ring[0] != ring[len(ring)-1] {
+			return nil, errors.New("polygon rings must end at their first position")
+		}
+		polygon[i] = ring
+	}
+	return polygon, nil
+}
+
+// ValidateCoordinates checks a latitude and longitude are in range
+func ValidateCoordinates(lat, lng float64) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("%w: latitude %v", ErrCoordinatesOutOfRange, lat)
+	}
+	if lng < -180 || lng > 180 {
+		return fmt.Errorf("%w: longitude %v", ErrCoordinatesOutOfRange, lng)
+	}
+	return nil
+}
+
+// BoundaryContains reports whether the point lies inside one of the polygons
+// and outside its holes
+func BoundaryContains(polygons []Polygon, lat, lng float64) bool {
+	for _, polygon := range polygons {
+		if !ringContains(polygon[0], lat, lng) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if ringContains(hole, lat, lng) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains is the even-odd ray casting test. Service areas are small
+// enough to treat coordinates as planar.
+func ringContains(ring []Position, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DistanceMeters is the great-circle (haversine) distance between two points
+func DistanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLng := (lng2 - lng1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
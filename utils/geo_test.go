@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// ring builds a closed ring from longitude, latitude pairs
+func ring(coords ...float64) []Position {
+	positions := make([]Position, 0, len(coords)/2+1)
+	for i := 0; i+1 < len(coords); i += 2 {
+		positions = append(positions, Position{coords[i], coords[i+1]})
+	}
+	return append(positions, positions[0])
+}
+
+func TestRingContains(t *testing.T) {
+	square := ring(106, -7, 107, -7, 107, -6, 106, -6)
+	// A U shape open to the north, the notch between longitude 106.4 and 106.6
+	notched := ring(106, -7, 107, -7, 107, -6, 106.6, -6, 106.6, -6.5, 106.4, -6.5, 106.4, -6, 106, -6)
+
+	tests := []struct {
+		name     string
+		ring     []Position
+		lat, lng float64
+		want     bool
+	}{
+		{name: "centre", ring: square, lat: -6.5, lng: 106.5, want: true},
+		{name: "west", ring: square, lat: -6.5, lng: 105.9, want: false},
+		{name: "east", ring: square, lat: -6.5, lng: 107.1, want: false},
+		{name: "north", ring: square, lat: -5.9, lng: 106.5, want: false},
+		{name: "south", ring: square, lat: -7.1, lng: 106.5, want: false},
+		{name: "level with a vertex", ring: square, lat: -6, lng: 105.5, want: false},
+		{name: "u left arm", ring: notched, lat: -6.2, lng: 106.2, want: true},
+		{name: "u notch", ring: notched, lat: -6.2, lng: 106.5, want: false},
+		{name: "u base", ring: notched, lat: -6.8, lng: 106.5, want: true},
+		{name: "u right of notch", ring: notched, lat: -6.2, lng: 106.8, want: true},
+		{name: "degenerate ring", ring: ring(106, -6, 106, -6, 106, -6), lat: -6, lng: 106, want: false},
+		{name: "empty ring", ring: []Position{}, lat: -6, lng: 106, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ringContains(tt.ring, tt.lat, tt.lng); got != tt.want {
+				t.Fatalf("ringContains(%v, %v) = %v, want %v", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoundaryContains(t *testing.T) {
+	withHole := Polygon{
+		ring(106, -7, 107, -7, 107, -6, 106, -6),
+		ring(106.4, -6.6, 106.6, -6.6, 106.6, -6.4, 106.4, -6.4),
+	}
+	island := Polygon{ring(110, -8, 111, -8, 111, -7, 110, -7)}
+
+	tests := []struct {
+		name     string
+		polygons []Polygon
+		lat, lng float64
+		want     bool
+	}{
+		{name: "outside the hole", polygons: []Polygon{withHole}, lat: -6.8, lng: 106.2, want: true},
+		{name: "in the hole", polygons: []Polygon{withHole}, lat: -6.5, lng: 106.5, want: false},
+		{name: "second polygon", polygons: []Polygon{withHole, island}, lat: -7.5, lng: 110.5, want: true},
+		{name: "between polygons", polygons: []Polygon{withHole, island}, lat: -7.5, lng: 108, want: false},
+		{name: "no polygons", polygons: nil, lat: -6.5, lng: 106.5, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BoundaryContains(tt.polygons, tt.lat, tt.lng); got != tt.want {
+				t.Fatalf("BoundaryContains(%v, %v) = %v, want %v", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBoundary(t *testing.T) {
+	const square = `[[[106,-7],[107,-7],[107,-6],[106,-6],[106,-7]]]`
+	tests := []struct {
+		name     string
+		raw      string
+		polygons int
+		wantErr  bool
+	}{
+		{name: "polygon", raw: `{"type":"Polygon","coordinates":` + square + `}`, polygons: 1},
+		{name: "feature", raw: `{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":` + square + `}}`, polygons: 1},
+		{name: "multipolygon", raw: `{"type":"MultiPolygon","coordinates":[` + square + `,` + square + `]}`, polygons: 2},
+		{name: "not json", raw: `POLYGON((106 -7, 107 -7))`, wantErr: true},
+		{name: "point", raw: `{"type":"Point","coordinates":[106,-7]}`, wantErr: true},
+		{name: "feature without geometry", raw: `{"type":"Feature"}`, wantErr: true},
+		{name: "no rings", raw: `{"type":"Polygon","coordinates":[]}`, wantErr: true},
+		{name: "empty multipolygon", raw: `{"type":"MultiPolygon","coordinates":[]}`, wantErr: true},
+		{name: "too few positions", raw: `{"type":"Polygon","coordinates":[[[106,-7],[107,-7],[106,-7]]]}`, wantErr: true},
+		{name: "open ring", raw: `{"type":"Polygon","coordinates":[[[106,-7],[107,-7],[107,-6],[106,-6]]]}`, wantErr: true},
+		{name: "position without latitude", raw: `{"type":"Polygon","coordinates":[[[106],[107,-7],[107,-6],[106]]]}`, wantErr: true},
+		{name: "latitude out of range", raw: `{"type":"Polygon","coordinates":[[[106,-97],[107,-7],[107,-6],[106,-97]]]}`, wantErr: true},
+		{name: "coordinates not numbers", raw: `{"type":"Polygon","coordinates":[[["a","b"]]]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polygons, stored, err := ParseBoundary([]byte(tt.raw))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d polygons", len(polygons))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(polygons) != tt.polygons {
+				t.Fatalf("got %d polygons, want %d", len(polygons), tt.polygons)
+			}
+			// The stored geometry parses back to the same polygons
+			again, _, err := ParseBoundary(stored)
+			if err != nil || len(again) != tt.polygons {
+				t.Fatalf("stored geometry %s does not parse back: %v", stored, err)
+			}
+		})
+	}
+}
+
+func TestValidateCoordinates(t *testing.T) {
+	if err := ValidateCoordinates(-6.2, 106.8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range [][2]float64{{-90.1, 0}, {90.1, 0}, {0, -180.1}, {0, 180.1}} {
+		if err := ValidateCoordinates(c[0], c[1]); !errors.Is(err, ErrCoordinatesOutOfRange) {
+			t.Errorf("ValidateCoordinates(%v, %v) = %v, want ErrCoordinatesOutOfRange", c[0], c[1], err)
+		}
+	}
+}
+
+func TestDistanceMeters(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{name: "same point", lat1: -6.2, lng1: 106.8, lat2: -6.2, lng2: 106.8, want: 0},
+		{name: "one degree of latitude", lat1: 0, lng1: 0, lat2: 1, lng2: 0, want: 111195},
+		{name: "antipodes", lat1: 0, lng1: 0, lat2: 0, lng2: 180, want: math.Pi * earthRadiusMeters},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DistanceMeters(tt.lat1, tt.lng1, tt.lat2, tt.lng2); math.Abs(got-tt.want) > 1 {
+				t.Fatalf("got %.0f m, want %.0f m", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ThumbnailSize is the longest side of generated thumbnails in pixels
+const ThumbnailSize = 320
+
+// MaxImagePixels caps the dimensions of images decoded for thumbnails; a
+// small, highly compressed file can otherwise expand to gigabytes
+const MaxImagePixels = 50_000_000
+
+// ErrImageTooManyPixels is returned for images above MaxImagePixels
+var ErrImageTooManyPixels = errors.New("image dimensions are too large")
+
+// CreateThumbnail writes a JPEG of the image at srcPath scaled down so its
+// longest side is at most maxSize. Smaller images are copied at full size.
+func CreateThumbnail(srcPath, dstPath string, maxSize int) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	cfg, _, err := image.DecodeConfig(src)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %v", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return ErrImageTooManyPixels
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create thumbnail directory: %v", err)
+	}
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to create thumbnail: %v", err)
+	}
+	defer dst.Close()
+
+	return writeThumbnail(dst, img, maxSize)
+}
+
+func writeThumbnail(w io.Writer, img image.Image, maxSize int) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 80})
+	}
+
+	newWidth, newHeight := maxSize, height*maxSize/width
+	if height > width {
+		newWidth, newHeight = width*maxSize/height, maxSize
+	}
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
+	// Box filter: each target pixel averages the source pixels it covers
+	thumb := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		y0 := bounds.Min.Y + y*height/newHeight
+		y1 := bounds.Min.Y + (y+1)*height/newHeight
+		for x := 0; x < newWidth; x++ {
+			x0 := bounds.Min.X + x*width/newWidth
+			x1 := bounds.Min.X + (x+1)*width/newWidth
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			thumb.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return jpeg.Encode(w, thumb, &jpeg.Options{Quality: 80})
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidUploadToken = errors.New("invalid or expired upload token")
+
+// SignUploadToken returns a token that authorises one upload for id until
+// expiresAt, used for pre-signed upload URLs
+func SignUploadToken(id uuid.UUID, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s.%d", id.String(), expiresAt.Unix())
+	return payload + "." + uploadSignature(payload)
+}
+
+// VerifyUploadToken checks a token from SignUploadToken and returns its id
+func VerifyUploadToken(token string) (uuid.UUID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return uuid.Nil, ErrInvalidUploadToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(uploadSignature(payload))) {
+		return uuid.Nil, ErrInvalidUploadToken
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return uuid.Nil, ErrInvalidUploadToken
+	}
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		return uuid.Nil, ErrInvalidUploadToken
+	}
+	return id, nil
+}
+
+func uploadSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte("upload:" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
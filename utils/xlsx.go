@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxXLSXPartSize caps how much of a single workbook part is decompressed
+const maxXLSXPartSize = 50 * 1024 * 1024
+
+// Excel's sheet limits; row numbers and cell references beyond them are
+// rejected before they are used to size the result
+const (
+	maxXLSXRows    = 1048576
+	maxXLSXColumns = 16384
+)
+
+// maxXLSXCells caps the cells returned for a sheet, padding included, so a
+// small part cannot expand into a huge result
+const maxXLSXCells = 2_000_000
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				Text string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSXRows returns the cell text of the first worksheet of an Excel
+// workbook, one slice per spreadsheet row. Empty rows are kept so row
+// indexes match the line numbers shown in Excel. Rows are cut to the width
+// of the first row with cells, which imports use as the header.
+func ReadXLSXRows(r io.ReaderAt, size int64) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("file is not a valid .xlsx workbook")
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst xlsxSharedStrings
+		if err := decodeXLSXPart(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			var text strings.Builder
+			text.WriteString(item.Text)
+			for _, run := range item.Runs {
+				text.WriteString(run.Text)
+			}
+			sharedStrings = append(sharedStrings, text.String())
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, errors.New("workbook has no worksheet")
+	}
+	var sheet xlsxWorksheet
+	if err := decodeXLSXPart(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	// Row numbers must increase, and rows are no wider than the first row
+	// with cells (the header); cells beyond it are dropped
+	var rows [][]string
+	width, cells := 0, 0
+	for _, row := range sheet.Rows {
+		number := row.Number
+		if number == 0 {
+			number = len(rows) + 1
+		}
+		if number <= len(rows) {
+			return nil, fmt.Errorf("row %d is out of order", number)
+		}
+		if number > maxXLSXRows {
+			return nil, fmt.Errorf("row %d is beyond the sheet limit of %d rows", number, maxXLSXRows)
+		}
+		// Rows without cells are not stored in the sheet
+		for number > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			column := i
+			if ref := xlsxColumnIndex(cell.Ref); ref >= 0 {
+				column = ref
+			}
+			if column >= maxXLSXColumns {
+				return nil, fmt.Errorf("cell %s is beyond the sheet limit of %d columns", cell.Ref, maxXLSXColumns)
+			}
+			if width > 0 && column >= width {
+				continue
+			}
+			if grow := column + 1 - len(values); grow > 0 {
+				if cells+grow > maxXLSXCells {
+					return nil, fmt.Errorf("sheet has more than %d cells", maxXLSXCells)
+				}
+				cells += grow
+				values = append(values, make([]string, grow)...)
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(sharedStrings) {
+					return nil, fmt.Errorf("invalid shared string in cell %s", cell.Ref)
+				}
+				values[column] = sharedStrings[index]
+			case "inlineStr":
+				values[column] = cell.Inline.Text
+			default:
+				values[column] = cell.Value
+			}
+		}
+		if width == 0 {
+			width = len(values)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// firstSheetPath resolves the first sheet of the workbook to its part name
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	workbookFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("file is not a valid .xlsx workbook")
+	}
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(workbookFile, &workbook); err != nil {
+		return "", err
+	}
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if len(workbook.Sheets) == 0 || !ok {
+		return fallback, nil
+	}
+	var rels xlsxRelationships
+	if err := decodeXLSXPart(relsFile, &rels); err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+func decodeXLSXPart(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("failed to read %s: %v", f.Name, err)
+	}
+	return nil
+}
+
+// xlsxColumnIndex converts the column letters of a cell reference such as
+// "AB12" to a zero based index. Columns past the sheet limit stop counting
+// at maxXLSXColumns, so overlong references cannot overflow.
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		index = index*26 + int(ch-'A'+1)
+		if index > maxXLSXColumns {
+			return maxXLSXColumns
+		}
+	}
+	return index - 1
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testWorkbook = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+		xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+		<sheets><sheet name="Readings" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	testRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+		<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`
+)
+
+// buildXLSX zips the given parts into a workbook
+func buildXLSX(t *testing.T, parts map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// sheetXLSX is a workbook whose first sheet has the given sheetData
+func sheetXLSX(t *testing.T, sheetData string) *bytes.Reader {
+	return buildXLSX(t, map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml": `<sst><si><t>meter_code</t></si><si><t>reading</t></si>` +
+			`<si><r><t>BM-</t></r><r><t>01</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": "<worksheet><sheetData>" + sheetData + "</sheetData></worksheet>",
+	})
+}
+
+func readXLSX(reader *bytes.Reader) ([][]string, error) {
+	return ReadXLSXRows(reader, reader.Size())
+}
+
+func TestReadXLSXRows(t *testing.T) {
+	tests := []struct {
+		name  string
+		sheet string
+		want  [][]string
+	}{
+		{
+			name: "shared, rich and inline strings",
+			sheet: `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+				`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>1234.5</v></c></row>` +
+				`<row r="3"><c r="A3" t="inlineStr"><is><t>BM-02</t></is></c><c r="B3"><v>99</v></c></row>`,
+			want: [][]string{{"meter_code", "reading"}, {"BM-01", "1234.5"}, {"BM-02", "99"}},
+		},
+		{
+			name: "empty rows keep their line numbers",
+			sheet: `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+				`<row r="4"><c r="A4"><v>7</v></c></row>`,
+			want: [][]string{{"meter_code", "reading"}, nil, nil, {"7"}},
+		},
+		{
+			name: "skipped cells are padded",
+			sheet: `<row r="1"><c r="A1"><v>a</v></c><c r="B1"><v>b</v></c><c r="C1"><v>c</v></c></row>` +
+				`<row r="2"><c r="C2"><v>3</v></c></row>`,
+			want: [][]string{{"a", "b", "c"}, {"", "", "3"}},
+		},
+		{
+			name: "cells beyond the header are dropped",
+			sheet: `<row r="1"><c r="A1"><v>a</v></c><c r="B1"><v>b</v></c></row>` +
+				`<row r="2"><c r="A2"><v>1</v></c><c r="XFD2"><v>x</v></c></row>`,
+			want: [][]string{{"a", "b"}, {"1"}},
+		},
+		{
+			name:  "rows and cells without references",
+			sheet: `<row><c><v>a</v></c><c><v>b</v></c></row><row><c><v>1</v></c><c><v>2</v></c></row>`,
+			want:  [][]string{{"a", "b"}, {"1", "2"}},
+		},
+		{
+			name:  "empty sheet",
+			sheet: "",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readXLSX(sheetXLSX(t, tt.sheet))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Fatalf("got %q, want %q", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadXLSXRowsSheetLocation(t *testing.T) {
+	sheet := `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>ok</t></is></c></row></sheetData></worksheet>`
+	tests := []struct {
+		name  string
+		parts map[string]string
+	}{
+		{name: "absolute target", parts: map[string]string{
+			"xl/workbook.xml": testWorkbook,
+			"xl/_rels/workbook.xml.rels": `<Relationships>` +
+				`<Relationship Id="rId1" Target="/xl/worksheets/readings.xml"/></Relationships>`,
+			"xl/worksheets/readings.xml": sheet,
+		}},
+		{name: "no relationships", parts: map[string]string{
+			"xl/workbook.xml":          testWorkbook,
+			"xl/worksheets/sheet1.xml": sheet,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readXLSX(buildXLSX(t, tt.parts))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := [][]string{{"ok"}}; !reflect.DeepEqual(rows, want) {
+				t.Fatalf("got %q, want %q", rows, want)
+			}
+		})
+	}
+}
+
+func TestReadXLSXRowsRejectsHostileSheets(t *testing.T) {
+	// A header at the last column and enough rows reaching it to pass the cell cap
+	var wide strings.Builder
+	for row := 1; row <= maxXLSXCells/maxXLSXColumns+1; row++ {
+		fmt.Fprintf(&wide, `<row r="%d"><c r="XFD%d"><v>1</v></c></row>`, row, row)
+	}
+
+	tests := []struct {
+		name  string
+		sheet string
+	}{
+		{name: "rows out of order", sheet: `<row r="3"><c r="A3"><v>1</v></c></row><row r="2"><c r="A2"><v>1</v></c></row>`},
+		{name: "duplicate row", sheet: `<row r="1"><c r="A1"><v>1</v></c></row><row r="1"><c r="A1"><v>2</v></c></row>`},
+		{name: "negative row", sheet: `<row r="-5"><c r="A1"><v>1</v></c></row>`},
+		{name: "row beyond the sheet", sheet: `<row r="1048577"><c r="A1"><v>1</v></c></row>`},
+		{name: "column beyond the sheet", sheet: `<row r="1"><c r="XFE1"><v>1</v></c></row>`},
+		{name: "overlong column", sheet: `<row r="1"><c r="ZZZZZZZZZZZZZZZZZZZZ1"><v>1</v></c></row>`},
+		{name: "shared string out of range", sheet: `<row r="1"><c r="A1" t="s"><v>3</v></c></row>`},
+		{name: "negative shared string", sheet: `<row r="1"><c r="A1" t="s"><v>-1</v></c></row>`},
+		{name: "shared string not a number", sheet: `<row r="1"><c r="A1" t="s"><v>one</v></c></row>`},
+		{name: "too many cells", sheet: wide.String()},
+		{name: "malformed xml", sheet: `<row r="1"><c r="A1"><v>1</c></row>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rows, err := readXLSX(sheetXLSX(t, tt.sheet)); err == nil {
+				t.Fatalf("expected an error, got %d rows", len(rows))
+			}
+		})
+	}
+}
+
+func TestReadXLSXRowsRejectsBrokenWorkbooks(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader *bytes.Reader
+	}{
+		{name: "not a zip", reader: bytes.NewReader([]byte("meter_code,reading\nBM-01,10\n"))},
+		{name: "empty", reader: bytes.NewReader(nil)},
+		{name: "no workbook", reader: buildXLSX(t, map[string]string{"xl/worksheets/sheet1.xml": "<worksheet/>"})},
+		{name: "no worksheet", reader: buildXLSX(t, map[string]string{
+			"xl/workbook.xml":            testWorkbook,
+			"xl/_rels/workbook.xml.rels": testRels,
+		})},
+		{name: "malformed workbook", reader: buildXLSX(t, map[string]string{"xl/workbook.xml": "<workbook><sheets>"})},
+		{name: "malformed shared strings", reader: buildXLSX(t, map[string]string{
+			"xl/workbook.xml":          testWorkbook,
+			"xl/sharedStrings.xml":     "<sst><si><t>a</si></sst>",
+			"xl/worksheets/sheet1.xml": "<worksheet/>",
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readXLSX(tt.reader); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestXLSXColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0},
+		{"Z9", 25},
+		{"AA10", 26},
+		{"XFD1", maxXLSXColumns - 1},
+		{"XFE1", maxXLSXColumns},
+		{"ZZZZZZZZZZZZZZZZ1", maxXLSXColumns},
+		{"", -1},
+		{"12", -1},
+	}
+	for _, tt := range tests {
+		if got := xlsxColumnIndex(tt.ref); got != tt.want {
+			t.Errorf("xlsxColumnIndex(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}